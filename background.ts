@@ -1583,6 +1583,8 @@ class BackgroundCommandWorker implements CommandWorkerInterface {
         const { enabled, list } = cfg.application.extensions.allowed;
 
         if (await extension.install(enabled ? list : undefined)) {
+          mainEvents.emit('extensions/update', image, 'install');
+
           return { status: 201 };
         } else {
           return { status: 204 };
@@ -1607,6 +1609,7 @@ class BackgroundCommandWorker implements CommandWorkerInterface {
       try {
         if (await extension.uninstall()) {
           window.send('ok:extensions/uninstall', image);
+          mainEvents.emit('extensions/update', image, 'uninstall');
 
           return { status: 201 };
         } else {
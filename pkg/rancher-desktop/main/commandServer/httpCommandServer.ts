@@ -6,7 +6,7 @@ import { URL } from 'url';
 import express from 'express';
 import _ from 'lodash';
 
-import { State } from '@pkg/backend/backend';
+import { State, VMBackend } from '@pkg/backend/backend';
 import type { Settings } from '@pkg/config/settings';
 import type { TransientSettings } from '@pkg/config/transientSettings';
 import type { DiagnosticsResultCollection } from '@pkg/main/diagnostics/diagnostics';
@@ -30,6 +30,16 @@ export interface BackendState {
   locked:  boolean,
 }
 
+/**
+ * An event sent on the /v1/events stream. Each event is written as a single
+ * line of JSON, with the time at which the event was sent added.
+ */
+export type APIEvent =
+  | { type: 'backend-state', vmState: State, locked: boolean }
+  | { type: 'lock', locked: boolean, action?: string }
+  | { type: 'settings' }
+  | { type: 'extension', id: string, action: 'install' | 'uninstall' };
+
 export interface ServerState {
   user:     string;
   password: string;
@@ -63,6 +73,9 @@ export class HttpCommandServer {
   };
 
   protected commandWorker: CommandWorkerInterface;
+  // Responses for open /v1/events streams; they are ended when the server is
+  // closed, as they would otherwise keep it open.
+  protected eventStreams = new Set<express.Response>();
 
   protected dispatchTable: Record<HttpMethod, Record<string, readonly [number, DispatchFunctionType]>> = _.merge(
     {
@@ -75,6 +88,7 @@ export class HttpCommandServer {
         '/v1/settings/locked':       [0, this.listLockedSettings],
         '/v1/transient_settings':    [0, this.listTransientSettings],
         '/v1/backend_state':         [1, this.getBackendState],
+        '/v1/events':                [1, this.streamEvents],
       },
       post: { '/v1/diagnostic_checks': [0, this.diagnosticRunChecks] },
       put:  {
@@ -667,6 +681,10 @@ export class HttpCommandServer {
 
   closeServer() {
     this.server.close();
+    for (const response of this.eventStreams) {
+      response.end();
+    }
+    this.eventStreams.clear();
   }
 
   protected listTransientSettings(request: express.Request, response: express.Response, context: commandContext): Promise<void> {
@@ -774,6 +792,59 @@ export class HttpCommandServer {
     return Promise.resolve();
   }
 
+  /**
+   * Stream changes to the backend state, the backend lock, the settings, and
+   * the installed extensions, as newline-delimited JSON. The current backend
+   * state is always sent first; the response is kept open until the client
+   * disconnects or the server shuts down.
+   */
+  protected async streamEvents(request: express.Request, response: express.Response, context: commandContext): Promise<void> {
+    let { vmState, locked } = await this.commandWorker.getBackendState();
+    const send = (event: APIEvent) => {
+      response.write(`${ JSON.stringify({ ...event, time: new Date().toISOString() }) }\n`);
+    };
+    const sendBackendState = () => send({
+      type: 'backend-state', vmState, locked,
+    });
+    const onStateChange = (mgr: VMBackend) => {
+      if (mgr.state !== vmState) {
+        vmState = mgr.state;
+        sendBackendState();
+      }
+    };
+    const onLockChange = (backendIsLocked: string, action?: string) => {
+      const newLocked = !!backendIsLocked;
+
+      if (newLocked !== locked) {
+        locked = newLocked;
+        send({
+          type: 'lock', locked, action,
+        });
+      }
+    };
+    const onSettingsChange = () => send({ type: 'settings' });
+    const onExtensionChange = (id: string, action: 'install' | 'uninstall') => send({
+      type: 'extension', id, action,
+    });
+
+    console.debug('GET events: streaming 200');
+    response.status(200).type('application/x-ndjson').set('Cache-Control', 'no-cache');
+    response.flushHeaders();
+    sendBackendState();
+    mainEvents.on('k8s-check-state', onStateChange);
+    mainEvents.on('backend-locked-update', onLockChange);
+    mainEvents.on('settings-update', onSettingsChange);
+    mainEvents.on('extensions/update', onExtensionChange);
+    this.eventStreams.add(response);
+    request.on('close', () => {
+      mainEvents.off('k8s-check-state', onStateChange);
+      mainEvents.off('backend-locked-update', onLockChange);
+      mainEvents.off('settings-update', onSettingsChange);
+      mainEvents.off('extensions/update', onExtensionChange);
+      this.eventStreams.delete(response);
+    });
+  }
+
   protected async setBackendState(request: express.Request, response: express.Response, context: commandContext): Promise<void> {
     let result = 'received backend state';
     let statusCode = 202;
@@ -125,6 +125,13 @@ interface MainEventNames {
    */
   'extensions/ui/uninstall'(id: string): void;
 
+  /**
+   * Emitted after an extension has been installed or uninstalled.
+   * @param id The ID of the extension.
+   * @param action Whether the extension was installed or uninstalled.
+   */
+  'extensions/update'(id: string, action: 'install' | 'uninstall'): void;
+
   /**
    * Emitted on application quit; this is used to shut down extensions.
    */
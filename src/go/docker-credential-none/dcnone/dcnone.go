@@ -3,6 +3,14 @@
 // ~/.docker/plaintext-credentials.config.json
 // in the `auths` section
 // as `ServerURL: auth : base64Encode(Username + ":" + Secret)`
+//
+// Alternatively, when a key file or a passphrase is configured (see
+// encrypted.go), credentials are encrypted at rest in
+// ~/.docker/encrypted-credentials.json
+//
+// Entries may have an expiry time, repository path prefixes and a note
+// (see scope.go); lookups use the entry with the longest matching prefix,
+// or only the unscoped entry of the host when only the host is given.
 
 package dcnone
 
@@ -20,13 +28,18 @@ const configFileName = "plaintext-credentials.config.json"
 
 const VERSION = "0.6.4"
 
-// DCNone handles secrets using HOME/.docker/plaintext-credentials.config.json as a store.
+// DCNone handles secrets using HOME/.docker/plaintext-credentials.config.json as a store,
+// or HOME/.docker/encrypted-credentials.json if encryption is configured.
 type DCNone struct{}
 
+// plaintextStore is the default store, HOME/.docker/plaintext-credentials.config.json.
+type plaintextStore struct{}
+
 var configFile string
 
 func init() {
 	configFile = filepath.Join(dockerconfig.Dir(), configFileName)
+	encryptedFile = filepath.Join(dockerconfig.Dir(), encryptedFileName)
 	credentials.Name = "docker-credential-none"
 	credentials.Package = "github.com/rancher-sandbox/rancher-desktop/src/go/docker-credential-none"
 	credentials.Version = VERSION
@@ -34,15 +47,55 @@ func init() {
 
 // Add stores a new credentials or updates an existing one.
 func (p DCNone) Add(creds *credentials.Credentials) error {
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	return store.Add(creds)
+}
+
+// Delete removes credentials from the store.
+func (p DCNone) Delete(serverURL string) error {
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	return store.Delete(serverURL)
+}
+
+// Get returns the username and secret to use for a given registry server URL.
+func (p DCNone) Get(serverURL string) (string, string, error) {
+	store, err := openStore()
+	if err != nil {
+		return "", "", err
+	}
+	return store.Get(serverURL)
+}
+
+// List returns the stored URLs and corresponding usernames for a given credentials label
+func (p DCNone) List() (map[string]string, error) {
+	store, err := openStore()
+	if err != nil {
+		return map[string]string{}, err
+	}
+	return store.List()
+}
+
+func (p plaintextStore) Add(creds *credentials.Credentials) error {
 	var auths map[string]any
 
 	if creds == nil {
 		return errors.New("missing credentials")
 	}
+	metadata, err := metadataFromEnv()
+	if err != nil {
+		return err
+	}
 	config, err := getParsedConfig()
 	if err != nil {
 		return err
 	}
+	removeExpiredEntries(&config)
 	authsInterface, ok := config["auths"]
 	if ok {
 		auths, ok = authsInterface.(map[string]any)
@@ -54,12 +107,11 @@ func (p DCNone) Add(creds *credentials.Credentials) error {
 	}
 	payload := fmt.Sprintf("%s:%s", creds.Username, creds.Secret)
 	encoded := base64.URLEncoding.EncodeToString([]byte(payload))
-	auths[creds.ServerURL] = map[string]string{"auth": encoded}
+	auths[creds.ServerURL] = plaintextEntry(encoded, metadata)
 	return saveParsedConfig(&config)
 }
 
-// Delete removes credentials from the store.
-func (p DCNone) Delete(serverURL string) error {
+func (p plaintextStore) Delete(serverURL string) error {
 	if serverURL == "" {
 		return errors.New("missing server url")
 	}
@@ -87,8 +139,7 @@ func (p DCNone) Delete(serverURL string) error {
 	return saveParsedConfig(&config)
 }
 
-// Get returns the username and secret to use for a given registry server URL.
-func (p DCNone) Get(serverURL string) (string, string, error) {
+func (p plaintextStore) Get(serverURL string) (string, string, error) {
 	if serverURL == "" {
 		return "", "", errors.New("missing server url")
 	}
@@ -96,20 +147,33 @@ func (p DCNone) Get(serverURL string) (string, string, error) {
 	if err != nil {
 		return "", "", err
 	}
-	username, secret, err := getRecordForServerURL(&config, serverURL)
+	if removeExpiredEntries(&config) {
+		if err := saveParsedConfig(&config); err != nil {
+			return "", "", err
+		}
+	}
+	key, ok := matchServerURL(serverURL, plaintextMetadata(&config))
+	if !ok {
+		return "", "", credentials.NewErrCredentialsNotFound()
+	}
+	username, secret, err := getRecordForServerURL(&config, key)
 	if err != nil {
 		return "", "", err
 	}
 	return username, secret, nil
 }
 
-// List returns the stored URLs and corresponding usernames for a given credentials label
-func (p DCNone) List() (map[string]string, error) {
+func (p plaintextStore) List() (map[string]string, error) {
 	entries := make(map[string]string)
 	config, err := getParsedConfig()
 	if err != nil {
 		return entries, err
 	}
+	if removeExpiredEntries(&config) {
+		if err := saveParsedConfig(&config); err != nil {
+			return entries, err
+		}
+	}
 	authsInterface, ok := config["auths"]
 	if ok {
 		auths, ok := authsInterface.(map[string]any)
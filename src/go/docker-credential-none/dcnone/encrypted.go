@@ -0,0 +1,410 @@
+package dcnone
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker-credential-helpers/credentials"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+const encryptedFileName = "encrypted-credentials.json"
+
+// The encryption key is read from the key file if it is set; otherwise it
+// is derived from the passphrase, which is either given directly or printed
+// by a command (e.g. `pass show docker-credential-none` or a keyring agent).
+const (
+	keyFileEnv           = "DOCKER_CREDENTIAL_NONE_KEY_FILE"
+	passphraseEnv        = "DOCKER_CREDENTIAL_NONE_PASSPHRASE"
+	passphraseCommandEnv = "DOCKER_CREDENTIAL_NONE_PASSPHRASE_COMMAND"
+)
+
+const (
+	keySize          = 32
+	nonceSize        = 24
+	saltSize         = 16
+	encryptedVersion = 1
+	// scrypt parameters recommended for interactive logins.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var encryptedFile string
+
+// encryptedFileContents is the on-disk format of the encrypted store; Data
+// is the NaCl secretbox of the JSON-encoded encryptedAuths.
+type encryptedFileContents struct {
+	Version int `json:"version"`
+	// Salt is only set when the key is derived from a passphrase.
+	Salt  []byte `json:"salt,omitempty"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+type encryptedAuths struct {
+	Auths map[string]encryptedRecord `json:"auths"`
+}
+
+type encryptedRecord struct {
+	Username string `json:"username"`
+	Secret   string `json:"secret"`
+	entryMetadata
+}
+
+// encryptedStore keeps credentials encrypted at rest in HOME/.docker/encrypted-credentials.json.
+type encryptedStore struct {
+	keyFile    string
+	passphrase string
+}
+
+// openStore returns the encrypted store if a key file or a passphrase is
+// configured, and the plaintext store otherwise.
+func openStore() (credentials.Helper, error) {
+	if keyFile := os.Getenv(keyFileEnv); keyFile != "" {
+		return encryptedStore{keyFile: keyFile}, nil
+	}
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return encryptedStore{passphrase: passphrase}, nil
+	}
+	if command := os.Getenv(passphraseCommandEnv); command != "" {
+		var stderr bytes.Buffer
+		cmd := exec.Command("/bin/sh", "-c", command)
+		cmd.Stderr = &stderr
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("running %s: %w: %s", passphraseCommandEnv, err, strings.TrimSpace(stderr.String()))
+		}
+		passphrase := strings.TrimRight(string(output), "\r\n")
+		if passphrase == "" {
+			return nil, fmt.Errorf("%s returned an empty passphrase", passphraseCommandEnv)
+		}
+		return encryptedStore{passphrase: passphrase}, nil
+	}
+	// Don't silently fall back to plaintext once credentials are encrypted.
+	if _, err := os.Stat(encryptedFile); err == nil {
+		return nil, fmt.Errorf("credentials are encrypted in %s: set %s, %s or %s",
+			encryptedFile, keyFileEnv, passphraseEnv, passphraseCommandEnv)
+	}
+	return plaintextStore{}, nil
+}
+
+// Migrate moves the credentials of the plaintext store to the encrypted
+// store, and returns the number of entries moved. Entries that can't be
+// decoded are left in place. The encrypted store also does this whenever
+// it is changed, so this only needs to be run to remove the plaintext
+// credentials right away.
+func Migrate() (int, error) {
+	store, err := openStore()
+	if err != nil {
+		return 0, err
+	}
+	encrypted, ok := store.(encryptedStore)
+	if !ok {
+		return 0, fmt.Errorf("migrating credentials requires %s, %s or %s", keyFileEnv, passphraseEnv, passphraseCommandEnv)
+	}
+	contents, key, err := encrypted.load()
+	if err != nil {
+		return 0, err
+	}
+	return encrypted.migrate(contents, key)
+}
+
+// migrate moves the credentials of the plaintext store into contents, and
+// saves both stores.
+func (s encryptedStore) migrate(contents *encryptedAuths, key *storeKey) (int, error) {
+	config, records, err := plaintextRecords()
+	if err != nil {
+		return 0, err
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+	maps.Copy(contents.Auths, records)
+	if err := s.save(contents, key); err != nil {
+		return 0, err
+	}
+	auths := config["auths"].(map[string]any)
+	for serverURL := range records {
+		delete(auths, serverURL)
+	}
+	if err := saveParsedConfig(&config); err != nil {
+		return 0, fmt.Errorf("removing migrated credentials from %s: %w", configFile, err)
+	}
+	return len(records), nil
+}
+
+// plaintextRecords returns the parsed plaintext store, along with its
+// entries that can be decoded.
+func plaintextRecords() (dockerConfigType, map[string]encryptedRecord, error) {
+	config, err := getParsedConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	records := map[string]encryptedRecord{}
+	auths, ok := config["auths"].(map[string]any)
+	if !ok {
+		return config, records, nil
+	}
+	metadata := plaintextMetadata(&config)
+	for serverURL := range auths {
+		username, secret, err := getRecordForServerURL(&config, serverURL)
+		if err != nil {
+			continue
+		}
+		records[serverURL] = encryptedRecord{Username: username, Secret: secret, entryMetadata: metadata[serverURL]}
+	}
+	return config, records, nil
+}
+
+func (s encryptedStore) Add(creds *credentials.Credentials) error {
+	if creds == nil {
+		return errors.New("missing credentials")
+	}
+	metadata, err := metadataFromEnv()
+	if err != nil {
+		return err
+	}
+	contents, key, err := s.loadMigrated()
+	if err != nil {
+		return err
+	}
+	contents.removeExpired()
+	contents.Auths[creds.ServerURL] = encryptedRecord{Username: creds.Username, Secret: creds.Secret, entryMetadata: metadata}
+	return s.save(contents, key)
+}
+
+func (s encryptedStore) Delete(serverURL string) error {
+	if serverURL == "" {
+		return errors.New("missing server url")
+	}
+	contents, key, err := s.loadMigrated()
+	if err != nil {
+		return err
+	}
+	if _, ok := contents.Auths[serverURL]; !ok {
+		// Not an error if there's no URL
+		return nil
+	}
+	delete(contents.Auths, serverURL)
+	return s.save(contents, key)
+}
+
+func (s encryptedStore) Get(serverURL string) (string, string, error) {
+	if serverURL == "" {
+		return "", "", errors.New("missing server url")
+	}
+	contents, err := s.loadWithPlaintext()
+	if err != nil {
+		return "", "", err
+	}
+	metadata := make(map[string]entryMetadata, len(contents.Auths))
+	for key, record := range contents.Auths {
+		metadata[key] = record.entryMetadata
+	}
+	key, ok := matchServerURL(serverURL, metadata)
+	if !ok {
+		return "", "", credentials.NewErrCredentialsNotFound()
+	}
+	record := contents.Auths[key]
+	if record.Username == "" {
+		return "", "", credentials.NewErrCredentialsMissingUsername()
+	}
+	return record.Username, record.Secret, nil
+}
+
+func (s encryptedStore) List() (map[string]string, error) {
+	entries := make(map[string]string)
+	contents, err := s.loadWithPlaintext()
+	if err != nil {
+		return entries, err
+	}
+	for serverURL, record := range contents.Auths {
+		if record.Username != "" {
+			entries[serverURL] = record.Username
+		}
+	}
+	return entries, nil
+}
+
+// removeExpired removes the expired entries, and returns whether there were any.
+func (a *encryptedAuths) removeExpired() bool {
+	removed := false
+	for serverURL, record := range a.Auths {
+		if record.expired() {
+			delete(a.Auths, serverURL)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// loadMigrated is like load, but first moves the credentials that were
+// added to the plaintext store before encryption was configured, so that
+// they keep working. It is only used before changing the store, so that
+// reading credentials never writes to either store.
+func (s encryptedStore) loadMigrated() (*encryptedAuths, *storeKey, error) {
+	contents, key, err := s.load()
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := s.migrate(contents, key); err != nil {
+		return nil, nil, fmt.Errorf("migrating plaintext credentials: %w", err)
+	}
+	return contents, key, nil
+}
+
+// loadWithPlaintext is like load, but also includes the credentials that
+// were added to the plaintext store before encryption was configured; they
+// are migrated on the next change. Expired entries of the encrypted store
+// are still removed, as for the plaintext store.
+func (s encryptedStore) loadWithPlaintext() (*encryptedAuths, error) {
+	contents, key, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	if contents.removeExpired() {
+		if err := s.save(contents, key); err != nil {
+			return nil, err
+		}
+	}
+	_, records, err := plaintextRecords()
+	if err != nil {
+		return nil, fmt.Errorf("reading plaintext credentials: %w", err)
+	}
+	maps.Copy(contents.Auths, records)
+	contents.removeExpired()
+	return contents, nil
+}
+
+// storeKey is the encryption key of an existing store, kept between load
+// and save so that the passphrase is only derived once.
+type storeKey struct {
+	salt []byte
+	key  *[keySize]byte
+}
+
+// load decrypts the store, returning an empty one if it doesn't exist yet,
+// along with its key; the key is nil if the store doesn't exist.
+func (s encryptedStore) load() (*encryptedAuths, *storeKey, error) {
+	contents := &encryptedAuths{Auths: map[string]encryptedRecord{}}
+	raw, err := os.ReadFile(encryptedFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return contents, nil, nil
+		}
+		return nil, nil, err
+	}
+	var file encryptedFileContents
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, nil, fmt.Errorf("reading encrypted credentials %s: %w", encryptedFile, err)
+	}
+	if file.Version != encryptedVersion {
+		return nil, nil, fmt.Errorf("reading encrypted credentials %s: unsupported version %d", encryptedFile, file.Version)
+	}
+	if len(file.Nonce) != nonceSize {
+		return nil, nil, fmt.Errorf("reading encrypted credentials %s: invalid nonce", encryptedFile)
+	}
+	key, err := s.key(file.Salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, ok := secretbox.Open(nil, file.Data, (*[nonceSize]byte)(file.Nonce), key)
+	if !ok {
+		return nil, nil, fmt.Errorf("decrypting credentials %s: wrong key or passphrase", encryptedFile)
+	}
+	if err := json.Unmarshal(plaintext, contents); err != nil {
+		return nil, nil, fmt.Errorf("reading encrypted credentials %s: %w", encryptedFile, err)
+	}
+	if contents.Auths == nil {
+		contents.Auths = map[string]encryptedRecord{}
+	}
+	return contents, &storeKey{salt: file.Salt, key: key}, nil
+}
+
+// save encrypts the store with a new nonce. The key returned by load is
+// reused if there is one; otherwise, a new one is read or derived with a
+// new salt.
+func (s encryptedStore) save(contents *encryptedAuths, key *storeKey) error {
+	if key == nil {
+		var salt []byte
+		if s.keyFile == "" {
+			salt = make([]byte, saltSize)
+			if _, err := rand.Read(salt); err != nil {
+				return err
+			}
+		}
+		derived, err := s.key(salt)
+		if err != nil {
+			return err
+		}
+		key = &storeKey{salt: salt, key: derived}
+	}
+	plaintext, err := json.Marshal(contents)
+	if err != nil {
+		return err
+	}
+	var nonce [nonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+	file := encryptedFileContents{
+		Version: encryptedVersion,
+		Salt:    key.salt,
+		Nonce:   nonce[:],
+		Data:    secretbox.Seal(nil, plaintext, &nonce, key.key),
+	}
+	raw, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	scratchFile, err := os.CreateTemp(filepath.Dir(encryptedFile), "tmpcredentials.json")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(scratchFile.Name(), raw, 0o600)
+	scratchFile.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(scratchFile.Name(), encryptedFile)
+}
+
+// key returns the encryption key, read from the key file or derived from
+// the passphrase with the given salt.
+func (s encryptedStore) key(salt []byte) (*[keySize]byte, error) {
+	var key [keySize]byte
+	if s.keyFile != "" {
+		raw, err := os.ReadFile(s.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading key file: %w", err)
+		}
+		// Accept both raw keys (e.g. `head -c 32 /dev/urandom`) and base64-encoded ones.
+		if len(raw) != keySize {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+			if err != nil || len(decoded) != keySize {
+				return nil, fmt.Errorf("key file %s must contain %d bytes, raw or base64-encoded", s.keyFile, keySize)
+			}
+			raw = decoded
+		}
+		copy(key[:], raw)
+		return &key, nil
+	}
+	derived, err := scrypt.Key([]byte(s.passphrase), salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+	copy(key[:], derived)
+	return &key, nil
+}
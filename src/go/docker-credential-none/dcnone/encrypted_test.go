@@ -0,0 +1,220 @@
+package dcnone
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker-credential-helpers/credentials"
+)
+
+// useTempStores points the stores at a temporary directory, and clears the
+// encryption settings.
+func useTempStores(t *testing.T) string {
+	dir := t.TempDir()
+	savedConfigFile, savedEncryptedFile := configFile, encryptedFile
+	configFile = filepath.Join(dir, configFileName)
+	encryptedFile = filepath.Join(dir, encryptedFileName)
+	t.Cleanup(func() {
+		configFile, encryptedFile = savedConfigFile, savedEncryptedFile
+	})
+	t.Setenv(keyFileEnv, "")
+	t.Setenv(passphraseEnv, "")
+	t.Setenv(passphraseCommandEnv, "")
+	return dir
+}
+
+func writeKeyFile(t *testing.T, dir string) string {
+	keyFile := filepath.Join(dir, "credentials.key")
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0x5a}, keySize))
+	if err := os.WriteFile(keyFile, []byte(key+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return keyFile
+}
+
+func TestEncryptedStore(t *testing.T) {
+	dir := useTempStores(t)
+	t.Setenv(keyFileEnv, writeKeyFile(t, dir))
+	helper := DCNone{}
+
+	const server1 = "https://registry.example.com"
+	const server2 = "https://ghcr.io"
+	for _, server := range []string{server1, server2} {
+		err := helper.Add(&credentials.Credentials{ServerURL: server, Username: "user", Secret: "hunter2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	contents, err := os.ReadFile(encryptedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(contents, []byte("hunter2")) || bytes.Contains(contents, []byte(server1)) {
+		t.Fatalf("credentials are not encrypted: %s", contents)
+	}
+	if _, err := os.Stat(configFile); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("plaintext store should not be written, got %v", err)
+	}
+
+	credsList, err := helper.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credsList) != 2 || credsList[server1] != "user" || credsList[server2] != "user" {
+		t.Fatalf("unexpected list: %v", credsList)
+	}
+	username, secret, err := helper.Get(server1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if username != "user" || secret != "hunter2" {
+		t.Fatalf("unexpected credentials: %s %s", username, secret)
+	}
+	if err := helper.Delete(server1); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = helper.Get(server1)
+	if !errors.Is(err, credentials.NewErrCredentialsNotFound()) {
+		t.Fatalf("expected not found error for deleted server, got %v", err)
+	}
+
+	// Once credentials are encrypted, a missing key must not fall back to plaintext.
+	t.Setenv(keyFileEnv, "")
+	if _, err := helper.List(); err == nil {
+		t.Fatal("expected an error without a key")
+	}
+}
+
+func TestEncryptedStorePassphrase(t *testing.T) {
+	useTempStores(t)
+	t.Setenv(passphraseCommandEnv, "echo correct horse")
+	helper := DCNone{}
+
+	err := helper.Add(&credentials.Credentials{ServerURL: "https://ghcr.io", Username: "user", Secret: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(passphraseCommandEnv, "")
+	t.Setenv(passphraseEnv, "correct horse")
+	_, secret, err := helper.Get("https://ghcr.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != "token" {
+		t.Fatalf("unexpected secret: %s", secret)
+	}
+	t.Setenv(passphraseEnv, "battery staple")
+	if _, _, err := helper.Get("https://ghcr.io"); err == nil {
+		t.Fatal("expected an error with the wrong passphrase")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := useTempStores(t)
+	helper := DCNone{}
+	servers := []string{"https://registry.example.com", "https://ghcr.io"}
+	for _, server := range servers {
+		err := helper.Add(&credentials.Credentials{ServerURL: server, Username: "user", Secret: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Migrate(); err == nil {
+		t.Fatal("expected an error migrating without a key")
+	}
+
+	t.Setenv(keyFileEnv, writeKeyFile(t, dir))
+	count, err := Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(servers) {
+		t.Fatalf("expected %d migrated credentials, got %d", len(servers), count)
+	}
+	plaintext, err := plaintextStore{}.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plaintext) != 0 {
+		t.Fatalf("plaintext credentials should be removed, got %v", plaintext)
+	}
+	for _, server := range servers {
+		username, secret, err := helper.Get(server)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if username != "user" || secret != "secret" {
+			t.Fatalf("unexpected credentials for %s: %s %s", server, username, secret)
+		}
+	}
+}
+
+func TestEncryptedStoreMigratesPlaintext(t *testing.T) {
+	dir := useTempStores(t)
+	helper := DCNone{}
+	const plaintextServer = "https://registry.example.com"
+	err := helper.Add(&credentials.Credentials{ServerURL: plaintextServer, Username: "user", Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Credentials added before encryption was configured keep working.
+	t.Setenv(keyFileEnv, writeKeyFile(t, dir))
+	credsList, err := helper.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credsList) != 1 || credsList[plaintextServer] != "user" {
+		t.Fatalf("unexpected list: %v", credsList)
+	}
+	username, secret, err := helper.Get(plaintextServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if username != "user" || secret != "secret" {
+		t.Fatalf("unexpected credentials: %s %s", username, secret)
+	}
+	// Reading credentials doesn't write to either store.
+	plaintext, err := plaintextStore{}.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plaintext) != 1 {
+		t.Fatalf("plaintext credentials should only be migrated on changes, got %v", plaintext)
+	}
+	if _, err := os.Stat(encryptedFile); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("the encrypted store should not be created by reads: %v", err)
+	}
+
+	// They are migrated on the next change.
+	err = helper.Add(&credentials.Credentials{ServerURL: "https://other.example.com", Username: "other", Secret: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err = plaintextStore{}.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plaintext) != 0 {
+		t.Fatalf("plaintext credentials should be migrated, got %v", plaintext)
+	}
+	username, secret, err = helper.Get(plaintextServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if username != "user" || secret != "secret" {
+		t.Fatalf("unexpected migrated credentials: %s %s", username, secret)
+	}
+	contents, err := os.ReadFile(encryptedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(contents, []byte("secret")) {
+		t.Fatalf("migrated credentials are not encrypted: %s", contents)
+	}
+}
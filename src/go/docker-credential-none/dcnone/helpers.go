@@ -7,9 +7,10 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
-	dockerconfig "github.com/docker/cli/cli/config"
 	"github.com/docker/docker-credential-helpers/credentials"
 )
 
@@ -37,7 +38,7 @@ func saveParsedConfig(config *dockerConfigType) error {
 	if err != nil {
 		return err
 	}
-	scratchFile, err := os.CreateTemp(dockerconfig.Dir(), "tmpconfig.json")
+	scratchFile, err := os.CreateTemp(filepath.Dir(configFile), "tmpconfig.json")
 	if err != nil {
 		return err
 	}
@@ -79,3 +80,54 @@ func getRecordForServerURL(config *dockerConfigType, urlArg string) (string, str
 	}
 	return parts[0], parts[1], nil
 }
+
+// plaintextEntry returns the `auths` entry for the encoded credentials and
+// their metadata.
+func plaintextEntry(encoded string, metadata entryMetadata) map[string]any {
+	entry := map[string]any{"auth": encoded}
+	if !metadata.Expires.IsZero() {
+		entry["expires"] = metadata.Expires.Format(time.RFC3339)
+	}
+	if len(metadata.Prefixes) > 0 {
+		entry["prefixes"] = metadata.Prefixes
+	}
+	if metadata.Note != "" {
+		entry["note"] = metadata.Note
+	}
+	return entry
+}
+
+// plaintextMetadata returns the metadata of the `auths` entries, by server URL.
+func plaintextMetadata(config *dockerConfigType) map[string]entryMetadata {
+	entries := make(map[string]entryMetadata)
+	auths, ok := (*config)["auths"].(map[string]any)
+	if !ok {
+		return entries
+	}
+	for serverURL, entry := range auths {
+		var metadata entryMetadata
+		// Entries without valid metadata are kept, and never expire.
+		if contents, err := json.Marshal(entry); err == nil {
+			_ = json.Unmarshal(contents, &metadata)
+		}
+		entries[serverURL] = metadata
+	}
+	return entries
+}
+
+// removeExpiredEntries removes the expired `auths` entries, and returns
+// whether there were any.
+func removeExpiredEntries(config *dockerConfigType) bool {
+	auths, ok := (*config)["auths"].(map[string]any)
+	if !ok {
+		return false
+	}
+	removed := false
+	for serverURL, metadata := range plaintextMetadata(config) {
+		if metadata.expired() {
+			delete(auths, serverURL)
+			removed = true
+		}
+	}
+	return removed
+}
@@ -0,0 +1,111 @@
+package dcnone
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Optional metadata of stored credentials, read from the environment when
+// they are stored, e.g.
+//
+//	DOCKER_CREDENTIAL_NONE_EXPIRES=720h DOCKER_CREDENTIAL_NONE_PREFIXES=orgA docker login ghcr.io
+const (
+	// expiresEnv is either an RFC 3339 time, or a duration from now.
+	expiresEnv = "DOCKER_CREDENTIAL_NONE_EXPIRES"
+	// prefixesEnv is a comma-separated list of repository path prefixes.
+	prefixesEnv = "DOCKER_CREDENTIAL_NONE_PREFIXES"
+	noteEnv     = "DOCKER_CREDENTIAL_NONE_NOTE"
+)
+
+// entryMetadata is the optional metadata of a credentials entry.
+type entryMetadata struct {
+	// Expires is the time after which the entry is removed.
+	Expires time.Time `json:"expires,omitzero"`
+	// Prefixes restricts the entry to the repositories under these paths.
+	Prefixes []string `json:"prefixes,omitempty"`
+	Note     string   `json:"note,omitempty"`
+}
+
+// now is overridden in tests.
+var now = time.Now
+
+func metadataFromEnv() (entryMetadata, error) {
+	var metadata entryMetadata
+	if value := os.Getenv(expiresEnv); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			metadata.Expires = now().Add(duration).UTC().Truncate(time.Second)
+		} else if expires, err := time.Parse(time.RFC3339, value); err == nil {
+			metadata.Expires = expires
+		} else {
+			return metadata, fmt.Errorf("invalid %s %q: not a duration or an RFC 3339 time", expiresEnv, value)
+		}
+	}
+	for prefix := range strings.SplitSeq(os.Getenv(prefixesEnv), ",") {
+		if prefix = strings.Trim(strings.TrimSpace(prefix), "/"); prefix != "" {
+			metadata.Prefixes = append(metadata.Prefixes, prefix)
+		}
+	}
+	metadata.Note = os.Getenv(noteEnv)
+	return metadata, nil
+}
+
+func (m entryMetadata) expired() bool {
+	return !m.Expires.IsZero() && !now().Before(m.Expires)
+}
+
+// splitServerURL returns the host (including the port) and the repository
+// path of a server URL, without the scheme and the surrounding slashes.
+func splitServerURL(serverURL string) (string, string) {
+	if _, rest, ok := strings.Cut(serverURL, "://"); ok {
+		serverURL = rest
+	}
+	host, path, _ := strings.Cut(serverURL, "/")
+	return strings.ToLower(host), strings.Trim(path, "/")
+}
+
+// hasPathPrefix returns whether path is prefix, or is under it.
+func hasPathPrefix(path, prefix string) bool {
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// matchServerURL returns the key of the entry to use for serverURL: among
+// the entries of the same host, the one whose path prefix is the longest
+// one matching the path of serverURL. The prefixes of an entry are those of
+// its metadata, joined to the path of its key; a key without a path and
+// without prefixes matches every repository of the host.
+//
+// Docker and nerdctl only pass the host to the helper, so a serverURL
+// without a path only matches the unscoped entry of the host; the scoped
+// entries are never returned for it, as that would hand out a token for
+// the wrong repositories.
+func matchServerURL(serverURL string, entries map[string]entryMetadata) (string, bool) {
+	host, path := splitServerURL(serverURL)
+	best, bestLength := "", -1
+	for key, metadata := range entries {
+		keyHost, keyPath := splitServerURL(key)
+		if keyHost != host {
+			continue
+		}
+		prefixes := []string{keyPath}
+		if len(metadata.Prefixes) > 0 {
+			prefixes = prefixes[:0]
+			for _, prefix := range metadata.Prefixes {
+				prefixes = append(prefixes, strings.Trim(keyPath+"/"+prefix, "/"))
+			}
+		}
+		for _, prefix := range prefixes {
+			if !hasPathPrefix(path, prefix) {
+				continue
+			}
+			// Ties go to the exact match, then to the smallest key, so that
+			// the result is stable.
+			length := len(prefix)
+			if length > bestLength || (length == bestLength && (key == serverURL || (best != serverURL && key < best))) {
+				best, bestLength = key, length
+			}
+		}
+	}
+	return best, bestLength >= 0
+}
@@ -0,0 +1,141 @@
+package dcnone
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker-credential-helpers/credentials"
+)
+
+func TestMatchServerURL(t *testing.T) {
+	entries := map[string]entryMetadata{
+		"ghcr.io":                       {},
+		"https://ghcr.io/orgA":          {},
+		"ghcr.io/orgB":                  {},
+		"quay.io":                       {Prefixes: []string{"team", "other/sub"}},
+		"https://index.docker.io/v1/":   {},
+		"registry.example.com:5000/a/b": {},
+	}
+	tests := []struct {
+		serverURL string
+		expected  string
+	}{
+		{"ghcr.io", "ghcr.io"},
+		{"https://ghcr.io/orgA/image", "https://ghcr.io/orgA"},
+		{"ghcr.io/orgA", "https://ghcr.io/orgA"},
+		{"ghcr.io/orgB/nested/image", "ghcr.io/orgB"},
+		{"ghcr.io/orgBis/image", "ghcr.io"},
+		{"quay.io/team/image", "quay.io"},
+		{"quay.io/other/sub", "quay.io"},
+		{"quay.io/other", ""},
+		{"quay.io", ""},
+		{"https://index.docker.io/v1/", "https://index.docker.io/v1/"},
+		{"registry.example.com:5000/a/b/c", "registry.example.com:5000/a/b"},
+		{"registry.example.com:5000/a", ""},
+		{"registry.example.com:5000", ""},
+		{"registry.example.com/a/b", ""},
+	}
+	for _, tt := range tests {
+		key, ok := matchServerURL(tt.serverURL, entries)
+		if ok != (tt.expected != "") || key != tt.expected {
+			t.Errorf("matching %s: expected %q, got %q (%v)", tt.serverURL, tt.expected, key, ok)
+		}
+	}
+}
+
+func TestScopedCredentials(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		name := "plaintext"
+		if encrypted {
+			name = "encrypted"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir := useTempStores(t)
+			if encrypted {
+				t.Setenv(keyFileEnv, writeKeyFile(t, dir))
+			}
+			current := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			now = func() time.Time { return current }
+			t.Cleanup(func() { now = time.Now })
+			helper := DCNone{}
+
+			add := func(serverURL, secret, expires, prefixes string) {
+				t.Setenv(expiresEnv, expires)
+				t.Setenv(prefixesEnv, prefixes)
+				t.Setenv(noteEnv, "added by the test")
+				err := helper.Add(&credentials.Credentials{ServerURL: serverURL, Username: "user", Secret: secret})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			get := func(serverURL string) string {
+				_, secret, err := helper.Get(serverURL)
+				if errors.Is(err, credentials.NewErrCredentialsNotFound()) {
+					return ""
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				return secret
+			}
+
+			add("ghcr.io", "default", "", "")
+			add("ghcr.io/orgA", "tokenA", "1h", "")
+			add("ghcr.io/orgB", "tokenB", "2026-01-01T03:00:00Z", "")
+			add("quay.io", "team", "", "team, /other/")
+
+			add("registry.example.com", "temporary", "1h", "")
+
+			// Clients only pass the host of the registry to the helper.
+			if secret := get("ghcr.io"); secret != "default" {
+				t.Errorf("expected the unscoped token, got %q", secret)
+			}
+			if secret := get("quay.io"); secret != "" {
+				t.Errorf("expected no token for a host with only scoped entries, got %q", secret)
+			}
+			if secret := get("registry.example.com"); secret != "temporary" {
+				t.Errorf("expected the temporary token, got %q", secret)
+			}
+			if secret := get("docker.io"); secret != "" {
+				t.Errorf("expected no token for an unknown host, got %q", secret)
+			}
+
+			current = current.Add(2 * time.Hour)
+			if secret := get("registry.example.com"); secret != "" {
+				t.Errorf("expected the expired entry to be removed, got %q", secret)
+			}
+			if secret := get("ghcr.io"); secret != "default" {
+				t.Errorf("expected the unscoped token once the orgA one expired, got %q", secret)
+			}
+
+			current = current.Add(2 * time.Hour)
+			credsList, err := helper.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := credsList["ghcr.io/orgB"]; ok || len(credsList) != 2 {
+				t.Errorf("expected the expired entries to be removed, got %v", credsList)
+			}
+
+			// The expired entries are garbage-collected from the store itself.
+			var stored int
+			if encrypted {
+				contents, _, err := encryptedStore{keyFile: filepath.Join(dir, "credentials.key")}.load()
+				if err != nil {
+					t.Fatal(err)
+				}
+				stored = len(contents.Auths)
+			} else {
+				config, err := getParsedConfig()
+				if err != nil {
+					t.Fatal(err)
+				}
+				stored = len(config["auths"].(map[string]any))
+			}
+			if stored != 2 {
+				t.Errorf("expected 2 stored entries, got %d", stored)
+			}
+		})
+	}
+}
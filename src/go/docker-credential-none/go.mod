@@ -5,6 +5,7 @@ go 1.25.0
 require (
 	github.com/docker/cli v29.6.0+incompatible
 	github.com/docker/docker-credential-helpers v0.9.8
+	golang.org/x/crypto v0.53.0
 )
 
 require (
@@ -1,11 +1,25 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/docker/docker-credential-helpers/credentials"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/docker-credential-none/dcnone"
 )
 
 func main() {
+	// `migrate` isn't part of the credential helper protocol; it moves the
+	// plaintext credentials to the encrypted store.
+	if len(os.Args) == 2 && os.Args[1] == "migrate" {
+		count, err := dcnone.Migrate()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("migrated %d credentials\n", count)
+		return
+	}
 	credentials.Serve(dcnone.DCNone{})
 }
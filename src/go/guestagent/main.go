@@ -26,6 +26,7 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -36,9 +37,12 @@ import (
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/containerd"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/docker"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/forwarder"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/hosts"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/iptables"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/kube"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/policy"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/procnet"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/status"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/tracker"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
 )
@@ -50,6 +54,9 @@ const (
 	socketRetryTimeout     = 2 * time.Minute
 	dockerSocketFile       = "/var/run/docker.sock"
 	containerdSocketFile   = "/run/k3s/containerd/containerd.sock"
+	statusSocketFile       = "/run/rancher-desktop-guestagent.sock"
+	stateFilePath          = "/var/lib/rancher-desktop-guestagent/portmappings.json"
+	policyFilePath         = "/etc/rancher-desktop/port-forwarding-policy.json"
 )
 
 func main() {
@@ -69,6 +76,17 @@ func main() {
 			"K8sAPI port number to forward to rancher-desktop wsl-proxy as a static portMapping event")
 		tapIfaceIP = flag.String("tap-interface-ip", "192.168.127.2",
 			"IP address for the tap interface eth0 in network namespace")
+		statusSock = flag.String("statusSock", statusSocketFile,
+			"file path for the socket serving the status API; empty to disable")
+		stateFile = flag.String("stateFile", stateFilePath,
+			"file path to persist exposed port mappings to, so that they can be reconciled on restart; empty to disable")
+		policyFile = flag.String("policyFile", policyFilePath,
+			"file path for the port forwarding policy; it is ignored if it does not exist")
+		remapPortRange = flag.String("remapPortRange", "",
+			"range of host ports, e.g. 49152-49999, to expose ports on when their host port is in use; empty to disable")
+		hostnameZones = flag.String("hostnameZones", "localhost",
+			"comma-separated DNS zones to publish Kubernetes hostnames within to the host switch DNS server, "+
+				"which only resolvers in the VM use; empty to disable")
 	)
 
 	// Setup logging with debug and trace levels
@@ -101,7 +119,8 @@ func main() {
 	if err := runAgent(
 		*enableContainerd, *enableDocker, *enableKubernetes,
 		*containerdSock, *configPath, *k8sServiceListenerAddr,
-		*adminInstall, *k8sAPIPort, *tapIfaceIP,
+		*adminInstall, *k8sAPIPort, *tapIfaceIP, *statusSock, *stateFile, *policyFile,
+		*remapPortRange, *hostnameZones,
 	); err != nil {
 		log.Fatal(err)
 	}
@@ -113,7 +132,8 @@ func runAgent(
 	enableContainerd, enableDocker, enableKubernetes bool,
 	containerdSock, configPath, k8sServiceListenerAddr string,
 	adminInstall bool,
-	k8sAPIPort, tapIfaceIP string,
+	k8sAPIPort, tapIfaceIP, statusSock, stateFile, policyFile string,
+	remapPortRange, hostnameZones string,
 ) error {
 	bindIP := net.ParseIP(tapIfaceIP)
 	if bindIP == nil {
@@ -133,10 +153,49 @@ func runAgent(
 		cancel()
 	}()
 
-	var portTracker tracker.Tracker
-
 	wslProxyForwarder := forwarder.NewWSLProxyForwarder(ctx, "/run/wsl-proxy.sock")
-	portTracker = tracker.NewAPITracker(ctx, wslProxyForwarder, tracker.GatewayBaseURL, tapIfaceIP, adminInstall)
+	portTracker := tracker.NewAPITracker(ctx, wslProxyForwarder, tracker.GatewayBaseURL, tapIfaceIP, adminInstall)
+	hostTracker := hosts.NewTracker()
+
+	if hostnameZones != "" {
+		hostTracker.SetPublisher(hosts.NewDNSPublisher(
+			forwarder.NewAPIForwarder(tracker.GatewayBaseURL), bindIP, strings.Split(hostnameZones, ",")))
+	}
+
+	if policyFile != "" {
+		portPolicy, err := policy.Load(policyFile)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			log.Debugf("no port forwarding policy at %s, all ports are forwarded", policyFile)
+		case err != nil:
+			// Forwarding everything would defeat the purpose of the policy.
+			return err
+		default:
+			log.Infof("loaded port forwarding policy from %s with %d rules", policyFile, len(portPolicy.Rules))
+			portTracker.SetPolicy(portPolicy)
+		}
+	}
+
+	if remapPortRange != "" {
+		if err := portTracker.SetRemapPortRange(remapPortRange); err != nil {
+			return fmt.Errorf("invalid remapPortRange: %w", err)
+		}
+	}
+
+	if stateFile != "" {
+		// Clean up after a previous instance that did not shut down cleanly;
+		// failures here should not prevent port forwarding from working.
+		if err := portTracker.Reconcile(stateFile); err != nil {
+			log.Errorf("failed to reconcile port mappings from %s: %v", stateFile, err)
+		}
+	}
+
+	if statusSock != "" {
+		group.Go(func() error {
+			return status.NewServer(portTracker, hostTracker).Serve(ctx, statusSock)
+		})
+	}
+
 	// Manually register the port for K8s API, we would
 	// only want to send this manual port mapping if both
 	// of the following conditions are met:
@@ -167,7 +226,7 @@ func runAgent(
 	if enableContainerd {
 		group.Go(func() error {
 			for {
-				eventMonitor, err := containerd.NewEventMonitor(containerdSock, portTracker)
+				eventMonitor, err := containerd.NewEventMonitor(containerdSock, tracker.WithSource(portTracker, types.SourceContainerd))
 				if err != nil {
 					return fmt.Errorf("error initializing containerd event monitor: %w", err)
 				}
@@ -192,7 +251,7 @@ func runAgent(
 	if enableDocker {
 		group.Go(func() error {
 			for {
-				eventMonitor, err := docker.NewEventMonitor(portTracker)
+				eventMonitor, err := docker.NewEventMonitor(tracker.WithSource(portTracker, types.SourceDocker))
 				if err != nil {
 					return fmt.Errorf("error initializing docker event monitor: %w", err)
 				}
@@ -224,7 +283,8 @@ func runAgent(
 			err := kube.WatchForServices(ctx,
 				configPath,
 				k8sServiceListenerIP,
-				portTracker)
+				tracker.WithSource(portTracker, types.SourceKubernetes),
+				hostTracker)
 			if err != nil {
 				return fmt.Errorf("kubernetes service watcher failed: %w", err)
 			}
@@ -233,7 +293,7 @@ func runAgent(
 
 		group.Go(func() error {
 			iptablesScanner := iptables.NewIptablesScanner()
-			iptablesHandler := iptables.New(ctx, portTracker, iptablesScanner, k8sServiceListenerIP, iptablesUpdateInterval)
+			iptablesHandler := iptables.New(ctx, tracker.WithSource(portTracker, types.SourceIptables), iptablesScanner, k8sServiceListenerIP, iptablesUpdateInterval)
 			err := iptablesHandler.ForwardPorts()
 			if err != nil {
 				return fmt.Errorf("iptables port forwarding failed: %w", err)
@@ -243,7 +303,7 @@ func runAgent(
 	}
 
 	group.Go(func() error {
-		procScanner, err := procnet.NewProcNetScanner(ctx, portTracker, bindIP, procNetScanInterval)
+		procScanner, err := procnet.NewProcNetScanner(ctx, tracker.WithSource(portTracker, types.SourceProcNet), bindIP, procNetScanInterval)
 		if err != nil {
 			return fmt.Errorf("scanning /proc/net/{tcp, udp} failed: %w", err)
 		}
@@ -38,6 +38,7 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/tracker"
+	guestagentTypes "github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/utils"
 )
 
@@ -46,6 +47,8 @@ const (
 	portsKey     = "nerdctl/ports"
 	stateDirKey  = "nerdctl/state-dir"
 	networkKey   = "nerdctl/networks"
+	// podNamespaceKey is set by the CRI on the containers of Kubernetes pods.
+	podNamespaceKey = "io.kubernetes.pod.namespace"
 )
 
 // EventMonitor monitors the Containerd API
@@ -120,7 +123,7 @@ func (e *EventMonitor) MonitorPorts(ctx context.Context) {
 					log.Errorf("failed running iptable rules to update DNAT rule in CNI-HOSTPORT-DNAT chain: %v", err)
 				}
 
-				err = e.portTracker.Add(startTask.ContainerID, ports)
+				err = tracker.AddWithMetadata(e.portTracker, startTask.ContainerID, ports, containerMetadata(envelope.Namespace, container.Labels))
 				if err != nil {
 					log.Errorf("adding port mapping to tracker failed: %v", err)
 
@@ -156,7 +159,7 @@ func (e *EventMonitor) MonitorPorts(ctx context.Context) {
 							log.Errorf("failed to remove port mapping from container update event: %v", err)
 						}
 
-						err = e.portTracker.Add(cuEvent.ID, ports)
+						err = tracker.AddWithMetadata(e.portTracker, cuEvent.ID, ports, containerMetadata(envelope.Namespace, container.Labels))
 						if err != nil {
 							log.Errorf("failed to add port mapping from container update event: %v", err)
 
@@ -167,7 +170,7 @@ func (e *EventMonitor) MonitorPorts(ctx context.Context) {
 					continue
 				}
 				// Not 100% sure if we ever get here...
-				if err = e.portTracker.Add(cuEvent.ID, ports); err != nil {
+				if err = tracker.AddWithMetadata(e.portTracker, cuEvent.ID, ports, containerMetadata(envelope.Namespace, container.Labels)); err != nil {
 					log.Errorf("failed to add port mapping from container update event: %v", err)
 				}
 
@@ -284,7 +287,7 @@ func (e *EventMonitor) initializeRunningContainers(ctx context.Context) {
 			log.Errorf("failed running iptable rules to update DNAT rule in CNI-HOSTPORT-DNAT chain: %v", err)
 		}
 
-		err = e.portTracker.Add(c.ID(), ports)
+		err = tracker.AddWithMetadata(e.portTracker, c.ID(), ports, containerMetadata(labels[namespaceKey], labels))
 		if err != nil {
 			log.Errorf("adding port mapping to tracker failed: %v", err)
 
@@ -459,6 +462,16 @@ func createPortMappingFromContainer(id string, labels map[string]string) (nat.Po
 	return portMap, nil
 }
 
+// containerMetadata returns the metadata the port forwarding policy is
+// evaluated against for a container.
+func containerMetadata(namespace string, labels map[string]string) guestagentTypes.PortMappingMetadata {
+	return guestagentTypes.PortMappingMetadata{
+		Labels:              labels,
+		ContainerNamespace:  namespace,
+		KubernetesNamespace: labels[podNamespaceKey],
+	}
+}
+
 func extractIPAddress(ctx context.Context, pid string) (string, error) {
 	// retrieve the eth0 IP address from the container
 	nsenterInfIPCmd := exec.CommandContext(ctx, "nsenter", "-t", pid, "-n", "ip", "-o", "-4", "addr", "show", "dev", "eth0")
@@ -31,9 +31,13 @@ import (
 	"github.com/docker/go-connections/nat"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/tracker"
+	guestagentTypes "github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/utils"
 )
 
+// podNamespaceKey is set by cri-dockerd on the containers of Kubernetes pods.
+const podNamespaceKey = "io.kubernetes.pod.namespace"
+
 // EventMonitor monitors the Docker engine's Event API
 // for container events.
 type EventMonitor struct {
@@ -97,7 +101,11 @@ func (e *EventMonitor) MonitorPorts(ctx context.Context) {
 			case events.ActionStart:
 				if len(container.NetworkSettings.Ports) != 0 {
 					validatePortMapping(container.NetworkSettings.Ports)
-					err = e.portTracker.Add(container.ID, container.NetworkSettings.Ports)
+					var labels map[string]string
+					if container.Config != nil {
+						labels = container.Config.Labels
+					}
+					err = tracker.AddWithMetadata(e.portTracker, container.ID, container.NetworkSettings.Ports, containerMetadata(labels))
 					if err != nil {
 						log.Errorf("adding port mapping to tracker failed: %s", err)
 					}
@@ -161,7 +169,7 @@ func (e *EventMonitor) initializeRunningContainers(ctx context.Context) error {
 
 				continue
 			}
-			if err := e.portTracker.Add(container.ID, portMap); err != nil {
+			if err := tracker.AddWithMetadata(e.portTracker, container.ID, portMap, containerMetadata(container.Labels)); err != nil {
 				log.Errorf("registering already running containers failed: %v", err)
 				continue
 			}
@@ -177,6 +185,15 @@ func (e *EventMonitor) initializeRunningContainers(ctx context.Context) error {
 	return nil
 }
 
+// containerMetadata returns the metadata the port forwarding policy is
+// evaluated against for a container.
+func containerMetadata(labels map[string]string) guestagentTypes.PortMappingMetadata {
+	return guestagentTypes.PortMappingMetadata{
+		Labels:              labels,
+		KubernetesNamespace: labels[podNamespaceKey],
+	}
+}
+
 func createPortMapping(ports []containerapi.Port) (nat.PortMap, error) {
 	portMap := make(nat.PortMap)
 
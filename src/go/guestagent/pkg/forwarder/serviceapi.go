@@ -29,16 +29,41 @@ import (
 )
 
 const (
+	allAPI      = "/services/forwarder/all"
 	exposeAPI   = "/services/forwarder/expose"
 	unexposeAPI = "/services/forwarder/unexpose"
+	dnsAddAPI   = "/services/dns/add"
+	dnsAllAPI   = "/services/dns/all"
 )
 
 var (
 	ErrAPI         = errors.New("error from API")
 	ErrExposeAPI   = fmt.Errorf("error from %s API", exposeAPI)
 	ErrUnexposeAPI = fmt.Errorf("error from %s API", unexposeAPI)
+	ErrAllAPI      = fmt.Errorf("error from %s API", allAPI)
+	ErrDNSAddAPI   = fmt.Errorf("error from %s API", dnsAddAPI)
+	ErrDNSAllAPI   = fmt.Errorf("error from %s API", dnsAllAPI)
+	// ErrPortConflict is wrapped by errors from Expose when the host port
+	// is already in use, either by another exposed port or by a process
+	// on the host.
+	ErrPortConflict = errors.New("host port is already in use")
 )
 
+// portConflictMessages are substrings of the errors returned by the expose
+// API when the host port is in use.
+var portConflictMessages = []string{
+	// Another port mapping is already exposed on the same address; this
+	// may also be the same port mapping exposed again, which callers need
+	// to tell apart.
+	"proxy already running",
+	// EADDRINUSE on Linux and macOS.
+	"address already in use",
+	// WSAEADDRINUSE on Windows.
+	"Only one usage of each socket address",
+	// WSAEACCES on Windows, for ports that are reserved by the system.
+	"An attempt was made to access a socket in a way forbidden by its access permissions",
+}
+
 // APIForwarder forwards the PortMappings to /services/forwarder/expose
 // or /services/forwarder/unexpose that is host in the host-switch.
 type APIForwarder struct {
@@ -104,6 +129,98 @@ func (a *APIForwarder) Unexpose(unexposeReq *types.UnexposeRequest) error {
 	return verifyResponseBody(res)
 }
 
+// List calls /services/forwarder/all to get all the port mappings
+// that are currently exposed on the host.
+func (a *APIForwarder) List() ([]types.ExposeRequest, error) {
+	log.Debugf("sending a HTTP GET to %s API", allAPI)
+	req, err := http.NewRequestWithContext(
+		context.Background(),
+		http.MethodGet,
+		a.urlBuilder(allAPI),
+		http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %w", ErrAllAPI, verifyResponseBody(res))
+	}
+
+	var exposed []types.ExposeRequest
+	if err := json.NewDecoder(res.Body).Decode(&exposed); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode response: %w", ErrAllAPI, err)
+	}
+
+	return exposed, nil
+}
+
+// AddZone calls /services/dns/add to add the records of the zone to the
+// DNS server of the host-switch.
+func (a *APIForwarder) AddZone(zone types.Zone) error {
+	bin, err := json.Marshal(zone)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("sending a HTTP POST to %s API with zone: %+v", dnsAddAPI, zone)
+	req, err := http.NewRequestWithContext(
+		context.Background(),
+		http.MethodPost,
+		a.urlBuilder(dnsAddAPI),
+		bytes.NewReader(bin))
+	if err != nil {
+		return err
+	}
+
+	res, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyResponseBody(res); err != nil {
+		return fmt.Errorf("%w: %w", ErrDNSAddAPI, err)
+	}
+
+	return nil
+}
+
+// Zones calls /services/dns/all to get the zones of the DNS server of the
+// host-switch.
+func (a *APIForwarder) Zones() ([]types.Zone, error) {
+	log.Debugf("sending a HTTP GET to %s API", dnsAllAPI)
+	req, err := http.NewRequestWithContext(
+		context.Background(),
+		http.MethodGet,
+		a.urlBuilder(dnsAllAPI),
+		http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %w", ErrDNSAllAPI, verifyResponseBody(res))
+	}
+
+	var zones []types.Zone
+	if err := json.NewDecoder(res.Body).Decode(&zones); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode response: %w", ErrDNSAllAPI, err)
+	}
+
+	return zones, nil
+}
+
 func (a *APIForwarder) urlBuilder(api string) string {
 	return a.baseURL + api
 }
@@ -119,6 +236,12 @@ func verifyResponseBody(res *http.Response) error {
 
 		errMsg := strings.TrimSpace(string(apiResponse))
 
+		for _, message := range portConflictMessages {
+			if strings.Contains(errMsg, message) {
+				return fmt.Errorf("%w: %w: %s", ErrAPI, ErrPortConflict, errMsg)
+			}
+		}
+
 		return fmt.Errorf("%w: %s", ErrAPI, errMsg)
 	}
 
@@ -0,0 +1,183 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hosts
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/log-go"
+	"github.com/containers/gvisor-tap-vsock/pkg/types"
+)
+
+// Publisher is implemented by anything that makes the tracked hostnames
+// resolvable.
+type Publisher interface {
+	// Publish makes the hostnames resolvable; hostnames that were already
+	// published may be passed again.
+	Publish(hostnames []string) error
+}
+
+// ZoneAdder adds DNS records to the DNS server of the host switch.
+type ZoneAdder interface {
+	// AddZone adds the records of the zone to the DNS server. This replaces
+	// the other settings of an existing zone of the same name.
+	AddZone(zone types.Zone) error
+	// Zones returns the zones of the DNS server.
+	Zones() ([]types.Zone, error)
+}
+
+// DNSPublisher publishes hostnames as records of the DNS server of the host
+// switch, resolving to the VM. The DNS server answers every name within a
+// zone from the records of that zone, so only hostnames within the given
+// zones are published, to avoid shadowing other names of the same domain.
+// The DNS services API can't remove records, so published hostnames keep
+// resolving until the host switch restarts.
+//
+// Only resolvers that query the host switch DNS server see the records; this
+// is the VM and its containers, but not the host, which resolves names with
+// its own resolver. In particular, names within the default "localhost" zone
+// already resolve to the loopback address on the host (and reach the VM
+// through forwarded ports), so publishing them only makes them resolve to
+// the VM from inside it.
+type DNSPublisher struct {
+	adder ZoneAdder
+	ip    net.IP
+	zones []string
+	// published contains the published hostnames.
+	published map[string]struct{}
+	mutex     sync.Mutex
+}
+
+// NewDNSPublisher returns a publisher that adds records resolving to ip to
+// the given zones through adder.
+func NewDNSPublisher(adder ZoneAdder, ip net.IP, zones []string) *DNSPublisher {
+	normalized := make([]string, 0, len(zones))
+
+	for _, zone := range zones {
+		zone = strings.Trim(strings.ToLower(strings.TrimSpace(zone)), ".")
+		if zone != "" {
+			normalized = append(normalized, zone)
+		}
+	}
+
+	return &DNSPublisher{
+		adder:     adder,
+		ip:        ip,
+		zones:     normalized,
+		published: make(map[string]struct{}),
+	}
+}
+
+// Publish implements Publisher.
+func (p *DNSPublisher) Publish(hostnames []string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var (
+		zones []types.Zone
+		added [][]string
+	)
+
+	for _, hostname := range hostnames {
+		if _, ok := p.published[hostname]; ok {
+			continue
+		}
+
+		zone, record, ok := p.record(hostname)
+		if !ok {
+			log.Debugf("hostname %s is not within the DNS zones %v, not publishing it", hostname, p.zones)
+
+			continue
+		}
+
+		i := 0
+		for i < len(zones) && zones[i].Name != zone {
+			i++
+		}
+
+		if i == len(zones) {
+			zones = append(zones, types.Zone{Name: zone})
+			added = append(added, nil)
+		}
+
+		zones[i].Records = append(zones[i].Records, record)
+		added[i] = append(added[i], hostname)
+	}
+
+	if len(zones) == 0 {
+		return nil
+	}
+
+	// Adding records replaces the settings of an existing zone, so its
+	// default IP, which answers names without a record, must be kept.
+	existing, err := p.adder.Zones()
+	if err != nil {
+		return fmt.Errorf("listing DNS zones to publish %v: %w", added, err)
+	}
+
+	for i := range zones {
+		for _, zone := range existing {
+			if zone.Name == zones[i].Name {
+				zones[i].DefaultIP = zone.DefaultIP
+			}
+		}
+	}
+
+	var errs []error
+
+	for i, zone := range zones {
+		if err := p.adder.AddZone(zone); err != nil {
+			errs = append(errs, fmt.Errorf("publishing %v: %w", added[i], err))
+
+			continue
+		}
+
+		log.Infof("published hostnames %v in DNS zone %s", added[i], zone.Name)
+
+		for _, hostname := range added[i] {
+			p.published[hostname] = struct{}{}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// record returns the DNS zone and record for the hostname, which may be a
+// wildcard hostname matching a single label.
+func (p *DNSPublisher) record(hostname string) (string, types.Record, bool) {
+	for _, zone := range p.zones {
+		name, ok := strings.CutSuffix(hostname, "."+zone)
+		if !ok || name == "" {
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(name, "*."); ok {
+			return zone + ".", types.Record{
+				Regexp: regexp.MustCompile(`^[^.]+\.` + regexp.QuoteMeta(rest) + `$`),
+				IP:     p.ip,
+			}, true
+		} else if name == "*" {
+			return zone + ".", types.Record{Regexp: regexp.MustCompile(`^[^.]+$`), IP: p.ip}, true
+		}
+
+		return zone + ".", types.Record{Name: name, IP: p.ip}, true
+	}
+
+	return "", types.Record{}, false
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hosts_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/containers/gvisor-tap-vsock/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/hosts"
+	guestagentTypes "github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
+)
+
+// testZoneAdder records the zones added to the DNS server.
+type testZoneAdder struct {
+	existing []types.Zone
+	zones    []types.Zone
+	err      error
+}
+
+func (a *testZoneAdder) Zones() ([]types.Zone, error) {
+	return a.existing, a.err
+}
+
+func (a *testZoneAdder) AddZone(zone types.Zone) error {
+	if a.err != nil {
+		return a.err
+	}
+
+	a.zones = append(a.zones, zone)
+
+	return nil
+}
+
+func TestDNSPublisher(t *testing.T) {
+	t.Parallel()
+
+	vmIP := net.ParseIP("192.168.127.2")
+	adder := &testZoneAdder{}
+	tracker := hosts.NewTracker()
+	tracker.SetPublisher(hosts.NewDNSPublisher(adder, vmIP, []string{"localhost", " Example.TEST. "}))
+
+	tracker.Set("uid-1", guestagentTypes.SourceIngress, "default", "myapp",
+		[]string{"myapp.rd.localhost", "*.myapp.rd.localhost", "api.example.test", "www.example.com", "localhost"})
+
+	require.Len(t, adder.zones, 2)
+	assert.Equal(t, "localhost.", adder.zones[0].Name)
+	require.Len(t, adder.zones[0].Records, 2)
+	assert.Equal(t, `^[^.]+\.myapp\.rd$`, adder.zones[0].Records[0].Regexp.String())
+	assert.True(t, adder.zones[0].Records[0].Regexp.MatchString("web.myapp.rd"))
+	assert.False(t, adder.zones[0].Records[0].Regexp.MatchString("a.web.myapp.rd"))
+	assert.Equal(t, types.Record{Name: "myapp.rd", IP: vmIP}, adder.zones[0].Records[1])
+	assert.Equal(t, "example.test.", adder.zones[1].Name)
+	assert.Equal(t, []types.Record{{Name: "api", IP: vmIP}}, adder.zones[1].Records)
+
+	// Published hostnames are not published again.
+	adder.zones = nil
+	tracker.Set("uid-2", guestagentTypes.SourceHTTPRoute, "web", "shop", []string{"myapp.rd.localhost", "shop.localhost"})
+	require.Len(t, adder.zones, 1)
+	assert.Equal(t, []types.Record{{Name: "shop", IP: vmIP}}, adder.zones[0].Records)
+
+	// Failures are retried the next time the hostnames are set.
+	adder.zones = nil
+	adder.err = errors.New("host switch is not ready")
+	tracker.Set("uid-3", guestagentTypes.SourceIngress, "default", "blog", []string{"blog.localhost"})
+	assert.Empty(t, adder.zones)
+	adder.err = nil
+	tracker.Set("uid-3", guestagentTypes.SourceIngress, "default", "blog", []string{"blog.localhost"})
+	require.Len(t, adder.zones, 1)
+	assert.Equal(t, []types.Record{{Name: "blog", IP: vmIP}}, adder.zones[0].Records)
+}
+
+func TestDNSPublisherKeepsDefaultIP(t *testing.T) {
+	t.Parallel()
+
+	vmIP := net.ParseIP("192.168.127.2")
+	defaultIP := net.ParseIP("192.168.127.254")
+	adder := &testZoneAdder{existing: []types.Zone{{Name: "example.test.", DefaultIP: defaultIP}}}
+	publisher := hosts.NewDNSPublisher(adder, vmIP, []string{"localhost", "example.test"})
+
+	require.NoError(t, publisher.Publish([]string{"api.example.test", "app.localhost"}))
+	require.Len(t, adder.zones, 2)
+	assert.Equal(t, "example.test.", adder.zones[0].Name)
+	assert.Equal(t, defaultIP, adder.zones[0].DefaultIP, "the default IP of an existing zone should be kept")
+	assert.Equal(t, "localhost.", adder.zones[1].Name)
+	assert.Nil(t, adder.zones[1].DefaultIP)
+}
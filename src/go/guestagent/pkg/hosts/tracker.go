@@ -0,0 +1,141 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hosts keeps track of the hostnames that are routed to
+// workloads in the VM (e.g. by Kubernetes Ingress objects), so that
+// they can be published to the host through the status API and the
+// DNS server of the host switch.
+package hosts
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/log-go"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
+)
+
+// Reporter is implemented by anything that can list the tracked hostnames.
+type Reporter interface {
+	// Hostnames returns the tracked hostnames, sorted by namespace and name.
+	Hostnames() []types.HostnameStatus
+}
+
+// Tracker keeps the hostnames routed by each Kubernetes object.
+type Tracker struct {
+	entries map[string]*types.HostnameStatus
+	mutex   sync.Mutex
+	// publisher, if set, makes the tracked hostnames resolvable.
+	publisher Publisher
+}
+
+// NewTracker returns an empty hostname tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		entries: make(map[string]*types.HostnameStatus),
+	}
+}
+
+// SetPublisher sets the publisher that makes the tracked hostnames
+// resolvable. This must be called before any hostnames are set.
+func (t *Tracker) SetPublisher(p Publisher) {
+	t.publisher = p
+}
+
+// Set replaces the hostnames for the object with the given ID. The
+// hostnames are normalized, and an entry without any hostnames is removed.
+// The hostnames are published every time, so that publishing is retried
+// when the object is updated or resynchronized.
+func (t *Tracker) Set(id string, source types.HostnameSource, namespace, name string, hostnames []string) {
+	normalized := make([]string, 0, len(hostnames))
+
+	for _, hostname := range hostnames {
+		hostname = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".")
+		if hostname != "" {
+			normalized = append(normalized, hostname)
+		}
+	}
+
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+
+	if t.publisher != nil && len(normalized) != 0 {
+		if err := t.publisher.Publish(normalized); err != nil {
+			log.Errorf("failed to publish hostnames of %s/%s: %v", namespace, name, err)
+		}
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(normalized) == 0 {
+		delete(t.entries, id)
+
+		return
+	}
+
+	if entry, ok := t.entries[id]; ok && slices.Equal(entry.Hostnames, normalized) {
+		return
+	}
+
+	t.entries[id] = &types.HostnameStatus{
+		ID:        id,
+		Source:    source,
+		Namespace: namespace,
+		Name:      name,
+		Hostnames: normalized,
+		Updated:   time.Now(),
+	}
+}
+
+// Remove drops the hostnames for the object with the given ID.
+func (t *Tracker) Remove(id string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.entries, id)
+}
+
+// RemoveAll drops all tracked hostnames.
+func (t *Tracker) RemoveAll() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	clear(t.entries)
+}
+
+// Hostnames implements Reporter.
+func (t *Tracker) Hostnames() []types.HostnameStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	result := make([]types.HostnameStatus, 0, len(t.entries))
+
+	for _, entry := range t.entries {
+		status := *entry
+		status.Hostnames = slices.Clone(entry.Hostnames)
+		result = append(result, status)
+	}
+
+	slices.SortFunc(result, func(a, b types.HostnameStatus) int {
+		return cmp.Or(
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.Source, b.Source),
+			cmp.Compare(a.ID, b.ID))
+	})
+
+	return result
+}
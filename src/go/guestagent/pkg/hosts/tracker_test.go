@@ -0,0 +1,60 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hosts_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/hosts"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
+)
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := hosts.NewTracker()
+	assert.Empty(t, tracker.Hostnames())
+
+	tracker.Set("uid-2", types.SourceHTTPRoute, "web", "shop", []string{"shop.rd.localhost"})
+	tracker.Set("uid-1", types.SourceIngress, "default", "myapp",
+		[]string{"MyApp.rd.localhost.", "", "*.myapp.rd.localhost", "myapp.rd.localhost"})
+
+	actual := tracker.Hostnames()
+	require.Len(t, actual, 2)
+	assert.Equal(t, "uid-1", actual[0].ID)
+	assert.Equal(t, types.SourceIngress, actual[0].Source)
+	assert.Equal(t, []string{"*.myapp.rd.localhost", "myapp.rd.localhost"}, actual[0].Hostnames)
+	assert.False(t, actual[0].Updated.IsZero())
+	assert.Equal(t, "uid-2", actual[1].ID)
+	assert.Equal(t, []string{"shop.rd.localhost"}, actual[1].Hostnames)
+
+	// Setting the same hostnames should not change the entry.
+	tracker.Set("uid-1", types.SourceIngress, "default", "myapp", []string{"myapp.rd.localhost", "*.myapp.rd.localhost"})
+	assert.Equal(t, actual[0].Updated, tracker.Hostnames()[0].Updated)
+
+	// Removing all hostnames from an object drops the entry.
+	tracker.Set("uid-2", types.SourceHTTPRoute, "web", "shop", nil)
+	assert.Len(t, tracker.Hostnames(), 1)
+
+	tracker.Remove("uid-1")
+	assert.Empty(t, tracker.Hostnames())
+
+	tracker.Set("uid-1", types.SourceIngress, "default", "myapp", []string{"myapp.rd.localhost"})
+	tracker.Set("uid-2", types.SourceHTTPRoute, "web", "shop", []string{"shop.rd.localhost"})
+	tracker.RemoveAll()
+	assert.Empty(t, tracker.Hostnames())
+}
@@ -0,0 +1,211 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Masterminds/log-go"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/hosts"
+	guestagentTypes "github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
+)
+
+// httpRouteResource is the Gateway API HTTPRoute resource; it is only
+// served if the Gateway API CRDs are installed.
+var httpRouteResource = schema.GroupVersionResource{
+	Group:    "gateway.networking.k8s.io",
+	Version:  "v1",
+	Resource: "httproutes",
+}
+
+// hostnameExtractor returns the hostnames routed by a Kubernetes object.
+type hostnameExtractor func(obj interface{}) []string
+
+// watchHostnames monitors Ingress and Gateway API HTTPRoute objects, and
+// keeps the hostnames they route in the given tracker. HTTPRoutes are only
+// watched if the Gateway API is installed when the watch starts.
+func watchHostnames(
+	ctx context.Context,
+	client *kubernetes.Clientset,
+	dynamicClient dynamic.Interface,
+	hostTracker *hosts.Tracker,
+) (<-chan error, error) {
+	errorCh := make(chan error)
+
+	informerFactory := informers.NewSharedInformerFactory(client, 1*time.Hour)
+	ingressInformer := informerFactory.Networking().V1().Ingresses().Informer()
+
+	err := addHostnameHandlers(ingressInformer, errorCh, hostTracker, guestagentTypes.SourceIngress, ingressHostnames)
+	if err != nil {
+		return nil, fmt.Errorf("error watching ingresses: %w", err)
+	}
+
+	hasHTTPRoutes, err := hasResource(client, httpRouteResource)
+	if err != nil {
+		return nil, fmt.Errorf("error discovering %s: %w", httpRouteResource.GroupVersion(), err)
+	}
+
+	dynamicInformerFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 1*time.Hour)
+
+	if hasHTTPRoutes {
+		routeInformer := dynamicInformerFactory.ForResource(httpRouteResource).Informer()
+
+		err := addHostnameHandlers(routeInformer, errorCh, hostTracker, guestagentTypes.SourceHTTPRoute, httpRouteHostnames)
+		if err != nil {
+			return nil, fmt.Errorf("error watching HTTPRoutes: %w", err)
+		}
+	} else {
+		log.Debugf("kubernetes: %s is not available, not watching HTTPRoutes", httpRouteResource.GroupVersion())
+	}
+
+	// The informers list the existing objects on start, so there is no need
+	// to list them separately.
+	informerFactory.Start(ctx.Done())
+	dynamicInformerFactory.Start(ctx.Done())
+
+	return errorCh, nil
+}
+
+// hasResource checks whether the API server serves the given resource.
+func hasResource(client kubernetes.Interface, resource schema.GroupVersionResource) (bool, error) {
+	resources, err := client.Discovery().ServerResourcesForGroupVersion(resource.GroupVersion().String())
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return slices.ContainsFunc(resources.APIResources, func(r v1.APIResource) bool {
+		return r.Name == resource.Resource
+	}), nil
+}
+
+// addHostnameHandlers sets up the informer to keep the hostnames routed by
+// the watched objects in the given tracker.
+func addHostnameHandlers(
+	informer cache.SharedIndexInformer,
+	errorCh chan<- error,
+	hostTracker *hosts.Tracker,
+	source guestagentTypes.HostnameSource,
+	extract hostnameExtractor,
+) error {
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			log.Tracef("%s Informer: Add func called with: %+v", source, obj)
+			updateHostnames(hostTracker, source, obj, extract)
+		},
+		DeleteFunc: func(obj interface{}) {
+			log.Tracef("%s Informer: Del func called with: %+v", source, obj)
+			removeHostnames(hostTracker, source, obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			log.Tracef("%s Informer: Update func called with old object %+v and new Object: %+v", source, oldObj, newObj)
+			updateHostnames(hostTracker, source, newObj, extract)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return informer.SetWatchErrorHandler(watchErrorHandler(errorCh))
+}
+
+func updateHostnames(
+	hostTracker *hosts.Tracker,
+	source guestagentTypes.HostnameSource,
+	obj interface{},
+	extract hostnameExtractor,
+) {
+	object, err := meta.Accessor(obj)
+	if err != nil {
+		log.Errorf("kubernetes %s: unexpected object %T: %s", source, obj, err)
+
+		return
+	}
+
+	hostnames := extract(obj)
+	hostTracker.Set(string(object.GetUID()), source, object.GetNamespace(), object.GetName(), hostnames)
+
+	log.Debugf("kubernetes %s update: %s/%s routes hostnames %v",
+		source, object.GetNamespace(), object.GetName(), hostnames)
+}
+
+func removeHostnames(hostTracker *hosts.Tracker, source guestagentTypes.HostnameSource, obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	object, err := meta.Accessor(obj)
+	if err != nil {
+		log.Errorf("kubernetes %s: unexpected deleted object %T: %s", source, obj, err)
+
+		return
+	}
+
+	hostTracker.Remove(string(object.GetUID()))
+
+	log.Debugf("kubernetes %s deleted: %s/%s", source, object.GetNamespace(), object.GetName())
+}
+
+// ingressHostnames returns the hosts of the rules of an Ingress.
+func ingressHostnames(obj interface{}) []string {
+	ingress, ok := obj.(*networkingv1.Ingress)
+	if !ok {
+		return nil
+	}
+
+	var hostnames []string
+
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != "" {
+			hostnames = append(hostnames, rule.Host)
+		}
+	}
+
+	return hostnames
+}
+
+// httpRouteHostnames returns the hostnames of an HTTPRoute. Routes that
+// do not list any hostnames match the hostnames of their parent Gateway
+// listeners, which are not reported.
+func httpRouteHostnames(obj interface{}) []string {
+	route, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+
+	hostnames, _, err := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+	if err != nil {
+		log.Debugf("kubernetes httproute: invalid hostnames in %s/%s: %s", route.GetNamespace(), route.GetName(), err)
+
+		return nil
+	}
+
+	return hostnames
+}
@@ -0,0 +1,91 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/hosts"
+	guestagentTypes "github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
+)
+
+func TestIngressHostnames(t *testing.T) {
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: v1.ObjectMeta{UID: "ingress-uid", Namespace: "default", Name: "myapp"},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{
+				{Host: "myapp.rd.localhost"},
+				{},
+				{Host: "*.myapp.rd.localhost"},
+			},
+		},
+	}
+
+	assert.Equal(t, []string{"myapp.rd.localhost", "*.myapp.rd.localhost"}, ingressHostnames(ingress))
+	assert.Empty(t, ingressHostnames(&networkingv1.Ingress{}))
+
+	hostTracker := hosts.NewTracker()
+	updateHostnames(hostTracker, guestagentTypes.SourceIngress, ingress, ingressHostnames)
+
+	actual := hostTracker.Hostnames()
+	require.Len(t, actual, 1)
+	assert.Equal(t, "ingress-uid", actual[0].ID)
+	assert.Equal(t, guestagentTypes.SourceIngress, actual[0].Source)
+	assert.Equal(t, "default", actual[0].Namespace)
+	assert.Equal(t, "myapp", actual[0].Name)
+	assert.Equal(t, []string{"*.myapp.rd.localhost", "myapp.rd.localhost"}, actual[0].Hostnames)
+
+	removeHostnames(hostTracker, guestagentTypes.SourceIngress, cache.DeletedFinalStateUnknown{Key: "default/myapp", Obj: ingress})
+	assert.Empty(t, hostTracker.Hostnames())
+}
+
+func TestHTTPRouteHostnames(t *testing.T) {
+	route := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "gateway.networking.k8s.io/v1",
+			"kind":       "HTTPRoute",
+			"metadata": map[string]interface{}{
+				"uid":       "route-uid",
+				"namespace": "web",
+				"name":      "shop",
+			},
+			"spec": map[string]interface{}{
+				"hostnames": []interface{}{"shop.rd.localhost"},
+			},
+		},
+	}
+
+	assert.Equal(t, []string{"shop.rd.localhost"}, httpRouteHostnames(route))
+	assert.Empty(t, httpRouteHostnames(&unstructured.Unstructured{Object: map[string]interface{}{}}))
+
+	hostTracker := hosts.NewTracker()
+	updateHostnames(hostTracker, guestagentTypes.SourceHTTPRoute, route, httpRouteHostnames)
+
+	actual := hostTracker.Hostnames()
+	require.Len(t, actual, 1)
+	assert.Equal(t, "route-uid", actual[0].ID)
+	assert.Equal(t, guestagentTypes.SourceHTTPRoute, actual[0].Source)
+	assert.Equal(t, "web", actual[0].Namespace)
+	assert.Equal(t, "shop", actual[0].Name)
+
+	removeHostnames(hostTracker, guestagentTypes.SourceHTTPRoute, route)
+	assert.Empty(t, hostTracker.Hostnames())
+}
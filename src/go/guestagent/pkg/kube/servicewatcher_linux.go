@@ -63,7 +63,36 @@ func watchServices(ctx context.Context, client *kubernetes.Clientset) (<-chan ev
 		},
 	})
 
-	err := sharedInformer.SetWatchErrorHandler(func(_ *cache.Reflector, err error) {
+	err := sharedInformer.SetWatchErrorHandler(watchErrorHandler(errorCh))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error watching services: %w", err)
+	}
+
+	informerFactory.WaitForCacheSync(ctx.Done())
+	informerFactory.Start(ctx.Done())
+
+	services, err := client.CoreV1().Services(corev1.NamespaceAll).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing services: %w", err)
+	}
+	log.Debugf("coreV1 services list :%+v", services.Items)
+
+	// List the initial set of services asynchronously, so that we don't have to
+	// worry about the channel blocking.
+	go func() {
+		for i := range services.Items {
+			handleUpdate(nil, &services.Items[i], eventCh)
+		}
+	}()
+
+	return eventCh, errorCh, nil
+}
+
+// watchErrorHandler returns an informer watch error handler that ignores
+// errors the informer recovers from, and reports the server going away
+// on the given channel.
+func watchErrorHandler(errorCh chan<- error) cache.WatchErrorHandler {
+	return func(_ *cache.Reflector, err error) {
 		log.Debugw("kubernetes: error watching", log.Fields{
 			"error": err,
 		})
@@ -96,29 +125,7 @@ func watchServices(ctx context.Context, client *kubernetes.Clientset) (<-chan ev
 				"error": err,
 			})
 		}
-	})
-	if err != nil {
-		return nil, nil, fmt.Errorf("error watching services: %w", err)
-	}
-
-	informerFactory.WaitForCacheSync(ctx.Done())
-	informerFactory.Start(ctx.Done())
-
-	services, err := client.CoreV1().Services(corev1.NamespaceAll).List(ctx, v1.ListOptions{})
-	if err != nil {
-		return nil, nil, fmt.Errorf("error listing services: %w", err)
 	}
-	log.Debugf("coreV1 services list :%+v", services.Items)
-
-	// List the initial set of services asynchronously, so that we don't have to
-	// worry about the channel blocking.
-	go func() {
-		for i := range services.Items {
-			handleUpdate(nil, &services.Items[i], eventCh)
-		}
-	}()
-
-	return eventCh, errorCh, nil
 }
 
 func statusDebugString(err *apierrors.StatusError) string {
@@ -11,7 +11,8 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// Package kube watches Kubernetes for NodePort and LoadBalancer service types.
+// Package kube watches Kubernetes for NodePort and LoadBalancer service types,
+// as well as the hostnames routed by Ingress and Gateway API HTTPRoute objects.
 // It exposes the services as follows:
 // - [namespaced network - admin install]: It uses API tracker to expose the ports
 // on the host through host-switch.exe
@@ -34,11 +35,14 @@ import (
 	"github.com/docker/go-connections/nat"
 	"golang.org/x/sys/unix"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/hosts"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/tracker"
+	guestagentTypes "github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
 )
 
 // watcherState is an enumeration to track the state of the watcher.
@@ -53,22 +57,26 @@ const (
 )
 
 // WatchForServices watches Kubernetes for NodePort and LoadBalancer services
-// and create listeners on 0.0.0.0 matching them.
+// and create listeners on 0.0.0.0 matching them. It also keeps the hostnames
+// routed by Ingress and HTTPRoute objects in the given hostname tracker.
 // Any connection errors are ignored and retried.
 func WatchForServices(
 	ctx context.Context,
 	configPath string,
 	k8sServiceListenerIP net.IP,
 	portTracker tracker.Tracker,
+	hostTracker *hosts.Tracker,
 ) error {
 	// These variables are shared across the different states
 	var (
-		state     = stateNoConfig
-		err       error
-		config    *restclient.Config
-		clientset *kubernetes.Clientset
-		eventCh   <-chan event
-		errorCh   <-chan error
+		state         = stateNoConfig
+		err           error
+		config        *restclient.Config
+		clientset     *kubernetes.Clientset
+		dynamicClient *dynamic.DynamicClient
+		eventCh       <-chan event
+		errorCh       <-chan error
+		hostErrorCh   <-chan error
 	)
 
 	watchContext, watchCancel := context.WithCancel(ctx)
@@ -129,6 +137,18 @@ func WatchForServices(
 
 			log.Debugf("watching kubernetes services")
 
+			// Hostnames are only informational, so failing to watch them
+			// should not prevent the services from being forwarded.
+			dynamicClient, err = dynamic.NewForConfig(config)
+			if err == nil {
+				hostErrorCh, err = watchHostnames(watchContext, clientset, dynamicClient, hostTracker)
+			}
+			if err != nil {
+				log.Errorf("failed to watch kubernetes hostnames: %s", err)
+			} else {
+				log.Debugf("watching kubernetes hostnames")
+			}
+
 			state = stateWatching
 		case stateWatching:
 			select {
@@ -143,6 +163,20 @@ func WatchForServices(
 					"error": err,
 				})
 				watchCancel()
+				// The hostnames are listed again once reconnected.
+				hostTracker.RemoveAll()
+
+				state = stateNoConfig
+
+				time.Sleep(time.Second)
+
+				continue
+			case err = <-hostErrorCh:
+				log.Debugw("kubernetes: got error watching hostnames, rolling back", log.Fields{
+					"error": err,
+				})
+				watchCancel()
+				hostTracker.RemoveAll()
 
 				state = stateNoConfig
 
@@ -174,7 +208,8 @@ func WatchForServices(
 
 						continue
 					}
-					if err := portTracker.Add(string(event.UID), portMapping); err != nil {
+					metadata := guestagentTypes.PortMappingMetadata{KubernetesNamespace: event.namespace}
+					if err := tracker.AddWithMetadata(portTracker, string(event.UID), portMapping, metadata); err != nil {
 						log.Errorf("failed to add port mapping: %v from tracker UID: %v namespace: %s name: %s failed: %s",
 							event.portMapping,
 							event.UID,
@@ -0,0 +1,359 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy implements the port forwarding policy, which decides
+// whether the port bindings discovered by the agent may be exposed on the
+// host, and which address and port they are exposed on.
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/go-connections/nat"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
+)
+
+// Action is what a policy rule does with the port bindings it matches.
+type Action string
+
+const (
+	ActionAllow   Action = "allow"
+	ActionDeny    Action = "deny"
+	ActionRewrite Action = "rewrite"
+)
+
+// ErrInvalidPolicy is returned when the policy file can't be used.
+var ErrInvalidPolicy = errors.New("invalid port forwarding policy")
+
+// Policy is a port forwarding policy, as read from the policy file.
+type Policy struct {
+	// DefaultAction applies to the port bindings that do not match any
+	// rule; it is either allow (the default) or deny.
+	DefaultAction Action `json:"defaultAction,omitempty"`
+	// Rules are evaluated in order; the first matching rule applies.
+	Rules []Rule `json:"rules"`
+}
+
+// Rule is a single rule of the port forwarding policy.
+type Rule struct {
+	// Name is used to identify the rule in logs; it is optional.
+	Name   string `json:"name,omitempty"`
+	Action Action `json:"action"`
+	Match  Match  `json:"match"`
+	// HostIP and HostPort, for rewrite rules, replace the address and port
+	// the port binding is exposed on. HostPort may only be set if the rule
+	// matches a single port.
+	HostIP   string `json:"hostIP,omitempty"`
+	HostPort string `json:"hostPort,omitempty"`
+}
+
+// Match selects the port bindings a rule applies to. All the given
+// criteria must match; criteria that are not given match everything.
+type Match struct {
+	// Ports are host ports or port ranges, e.g. "5432" or "8000-8999".
+	Ports []string `json:"ports,omitempty"`
+	// Protocols are "tcp" or "udp".
+	Protocols []string `json:"protocols,omitempty"`
+	// Sources are the components of the agent that discovered the port
+	// bindings, e.g. "docker" or "procnet".
+	Sources []types.PortMappingSource `json:"sources,omitempty"`
+	// BindAddresses are the addresses, or CIDR ranges, the port is
+	// requested to be bound to on the host; an empty address is 0.0.0.0.
+	BindAddresses []string `json:"bindAddresses,omitempty"`
+	// Labels must all be set on the container; a value of "*" matches
+	// any value.
+	Labels map[string]string `json:"labels,omitempty"`
+	// ContainerNamespaces are containerd namespaces, e.g. "default".
+	ContainerNamespaces []string `json:"containerNamespaces,omitempty"`
+	// KubernetesNamespaces are the namespaces of Kubernetes services and pods.
+	KubernetesNamespaces []string `json:"kubernetesNamespaces,omitempty"`
+
+	portRanges []portRange
+	networks   []*net.IPNet
+}
+
+type portRange struct {
+	first, last int
+}
+
+// Request is a port binding to be evaluated against the policy.
+type Request struct {
+	Source   types.PortMappingSource
+	Metadata types.PortMappingMetadata
+	Port     nat.Port
+	Binding  nat.PortBinding
+}
+
+// Decision is the result of evaluating a port binding against the policy.
+type Decision struct {
+	// Allowed is whether the port binding may be exposed on the host.
+	Allowed bool
+	// Binding is the port binding to expose on the host; it differs from
+	// the requested binding if a rewrite rule matched.
+	Binding nat.PortBinding
+	// Rule describes the rule that matched; it is empty if none did.
+	Rule string
+}
+
+// Load reads the policy from the given file. The returned error wraps
+// os.ErrNotExist if the file does not exist.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read port forwarding policy: %w", err)
+	}
+
+	policy, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return policy, nil
+}
+
+// Parse parses and validates a JSON port forwarding policy.
+func Parse(data []byte) (*Policy, error) {
+	var policy Policy
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+
+	switch policy.DefaultAction {
+	case "", ActionAllow, ActionDeny:
+	default:
+		return nil, fmt.Errorf("%w: invalid default action %q", ErrInvalidPolicy, policy.DefaultAction)
+	}
+
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidPolicy, rule.describe(i), err)
+		}
+	}
+
+	return &policy, nil
+}
+
+func (r *Rule) compile() error {
+	switch r.Action {
+	case ActionAllow, ActionDeny:
+		if r.HostIP != "" || r.HostPort != "" {
+			return fmt.Errorf("hostIP and hostPort are only supported for %s rules", ActionRewrite)
+		}
+	case ActionRewrite:
+		if r.HostIP == "" && r.HostPort == "" {
+			return errors.New("rewrite rules must set hostIP or hostPort")
+		}
+
+		// The host switch can only expose IPv4 addresses.
+		if r.HostIP != "" {
+			if ip := net.ParseIP(r.HostIP); ip == nil || ip.To4() == nil {
+				return fmt.Errorf("invalid hostIP %q", r.HostIP)
+			}
+		}
+
+		if r.HostPort != "" {
+			if _, err := parsePort(r.HostPort); err != nil {
+				return fmt.Errorf("invalid hostPort: %w", err)
+			}
+
+			if len(r.Match.Ports) != 1 || strings.Contains(r.Match.Ports[0], "-") {
+				return errors.New("hostPort requires the rule to match a single port")
+			}
+		}
+	default:
+		return fmt.Errorf("invalid action %q", r.Action)
+	}
+
+	for _, ports := range r.Match.Ports {
+		first, last, found := strings.Cut(ports, "-")
+		if !found {
+			last = first
+		}
+
+		firstPort, err := parsePort(first)
+		if err != nil {
+			return fmt.Errorf("invalid port range %q: %w", ports, err)
+		}
+
+		lastPort, err := parsePort(last)
+		if err != nil {
+			return fmt.Errorf("invalid port range %q: %w", ports, err)
+		}
+
+		if firstPort > lastPort {
+			return fmt.Errorf("invalid port range %q", ports)
+		}
+
+		r.Match.portRanges = append(r.Match.portRanges, portRange{first: firstPort, last: lastPort})
+	}
+
+	for i, protocol := range r.Match.Protocols {
+		protocol = strings.ToLower(protocol)
+		if protocol != "tcp" && protocol != "udp" {
+			return fmt.Errorf("invalid protocol %q", protocol)
+		}
+
+		r.Match.Protocols[i] = protocol
+	}
+
+	for _, address := range r.Match.BindAddresses {
+		if !strings.Contains(address, "/") {
+			ip := net.ParseIP(address)
+			if ip == nil {
+				return fmt.Errorf("invalid bind address %q", address)
+			}
+
+			bits := 8 * len(ip)
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+
+			r.Match.networks = append(r.Match.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(address)
+		if err != nil {
+			return fmt.Errorf("invalid bind address %q: %w", address, err)
+		}
+
+		r.Match.networks = append(r.Match.networks, network)
+	}
+
+	return nil
+}
+
+func parsePort(port string) (int, error) {
+	result, err := strconv.Atoi(strings.TrimSpace(port))
+	if err != nil {
+		return 0, err
+	}
+
+	if result < 1 || result > 65535 {
+		return 0, fmt.Errorf("port %d out of range", result)
+	}
+
+	return result, nil
+}
+
+// describe returns a description of the rule for use in logs.
+func (r *Rule) describe(index int) string {
+	if r.Name != "" {
+		return fmt.Sprintf("rule %d (%s)", index+1, r.Name)
+	}
+
+	return fmt.Sprintf("rule %d", index+1)
+}
+
+// Evaluate decides whether the given port binding may be exposed on the
+// host. A nil policy allows everything.
+func (p *Policy) Evaluate(req Request) Decision {
+	if p == nil {
+		return Decision{Allowed: true, Binding: req.Binding}
+	}
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !rule.Match.matches(req) {
+			continue
+		}
+
+		decision := Decision{
+			Allowed: rule.Action != ActionDeny,
+			Binding: req.Binding,
+			Rule:    rule.describe(i),
+		}
+
+		if rule.Action == ActionRewrite {
+			if rule.HostIP != "" {
+				decision.Binding.HostIP = rule.HostIP
+			}
+
+			if rule.HostPort != "" {
+				decision.Binding.HostPort = rule.HostPort
+			}
+		}
+
+		return decision
+	}
+
+	return Decision{Allowed: p.DefaultAction != ActionDeny, Binding: req.Binding}
+}
+
+func (m *Match) matches(req Request) bool {
+	if len(m.portRanges) > 0 {
+		port, err := strconv.Atoi(req.Binding.HostPort)
+		if err != nil {
+			port = req.Port.Int()
+		}
+
+		if !slices.ContainsFunc(m.portRanges, func(r portRange) bool {
+			return r.first <= port && port <= r.last
+		}) {
+			return false
+		}
+	}
+
+	if len(m.Protocols) > 0 && !slices.Contains(m.Protocols, strings.ToLower(req.Port.Proto())) {
+		return false
+	}
+
+	if len(m.Sources) > 0 && !slices.Contains(m.Sources, req.Source) {
+		return false
+	}
+
+	if len(m.networks) > 0 {
+		ip := net.IPv4zero
+		if req.Binding.HostIP != "" {
+			ip = net.ParseIP(req.Binding.HostIP)
+		}
+
+		if !slices.ContainsFunc(m.networks, func(network *net.IPNet) bool {
+			return ip != nil && network.Contains(ip)
+		}) {
+			return false
+		}
+	}
+
+	for key, value := range m.Labels {
+		actual, ok := req.Metadata.Labels[key]
+		if !ok || (value != "*" && value != actual) {
+			return false
+		}
+	}
+
+	if len(m.ContainerNamespaces) > 0 && !slices.Contains(m.ContainerNamespaces, req.Metadata.ContainerNamespace) {
+		return false
+	}
+
+	if len(m.KubernetesNamespaces) > 0 && !slices.Contains(m.KubernetesNamespaces, req.Metadata.KubernetesNamespace) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,195 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/policy"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
+)
+
+const testPolicy = `{
+	"defaultAction": "allow",
+	"rules": [
+		{"name": "system", "action": "allow", "match": {"kubernetesNamespaces": ["kube-system"]}},
+		{"name": "databases", "action": "deny", "match": {"ports": ["3306", "5432-5433"], "protocols": ["TCP"], "bindAddresses": ["0.0.0.0", "10.0.0.0/8"]}},
+		{"name": "buildkit", "action": "deny", "match": {"sources": ["containerd"], "containerNamespaces": ["buildkit"]}},
+		{"name": "dev", "action": "rewrite", "match": {"labels": {"com.example/env": "dev"}}, "hostIP": "127.0.0.1"},
+		{"name": "web", "action": "rewrite", "match": {"ports": ["80"], "labels": {"app": "*"}}, "hostPort": "8080"}
+	]
+}`
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+
+	portPolicy, err := policy.Parse([]byte(testPolicy))
+	require.NoError(t, err)
+
+	mustPort := func(proto, port string) nat.Port {
+		result, err := nat.NewPort(proto, port)
+		require.NoError(t, err)
+
+		return result
+	}
+
+	testCases := []struct {
+		name     string
+		request  policy.Request
+		expected policy.Decision
+	}{
+		{
+			name: "no rule matches",
+			request: policy.Request{
+				Port:    mustPort("tcp", "8000"),
+				Binding: nat.PortBinding{HostIP: "0.0.0.0", HostPort: "8000"},
+			},
+			expected: policy.Decision{Allowed: true, Binding: nat.PortBinding{HostIP: "0.0.0.0", HostPort: "8000"}},
+		},
+		{
+			name: "database on all addresses",
+			request: policy.Request{
+				Port:    mustPort("tcp", "5432"),
+				Binding: nat.PortBinding{HostPort: "5433"},
+			},
+			expected: policy.Decision{Binding: nat.PortBinding{HostPort: "5433"}, Rule: "rule 2 (databases)"},
+		},
+		{
+			name: "database on localhost",
+			request: policy.Request{
+				Port:    mustPort("tcp", "3306"),
+				Binding: nat.PortBinding{HostIP: "127.0.0.1", HostPort: "3306"},
+			},
+			expected: policy.Decision{Allowed: true, Binding: nat.PortBinding{HostIP: "127.0.0.1", HostPort: "3306"}},
+		},
+		{
+			name: "database over udp",
+			request: policy.Request{
+				Port:    mustPort("udp", "3306"),
+				Binding: nat.PortBinding{HostIP: "10.1.2.3", HostPort: "3306"},
+			},
+			expected: policy.Decision{Allowed: true, Binding: nat.PortBinding{HostIP: "10.1.2.3", HostPort: "3306"}},
+		},
+		{
+			name: "database in kube-system",
+			request: policy.Request{
+				Source:   types.SourceKubernetes,
+				Metadata: types.PortMappingMetadata{KubernetesNamespace: "kube-system"},
+				Port:     mustPort("tcp", "5432"),
+				Binding:  nat.PortBinding{HostIP: "0.0.0.0", HostPort: "5432"},
+			},
+			expected: policy.Decision{Allowed: true, Binding: nat.PortBinding{HostIP: "0.0.0.0", HostPort: "5432"}, Rule: "rule 1 (system)"},
+		},
+		{
+			name: "containerd namespace",
+			request: policy.Request{
+				Source:   types.SourceContainerd,
+				Metadata: types.PortMappingMetadata{ContainerNamespace: "buildkit"},
+				Port:     mustPort("tcp", "1234"),
+				Binding:  nat.PortBinding{HostIP: "127.0.0.1", HostPort: "1234"},
+			},
+			expected: policy.Decision{Binding: nat.PortBinding{HostIP: "127.0.0.1", HostPort: "1234"}, Rule: "rule 3 (buildkit)"},
+		},
+		{
+			name: "rewrite host IP",
+			request: policy.Request{
+				Metadata: types.PortMappingMetadata{Labels: map[string]string{"com.example/env": "dev", "app": "web"}},
+				Port:     mustPort("tcp", "80"),
+				Binding:  nat.PortBinding{HostIP: "0.0.0.0", HostPort: "80"},
+			},
+			expected: policy.Decision{Allowed: true, Binding: nat.PortBinding{HostIP: "127.0.0.1", HostPort: "80"}, Rule: "rule 4 (dev)"},
+		},
+		{
+			name: "rewrite host port",
+			request: policy.Request{
+				Metadata: types.PortMappingMetadata{Labels: map[string]string{"app": "web"}},
+				Port:     mustPort("tcp", "80"),
+				Binding:  nat.PortBinding{HostIP: "0.0.0.0", HostPort: "80"},
+			},
+			expected: policy.Decision{Allowed: true, Binding: nat.PortBinding{HostIP: "0.0.0.0", HostPort: "8080"}, Rule: "rule 5 (web)"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, portPolicy.Evaluate(tc.request))
+		})
+	}
+}
+
+func TestEvaluateDefaultDeny(t *testing.T) {
+	t.Parallel()
+
+	portPolicy, err := policy.Parse([]byte(`{"defaultAction": "deny", "rules": [{"action": "allow", "match": {"bindAddresses": ["127.0.0.1"]}}]}`))
+	require.NoError(t, err)
+
+	port, err := nat.NewPort("tcp", "80")
+	require.NoError(t, err)
+
+	assert.True(t, portPolicy.Evaluate(policy.Request{Port: port, Binding: nat.PortBinding{HostIP: "127.0.0.1", HostPort: "80"}}).Allowed)
+	assert.False(t, portPolicy.Evaluate(policy.Request{Port: port, Binding: nat.PortBinding{HostIP: "0.0.0.0", HostPort: "80"}}).Allowed)
+
+	var nilPolicy *policy.Policy
+	assert.True(t, nilPolicy.Evaluate(policy.Request{Port: port}).Allowed)
+}
+
+func TestParseInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]string{
+		"unknown field":      `{"rules": [{"action": "deny", "match": {"port": ["80"]}}]}`,
+		"default action":     `{"defaultAction": "rewrite"}`,
+		"action":             `{"rules": [{"action": "block"}]}`,
+		"port range":         `{"rules": [{"action": "deny", "match": {"ports": ["90-80"]}}]}`,
+		"port":               `{"rules": [{"action": "deny", "match": {"ports": ["70000"]}}]}`,
+		"protocol":           `{"rules": [{"action": "deny", "match": {"protocols": ["sctp"]}}]}`,
+		"bind address":       `{"rules": [{"action": "deny", "match": {"bindAddresses": ["localhost"]}}]}`,
+		"rewrite nothing":    `{"rules": [{"action": "rewrite"}]}`,
+		"rewrite IPv6":       `{"rules": [{"action": "rewrite", "hostIP": "::1"}]}`,
+		"rewrite port range": `{"rules": [{"action": "rewrite", "match": {"ports": ["80-81"]}, "hostPort": "8080"}]}`,
+		"rewrite any port":   `{"rules": [{"action": "rewrite", "hostPort": "8080"}]}`,
+		"host IP for allow":  `{"rules": [{"action": "allow", "hostIP": "127.0.0.1"}]}`,
+		"host port for deny": `{"rules": [{"action": "deny", "match": {"ports": ["80"]}, "hostPort": "8080"}]}`,
+	}
+
+	for name, input := range testCases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := policy.Parse([]byte(input))
+			require.ErrorIs(t, err, policy.ErrInvalidPolicy)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	_, err := policy.Load(filepath.Join(t.TempDir(), "missing.json"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(testPolicy), 0o600))
+
+	portPolicy, err := policy.Load(path)
+	require.NoError(t, err)
+	assert.Len(t, portPolicy.Rules, 5)
+}
@@ -186,6 +186,9 @@ func (p *ProcNetScanner) Tick(scanned nat.PortMap) {
 			continue
 		}
 		delete(p.addErrorLogged, port)
+		// A port only gets here after a failed publish, so drop the
+		// error rollback recorded for it as well.
+		tracker.ForgetError(p.tracker, utils.GenerateID(fmt.Sprintf("%s/%s", port.Proto(), port.Port())))
 	}
 }
 
@@ -198,9 +201,7 @@ func (p *ProcNetScanner) publish(port nat.Port, bindings []nat.PortBinding) erro
 	id := utils.GenerateID(fmt.Sprintf("%s/%s", port.Proto(), port.Port()))
 	if err := p.tracker.Add(id, nat.PortMap{port: bindings}); err != nil {
 		p.logAddFailure(port, fmt.Sprintf("failed to add: %s", err))
-		if removeErr := p.tracker.Remove(id); removeErr != nil {
-			p.logAddFailure(port, fmt.Sprintf("rollback after tracker.Add failure: %s", removeErr))
-		}
+		p.rollback(id, port, bindings, "tracker.Add failure", err)
 		return err
 	}
 
@@ -224,16 +225,13 @@ func (p *ProcNetScanner) publish(port nat.Port, bindings []nat.PortBinding) erro
 				// the error so the next Tick re-pends the port instead of
 				// recording it as published without a forwarder.
 				p.logAddFailure(port, fmt.Sprintf("bad port %q: %s", b.HostPort, err))
-				if removeErr := p.tracker.Remove(id); removeErr != nil {
-					p.logAddFailure(port, fmt.Sprintf("rollback after bad port: %s", removeErr))
-				}
-				return fmt.Errorf("/proc/net scanner: bad port %q: %w", b.HostPort, err)
+				err = fmt.Errorf("/proc/net scanner: bad port %q: %w", b.HostPort, err)
+				p.rollback(id, port, bindings, "bad port", err)
+				return err
 			}
 			if err := p.forwarder.Add(p.ctx, port.Proto(), uint16(portNum)); err != nil {
 				p.logAddFailure(port, fmt.Sprintf("loopback forwarder %s/%s: %s", port.Proto(), b.HostPort, err))
-				if removeErr := p.tracker.Remove(id); removeErr != nil {
-					p.logAddFailure(port, fmt.Sprintf("rollback after forwarder.Add failure: %s", removeErr))
-				}
+				p.rollback(id, port, bindings, "forwarder.Add failure", err)
 				return err
 			}
 		}
@@ -249,6 +247,17 @@ func (p *ProcNetScanner) publish(port nat.Port, bindings []nat.PortBinding) erro
 	return nil
 }
 
+// rollback removes the tracker entry of a port that failed to publish.
+// Removing the entry also drops its status, so the error is recorded
+// again afterwards to keep the failure visible in the port mapping
+// status until the port publishes or goes away.
+func (p *ProcNetScanner) rollback(id string, port nat.Port, bindings []nat.PortBinding, step string, err error) {
+	if removeErr := p.tracker.Remove(id); removeErr != nil {
+		p.logAddFailure(port, fmt.Sprintf("rollback after %s: %s", step, removeErr))
+	}
+	tracker.RecordError(p.tracker, id, nat.PortMap{port: bindings}, err)
+}
+
 // logAddFailure emits the first publish-failure message for port at
 // Error level and subsequent messages at Debug. addErrorLogged
 // resets when publish succeeds or when the sweep at the end of Tick
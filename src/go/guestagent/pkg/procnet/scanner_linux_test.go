@@ -20,6 +20,8 @@ import (
 
 	"github.com/docker/go-connections/nat"
 	"github.com/lima-vm/lima/pkg/guestagent/procnettcp"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/utils"
 )
 
 // fakeTracker records Add/Remove calls keyed by the containerID the
@@ -31,6 +33,9 @@ type fakeTracker struct {
 	removed   []string
 	addErr    error
 	removeErr error
+	// errors holds the errors recorded through RecordError that have
+	// not been forgotten yet.
+	errors map[string]error
 }
 
 func (t *fakeTracker) Add(id string, _ nat.PortMap) error {
@@ -42,6 +47,17 @@ func (t *fakeTracker) Remove(id string) error {
 	t.removed = append(t.removed, id)
 	return t.removeErr
 }
+func (t *fakeTracker) RecordError(id string, _ nat.PortMap, err error) {
+	if t.errors == nil {
+		t.errors = make(map[string]error)
+	}
+	t.errors[id] = err
+}
+
+func (t *fakeTracker) ForgetError(id string) {
+	delete(t.errors, id)
+}
+
 func (t *fakeTracker) Get(string) nat.PortMap { return nil }
 func (t *fakeTracker) RemoveAll() error       { return nil }
 
@@ -455,6 +471,25 @@ func TestRollbackAfterForwarderAddFailure(t *testing.T) {
 	}
 }
 
+func TestRollbackRecordsError(t *testing.T) {
+	tr := &fakeTracker{}
+	fwd := &fakeForwarder{addErr: fmt.Errorf("synthetic forwarder failure")}
+	s := newScanner(context.Background(), tr, fwd, nil, time.Second)
+	id := utils.GenerateID("tcp/8009")
+
+	scan := loopbackPortMap(t, 8009)
+	s.Tick(scan)
+	s.Tick(scan)
+	if err := tr.errors[id]; err != fwd.addErr {
+		t.Fatalf("recorded error = %v, want %v", err, fwd.addErr)
+	}
+
+	s.Tick(nat.PortMap{}) // port vanishes from /proc/net
+	if err, ok := tr.errors[id]; ok {
+		t.Fatalf("recorded error %v not forgotten after port vanished", err)
+	}
+}
+
 func TestBindingShapeChangePublishesCurrent(t *testing.T) {
 	tr := &fakeTracker{}
 	fwd := &fakeForwarder{}
@@ -0,0 +1,132 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package status implements a local HTTP API, served over a unix socket,
+// that reports what the agent is forwarding. It is used for debugging
+// port forwarding issues (e.g. via `wsl-helper guestagent status`).
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Masterminds/log-go"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/hosts"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/tracker"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
+)
+
+const (
+	// PortMappingsPath is the path of the endpoint that lists the tracked
+	// port mappings, as a JSON array of types.PortMappingStatus.
+	PortMappingsPath = "/v1/port_mappings"
+	// HostnamesPath is the path of the endpoint that lists the hostnames
+	// routed by Kubernetes objects, as a JSON array of types.HostnameStatus.
+	HostnamesPath     = "/v1/hostnames"
+	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+// Server serves the status API.
+type Server struct {
+	reporter  tracker.StatusReporter
+	hostnames hosts.Reporter
+}
+
+// NewServer returns a new status API server reporting the port mapping
+// status from the given reporter, and the hostnames from the given
+// hostname reporter; the latter may be nil if hostnames are not tracked.
+func NewServer(reporter tracker.StatusReporter, hostnames hosts.Reporter) *Server {
+	return &Server{reporter: reporter, hostnames: hostnames}
+}
+
+// Handler returns the HTTP handler for the status API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+PortMappingsPath, s.portMappings)
+	mux.HandleFunc("GET "+HostnamesPath, s.hostnameList)
+
+	return mux
+}
+
+// Serve listens on the given unix socket and serves the status API until
+// the context is done. Any existing file at the socket path is replaced.
+func (s *Server) Serve(ctx context.Context, socketPath string) error {
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove existing status socket %s: %w", socketPath, err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on status socket %s: %w", socketPath, err)
+	}
+
+	// The socket is only meant for local debugging by root.
+	if err := os.Chmod(socketPath, 0o600); err != nil {
+		_ = listener.Close()
+
+		return fmt.Errorf("failed to set permissions on status socket %s: %w", socketPath, err)
+	}
+
+	server := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Errorf("failed to shut down status API server: %v", err)
+		}
+	}()
+
+	log.Infof("serving status API on %s", socketPath)
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("status API server failed: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Server) portMappings(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(s.reporter.Status()); err != nil {
+		log.Errorf("failed to write port mapping status: %v", err)
+	}
+}
+
+func (s *Server) hostnameList(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	hostnames := []types.HostnameStatus{}
+	if s.hostnames != nil {
+		hostnames = s.hostnames.Hostnames()
+	}
+
+	if err := json.NewEncoder(w).Encode(hostnames); err != nil {
+		log.Errorf("failed to write hostnames: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/hosts"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/status"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
+)
+
+type testReporter []types.PortMappingStatus
+
+func (r testReporter) Status() []types.PortMappingStatus {
+	return r
+}
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+
+	port, err := nat.NewPort("tcp", "80")
+	require.NoError(t, err)
+
+	expected := []types.PortMappingStatus{
+		{
+			ID:        "containerID_1",
+			Source:    types.SourceDocker,
+			Requested: nat.PortMap{port: {{HostIP: "127.0.0.1", HostPort: "80"}}},
+			Ports:     nat.PortMap{port: {{HostIP: "127.0.0.1", HostPort: "80"}}},
+			Created:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			Updated:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}
+
+	hostTracker := hosts.NewTracker()
+	hostTracker.Set("ingressUID_1", types.SourceIngress, "default", "myapp", []string{"myapp.rd.localhost"})
+
+	socketPath := filepath.Join(t.TempDir(), "status.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+
+	go func() {
+		errCh <- status.NewServer(testReporter(expected), hostTracker).Serve(ctx, socketPath)
+	}()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	var resp *http.Response
+
+	require.Eventually(t, func() bool {
+		resp, err = client.Get("http://status" + status.PortMappingsPath)
+
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var actual []types.PortMappingStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&actual))
+	assert.Equal(t, expected, actual)
+
+	hostsResp, err := client.Get("http://status" + status.HostnamesPath)
+	require.NoError(t, err)
+
+	defer hostsResp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, hostsResp.StatusCode)
+
+	var actualHostnames []types.HostnameStatus
+	require.NoError(t, json.NewDecoder(hostsResp.Body).Decode(&actualHostnames))
+	require.Len(t, actualHostnames, 1)
+	assert.Equal(t, "ingressUID_1", actualHostnames[0].ID)
+	assert.Equal(t, []string{"myapp.rd.localhost"}, actualHostnames[0].Hostnames)
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
@@ -14,17 +14,22 @@ limitations under the License.
 package tracker
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"net"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Masterminds/log-go"
 	"github.com/containers/gvisor-tap-vsock/pkg/types"
 	"github.com/docker/go-connections/nat"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/forwarder"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/policy"
 	guestagentTypes "github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
 )
 
@@ -32,6 +37,13 @@ const (
 	// The gateway represents the hostname where the hostSwitch API is hosted.
 	gateway        = "gateway.rancher-desktop.internal"
 	GatewayBaseURL = "http://" + gateway + ":80"
+	// How long port mappings restored from the state file are kept without
+	// being added again, before they are considered stale and removed.
+	restoreGracePeriod = 2 * time.Minute
+	// How many times the exposed ports are listed when reconciling, and
+	// how long to wait in between, in case the host switch is not ready.
+	reconcileListAttempts = 5
+	reconcileListInterval = 500 * time.Millisecond
 )
 
 var (
@@ -51,7 +63,20 @@ type APITracker struct {
 	baseURL           string
 	tapInterfaceIP    string
 	portStorage       *portStorage
+	remapStorage      *remapStorage
+	statusStorage     *statusStorage
 	apiForwarder      *forwarder.APIForwarder
+	// policy, if set, decides which port bindings are exposed on the host.
+	policy *policy.Policy
+	// remapRange, if set, is the range of alternative host ports for port
+	// bindings whose host port is in use.
+	remapRange *portRange
+	// journal, if set, persists the exposed port mappings.
+	journal *journal
+	// restored contains the IDs of port mappings restored from the journal
+	// that have not been added again since.
+	restored      map[string]struct{}
+	restoredMutex sync.Mutex
 }
 
 // NewAPITracker creates a new instance of APITracker with the specified configuration.
@@ -74,38 +99,106 @@ func NewAPITracker(ctx context.Context, wslProxyForwarder forwarder.Forwarder, b
 		baseURL:           baseURL,
 		tapInterfaceIP:    tapIfaceIP,
 		portStorage:       newPortStorage(),
+		remapStorage:      newRemapStorage(),
+		statusStorage:     newStatusStorage(),
 		apiForwarder:      forwarder.NewAPIForwarder(baseURL),
+		restored:          make(map[string]struct{}),
 	}
 }
 
 // Add a container ID and port mapping to the tracker and calls the
 // /services/forwarder/expose endpoint to forward the port mappings.
 func (a *APITracker) Add(containerID string, portMap nat.PortMap) error {
+	return a.addFromSource("", containerID, portMap, guestagentTypes.PortMappingMetadata{})
+}
+
+// AddWithMetadata is like Add, but the port forwarding policy is also
+// evaluated against the given metadata.
+func (a *APITracker) AddWithMetadata(containerID string, portMap nat.PortMap, metadata guestagentTypes.PortMappingMetadata) error {
+	return a.addFromSource("", containerID, portMap, metadata)
+}
+
+// SetPolicy sets the port forwarding policy that decides which port
+// bindings are exposed on the host. This must be called before any port
+// mappings are added.
+func (a *APITracker) SetPolicy(p *policy.Policy) {
+	a.policy = p
+}
+
+// addFromSource implements Add, recording the source of the port
+// mappings in the status.
+func (a *APITracker) addFromSource(
+	source guestagentTypes.PortMappingSource,
+	containerID string,
+	portMap nat.PortMap,
+	metadata guestagentTypes.PortMappingMetadata,
+) error {
 	var errs []error
 
+	allowed, remaps, denied := a.applyPolicy(source, containerID, portMap, metadata)
 	successfullyForwarded := make(nat.PortMap)
+	// The port mappings may be added again (e.g. when a container is
+	// updated) while they are still exposed.
+	existing := a.portStorage.get(containerID)
+	existingRemaps := a.remapStorage.get(containerID)
+
+	// Keep the alternative host ports that were previously allocated.
+	for _, remap := range existingRemaps {
+		if isConflictRemap(remap) &&
+			slices.Contains(allowed[remap.Port], remap.Requested) &&
+			hostBinding(remaps, remap.Port, remap.Requested) == remap.Requested {
+			remaps = append(remaps, remap)
+		}
+	}
 
-	for portProto, portBindings := range portMap {
+	// Port mappings restored from the journal are already exposed; only
+	// expose the bindings that are new, and unexpose the ones that are gone.
+	restored := a.confirmRestored(containerID)
+	if restored {
+		errs = append(errs, a.unexposeRemoved(existing, existingRemaps, allowed, remaps)...)
+	}
+
+	for portProto, portBindings := range allowed {
 		var tmpPortBinding []nat.PortBinding
 
 		log.Debugf("called add with portProto: %+v, portBindings: %+v\n", portProto, portBindings)
 
 		for _, portBinding := range portBindings {
-			// The expose API only supports IPv4
-			ipv4, err := isIPv4(portBinding.HostIP)
-			if !ipv4 || err != nil {
+			exposeReq := a.exposeRequest(portProto, portBinding, hostBinding(remaps, portProto, portBinding))
+			if exposeReq == nil {
+				// The expose API only supports IPv4
 				log.Errorf("did not receive IPv4 for HostIP: %s", portBinding.HostIP)
 				continue
 			}
 
+			exposedBefore := slices.Contains(existing[portProto], portBinding) &&
+				hostBinding(existingRemaps, portProto, portBinding) == hostBinding(remaps, portProto, portBinding)
+			if restored && exposedBefore {
+				tmpPortBinding = append(tmpPortBinding, portBinding)
+
+				continue
+			}
+
 			log.Debugf("exposing the following port binding: %+v", portBinding)
 
-			err = a.apiForwarder.Expose(
-				&types.ExposeRequest{
-					Local:    ipPortBuilder(a.determineHostIP(portBinding.HostIP), portBinding.HostPort),
-					Remote:   ipPortBuilder(a.tapInterfaceIP, portBinding.HostPort),
-					Protocol: types.TransportProtocol(strings.ToLower(portProto.Proto())),
-				})
+			err := a.expose(exposeReq)
+			if errors.Is(err, forwarder.ErrPortConflict) && exposedBefore {
+				// The host port is taken by this very port binding.
+				log.Debugf("port binding %+v is already exposed as %s", portBinding, exposeReq.Local)
+
+				err = nil
+			}
+			// Only remap host ports that were not chosen by the policy.
+			if errors.Is(err, forwarder.ErrPortConflict) && a.remapRange != nil &&
+				hostBinding(remaps, portProto, portBinding) == portBinding {
+				var remap *guestagentTypes.PortRemap
+
+				remap, err = a.exposeRemapped(portProto, portBinding, portBinding, remaps)
+				if err == nil {
+					remaps = append(remaps, *remap)
+				}
+			}
+
 			if err != nil {
 				errs = append(errs, fmt.Errorf("exposing %+v failed: %w", portBinding, err))
 
@@ -120,8 +213,14 @@ func (a *APITracker) Add(containerID string, portMap nat.PortMap) error {
 		}
 	}
 
+	remaps = slices.DeleteFunc(remaps, func(remap guestagentTypes.PortRemap) bool {
+		return !slices.Contains(successfullyForwarded[remap.Port], remap.Requested)
+	})
+
 	if len(successfullyForwarded) != 0 {
 		a.portStorage.add(containerID, successfullyForwarded)
+		a.remapStorage.set(containerID, remaps)
+		a.saveJournal()
 		portMapping := guestagentTypes.PortMapping{
 			Remove: false,
 			Ports:  successfullyForwarded,
@@ -129,10 +228,15 @@ func (a *APITracker) Add(containerID string, portMap nat.PortMap) error {
 		log.Debugf("forwarding to wsl-proxy to add port mapping: %+v", portMapping)
 		err := a.wslProxyForwarder.Send(portMapping)
 		if err != nil {
-			return fmt.Errorf("sending port mappings to wsl proxy error: %w", err)
+			err = fmt.Errorf("sending port mappings to wsl proxy error: %w", err)
+			a.statusStorage.update(source, containerID, portMap, successfullyForwarded, remaps, denied, append(errs, err))
+
+			return err
 		}
 	}
 
+	a.statusStorage.update(source, containerID, portMap, successfullyForwarded, remaps, denied, errs)
+
 	if len(errs) != 0 {
 		return fmt.Errorf("%w: %+v", forwarder.ErrExposeAPI, errs)
 	}
@@ -140,6 +244,61 @@ func (a *APITracker) Add(containerID string, portMap nat.PortMap) error {
 	return nil
 }
 
+// applyPolicy evaluates the port forwarding policy against the requested
+// port mappings; it returns the port bindings that are allowed, the ones
+// that are exposed with a different address or port, and the ones that
+// are denied.
+func (a *APITracker) applyPolicy(
+	source guestagentTypes.PortMappingSource,
+	containerID string,
+	portMap nat.PortMap,
+	metadata guestagentTypes.PortMappingMetadata,
+) (nat.PortMap, []guestagentTypes.PortRemap, []guestagentTypes.PortDenial) {
+	allowed := make(nat.PortMap)
+
+	var (
+		remaps []guestagentTypes.PortRemap
+		denied []guestagentTypes.PortDenial
+	)
+
+	for portProto, portBindings := range portMap {
+		for _, portBinding := range portBindings {
+			decision := a.policy.Evaluate(policy.Request{
+				Source:   source,
+				Metadata: metadata,
+				Port:     portProto,
+				Binding:  portBinding,
+			})
+			if !decision.Allowed {
+				reason := "port forwarding policy " + cmp.Or(decision.Rule, "default action")
+				log.Infof("%s denied %s on %s for %s (source: %q)",
+					reason, portProto, ipPortBuilder(portBinding.HostIP, portBinding.HostPort), containerID, source)
+				denied = append(denied, guestagentTypes.PortDenial{
+					Port:      portProto,
+					Requested: portBinding,
+					Reason:    reason,
+				})
+
+				continue
+			}
+
+			allowed[portProto] = append(allowed[portProto], portBinding)
+
+			if decision.Binding != portBinding {
+				log.Debugf("port forwarding policy rewrote %s binding %+v to %+v by %s", portProto, portBinding, decision.Binding, decision.Rule)
+				remaps = append(remaps, guestagentTypes.PortRemap{
+					Port:      portProto,
+					Requested: portBinding,
+					Host:      decision.Binding,
+					Reason:    "port forwarding policy " + decision.Rule,
+				})
+			}
+		}
+	}
+
+	return allowed, remaps, denied
+}
+
 // Get looks up the port mapping by containerID and returns the result.
 func (a *APITracker) Get(containerID string) nat.PortMap {
 	return a.portStorage.get(containerID)
@@ -149,7 +308,11 @@ func (a *APITracker) Get(containerID string) nat.PortMap {
 // /services/forwarder/unexpose endpoint to remove the forwarded port mappings.
 func (a *APITracker) Remove(containerID string) error {
 	portMap := a.portStorage.get(containerID)
+	remaps := a.remapStorage.get(containerID)
+	defer a.saveJournal()
 	defer a.portStorage.remove(containerID)
+	defer a.remapStorage.remove(containerID)
+	defer a.statusStorage.remove(containerID)
 
 	var errs []error
 
@@ -164,9 +327,10 @@ func (a *APITracker) Remove(containerID string) error {
 
 			log.Debugf("unexposing the following port binding: %+v", portBinding)
 
-			err = a.apiForwarder.Unexpose(
+			hostPortBinding := hostBinding(remaps, portProto, portBinding)
+			err = a.unexpose(
 				&types.UnexposeRequest{
-					Local:    ipPortBuilder(a.determineHostIP(portBinding.HostIP), portBinding.HostPort),
+					Local:    ipPortBuilder(a.determineHostIP(hostPortBinding.HostIP), hostPortBinding.HostPort),
 					Protocol: types.TransportProtocol(strings.ToLower(portProto.Proto())),
 				})
 			if err != nil {
@@ -202,8 +366,10 @@ func (a *APITracker) Remove(containerID string) error {
 func (a *APITracker) RemoveAll() error {
 	var apiErrs, wslProxyErrs []error
 
-	for _, portMapping := range a.portStorage.getAll() {
-		for _, portBindings := range portMapping {
+	for containerID, portMapping := range a.portStorage.getAll() {
+		remaps := a.remapStorage.get(containerID)
+
+		for portProto, portBindings := range portMapping {
 			for _, portBinding := range portBindings {
 				// The unexpose API only supports IPv4
 				ipv4, err := isIPv4(portBinding.HostIP)
@@ -213,9 +379,10 @@ func (a *APITracker) RemoveAll() error {
 
 				log.Debugf("unexposing the following port binding: %+v", portBinding)
 
-				err = a.apiForwarder.Unexpose(
+				hostPortBinding := hostBinding(remaps, portProto, portBinding)
+				err = a.unexpose(
 					&types.UnexposeRequest{
-						Local: ipPortBuilder(a.determineHostIP(portBinding.HostIP), portBinding.HostPort),
+						Local: ipPortBuilder(a.determineHostIP(hostPortBinding.HostIP), hostPortBinding.HostPort),
 					})
 				if err != nil {
 					apiErrs = append(apiErrs,
@@ -240,6 +407,9 @@ func (a *APITracker) RemoveAll() error {
 	}
 
 	a.portStorage.removeAll()
+	a.remapStorage.removeAll()
+	a.statusStorage.removeAll()
+	a.saveJournal()
 
 	if len(apiErrs) != 0 {
 		return fmt.Errorf("%w: %+v", forwarder.ErrUnexposeAPI, apiErrs)
@@ -252,6 +422,315 @@ func (a *APITracker) RemoveAll() error {
 	return nil
 }
 
+// Reconcile restores the port mappings that a previous instance of the
+// agent journaled to stateFile, and makes the host switch match them:
+// exposures that the previous instance created on the host but that are
+// not in its port mappings anymore are removed, and journaled exposures
+// that are missing on the host are exposed again. Exposures that the
+// agent did not create are never touched. Restored port mappings that are
+// not added again (e.g. by the container event monitors) within
+// restoreGracePeriod are removed. From then on, the exposed port mappings
+// are journaled to stateFile; if the exposed ports can't be listed, the
+// state file is left untouched and nothing is journaled. This must be
+// called before any port mappings are added.
+func (a *APITracker) Reconcile(stateFile string) error {
+	state, err := loadJournal(stateFile)
+	if err != nil {
+		return err
+	}
+
+	exposed, err := a.listExposed()
+	if err != nil {
+		return fmt.Errorf("failed to list exposed ports: %w", err)
+	}
+
+	// The exposures that should exist, keyed by local address and protocol.
+	missing := make(map[string]*types.ExposeRequest)
+
+	for _, entry := range state.PortMappings {
+		for portProto, portBindings := range entry.Ports {
+			for _, portBinding := range portBindings {
+				exposeReq := a.exposeRequest(portProto, portBinding, hostBinding(entry.Remaps, portProto, portBinding))
+				if exposeReq != nil {
+					missing[exposeKey(exposeReq.Local, exposeReq.Protocol)] = exposeReq
+				}
+			}
+		}
+	}
+
+	// The exposures that the previous instance created.
+	created := make(map[string]struct{})
+	for _, unexposeReq := range state.Exposed {
+		created[exposeKey(unexposeReq.Local, unexposeReq.Protocol)] = struct{}{}
+	}
+
+	var (
+		errs []error
+		kept []types.UnexposeRequest
+	)
+
+	for _, exposeReq := range exposed {
+		key := exposeKey(exposeReq.Local, exposeReq.Protocol)
+		unexposeReq := types.UnexposeRequest{Local: exposeReq.Local, Protocol: exposeReq.Protocol}
+
+		if _, ok := missing[key]; ok {
+			delete(missing, key)
+
+			kept = append(kept, unexposeReq)
+
+			continue
+		}
+
+		if _, ok := created[key]; !ok {
+			// This was not exposed by the agent.
+			continue
+		}
+
+		log.Infof("removing orphaned exposed port %s (%s)", exposeReq.Local, exposeReq.Protocol)
+
+		if err := a.apiForwarder.Unexpose(&unexposeReq); err != nil {
+			errs = append(errs, fmt.Errorf("unexposing orphaned %s failed: %w", exposeReq.Local, err))
+			// Try again the next time.
+			kept = append(kept, unexposeReq)
+		}
+	}
+
+	a.journal = newJournal(stateFile, kept)
+
+	for _, exposeReq := range missing {
+		log.Infof("re-exposing missing port %s (%s)", exposeReq.Local, exposeReq.Protocol)
+
+		if err := a.expose(exposeReq); err != nil {
+			errs = append(errs, fmt.Errorf("re-exposing %s failed: %w", exposeReq.Local, err))
+		}
+	}
+
+	a.restoredMutex.Lock()
+	for _, entry := range state.PortMappings {
+		a.portStorage.add(entry.ID, entry.Ports)
+		a.remapStorage.set(entry.ID, entry.Remaps)
+		a.statusStorage.update(entry.Source, entry.ID, entry.Ports, entry.Ports, entry.Remaps, nil, nil)
+		a.restored[entry.ID] = struct{}{}
+	}
+	a.restoredMutex.Unlock()
+
+	if len(state.PortMappings) != 0 {
+		time.AfterFunc(restoreGracePeriod, a.removeUnconfirmed)
+	}
+
+	a.saveJournal()
+
+	if len(errs) != 0 {
+		return fmt.Errorf("failed to reconcile exposed ports: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// listExposed lists the exposures on the host, retrying for a while in
+// case the host switch is not ready yet.
+func (a *APITracker) listExposed() ([]types.ExposeRequest, error) {
+	for attempt := 1; ; attempt++ {
+		exposed, err := a.apiForwarder.List()
+		if err == nil || attempt == reconcileListAttempts {
+			return exposed, err
+		}
+
+		log.Debugf("failed to list exposed ports (attempt %d): %v", attempt, err)
+
+		select {
+		case <-a.context.Done():
+			return nil, errors.Join(err, a.context.Err())
+		case <-time.After(reconcileListInterval):
+		}
+	}
+}
+
+// confirmRestored marks the port mappings restored from the journal with
+// the given ID as still being in use; it returns whether there were any.
+func (a *APITracker) confirmRestored(containerID string) bool {
+	a.restoredMutex.Lock()
+	defer a.restoredMutex.Unlock()
+
+	_, ok := a.restored[containerID]
+	delete(a.restored, containerID)
+
+	return ok
+}
+
+// removeUnconfirmed removes all port mappings restored from the journal
+// that have not been added again.
+func (a *APITracker) removeUnconfirmed() {
+	a.restoredMutex.Lock()
+	containerIDs := make([]string, 0, len(a.restored))
+	for containerID := range a.restored {
+		containerIDs = append(containerIDs, containerID)
+	}
+	clear(a.restored)
+	a.restoredMutex.Unlock()
+
+	for _, containerID := range containerIDs {
+		if a.context.Err() != nil {
+			return
+		}
+
+		log.Infof("removing stale port mappings for %s restored from the state file", containerID)
+
+		if err := a.Remove(containerID); err != nil {
+			log.Errorf("failed to remove stale port mappings for %s: %v", containerID, err)
+		}
+	}
+}
+
+// unexposeRemoved unexposes the port bindings in existing that are not in
+// portMap, or that are exposed on a different host address or port.
+func (a *APITracker) unexposeRemoved(
+	existing nat.PortMap,
+	existingRemaps []guestagentTypes.PortRemap,
+	portMap nat.PortMap,
+	remaps []guestagentTypes.PortRemap,
+) []error {
+	var errs []error
+
+	for portProto, portBindings := range existing {
+		for _, portBinding := range portBindings {
+			existingHost := hostBinding(existingRemaps, portProto, portBinding)
+			if slices.Contains(portMap[portProto], portBinding) &&
+				existingHost == hostBinding(remaps, portProto, portBinding) {
+				continue
+			}
+
+			exposeReq := a.exposeRequest(portProto, portBinding, existingHost)
+			if exposeReq == nil {
+				continue
+			}
+
+			err := a.unexpose(&types.UnexposeRequest{Local: exposeReq.Local, Protocol: exposeReq.Protocol})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unexposing %+v failed: %w", portBinding, err))
+			}
+		}
+	}
+
+	return errs
+}
+
+// exposeRequest returns the request to expose the given port binding on
+// the host as hostPortBinding, or nil if it can't be exposed.
+func (a *APITracker) exposeRequest(portProto nat.Port, portBinding, hostPortBinding nat.PortBinding) *types.ExposeRequest {
+	// The expose API only supports IPv4
+	for _, hostIP := range []string{portBinding.HostIP, hostPortBinding.HostIP} {
+		if ipv4, err := isIPv4(hostIP); !ipv4 || err != nil {
+			return nil
+		}
+	}
+
+	return &types.ExposeRequest{
+		Local:    ipPortBuilder(a.determineHostIP(hostPortBinding.HostIP), hostPortBinding.HostPort),
+		Remote:   ipPortBuilder(a.tapInterfaceIP, portBinding.HostPort),
+		Protocol: types.TransportProtocol(strings.ToLower(portProto.Proto())),
+	}
+}
+
+// expose creates the exposure on the host. If journaling is enabled, the
+// exposure is journaled before it is created, so that it can be removed
+// when reconciling even if the agent stops right after.
+func (a *APITracker) expose(exposeReq *types.ExposeRequest) error {
+	journaled := false
+
+	if a.journal != nil {
+		var err error
+		if journaled, err = a.journal.addExposed(exposeReq); err != nil {
+			log.Errorf("failed to journal exposed port %s: %v", exposeReq.Local, err)
+		}
+	}
+
+	err := a.apiForwarder.Expose(exposeReq)
+	if err != nil && journaled {
+		// The exposure may belong to someone else (e.g. on a port
+		// conflict); it must not be removed when reconciling. Exposures
+		// that were journaled before are the agent's own, and are kept.
+		a.journalUnexposed(exposeReq.Local, exposeReq.Protocol)
+	}
+
+	return err
+}
+
+// unexpose removes the exposure from the host.
+func (a *APITracker) unexpose(unexposeReq *types.UnexposeRequest) error {
+	err := a.apiForwarder.Unexpose(unexposeReq)
+	if err == nil {
+		a.journalUnexposed(unexposeReq.Local, unexposeReq.Protocol)
+	}
+
+	return err
+}
+
+// journalUnexposed removes the exposure from the journal, if journaling
+// is enabled.
+func (a *APITracker) journalUnexposed(local string, protocol types.TransportProtocol) {
+	if a.journal == nil {
+		return
+	}
+
+	if err := a.journal.removeExposed(local, protocol); err != nil {
+		log.Errorf("failed to journal unexposed port %s: %v", local, err)
+	}
+}
+
+// saveJournal writes the exposed port mappings to the state file, if
+// journaling is enabled.
+func (a *APITracker) saveJournal() {
+	if a.journal == nil {
+		return
+	}
+
+	err := a.journal.save(func() []journalEntry {
+		entries := []journalEntry{}
+		for containerID, portMap := range a.portStorage.getAll() {
+			entries = append(entries, journalEntry{
+				ID:     containerID,
+				Source: a.statusStorage.source(containerID),
+				Ports:  portMap,
+				Remaps: a.remapStorage.get(containerID),
+			})
+		}
+
+		return entries
+	})
+	if err != nil {
+		log.Errorf("failed to journal port mappings: %v", err)
+	}
+}
+
+// RecordError records that the port mappings failed to be exposed, after
+// the caller removed them from the tracker.
+func (a *APITracker) RecordError(containerID string, portMap nat.PortMap, err error) {
+	a.recordErrorFromSource("", containerID, portMap, err)
+}
+
+func (a *APITracker) recordErrorFromSource(
+	source guestagentTypes.PortMappingSource,
+	containerID string,
+	portMap nat.PortMap,
+	err error,
+) {
+	a.statusStorage.update(source, containerID, portMap, nil, nil, nil, []error{err})
+}
+
+// ForgetError removes the status recorded by RecordError.
+func (a *APITracker) ForgetError(containerID string) {
+	if len(a.portStorage.get(containerID)) == 0 {
+		a.statusStorage.remove(containerID)
+	}
+}
+
+// Status returns the status of all the port mappings being tracked,
+// including the ones that could not be exposed.
+func (a *APITracker) Status() []guestagentTypes.PortMappingStatus {
+	return a.statusStorage.list()
+}
+
 func (a *APITracker) determineHostIP(hostIP string) string {
 	// If Rancher Desktop is installed as non-admin, we use the
 	// localhost IP address since binding to a port on 127.0.0.1
@@ -263,6 +742,15 @@ func (a *APITracker) determineHostIP(hostIP string) string {
 	return hostIP
 }
 
+// exposeKey identifies an exposed port on the host.
+func exposeKey(local string, protocol types.TransportProtocol) string {
+	if protocol == "" {
+		protocol = "tcp"
+	}
+
+	return local + "/" + string(protocol)
+}
+
 func ipPortBuilder(ip, port string) string {
 	return ip + ":" + port
 }
@@ -16,9 +16,13 @@ package tracker_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/containers/gvisor-tap-vsock/pkg/types"
@@ -27,6 +31,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/forwarder"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/policy"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/tracker"
 	guestagentType "github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
 )
@@ -683,6 +688,545 @@ func TestNonAdminInstall(t *testing.T) {
 	assert.Nil(t, portMapping)
 }
 
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/services/forwarder/expose", func(w http.ResponseWriter, r *http.Request) {
+		var tmpReq *types.ExposeRequest
+		err := json.NewDecoder(r.Body).Decode(&tmpReq)
+		require.NoError(t, err)
+		if tmpReq.Local == ipPortBuilder(hostIP2, hostPort) {
+			http.Error(w, "Bad API error", http.StatusRequestTimeout)
+
+			return
+		}
+	})
+	mux.HandleFunc("/services/forwarder/unexpose", func(_ http.ResponseWriter, _ *http.Request) {})
+
+	testSrv := httptest.NewServer(mux)
+	defer testSrv.Close()
+
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true)
+	dockerTracker := tracker.WithSource(apiTracker, guestagentType.SourceDocker)
+
+	protoPort, err := nat.NewPort(protocolTCP, hostPort)
+	require.NoError(t, err)
+
+	exposedBinding := nat.PortBinding{HostIP: hostIP, HostPort: hostPort}
+	failedBinding := nat.PortBinding{HostIP: hostIP2, HostPort: hostPort}
+
+	require.NoError(t, apiTracker.Add(containerID, nat.PortMap{protoPort: {exposedBinding}}))
+	require.Error(t, dockerTracker.Add(containerID2, nat.PortMap{protoPort: {failedBinding}}))
+
+	statuses := apiTracker.Status()
+	require.Len(t, statuses, 2)
+
+	assert.Equal(t, containerID, statuses[0].ID)
+	assert.Empty(t, statuses[0].Source)
+	assert.Equal(t, nat.PortMap{protoPort: {exposedBinding}}, statuses[0].Ports)
+	assert.Empty(t, statuses[0].Errors)
+	assert.False(t, statuses[0].Created.IsZero())
+	assert.Equal(t, statuses[0].Created, statuses[0].Updated)
+
+	assert.Equal(t, containerID2, statuses[1].ID)
+	assert.Equal(t, guestagentType.SourceDocker, statuses[1].Source)
+	assert.Equal(t, nat.PortMap{protoPort: {failedBinding}}, statuses[1].Requested)
+	assert.Empty(t, statuses[1].Ports)
+	require.Len(t, statuses[1].Errors, 1)
+	assert.Contains(t, statuses[1].Errors[0], "Bad API error")
+	assert.Nil(t, dockerTracker.Get(containerID2), "failed port mappings should not be tracked")
+
+	require.NoError(t, dockerTracker.Remove(containerID2))
+	statuses = apiTracker.Status()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, containerID, statuses[0].ID)
+
+	// Errors recorded after rolling back a failed mapping stay visible.
+	tracker.RecordError(dockerTracker, containerID2, nat.PortMap{protoPort: {failedBinding}}, errors.New("rolled back"))
+	statuses = apiTracker.Status()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, containerID2, statuses[1].ID)
+	assert.Equal(t, guestagentType.SourceDocker, statuses[1].Source)
+	assert.Equal(t, nat.PortMap{protoPort: {failedBinding}}, statuses[1].Requested)
+	assert.Equal(t, []string{"rolled back"}, statuses[1].Errors)
+	tracker.ForgetError(dockerTracker, containerID)
+	tracker.ForgetError(dockerTracker, containerID2)
+	statuses = apiTracker.Status()
+	require.Len(t, statuses, 1, "forgetting errors should not drop exposed port mappings")
+	assert.Equal(t, containerID, statuses[0].ID)
+
+	require.NoError(t, apiTracker.RemoveAll())
+	assert.Empty(t, apiTracker.Status())
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	var exposeReqs []*types.ExposeRequest
+	var unexposeReqs []*types.UnexposeRequest
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/services/forwarder/expose", func(_ http.ResponseWriter, r *http.Request) {
+		var tmpReq *types.ExposeRequest
+		err := json.NewDecoder(r.Body).Decode(&tmpReq)
+		require.NoError(t, err)
+		exposeReqs = append(exposeReqs, tmpReq)
+	})
+	mux.HandleFunc("/services/forwarder/unexpose", func(_ http.ResponseWriter, r *http.Request) {
+		var tmpReq *types.UnexposeRequest
+		err := json.NewDecoder(r.Body).Decode(&tmpReq)
+		require.NoError(t, err)
+		unexposeReqs = append(unexposeReqs, tmpReq)
+	})
+
+	testSrv := httptest.NewServer(mux)
+	defer testSrv.Close()
+
+	portPolicy, err := policy.Parse([]byte(`{
+		"rules": [
+			{"name": "databases", "action": "deny", "match": {"ports": ["5432"], "bindAddresses": ["0.0.0.0"]}},
+			{"action": "rewrite", "match": {"ports": ["80"], "labels": {"app": "*"}}, "hostIP": "127.0.0.1", "hostPort": "8080"}
+		]
+	}`))
+	require.NoError(t, err)
+
+	testForwarder := &testForwarder{}
+	apiTracker := tracker.NewAPITracker(context.Background(), testForwarder, testSrv.URL, hostSwitchIP, true)
+	apiTracker.SetPolicy(portPolicy)
+	dockerTracker := tracker.WithSource(apiTracker, guestagentType.SourceDocker)
+
+	dbPort, err := nat.NewPort(protocolTCP, "5432")
+	require.NoError(t, err)
+
+	webPort, err := nat.NewPort(protocolTCP, hostPort)
+	require.NoError(t, err)
+
+	deniedBinding := nat.PortBinding{HostIP: "0.0.0.0", HostPort: "5432"}
+	allowedBinding := nat.PortBinding{HostIP: hostIP, HostPort: "5432"}
+	webBinding := nat.PortBinding{HostIP: "0.0.0.0", HostPort: hostPort}
+	portMapping := nat.PortMap{
+		dbPort:  {deniedBinding, allowedBinding},
+		webPort: {webBinding},
+	}
+
+	metadata := guestagentType.PortMappingMetadata{Labels: map[string]string{"app": "web"}}
+	require.NoError(t, tracker.AddWithMetadata(dockerTracker, containerID, portMapping, metadata))
+
+	assert.ElementsMatch(t, exposeReqs, []*types.ExposeRequest{
+		{
+			Local:    ipPortBuilder(hostIP, "5432"),
+			Remote:   ipPortBuilder(hostSwitchIP, "5432"),
+			Protocol: types.TransportProtocol(protocolTCP),
+		},
+		{
+			Local:    ipPortBuilder(hostIP, additionalPort),
+			Remote:   ipPortBuilder(hostSwitchIP, hostPort),
+			Protocol: types.TransportProtocol(protocolTCP),
+		},
+	})
+
+	expectedPortMapping := nat.PortMap{
+		dbPort:  {allowedBinding},
+		webPort: {webBinding},
+	}
+	assert.Equal(t, expectedPortMapping, apiTracker.Get(containerID))
+	require.Len(t, testForwarder.receivedPortMappings, 1)
+	assert.Equal(t, expectedPortMapping, testForwarder.receivedPortMappings[0].Ports,
+		"rewrites should not apply to wsl-proxy")
+
+	statuses := apiTracker.Status()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, portMapping, statuses[0].Requested)
+	assert.Equal(t, expectedPortMapping, statuses[0].Ports)
+	assert.Equal(t, []guestagentType.PortRemap{
+		{
+			Port:      webPort,
+			Requested: webBinding,
+			Host:      nat.PortBinding{HostIP: hostIP, HostPort: additionalPort},
+			Reason:    "port forwarding policy rule 2",
+		},
+	}, statuses[0].Remaps)
+	assert.Equal(t, []guestagentType.PortDenial{
+		{
+			Port:      dbPort,
+			Requested: deniedBinding,
+			Reason:    "port forwarding policy rule 1 (databases)",
+		},
+	}, statuses[0].Denied)
+	assert.Empty(t, statuses[0].Errors)
+
+	// Without the labels, the rewrite rule does not apply.
+	require.NoError(t, apiTracker.Add(containerID2, nat.PortMap{webPort: {webBinding}}))
+	assert.Equal(t, ipPortBuilder("0.0.0.0", hostPort), exposeReqs[len(exposeReqs)-1].Local)
+
+	require.NoError(t, apiTracker.Remove(containerID))
+	assert.ElementsMatch(t, unexposeReqs, []*types.UnexposeRequest{
+		{
+			Local:    ipPortBuilder(hostIP, "5432"),
+			Protocol: types.TransportProtocol(protocolTCP),
+		},
+		{
+			Local:    ipPortBuilder(hostIP, additionalPort),
+			Protocol: types.TransportProtocol(protocolTCP),
+		},
+	})
+}
+
+func TestPortConflict(t *testing.T) {
+	t.Parallel()
+
+	var (
+		exposeReqs   []*types.ExposeRequest
+		unexposeReqs []*types.UnexposeRequest
+		mutex        sync.Mutex
+	)
+
+	// The first port of the remap range is taken by a process on the host.
+	exposed := map[string]bool{ipPortBuilder("0.0.0.0", "49152"): true}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/services/forwarder/expose", func(w http.ResponseWriter, r *http.Request) {
+		var tmpReq *types.ExposeRequest
+		err := json.NewDecoder(r.Body).Decode(&tmpReq)
+		require.NoError(t, err)
+		mutex.Lock()
+		defer mutex.Unlock()
+		if exposed[tmpReq.Local] {
+			http.Error(w, fmt.Sprintf("listen tcp %s: bind: address already in use", tmpReq.Local), http.StatusInternalServerError)
+
+			return
+		}
+		exposed[tmpReq.Local] = true
+		exposeReqs = append(exposeReqs, tmpReq)
+	})
+	mux.HandleFunc("/services/forwarder/unexpose", func(_ http.ResponseWriter, r *http.Request) {
+		var tmpReq *types.UnexposeRequest
+		err := json.NewDecoder(r.Body).Decode(&tmpReq)
+		require.NoError(t, err)
+		mutex.Lock()
+		defer mutex.Unlock()
+		delete(exposed, tmpReq.Local)
+		unexposeReqs = append(unexposeReqs, tmpReq)
+	})
+
+	testSrv := httptest.NewServer(mux)
+	defer testSrv.Close()
+
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true)
+	require.Error(t, apiTracker.SetRemapPortRange("49160-49152"))
+	require.Error(t, apiTracker.SetRemapPortRange("49152"))
+	require.NoError(t, apiTracker.SetRemapPortRange("49152-49160"))
+
+	protoPort, err := nat.NewPort(protocolTCP, hostPort)
+	require.NoError(t, err)
+
+	portBinding := nat.PortBinding{HostIP: "0.0.0.0", HostPort: hostPort}
+	portMapping := nat.PortMap{protoPort: {portBinding}}
+
+	require.NoError(t, apiTracker.Add(containerID, portMapping))
+	require.NoError(t, apiTracker.Add(containerID2, portMapping))
+
+	require.Len(t, exposeReqs, 2)
+	assert.Equal(t, &types.ExposeRequest{
+		Local:    ipPortBuilder("0.0.0.0", "49153"),
+		Remote:   ipPortBuilder(hostSwitchIP, hostPort),
+		Protocol: types.TransportProtocol(protocolTCP),
+	}, exposeReqs[1])
+
+	assert.Equal(t, portMapping, apiTracker.Get(containerID2))
+
+	statuses := apiTracker.Status()
+	require.Len(t, statuses, 2)
+
+	for _, status := range statuses {
+		if status.ID != containerID2 {
+			assert.Empty(t, status.Remaps)
+
+			continue
+		}
+
+		assert.Equal(t, []guestagentType.PortRemap{
+			{
+				Port:      protoPort,
+				Requested: portBinding,
+				Host:      nat.PortBinding{HostIP: "0.0.0.0", HostPort: "49153"},
+				Reason:    "host port conflict on " + ipPortBuilder("0.0.0.0", hostPort),
+			},
+		}, status.Remaps)
+		assert.Empty(t, status.Errors)
+	}
+
+	require.NoError(t, apiTracker.Remove(containerID2))
+	assert.Equal(t, []*types.UnexposeRequest{
+		{
+			Local:    ipPortBuilder("0.0.0.0", "49153"),
+			Protocol: types.TransportProtocol(protocolTCP),
+		},
+	}, unexposeReqs)
+}
+
+func TestPortConflictWithoutRemap(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/services/forwarder/expose", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "proxy already running", http.StatusInternalServerError)
+	})
+
+	testSrv := httptest.NewServer(mux)
+	defer testSrv.Close()
+
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true)
+
+	protoPort, err := nat.NewPort(protocolTCP, hostPort)
+	require.NoError(t, err)
+
+	err = apiTracker.Add(containerID, nat.PortMap{protoPort: {{HostIP: hostIP, HostPort: hostPort}}})
+	require.ErrorIs(t, err, forwarder.ErrExposeAPI)
+
+	statuses := apiTracker.Status()
+	require.Len(t, statuses, 1)
+	require.Len(t, statuses[0].Errors, 1)
+	assert.Contains(t, statuses[0].Errors[0], forwarder.ErrPortConflict.Error())
+	assert.Empty(t, statuses[0].Remaps)
+}
+
+func TestPortConflictWithItself(t *testing.T) {
+	t.Parallel()
+
+	var (
+		exposeCalls int
+		mutex       sync.Mutex
+	)
+
+	exposed := make(map[string]bool)
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/services/forwarder/all", func(w http.ResponseWriter, _ *http.Request) {
+		require.NoError(t, json.NewEncoder(w).Encode([]types.ExposeRequest{}))
+	})
+	mux.HandleFunc("/services/forwarder/expose", func(w http.ResponseWriter, r *http.Request) {
+		var tmpReq *types.ExposeRequest
+		err := json.NewDecoder(r.Body).Decode(&tmpReq)
+		require.NoError(t, err)
+		mutex.Lock()
+		defer mutex.Unlock()
+		exposeCalls++
+		if exposed[tmpReq.Local] {
+			http.Error(w, "proxy already running", http.StatusInternalServerError)
+
+			return
+		}
+		exposed[tmpReq.Local] = true
+	})
+
+	testSrv := httptest.NewServer(mux)
+	defer testSrv.Close()
+
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true)
+	require.NoError(t, apiTracker.SetRemapPortRange("49152-49160"))
+	require.NoError(t, apiTracker.Reconcile(stateFile))
+
+	protoPort, err := nat.NewPort(protocolTCP, hostPort)
+	require.NoError(t, err)
+
+	portMapping := nat.PortMap{protoPort: {{HostIP: "0.0.0.0", HostPort: hostPort}}}
+
+	// Adding the same port mappings again (e.g. when a container is
+	// updated) must neither fail nor remap them.
+	require.NoError(t, apiTracker.Add(containerID, portMapping))
+	require.NoError(t, apiTracker.Add(containerID, portMapping))
+	assert.Equal(t, 2, exposeCalls)
+	assert.Equal(t, portMapping, apiTracker.Get(containerID))
+
+	statuses := apiTracker.Status()
+	require.Len(t, statuses, 1)
+	assert.Empty(t, statuses[0].Remaps)
+	assert.Empty(t, statuses[0].Errors)
+
+	// The exposure must stay journaled, so that it is cleaned up if the
+	// agent stops.
+	contents, err := os.ReadFile(stateFile)
+	require.NoError(t, err)
+
+	var state struct {
+		Exposed []types.UnexposeRequest `json:"exposed"`
+	}
+
+	require.NoError(t, json.Unmarshal(contents, &state))
+	assert.Equal(t, []types.UnexposeRequest{
+		{Local: ipPortBuilder("0.0.0.0", hostPort), Protocol: types.TransportProtocol(protocolTCP)},
+	}, state.Exposed)
+}
+
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+
+	var exposeReqs []*types.ExposeRequest
+	var unexposeReqs []*types.UnexposeRequest
+
+	listCalls := 0
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/services/forwarder/all", func(w http.ResponseWriter, _ *http.Request) {
+		listCalls++
+		if listCalls == 1 {
+			// The host switch is not ready yet.
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+
+			return
+		}
+
+		err := json.NewEncoder(w).Encode([]types.ExposeRequest{
+			{
+				Local:    ipPortBuilder(hostIP, hostPort),
+				Remote:   ipPortBuilder(hostSwitchIP, hostPort),
+				Protocol: types.TransportProtocol(protocolTCP),
+			},
+			{
+				// Orphaned: exposed by a previous instance, which then
+				// removed the port mapping without unexposing it.
+				Local:    ipPortBuilder(hostIP, additionalPort),
+				Remote:   ipPortBuilder(hostSwitchIP, additionalPort),
+				Protocol: types.TransportProtocol(protocolTCP),
+			},
+			{
+				// A static port forward of the host switch.
+				Local:    ipPortBuilder(hostIP, "6443"),
+				Remote:   ipPortBuilder(hostSwitchIP, "6443"),
+				Protocol: types.TransportProtocol(protocolTCP),
+			},
+			{
+				// Not exposed by the agent.
+				Local:    ipPortBuilder(hostIP, "9000"),
+				Remote:   ipPortBuilder("192.168.127.254", "9000"),
+				Protocol: types.TransportProtocol(protocolTCP),
+			},
+		})
+		require.NoError(t, err)
+	})
+	mux.HandleFunc("/services/forwarder/expose", func(_ http.ResponseWriter, r *http.Request) {
+		var tmpReq *types.ExposeRequest
+		err := json.NewDecoder(r.Body).Decode(&tmpReq)
+		require.NoError(t, err)
+		exposeReqs = append(exposeReqs, tmpReq)
+	})
+	mux.HandleFunc("/services/forwarder/unexpose", func(_ http.ResponseWriter, r *http.Request) {
+		var tmpReq *types.UnexposeRequest
+		err := json.NewDecoder(r.Body).Decode(&tmpReq)
+		require.NoError(t, err)
+		unexposeReqs = append(unexposeReqs, tmpReq)
+	})
+
+	testSrv := httptest.NewServer(mux)
+	defer testSrv.Close()
+
+	protoPort, err := nat.NewPort(protocolTCP, hostPort)
+	require.NoError(t, err)
+
+	protoPort2, err := nat.NewPort(protocolTCP, hostPort2)
+	require.NoError(t, err)
+
+	portMapping := nat.PortMap{
+		protoPort: []nat.PortBinding{
+			{
+				HostIP:   hostIP,
+				HostPort: hostPort,
+			},
+		},
+		protoPort2: []nat.PortBinding{
+			{
+				// Journaled, but missing on the host.
+				HostIP:   hostIP,
+				HostPort: hostPort2,
+			},
+		},
+	}
+
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	journal, err := json.Marshal(map[string]any{
+		"portMappings": []map[string]any{
+			{"id": containerID, "source": guestagentType.SourceDocker, "ports": portMapping},
+		},
+		"exposed": []types.UnexposeRequest{
+			{Local: ipPortBuilder(hostIP, hostPort), Protocol: types.TransportProtocol(protocolTCP)},
+			{Local: ipPortBuilder(hostIP, additionalPort), Protocol: types.TransportProtocol(protocolTCP)},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(stateFile, journal, 0o644))
+
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true)
+	require.NoError(t, apiTracker.Reconcile(stateFile))
+	assert.Equal(t, 2, listCalls, "listing the exposed ports should be retried")
+
+	assert.Equal(t, []*types.UnexposeRequest{
+		{
+			Local:    ipPortBuilder(hostIP, additionalPort),
+			Protocol: types.TransportProtocol(protocolTCP),
+		},
+	}, unexposeReqs)
+	assert.Equal(t, []*types.ExposeRequest{
+		{
+			Local:    ipPortBuilder(hostIP, hostPort2),
+			Remote:   ipPortBuilder(hostSwitchIP, hostPort2),
+			Protocol: types.TransportProtocol(protocolTCP),
+		},
+	}, exposeReqs)
+	assert.Equal(t, portMapping, apiTracker.Get(containerID))
+
+	statuses := apiTracker.Status()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, guestagentType.SourceDocker, statuses[0].Source)
+
+	// Adding the restored port mappings again should not expose them again.
+	exposeReqs = nil
+	require.NoError(t, apiTracker.Add(containerID, portMapping))
+	assert.Empty(t, exposeReqs)
+	assert.Equal(t, portMapping, apiTracker.Get(containerID))
+
+	require.NoError(t, apiTracker.Remove(containerID))
+	contents, err := os.ReadFile(stateFile)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"portMappings": [], "exposed": []}`, string(contents))
+}
+
+func TestReconcileListFailure(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/services/forwarder/all", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/services/forwarder/expose", func(_ http.ResponseWriter, _ *http.Request) {})
+
+	testSrv := httptest.NewServer(mux)
+	defer testSrv.Close()
+
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	journal := `{"portMappings": [], "exposed": [{"local": "127.0.0.1:8080", "protocol": "tcp"}]}`
+	require.NoError(t, os.WriteFile(stateFile, []byte(journal), 0o644))
+
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true)
+	require.ErrorIs(t, apiTracker.Reconcile(stateFile), forwarder.ErrAllAPI)
+
+	// The previous journal must be kept for the next attempt.
+	protoPort, err := nat.NewPort(protocolTCP, hostPort)
+	require.NoError(t, err)
+	require.NoError(t, apiTracker.Add(containerID, nat.PortMap{protoPort: {{HostIP: hostIP, HostPort: hostPort}}}))
+	contents, err := os.ReadFile(stateFile)
+	require.NoError(t, err)
+	assert.JSONEq(t, journal, string(contents))
+}
+
 func ipPortBuilder(ip, port string) string {
 	return ip + ":" + port
 }
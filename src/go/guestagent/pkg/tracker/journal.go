@@ -0,0 +1,189 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/containers/gvisor-tap-vsock/pkg/types"
+	"github.com/docker/go-connections/nat"
+
+	guestagentTypes "github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
+)
+
+// journalEntry is a port mapping that was exposed on the host, as
+// recorded in the state file.
+type journalEntry struct {
+	ID     string                            `json:"id"`
+	Source guestagentTypes.PortMappingSource `json:"source,omitempty"`
+	Ports  nat.PortMap                       `json:"ports"`
+	Remaps []guestagentTypes.PortRemap       `json:"remaps,omitempty"`
+}
+
+// journalState is the contents of the state file.
+type journalState struct {
+	PortMappings []journalEntry `json:"portMappings"`
+	// Exposed lists the exposures that the agent created on the host and
+	// has not removed since. Only these are ever removed when reconciling,
+	// so that exposures created by others (e.g. the static port forwards
+	// of the host switch) are left alone.
+	Exposed []types.UnexposeRequest `json:"exposed"`
+}
+
+// journal persists the exposed port mappings to a state file, so that
+// they can be reconciled if the agent restarts unexpectedly.
+type journal struct {
+	path  string
+	mutex sync.Mutex
+	// entries are the port mappings last passed to save.
+	entries []journalEntry
+	// exposed are the exposures created on the host, by exposeKey.
+	exposed map[string]types.UnexposeRequest
+}
+
+// newJournal returns a journal writing to the state file at path, that
+// starts out with the given exposures on the host.
+func newJournal(path string, exposed []types.UnexposeRequest) *journal {
+	j := &journal{path: path, exposed: make(map[string]types.UnexposeRequest)}
+	for _, unexposeReq := range exposed {
+		j.exposed[exposeKey(unexposeReq.Local, unexposeReq.Protocol)] = unexposeReq
+	}
+
+	return j
+}
+
+// loadJournal reads the state file at path; a missing state file is
+// treated as an empty journal.
+func loadJournal(path string) (journalState, error) {
+	var state journalState
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return state, fmt.Errorf("failed to read state file %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+
+	return state, nil
+}
+
+// save replaces the journaled port mappings with the ones returned by
+// snapshot. The snapshot is taken while holding the lock, so that
+// concurrent saves always leave the latest state on disk.
+func (j *journal) save(snapshot func() []journalEntry) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.entries = snapshot()
+
+	return j.write()
+}
+
+// addExposed records an exposure on the host. It must be called before
+// the exposure is created, so that it is never left out of the state
+// file if the agent stops in between. It returns whether the exposure was
+// newly journaled, rather than already recorded by an earlier call.
+func (j *journal) addExposed(exposeReq *types.ExposeRequest) (bool, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	key := exposeKey(exposeReq.Local, exposeReq.Protocol)
+	if _, ok := j.exposed[key]; ok {
+		return false, nil
+	}
+
+	j.exposed[key] = types.UnexposeRequest{
+		Local:    exposeReq.Local,
+		Protocol: exposeReq.Protocol,
+	}
+
+	return true, j.write()
+}
+
+// removeExposed records that an exposure on the host was removed, or
+// was never created.
+func (j *journal) removeExposed(local string, protocol types.TransportProtocol) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	key := exposeKey(local, protocol)
+	if _, ok := j.exposed[key]; !ok {
+		return nil
+	}
+
+	delete(j.exposed, key)
+
+	return j.write()
+}
+
+// write replaces the state file; the caller must hold the lock.
+func (j *journal) write() error {
+	state := journalState{
+		PortMappings: j.entries,
+		Exposed:      []types.UnexposeRequest{},
+	}
+	if state.PortMappings == nil {
+		state.PortMappings = []journalEntry{}
+	}
+
+	for _, unexposeReq := range j.exposed {
+		state.Exposed = append(state.Exposed, unexposeReq)
+	}
+
+	slices.SortFunc(state.Exposed, func(a, b types.UnexposeRequest) int {
+		return strings.Compare(exposeKey(a.Local, a.Protocol), exposeKey(b.Local, b.Protocol))
+	})
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to serialize port mappings: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary state file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(data); err != nil {
+		_ = tempFile.Close()
+
+		return fmt.Errorf("failed to write temporary state file: %w", err)
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary state file: %w", err)
+	}
+
+	if err := os.Rename(tempFile.Name(), j.path); err != nil {
+		return fmt.Errorf("failed to replace state file %s: %w", j.path, err)
+	}
+
+	return nil
+}
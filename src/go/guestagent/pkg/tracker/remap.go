@@ -0,0 +1,223 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/log-go"
+	"github.com/docker/go-connections/nat"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/forwarder"
+
+	guestagentTypes "github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
+)
+
+const (
+	// conflictReason is the prefix of the reason of port bindings that
+	// were remapped because their host port was in use.
+	conflictReason = "host port conflict on"
+	// maxRemapAttempts limits the alternative host ports that are tried
+	// for a single port binding.
+	maxRemapAttempts = 10
+)
+
+// portRange is an inclusive range of ports.
+type portRange struct {
+	first, last int
+}
+
+// parsePortRange parses a port range such as "49152-49999".
+func parsePortRange(value string) (*portRange, error) {
+	first, last, found := strings.Cut(value, "-")
+	if !found {
+		return nil, fmt.Errorf("invalid port range %q", value)
+	}
+
+	firstPort, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return nil, fmt.Errorf("invalid port range %q: %w", value, err)
+	}
+
+	lastPort, err := strconv.Atoi(strings.TrimSpace(last))
+	if err != nil {
+		return nil, fmt.Errorf("invalid port range %q: %w", value, err)
+	}
+
+	if firstPort < 1 || lastPort > 65535 || firstPort > lastPort {
+		return nil, fmt.Errorf("invalid port range %q", value)
+	}
+
+	return &portRange{first: firstPort, last: lastPort}, nil
+}
+
+// remapStorage keeps the port bindings that are exposed on the host with a
+// different address or port than requested. The port storage only holds
+// the requested port bindings, as those are what the agent listens on.
+type remapStorage struct {
+	remaps map[string][]guestagentTypes.PortRemap
+	mutex  sync.Mutex
+}
+
+func newRemapStorage() *remapStorage {
+	return &remapStorage{
+		remaps: make(map[string][]guestagentTypes.PortRemap),
+	}
+}
+
+// set replaces the remapped port bindings for the given ID.
+func (r *remapStorage) set(containerID string, remaps []guestagentTypes.PortRemap) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(remaps) == 0 {
+		delete(r.remaps, containerID)
+
+		return
+	}
+
+	r.remaps[containerID] = slices.Clone(remaps)
+}
+
+func (r *remapStorage) get(containerID string) []guestagentTypes.PortRemap {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return slices.Clone(r.remaps[containerID])
+}
+
+func (r *remapStorage) remove(containerID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.remaps, containerID)
+}
+
+func (r *remapStorage) removeAll() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	clear(r.remaps)
+}
+
+// hostBinding returns the port binding that the given requested port
+// binding is exposed on the host as.
+func hostBinding(remaps []guestagentTypes.PortRemap, portProto nat.Port, portBinding nat.PortBinding) nat.PortBinding {
+	for _, remap := range remaps {
+		if remap.Port == portProto && remap.Requested == portBinding {
+			return remap.Host
+		}
+	}
+
+	return portBinding
+}
+
+// isConflictRemap returns whether the port binding was remapped because
+// its host port was in use.
+func isConflictRemap(remap guestagentTypes.PortRemap) bool {
+	return strings.HasPrefix(remap.Reason, conflictReason)
+}
+
+// SetRemapPortRange enables exposing port bindings whose host port is
+// already in use on an alternative host port from the given range (e.g.
+// "49152-49999") instead. This must be called before any port mappings
+// are added.
+func (a *APITracker) SetRemapPortRange(value string) error {
+	remapRange, err := parsePortRange(value)
+	if err != nil {
+		return err
+	}
+
+	a.remapRange = remapRange
+
+	return nil
+}
+
+// exposeRemapped exposes the port binding on an alternative host port from
+// the remap port range, as the host port of hostPortBinding is in use.
+// pending lists the remaps for the port mapping being added, which are not
+// yet in the remap storage.
+func (a *APITracker) exposeRemapped(
+	portProto nat.Port,
+	portBinding, hostPortBinding nat.PortBinding,
+	pending []guestagentTypes.PortRemap,
+) (*guestagentTypes.PortRemap, error) {
+	used := a.usedHostPorts(portProto.Proto(), pending)
+	lastErr := forwarder.ErrPortConflict
+	attempts := 0
+
+	for port := a.remapRange.first; port <= a.remapRange.last && attempts < maxRemapAttempts; port++ {
+		remapped := hostPortBinding
+		remapped.HostPort = strconv.Itoa(port)
+
+		if _, ok := used[remapped.HostPort]; ok {
+			continue
+		}
+
+		attempts++
+
+		err := a.expose(a.exposeRequest(portProto, portBinding, remapped))
+		if errors.Is(err, forwarder.ErrPortConflict) {
+			lastErr = err
+
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		log.Infof("host port %s is in use, exposed %s binding %+v on host port %s instead",
+			hostPortBinding.HostPort, portProto, portBinding, remapped.HostPort)
+
+		return &guestagentTypes.PortRemap{
+			Port:      portProto,
+			Requested: portBinding,
+			Host:      remapped,
+			Reason:    fmt.Sprintf("%s %s", conflictReason, ipPortBuilder(hostPortBinding.HostIP, hostPortBinding.HostPort)),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no alternative host port available in %d-%d: %w",
+		a.remapRange.first, a.remapRange.last, lastErr)
+}
+
+// usedHostPorts returns the host ports used by the exposed port bindings
+// of the given protocol.
+func (a *APITracker) usedHostPorts(proto string, pending []guestagentTypes.PortRemap) map[string]struct{} {
+	used := make(map[string]struct{})
+
+	for containerID, portMap := range a.portStorage.getAll() {
+		remaps := a.remapStorage.get(containerID)
+
+		for portProto, portBindings := range portMap {
+			if portProto.Proto() != proto {
+				continue
+			}
+
+			for _, portBinding := range portBindings {
+				used[hostBinding(remaps, portProto, portBinding).HostPort] = struct{}{}
+			}
+		}
+	}
+
+	for _, remap := range pending {
+		if remap.Port.Proto() == proto {
+			used[remap.Host.HostPort] = struct{}{}
+		}
+	}
+
+	return used
+}
@@ -0,0 +1,221 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+
+	guestagentTypes "github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
+)
+
+// StatusReporter is implemented by trackers that can report the
+// status of the port mappings they are tracking.
+type StatusReporter interface {
+	// Status returns the status of all tracked port mappings, sorted by ID.
+	Status() []guestagentTypes.PortMappingStatus
+}
+
+// ErrorRecorder is implemented by trackers that can keep reporting port
+// mappings that failed to be exposed after the caller rolled them back
+// with Remove, which also removes their status.
+type ErrorRecorder interface {
+	// RecordError records that the port mappings failed to be exposed.
+	RecordError(containerID string, portMap nat.PortMap, err error)
+	// ForgetError removes the status recorded by RecordError, once the
+	// port mappings are no longer requested.
+	ForgetError(containerID string)
+}
+
+// RecordError records the error in the status of the tracker, if it
+// supports it.
+func RecordError(tracker Tracker, containerID string, portMap nat.PortMap, err error) {
+	if recorder, ok := tracker.(ErrorRecorder); ok {
+		recorder.RecordError(containerID, portMap, err)
+	}
+}
+
+// ForgetError removes the status recorded by RecordError, if the tracker
+// supports it.
+func ForgetError(tracker Tracker, containerID string) {
+	if recorder, ok := tracker.(ErrorRecorder); ok {
+		recorder.ForgetError(containerID)
+	}
+}
+
+// sourceAdder is implemented by trackers that record the source of
+// the port mappings that are added.
+type sourceAdder interface {
+	addFromSource(
+		source guestagentTypes.PortMappingSource,
+		containerID string,
+		portMap nat.PortMap,
+		metadata guestagentTypes.PortMappingMetadata,
+	) error
+	recordErrorFromSource(
+		source guestagentTypes.PortMappingSource,
+		containerID string,
+		portMap nat.PortMap,
+		err error,
+	)
+}
+
+// sourceTracker wraps a Tracker to attribute added port mappings to a source.
+type sourceTracker struct {
+	Tracker
+	source guestagentTypes.PortMappingSource
+}
+
+// WithSource returns a Tracker that attributes all port mappings added
+// through it to the given source, so that it can be reported in the
+// status. All other calls are passed through to the given tracker.
+func WithSource(tracker Tracker, source guestagentTypes.PortMappingSource) Tracker {
+	return &sourceTracker{Tracker: tracker, source: source}
+}
+
+func (s *sourceTracker) Add(containerID string, portMap nat.PortMap) error {
+	return s.AddWithMetadata(containerID, portMap, guestagentTypes.PortMappingMetadata{})
+}
+
+func (s *sourceTracker) AddWithMetadata(containerID string, portMap nat.PortMap, metadata guestagentTypes.PortMappingMetadata) error {
+	if adder, ok := s.Tracker.(sourceAdder); ok {
+		return adder.addFromSource(s.source, containerID, portMap, metadata)
+	}
+
+	return AddWithMetadata(s.Tracker, containerID, portMap, metadata)
+}
+
+func (s *sourceTracker) RecordError(containerID string, portMap nat.PortMap, err error) {
+	if adder, ok := s.Tracker.(sourceAdder); ok {
+		adder.recordErrorFromSource(s.source, containerID, portMap, err)
+
+		return
+	}
+
+	RecordError(s.Tracker, containerID, portMap, err)
+}
+
+func (s *sourceTracker) ForgetError(containerID string) {
+	ForgetError(s.Tracker, containerID)
+}
+
+// statusStorage keeps the status of the port mappings, including
+// the ones that failed to be exposed.
+type statusStorage struct {
+	statuses map[string]*guestagentTypes.PortMappingStatus
+	mutex    sync.Mutex
+}
+
+func newStatusStorage() *statusStorage {
+	return &statusStorage{
+		statuses: make(map[string]*guestagentTypes.PortMappingStatus),
+	}
+}
+
+// update records the result of exposing the requested port mappings.
+func (s *statusStorage) update(
+	source guestagentTypes.PortMappingSource,
+	containerID string,
+	requested, exposed nat.PortMap,
+	remaps []guestagentTypes.PortRemap,
+	denied []guestagentTypes.PortDenial,
+	errs []error,
+) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	status, ok := s.statuses[containerID]
+	if !ok {
+		status = &guestagentTypes.PortMappingStatus{ID: containerID, Created: now}
+		s.statuses[containerID] = status
+	}
+
+	if source != "" {
+		status.Source = source
+	}
+
+	status.Requested = clonePortMap(requested)
+	status.Ports = clonePortMap(exposed)
+	status.Remaps = slices.Clone(remaps)
+	status.Denied = slices.Clone(denied)
+	status.Errors = nil
+
+	for _, err := range errs {
+		status.Errors = append(status.Errors, err.Error())
+	}
+
+	status.Updated = now
+}
+
+// source returns the source of the port mappings with the given ID.
+func (s *statusStorage) source(containerID string) guestagentTypes.PortMappingSource {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if status, ok := s.statuses[containerID]; ok {
+		return status.Source
+	}
+
+	return ""
+}
+
+func (s *statusStorage) remove(containerID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.statuses, containerID)
+}
+
+func (s *statusStorage) removeAll() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	clear(s.statuses)
+}
+
+func (s *statusStorage) list() []guestagentTypes.PortMappingStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	result := make([]guestagentTypes.PortMappingStatus, 0, len(s.statuses))
+
+	for _, status := range s.statuses {
+		entry := *status
+		entry.Requested = clonePortMap(status.Requested)
+		entry.Ports = clonePortMap(status.Ports)
+		entry.Remaps = slices.Clone(status.Remaps)
+		entry.Denied = slices.Clone(status.Denied)
+		entry.Errors = slices.Clone(status.Errors)
+		result = append(result, entry)
+	}
+
+	slices.SortFunc(result, func(a, b guestagentTypes.PortMappingStatus) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return result
+}
+
+func clonePortMap(portMap nat.PortMap) nat.PortMap {
+	result := make(nat.PortMap, len(portMap))
+
+	for port, bindings := range portMap {
+		result[port] = slices.Clone(bindings)
+	}
+
+	return result
+}
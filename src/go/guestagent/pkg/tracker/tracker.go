@@ -15,7 +15,11 @@ limitations under the License.
 // of the ports during various container event types e.g start, stop
 package tracker
 
-import "github.com/docker/go-connections/nat"
+import (
+	"github.com/docker/go-connections/nat"
+
+	guestagentTypes "github.com/rancher-sandbox/rancher-desktop/src/go/guestagent/pkg/types"
+)
 
 // Tracker is the interface that includes all the functions that
 // are used to keep track of the port mappings plus NetTracker methods
@@ -35,3 +39,21 @@ type Tracker interface {
 	// RemoveAll removes all the available portMappings in the storage.
 	RemoveAll() error
 }
+
+// MetadataAdder is implemented by trackers that make use of metadata
+// about the workload requesting the port mappings, e.g. to evaluate the
+// port forwarding policy.
+type MetadataAdder interface {
+	// AddWithMetadata is like Tracker.Add, with metadata about the workload.
+	AddWithMetadata(containerID string, portMapping nat.PortMap, metadata guestagentTypes.PortMappingMetadata) error
+}
+
+// AddWithMetadata adds the port mappings to the tracker, passing along the
+// metadata if the tracker makes use of it.
+func AddWithMetadata(tracker Tracker, containerID string, portMap nat.PortMap, metadata guestagentTypes.PortMappingMetadata) error {
+	if adder, ok := tracker.(MetadataAdder); ok {
+		return adder.AddWithMetadata(containerID, portMap, metadata)
+	}
+
+	return tracker.Add(containerID, portMap)
+}
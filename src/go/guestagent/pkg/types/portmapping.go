@@ -40,3 +40,14 @@ type ConnectAddrs struct {
 	// Addr is the network address, which can be either IPv4 or IPv6 (e.g., "192.0.2.1:25", "[2001:db8::1]:80")
 	Addr string `json:"addr"`
 }
+
+// PortMappingMetadata describes the workload that requested port mappings;
+// it is used to evaluate the port forwarding policy. All fields are optional.
+type PortMappingMetadata struct {
+	// Labels are the labels of the container.
+	Labels map[string]string
+	// ContainerNamespace is the containerd namespace of the container.
+	ContainerNamespace string
+	// KubernetesNamespace is the namespace of the Kubernetes service or pod.
+	KubernetesNamespace string
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"time"
+
+	"github.com/docker/go-connections/nat"
+)
+
+// PortMappingSource identifies the component of the agent that
+// discovered a port mapping.
+type PortMappingSource string
+
+const (
+	SourceDocker     PortMappingSource = "docker"
+	SourceContainerd PortMappingSource = "containerd"
+	SourceKubernetes PortMappingSource = "kube"
+	SourceProcNet    PortMappingSource = "procnet"
+	SourceIptables   PortMappingSource = "iptables"
+)
+
+// PortMappingStatus describes the port mappings tracked for a single
+// container, Kubernetes service, or listener. It is reported by the
+// agent's status API.
+type PortMappingStatus struct {
+	// ID is the key the port mappings are tracked under, e.g. the container ID.
+	ID string `json:"id"`
+	// Source is the component that reported the port mappings; it may be
+	// empty if unknown.
+	Source PortMappingSource `json:"source,omitempty"`
+	// Requested contains all the port mappings that were requested.
+	Requested nat.PortMap `json:"requested"`
+	// Ports contains the port mappings that were successfully exposed on the host.
+	Ports nat.PortMap `json:"ports"`
+	// Remaps lists the port bindings that were exposed on the host with a
+	// different address or port than requested.
+	Remaps []PortRemap `json:"remaps,omitempty"`
+	// Denied lists the port bindings that were not exposed due to the port
+	// forwarding policy.
+	Denied []PortDenial `json:"denied,omitempty"`
+	// Errors lists the errors that occurred while exposing the port mappings.
+	Errors []string `json:"errors,omitempty"`
+	// Created is the time the ID was first tracked.
+	Created time.Time `json:"created"`
+	// Updated is the time the port mappings were last changed.
+	Updated time.Time `json:"updated"`
+}
+
+// PortRemap records that a requested port binding was exposed on the host
+// with a different address or port.
+type PortRemap struct {
+	// Port is the port the binding was requested for.
+	Port nat.Port `json:"port"`
+	// Requested is the port binding as requested.
+	Requested nat.PortBinding `json:"requested"`
+	// Host is the port binding as exposed on the host.
+	Host nat.PortBinding `json:"host"`
+	// Reason describes why the binding was remapped.
+	Reason string `json:"reason"`
+}
+
+// PortDenial records that a requested port binding was not exposed on the
+// host due to the port forwarding policy.
+type PortDenial struct {
+	// Port is the port the binding was requested for.
+	Port nat.Port `json:"port"`
+	// Requested is the port binding as requested.
+	Requested nat.PortBinding `json:"requested"`
+	// Reason describes the policy rule that denied the binding.
+	Reason string `json:"reason"`
+}
+
+// HostnameSource identifies the kind of Kubernetes object that a
+// hostname was read from.
+type HostnameSource string
+
+const (
+	SourceIngress   HostnameSource = "ingress"
+	SourceHTTPRoute HostnameSource = "httproute"
+)
+
+// HostnameStatus describes the hostnames routed by a single Ingress or
+// Gateway API HTTPRoute object. It is reported by the agent's status API.
+type HostnameStatus struct {
+	// ID is the UID of the Kubernetes object.
+	ID string `json:"id"`
+	// Source is the kind of the Kubernetes object.
+	Source HostnameSource `json:"source"`
+	// Namespace and Name identify the Kubernetes object.
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Hostnames are the hostnames routed by the object, lower cased and
+	// sorted; they may include wildcards (e.g. `*.example.com`).
+	Hostnames []string `json:"hostnames"`
+	// Updated is the time the hostnames were last changed.
+	Updated time.Time `json:"updated"`
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"gopkg.in/yaml.v3"
+)
+
+// This file contains the handling of compose files for `nerdctl compose`.
+// Host paths in the compose files are only valid on the host, so a rewritten
+// copy of each compose file, with the host paths replaced, is passed to
+// nerdctl instead.
+
+// composeFile is a compose file given with `nerdctl compose --file`, or found
+// in the project directory.
+type composeFile struct {
+	// hostPath is the path to the compose file, as given.
+	hostPath string
+	// tempPath is the path to the rewritten copy that is passed to nerdctl.
+	tempPath string
+}
+
+// composeProject collects the options of `nerdctl compose`; the compose files
+// can only be rewritten once all of them are known, as relative paths in the
+// compose files are relative to the project directory.
+type composeProject struct {
+	// files are the compose files, in the order they were given.
+	files []composeFile
+	// filesGiven is set if --file was given, even if only to read the compose
+	// file from stdin.
+	filesGiven bool
+	// projectDirectory is the host path given with --project-directory.
+	projectDirectory string
+}
+
+// compose is the compose project for the command line being parsed.
+var compose composeProject
+
+// fileArgHandler handles `nerdctl compose --file`; it creates the temporary
+// file the rewritten compose file is written to by composeHandler.
+func (p *composeProject) fileArgHandler(arg string) (string, []cleanupFunc, error) {
+	p.filesGiven = true
+	if arg == "-" {
+		// The compose file is read from stdin; there is nothing to rewrite.
+		return arg, nil, nil
+	}
+	return p.addFile(arg, argHandlers)
+}
+
+// addFile creates the temporary file the compose file at the given host path
+// is rewritten to, returning the path to pass to nerdctl.
+func (p *composeProject) addFile(hostPath string, argHandlers argHandlersType) (string, []cleanupFunc, error) {
+	file, err := os.CreateTemp("", "compose.*.yaml")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanups := []cleanupFunc{func() error { return os.Remove(file.Name()) }}
+	if err := file.Close(); err != nil {
+		return "", cleanups, err
+	}
+	converted, newCleanups, err := argHandlers.filePathArgHandler(file.Name())
+	cleanups = append(newCleanups, cleanups...)
+	if err != nil {
+		return "", cleanups, err
+	}
+	p.files = append(p.files, composeFile{hostPath: hostPath, tempPath: file.Name()})
+	return converted, cleanups, nil
+}
+
+// composeDefaultFileNames are the names of the compose files nerdctl looks
+// for in the project directory when no --file is given, in order of
+// preference; composeOverrideFileNames are the names of the file that is
+// merged into it.
+var (
+	composeDefaultFileNames  = []string{"compose.yaml", "compose.yml", "docker-compose.yml", "docker-compose.yaml"}
+	composeOverrideFileNames = []string{"compose.override.yml", "compose.override.yaml", "docker-compose.override.yml", "docker-compose.override.yaml"}
+)
+
+// findDefaultFiles returns the compose files nerdctl uses when no --file is
+// given: the first default compose file in the project directory (or the
+// working directory), followed by its override file if there is one.
+func (p *composeProject) findDefaultFiles() []string {
+	directory := p.projectDirectory
+	if directory == "" {
+		directory = "."
+	}
+	var result []string
+	for _, names := range [][]string{composeDefaultFileNames, composeOverrideFileNames} {
+		for _, name := range names {
+			candidate := filepath.Join(directory, name)
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				result = append(result, candidate)
+				break
+			}
+		}
+		if len(result) == 0 {
+			return nil
+		}
+	}
+	return result
+}
+
+// projectDirectoryArgHandler handles `nerdctl compose --project-directory`.
+func (p *composeProject) projectDirectoryArgHandler(arg string) (string, []cleanupFunc, error) {
+	p.projectDirectory = arg
+	return argHandlers.filePathArgHandler(arg)
+}
+
+// composeHandler handles `nerdctl compose`; it is called after the options
+// and the subcommand have been parsed, and writes the rewritten compose files.
+func composeHandler(c *commandDefinition, args []string, argHandlers argHandlersType) (*parsedArgs, error) {
+	return compose.rewrite(args, argHandlers)
+}
+
+// rewrite writes the rewritten compose files.  The returned arguments are
+// options for `nerdctl compose` itself.
+func (p *composeProject) rewrite(args []string, argHandlers argHandlersType) (*parsedArgs, error) {
+	result := &parsedArgs{args: args}
+	fail := func(err error) (*parsedArgs, error) {
+		if cleanupErr := runCleanups(result.cleanup); cleanupErr != nil {
+			err = multierror.Append(err, cleanupErr)
+		}
+		return nil, err
+	}
+	if !p.filesGiven {
+		// The default compose files must be rewritten too; pass the rewritten
+		// copies explicitly.
+		var fileArgs []string
+		for _, hostPath := range p.findDefaultFiles() {
+			converted, cleanups, err := p.addFile(hostPath, argHandlers)
+			result.cleanup = append(result.cleanup, cleanups...)
+			if err != nil {
+				return fail(err)
+			}
+			fileArgs = append(fileArgs, "--file", converted)
+		}
+		result.args = append(fileArgs, result.args...)
+	}
+	if len(p.files) == 0 {
+		return result, nil
+	}
+	projectDirectory := p.projectDirectory
+	if projectDirectory == "" {
+		// nerdctl defaults to the directory of the first compose file, which
+		// would be the directory of its rewritten copy.
+		projectDirectory = filepath.Dir(p.files[0].hostPath)
+		converted, cleanups, err := argHandlers.filePathArgHandler(projectDirectory)
+		result.cleanup = append(result.cleanup, cleanups...)
+		if err != nil {
+			return fail(err)
+		}
+		result.args = append([]string{"--project-directory", converted}, result.args...)
+	}
+	projectDirectory, err := filepath.Abs(projectDirectory)
+	if err != nil {
+		return fail(err)
+	}
+	for _, file := range p.files {
+		buf, err := os.ReadFile(file.hostPath)
+		if err != nil {
+			return fail(err)
+		}
+		rewritten, cleanups, err := rewriteComposeFile(buf, projectDirectory, argHandlers)
+		result.cleanup = append(result.cleanup, cleanups...)
+		if err != nil {
+			return fail(err)
+		}
+		if err := os.WriteFile(file.tempPath, rewritten, 0o600); err != nil {
+			return fail(err)
+		}
+	}
+	return result, nil
+}
+
+// composeRewriter replaces the host paths in a compose file.
+type composeRewriter struct {
+	// projectDirectory is the absolute host path relative paths are resolved
+	// against.
+	projectDirectory string
+	argHandlers      argHandlersType
+	cleanups         []cleanupFunc
+	// done are the nodes already rewritten; with YAML anchors, the same node
+	// may be reached more than once.
+	done map[*yaml.Node]bool
+}
+
+// rewriteComposeFile replaces the host paths in the volumes, build contexts,
+// env files, secrets and configs of a compose file.
+func rewriteComposeFile(buf []byte, projectDirectory string, argHandlers argHandlersType) ([]byte, []cleanupFunc, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(buf, &document); err != nil {
+		return nil, nil, err
+	}
+	if len(document.Content) == 0 {
+		// Empty file
+		return buf, nil, nil
+	}
+	r := &composeRewriter{
+		projectDirectory: projectDirectory,
+		argHandlers:      argHandlers,
+		done:             make(map[*yaml.Node]bool),
+	}
+	err := r.rewriteDocument(document.Content[0])
+	if err != nil {
+		if cleanupErr := runCleanups(r.cleanups); cleanupErr != nil {
+			err = multierror.Append(err, cleanupErr)
+		}
+		return nil, nil, err
+	}
+	var result bytes.Buffer
+	encoder := yaml.NewEncoder(&result)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, r.cleanups, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, r.cleanups, err
+	}
+	return result.Bytes(), r.cleanups, nil
+}
+
+func (r *composeRewriter) rewriteDocument(root *yaml.Node) error {
+	for _, service := range composeMappingValues(composeValue(root, "services")) {
+		if err := r.rewriteService(service); err != nil {
+			return err
+		}
+	}
+	// Top-level secrets and configs can be read from files.
+	for _, key := range []string{"secrets", "configs"} {
+		for _, entry := range composeMappingValues(composeValue(root, key)) {
+			if err := r.rewritePath(composeValue(entry, "file"), r.argHandlers.filePathArgHandler); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *composeRewriter) rewriteService(service *yaml.Node) error {
+	if volumes := composeValue(service, "volumes"); volumes != nil && volumes.Kind == yaml.SequenceNode {
+		for _, volume := range volumes.Content {
+			volume = resolveComposeAlias(volume)
+			var err error
+			if volume.Kind == yaml.ScalarNode {
+				err = r.rewriteShortVolume(volume)
+			} else if composeScalar(composeValue(volume, "type")) == "bind" {
+				err = r.rewritePath(composeValue(volume, "source"), r.argHandlers.filePathArgHandler)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	build := composeValue(service, "build")
+	if build != nil && build.Kind == yaml.MappingNode {
+		build = composeValue(build, "context")
+	}
+	if build != nil && !isRemoteBuildContext(composeScalar(build)) {
+		if err := r.rewritePath(build, r.argHandlers.filePathArgHandler); err != nil {
+			return err
+		}
+	}
+
+	envFiles := composeValue(service, "env_file")
+	if envFiles != nil && envFiles.Kind == yaml.ScalarNode {
+		return r.rewritePath(envFiles, r.argHandlers.filePathArgHandler)
+	}
+	if envFiles != nil && envFiles.Kind == yaml.SequenceNode {
+		for _, envFile := range envFiles.Content {
+			envFile = resolveComposeAlias(envFile)
+			if envFile.Kind == yaml.MappingNode {
+				pathNode := composeValue(envFile, "path")
+				if composeScalar(composeValue(envFile, "required")) == "false" && !r.exists(pathNode) {
+					// Optional env files that don't exist are skipped by nerdctl.
+					continue
+				}
+				envFile = pathNode
+			}
+			if err := r.rewritePath(envFile, r.argHandlers.filePathArgHandler); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// windowsDrivePattern matches paths starting with a Windows drive letter.
+var windowsDrivePattern = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+
+// rewriteShortVolume rewrites a volume in the short syntax,
+// [SOURCE:]TARGET[:MODE], if its source is a host path (rather than the name
+// of a volume).
+func (r *composeRewriter) rewriteShortVolume(node *yaml.Node) error {
+	if r.done[node] {
+		return nil
+	}
+	start := 0
+	if windowsDrivePattern.MatchString(node.Value) {
+		start = 2
+	}
+	sep := strings.Index(node.Value[start:], ":")
+	if sep < 0 {
+		// This is an anonymous volume.
+		return nil
+	}
+	source := node.Value[:start+sep]
+	target, mode, _ := strings.Cut(node.Value[start+sep+1:], ":")
+	if !strings.HasPrefix(source, ".") && !strings.HasPrefix(source, "~") && !filepath.IsAbs(source) {
+		// This is a named volume.
+		return nil
+	}
+	hostPath, ok := r.resolve(source)
+	if !ok {
+		return nil
+	}
+	converted, cleanups, err := r.argHandlers.volumeArgHandler(hostPath + ":" + target)
+	r.cleanups = append(r.cleanups, cleanups...)
+	if err != nil {
+		return err
+	}
+	if mode != "" {
+		converted += ":" + mode
+	}
+	node.Value = converted
+	r.done[node] = true
+	return nil
+}
+
+// rewritePath rewrites a scalar node containing a host path with the given
+// handler.
+func (r *composeRewriter) rewritePath(node *yaml.Node, handler argHandler) error {
+	if node == nil || node.Kind != yaml.ScalarNode || r.done[node] {
+		return nil
+	}
+	hostPath, ok := r.resolve(node.Value)
+	if !ok {
+		return nil
+	}
+	converted, cleanups, err := handler(hostPath)
+	r.cleanups = append(r.cleanups, cleanups...)
+	if err != nil {
+		return err
+	}
+	node.Value = converted
+	r.done[node] = true
+	return nil
+}
+
+// resolve returns the absolute host path for a path in the compose file;
+// this returns false if the path can't be resolved, because it contains
+// variables (which nerdctl interpolates) or, on Windows, because it is a
+// Linux path such as /var/run/docker.sock.
+func (r *composeRewriter) resolve(value string) (string, bool) {
+	if value == "" || strings.Contains(value, "$") {
+		return "", false
+	}
+	if value == "~" || strings.HasPrefix(value, "~/") || strings.HasPrefix(value, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		return filepath.Join(home, filepath.FromSlash(value[1:])), true
+	}
+	if filepath.IsAbs(value) {
+		return value, true
+	}
+	if strings.HasPrefix(value, "/") || strings.HasPrefix(value, `\`) {
+		return "", false
+	}
+	return filepath.Join(r.projectDirectory, filepath.FromSlash(value)), true
+}
+
+// exists checks if the host path in the given scalar node exists.
+func (r *composeRewriter) exists(node *yaml.Node) bool {
+	hostPath, ok := r.resolve(composeScalar(node))
+	if !ok {
+		return true
+	}
+	_, err := os.Stat(hostPath)
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+// remoteBuildContextPattern matches build contexts that are URLs.
+var remoteBuildContextPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*://`)
+
+// isRemoteBuildContext checks if a build context is a URL or git repository,
+// rather than a host path.
+func isRemoteBuildContext(context string) bool {
+	return remoteBuildContextPattern.MatchString(context) ||
+		strings.HasPrefix(context, "git@") ||
+		strings.HasPrefix(context, "github.com/")
+}
+
+// resolveComposeAlias returns the node a YAML alias refers to.
+func resolveComposeAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// composeValue returns the value for the key in a mapping node, following
+// YAML aliases and merge keys (`<<: *defaults`); this returns nil if the key
+// doesn't exist.
+func composeValue(node *yaml.Node, key string) *yaml.Node {
+	node = resolveComposeAlias(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	var merges []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case key:
+			return resolveComposeAlias(node.Content[i+1])
+		case "<<":
+			merges = append(merges, resolveComposeAlias(node.Content[i+1]))
+		}
+	}
+	// Keys in the mapping itself take precedence over merged ones.
+	for _, merge := range merges {
+		sources := []*yaml.Node{merge}
+		if merge != nil && merge.Kind == yaml.SequenceNode {
+			sources = merge.Content
+		}
+		for _, source := range sources {
+			if value := composeValue(source, key); value != nil {
+				return value
+			}
+		}
+	}
+	return nil
+}
+
+// composeMappingValues returns the values of a mapping node.
+func composeMappingValues(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	var values []*yaml.Node
+	for i := 1; i < len(node.Content); i += 2 {
+		values = append(values, resolveComposeAlias(node.Content[i]))
+	}
+	return values
+}
+
+// composeScalar returns the value of a scalar node, or an empty string if the
+// node is not a scalar.
+func composeScalar(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
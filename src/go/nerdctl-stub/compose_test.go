@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// composeTestHandlers are argument handlers that mark the values they were
+// given, so the tests can check which handler was used.
+var composeTestHandlers = argHandlersType{
+	volumeArgHandler: func(s string) (string, []cleanupFunc, error) {
+		return fmt.Sprintf("<volume:%s>", s), nil, nil
+	},
+	filePathArgHandler: func(s string) (string, []cleanupFunc, error) {
+		return fmt.Sprintf("<file:%s>", s), nil, nil
+	},
+}
+
+const testComposeFile = `
+x-defaults: &defaults
+  volumes:
+    - ./shared:/shared
+services:
+  web:
+    <<: *defaults
+    build: .
+    env_file: web.env
+  api:
+    <<: *defaults
+    build:
+      context: ./api
+      dockerfile: Dockerfile.dev
+    volumes:
+      - ./data:/data:ro,z
+      - cache:/cache
+      - /anonymous
+      - ${DATA_DIR}:/external
+      - /var/run/docker.sock:/var/run/docker.sock
+      - C:\data:/windows
+      - type: bind
+        source: ./config
+        target: /config
+      - type: volume
+        source: logs
+        target: /logs
+    env_file:
+      - api.env
+      - path: ./missing.env
+        required: false
+      - path: ./present.env
+        required: false
+  remote:
+    build: https://github.com/example/app.git#main
+secrets:
+  token:
+    file: ./token.txt
+  external:
+    environment: TOKEN
+configs:
+  settings:
+    file: settings.json
+`
+
+func TestRewriteComposeFile(t *testing.T) {
+	t.Parallel()
+	projectDirectory := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(projectDirectory, "present.env"), nil, 0o644))
+	inProject := func(name string) string {
+		return filepath.Join(projectDirectory, name)
+	}
+
+	rewritten, cleanups, err := rewriteComposeFile([]byte(testComposeFile), projectDirectory, composeTestHandlers)
+	require.NoError(t, err)
+	assert.Empty(t, cleanups)
+
+	var result struct {
+		Services struct {
+			Web struct {
+				Volumes []string `yaml:"volumes"`
+				Build   string   `yaml:"build"`
+				EnvFile string   `yaml:"env_file"`
+			} `yaml:"web"`
+			API struct {
+				Volumes []any          `yaml:"volumes"`
+				Build   map[string]any `yaml:"build"`
+				EnvFile []any          `yaml:"env_file"`
+			} `yaml:"api"`
+			Remote struct {
+				Build string `yaml:"build"`
+			} `yaml:"remote"`
+		} `yaml:"services"`
+		Secrets map[string]map[string]string `yaml:"secrets"`
+		Configs map[string]map[string]string `yaml:"configs"`
+	}
+	require.NoError(t, yaml.Unmarshal(rewritten, &result))
+
+	web := result.Services.Web
+	assert.Equal(t, []string{fmt.Sprintf("<volume:%s:/shared>", inProject("shared"))}, web.Volumes)
+	assert.Equal(t, fmt.Sprintf("<file:%s>", projectDirectory), web.Build)
+	assert.Equal(t, fmt.Sprintf("<file:%s>", inProject("web.env")), web.EnvFile)
+
+	api := result.Services.API
+	dockerSocket := "/var/run/docker.sock:/var/run/docker.sock"
+	if filepath.IsAbs("/var/run/docker.sock") {
+		dockerSocket = fmt.Sprintf("<volume:%s>", dockerSocket)
+	}
+	windowsPath := `C:\data:/windows`
+	if filepath.IsAbs(`C:\data`) {
+		windowsPath = fmt.Sprintf("<volume:%s>", windowsPath)
+	}
+	assert.Equal(t, []any{
+		fmt.Sprintf("<volume:%s:/data>:ro,z", inProject("data")),
+		"cache:/cache",
+		"/anonymous",
+		"${DATA_DIR}:/external",
+		dockerSocket,
+		windowsPath,
+		map[string]any{"type": "bind", "source": fmt.Sprintf("<file:%s>", inProject("config")), "target": "/config"},
+		map[string]any{"type": "volume", "source": "logs", "target": "/logs"},
+	}, api.Volumes)
+	assert.Equal(t, map[string]any{
+		"context":    fmt.Sprintf("<file:%s>", inProject("api")),
+		"dockerfile": "Dockerfile.dev",
+	}, api.Build)
+	assert.Equal(t, []any{
+		fmt.Sprintf("<file:%s>", inProject("api.env")),
+		map[string]any{"path": "./missing.env", "required": false},
+		map[string]any{"path": fmt.Sprintf("<file:%s>", inProject("present.env")), "required": false},
+	}, api.EnvFile)
+
+	assert.Equal(t, "https://github.com/example/app.git#main", result.Services.Remote.Build)
+	assert.Equal(t, map[string]map[string]string{
+		"token":    {"file": fmt.Sprintf("<file:%s>", inProject("token.txt"))},
+		"external": {"environment": "TOKEN"},
+	}, result.Secrets)
+	assert.Equal(t, map[string]map[string]string{
+		"settings": {"file": fmt.Sprintf("<file:%s>", inProject("settings.json"))},
+	}, result.Configs)
+}
+
+func TestRewriteComposeFileErrors(t *testing.T) {
+	t.Parallel()
+	t.Run("invalid YAML", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := rewriteComposeFile([]byte("services: ["), t.TempDir(), composeTestHandlers)
+		assert.Error(t, err)
+	})
+	t.Run("empty file", func(t *testing.T) {
+		t.Parallel()
+		rewritten, cleanups, err := rewriteComposeFile(nil, t.TempDir(), composeTestHandlers)
+		assert.NoError(t, err)
+		assert.Empty(t, rewritten)
+		assert.Empty(t, cleanups)
+	})
+	t.Run("handler errors run cleanups", func(t *testing.T) {
+		t.Parallel()
+		ranCleanups := 0
+		cleanup := func() error {
+			ranCleanups++
+			return nil
+		}
+		handlers := argHandlersType{
+			volumeArgHandler: func(s string) (string, []cleanupFunc, error) {
+				return "mounted", []cleanupFunc{cleanup}, nil
+			},
+			filePathArgHandler: func(s string) (string, []cleanupFunc, error) {
+				return "", []cleanupFunc{cleanup}, errExpected
+			},
+		}
+		contents := "services:\n  web:\n    volumes: [./data:/data]\n    env_file: web.env\n"
+		_, cleanups, err := rewriteComposeFile([]byte(contents), t.TempDir(), handlers)
+		assert.ErrorIs(t, err, errExpected)
+		assert.Empty(t, cleanups)
+		assert.Equal(t, 2, ranCleanups)
+	})
+}
+
+func TestComposeProjectRewrite(t *testing.T) {
+	t.Parallel()
+	writeProject := func(t *testing.T) (composeProject, string) {
+		dir := t.TempDir()
+		hostPath := filepath.Join(dir, "compose.yaml")
+		tempPath := filepath.Join(t.TempDir(), "compose.rewritten.yaml")
+		contents := "services:\n  web:\n    volumes:\n      - ./data:/data\n"
+		require.NoError(t, os.WriteFile(hostPath, []byte(contents), 0o644))
+		return composeProject{files: []composeFile{{hostPath: hostPath, tempPath: tempPath}}, filesGiven: true}, dir
+	}
+	readVolumes := func(t *testing.T, p composeProject) []string {
+		buf, err := os.ReadFile(p.files[0].tempPath)
+		require.NoError(t, err)
+		var result struct {
+			Services map[string]struct {
+				Volumes []string `yaml:"volumes"`
+			} `yaml:"services"`
+		}
+		require.NoError(t, yaml.Unmarshal(buf, &result))
+		return result.Services["web"].Volumes
+	}
+
+	t.Run("without compose files", func(t *testing.T) {
+		t.Parallel()
+		p := composeProject{}
+		result, err := p.rewrite(nil, composeTestHandlers)
+		if assert.NoError(t, err) {
+			assert.Empty(t, result.args)
+		}
+	})
+	t.Run("compose file from stdin", func(t *testing.T) {
+		t.Parallel()
+		p := composeProject{filesGiven: true}
+		result, err := p.rewrite([]string{"up"}, composeTestHandlers)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []string{"up"}, result.args)
+		}
+	})
+	t.Run("default compose files", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		contents := "services:\n  web:\n    volumes:\n      - ./data:/data\n"
+		for _, name := range []string{"docker-compose.yml", "compose.yml", "compose.override.yaml", "docker-compose.override.yml"} {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644))
+		}
+		p := composeProject{projectDirectory: dir}
+		result, err := p.rewrite([]string{"up"}, composeTestHandlers)
+		require.NoError(t, err)
+		t.Cleanup(func() { assert.NoError(t, runCleanups(result.cleanup)) })
+		require.Len(t, p.files, 2)
+		assert.Equal(t, filepath.Join(dir, "compose.yml"), p.files[0].hostPath)
+		assert.Equal(t, filepath.Join(dir, "compose.override.yaml"), p.files[1].hostPath)
+		assert.Equal(t, []string{
+			"--file", fmt.Sprintf("<file:%s>", p.files[0].tempPath),
+			"--file", fmt.Sprintf("<file:%s>", p.files[1].tempPath),
+			"up",
+		}, result.args)
+		assert.Equal(t, []string{fmt.Sprintf("<volume:%s:/data>", filepath.Join(dir, "data"))}, readVolumes(t, p))
+	})
+	t.Run("default project directory", func(t *testing.T) {
+		t.Parallel()
+		p, dir := writeProject(t)
+		result, err := p.rewrite(nil, composeTestHandlers)
+		require.NoError(t, err)
+		// The project directory must stay the directory of the original file.
+		assert.Equal(t, []string{"--project-directory", fmt.Sprintf("<file:%s>", dir)}, result.args)
+		assert.Equal(t, []string{fmt.Sprintf("<volume:%s:/data>", filepath.Join(dir, "data"))}, readVolumes(t, p))
+	})
+	t.Run("explicit project directory", func(t *testing.T) {
+		t.Parallel()
+		p, _ := writeProject(t)
+		p.projectDirectory = t.TempDir()
+		result, err := p.rewrite(nil, composeTestHandlers)
+		require.NoError(t, err)
+		assert.Empty(t, result.args)
+		assert.Equal(t, []string{fmt.Sprintf("<volume:%s:/data>", filepath.Join(p.projectDirectory, "data"))}, readVolumes(t, p))
+	})
+	t.Run("missing compose file", func(t *testing.T) {
+		t.Parallel()
+		ranCleanup := false
+		p, dir := writeProject(t)
+		p.files[0].hostPath = filepath.Join(dir, "missing.yaml")
+		handlers := composeTestHandlers
+		handlers.filePathArgHandler = func(s string) (string, []cleanupFunc, error) {
+			return s, []cleanupFunc{generateCleanupFunc(&ranCleanup, false)}, nil
+		}
+		_, err := p.rewrite(nil, handlers)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		assert.True(t, ranCleanup)
+	})
+}
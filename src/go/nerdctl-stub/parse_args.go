@@ -54,6 +54,9 @@ type commandDefinition struct {
 	// handler for any positional arguments and subcommands.  This should not
 	// include the name of the subcommand itself.  If this is not given, all
 	// subcommands are searched for, and positional arguments are ignored.
+	// For commands with subcommands, this is called after the subcommand has
+	// been parsed, and any arguments it returns are inserted before the
+	// subcommand.
 	handler commandHandlerType
 }
 
@@ -153,6 +156,8 @@ func (c commandDefinition) parse(args []string) (*parsedArgs, error) {
 	// - `--` stops parsing of options.
 	var result parsedArgs
 	var positionalArgs []string
+	// subcommandIndex is the index in result.args of the subcommand, if any.
+	subcommandIndex := -1
 	for argIndex := 0; argIndex < len(args); argIndex++ {
 		arg := args[argIndex]
 		if arg == "--" {
@@ -188,6 +193,7 @@ func (c commandDefinition) parse(args []string) (*parsedArgs, error) {
 				subcommandPath += " "
 			}
 			subcommandPath += arg
+			subcommandIndex = len(result.args)
 			globalCommands := c.commands
 			if globalCommands == nil {
 				globalCommands = &commands
@@ -224,9 +230,19 @@ func (c commandDefinition) parse(args []string) (*parsedArgs, error) {
 	if c.handler != nil {
 		childResult, err := c.handler(&c, positionalArgs, argHandlers)
 		if err != nil {
+			for _, cleanup := range result.cleanup {
+				cleanupErr := cleanup()
+				if cleanupErr != nil {
+					log.Printf("Error running cleanup: %s", cleanupErr)
+				}
+			}
 			return nil, err
 		}
-		result.args = append(result.args, childResult.args...)
+		if subcommandIndex >= 0 {
+			result.args = slices.Insert(result.args, subcommandIndex, childResult.args...)
+		} else {
+			result.args = append(result.args, childResult.args...)
+		}
 		result.cleanup = append(result.cleanup, childResult.cleanup...)
 	} else {
 		if len(positionalArgs) > 0 && !slices.Contains(result.args, "--") {
@@ -344,9 +360,9 @@ func init() {
 	registerArgHandler("checkpoint create", "--checkpoint-dir", argHandlers.filePathArgHandler)
 	registerArgHandler("checkpoint ls", "--checkpoint-dir", argHandlers.filePathArgHandler)
 	registerArgHandler("checkpoint rm", "--checkpoint-dir", argHandlers.filePathArgHandler)
-	registerArgHandler("compose", "--file", argHandlers.filePathArgHandler)
-	registerArgHandler("compose", "-f", argHandlers.filePathArgHandler)
-	registerArgHandler("compose", "--project-directory", argHandlers.filePathArgHandler)
+	registerArgHandler("compose", "--file", compose.fileArgHandler)
+	registerArgHandler("compose", "-f", compose.fileArgHandler)
+	registerArgHandler("compose", "--project-directory", compose.projectDirectoryArgHandler)
 	registerArgHandler("compose", "--env-file", argHandlers.filePathArgHandler)
 	// nerdctl's help text renders these with a metavar because the
 	// description quotes the "volumes" Compose section, but they are booleans.
@@ -379,6 +395,7 @@ func init() {
 
 	// Set up command handlers
 	registerCommandHandler("builder build", builderBuildHandler)
+	registerCommandHandler("compose", composeHandler)
 	registerCommandHandler("container cp", containerCopyHandler)
 	registerCommandHandler("image import", imageImportHandler)
 
@@ -290,6 +290,53 @@ func TestParse(t *testing.T) {
 			assert.Equal(t, []string{"subcommand", "--foo", "FOO"}, result.args)
 		}
 	})
+	t.Run("handler of command with subcommands", func(t *testing.T) {
+		t.Parallel()
+		cleanupRun := false
+		localCommands := make(map[string]commandDefinition)
+		localCommands[""] = commandDefinition{
+			commands: &localCommands,
+			subcommands: map[string]struct{}{
+				"subcommand": {},
+			},
+			options: map[string]argHandler{
+				"--foo": ignoredArgHandler,
+			},
+			handler: func(cd *commandDefinition, s []string, argHandlers argHandlersType) (*parsedArgs, error) {
+				assert.Empty(t, s)
+				return &parsedArgs{
+					args:    []string{"--injected", "value"},
+					cleanup: []cleanupFunc{generateCleanupFunc(&cleanupRun, false)},
+				}, nil
+			},
+		}
+		localCommands["subcommand"] = commandDefinition{
+			commandPath: "subcommand",
+			commands:    &localCommands,
+		}
+		result, err := localCommands[""].parse([]string{"--foo", "FOO", "subcommand", "qq", "zz"})
+		if assert.NoError(t, err) {
+			// Arguments from the handler are options for the parent command.
+			assert.Equal(t, []string{"--foo", "FOO", "--injected", "value", "subcommand", "--", "qq", "zz"}, result.args)
+			assert.NoError(t, runCleanups(result.cleanup))
+			assert.True(t, cleanupRun)
+		}
+	})
+	t.Run("handler errors run cleanups", func(t *testing.T) {
+		t.Parallel()
+		cleanupRun := false
+		c := commandDefinition{
+			options: map[string]argHandler{
+				"-o": generateOptionHandler(&cleanupRun, false, false),
+			},
+			handler: func(c *commandDefinition, args []string, argHandlers argHandlersType) (*parsedArgs, error) {
+				return nil, errExpected
+			},
+		}
+		_, err := c.parse([]string{"-o", "value"})
+		assert.ErrorIs(t, err, errExpected)
+		assert.True(t, cleanupRun)
+	})
 	t.Run("compose down volumes flag is boolean", func(t *testing.T) {
 		t.Parallel()
 		for _, flag := range []string{"--volumes", "-v"} {
@@ -39,7 +39,7 @@ func (i *arrayFlags) Set(value string) error {
 	return nil
 }
 
-func newConfig(subnet config.Subnet, staticPortForwarding map[string]string, debug bool) types.Configuration {
+func newConfig(subnet config.Subnet, staticPortForwarding map[string]string, zones []types.Zone, debug bool) types.Configuration {
 	c := types.Configuration{
 		Debug:             debug,
 		MTU:               defaultMTU,
@@ -47,36 +47,9 @@ func newConfig(subnet config.Subnet, staticPortForwarding map[string]string, deb
 		GatewayIP:         subnet.GatewayIP,
 		GatewayMacAddress: gatewayMacAddr,
 		DHCPStaticLeases:  subnet.StaticDHCPLease,
-		DNS: []types.Zone{
-			{
-				Name: "rancher-desktop.internal.",
-				Records: []types.Record{
-					{
-						Name: "gateway",
-						IP:   net.ParseIP(subnet.GatewayIP),
-					},
-					{
-						Name: "host",
-						IP:   net.ParseIP(subnet.StaticDNSHost),
-					},
-				},
-			},
-			{
-				Name: "docker.internal.",
-				Records: []types.Record{
-					{
-						Name: "gateway",
-						IP:   net.ParseIP(subnet.GatewayIP),
-					},
-					{
-						Name: "host",
-						IP:   net.ParseIP(subnet.StaticDNSHost),
-					},
-				},
-			},
-		},
-		DNSSearchDomains: config.SearchDomains(),
-		Forwards:         staticPortForwarding,
+		DNS:               config.MergeZones(builtinZones(subnet), zones...),
+		DNSSearchDomains:  config.SearchDomains(),
+		Forwards:          staticPortForwarding,
 		NAT: map[string]string{
 			subnet.StaticDNSHost: localHost,
 		},
@@ -87,3 +60,36 @@ func newConfig(subnet config.Subnet, staticPortForwarding map[string]string, deb
 	}
 	return c
 }
+
+// builtinZones returns the zones that are always served by the DNS server,
+// for the gateway and the host.
+func builtinZones(subnet config.Subnet) []types.Zone {
+	return []types.Zone{
+		{
+			Name: "rancher-desktop.internal.",
+			Records: []types.Record{
+				{
+					Name: "gateway",
+					IP:   net.ParseIP(subnet.GatewayIP),
+				},
+				{
+					Name: "host",
+					IP:   net.ParseIP(subnet.StaticDNSHost),
+				},
+			},
+		},
+		{
+			Name: "docker.internal.",
+			Records: []types.Record{
+				{
+					Name: "gateway",
+					IP:   net.ParseIP(subnet.GatewayIP),
+				},
+				{
+					Name: "host",
+					IP:   net.ParseIP(subnet.StaticDNSHost),
+				},
+			},
+		},
+	}
+}
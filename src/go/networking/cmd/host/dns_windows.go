@@ -0,0 +1,107 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/containers/gvisor-tap-vsock/pkg/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/config"
+)
+
+// maxZoneRequestSize limits the size of zones added through the DNS
+// services API.
+const maxZoneRequestSize = 1 << 20
+
+// loadZones returns the additional DNS zones from the DNS config file, if
+// any, followed by the ones from the dns-record flags.
+func loadZones(subnet config.Subnet, dnsConfigPath string, dnsRecords []string) ([]types.Zone, error) {
+	var zones []types.Zone
+	if dnsConfigPath != "" {
+		fileZones, err := config.LoadDNSConfig(dnsConfigPath)
+		if err != nil {
+			return nil, err
+		}
+		zones = config.MergeZones(zones, fileZones...)
+	}
+	flagZones, err := config.ParseDNSRecords(dnsRecords)
+	if err != nil {
+		return nil, err
+	}
+	zones = config.MergeZones(zones, flagZones...)
+	for _, zone := range zones {
+		if err := validateZone(subnet, zone); err != nil {
+			return nil, err
+		}
+	}
+	return zones, nil
+}
+
+// validateZone checks the zone against the subnet, and ensures it does not
+// redefine the built-in records for the gateway and the host.
+func validateZone(subnet config.Subnet, zone types.Zone) error {
+	if err := config.ValidateZone(subnet, zone); err != nil {
+		return err
+	}
+	for _, builtin := range builtinZones(subnet) {
+		if builtin.Name != config.NormalizeZoneName(zone.Name) {
+			continue
+		}
+		for _, record := range zone.Records {
+			for _, reserved := range builtin.Records {
+				if record.Name != "" && record.Name == reserved.Name {
+					return fmt.Errorf("zone %q: record %q is reserved", zone.Name, record.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// dnsAddHandler validates the zones added at runtime through the DNS
+// services API before passing them on to the DNS server of the virtual
+// network, which merges them with the existing zones.
+func dnsAddHandler(subnet config.Subnet, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+		var zone types.Zone
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxZoneRequestSize)).Decode(&zone); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateZone(subnet, zone); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		zone.Name = config.NormalizeZoneName(zone.Name)
+		body, err := json.Marshal(zone)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logrus.Infof("adding %d DNS records to zone %s", len(zone.Records), zone.Name)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -26,6 +26,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/containers/gvisor-tap-vsock/pkg/types"
 	"github.com/containers/gvisor-tap-vsock/pkg/virtualnetwork"
 	"github.com/dustin/go-humanize"
 	"github.com/linuxkit/virtsock/pkg/hvsock"
@@ -40,6 +41,8 @@ var (
 	debug             bool
 	virtualSubnet     string
 	staticPortForward arrayFlags
+	dnsConfigFile     string
+	dnsRecords        arrayFlags
 )
 
 const (
@@ -55,6 +58,10 @@ func main() {
 		fmt.Sprintf("Subnet range with CIDR suffix for virtual network, e,g: %s", config.DefaultSubnet))
 	flag.Var(&staticPortForward, "port-forward",
 		"List of ports that needs to be pre forwarded to the WSL VM in Host:Port=Guest:Port format e.g: 127.0.0.1:2222=192.168.127.2:22")
+	flag.StringVar(&dnsConfigFile, "dns-config", "",
+		"Path to a JSON file defining additional DNS zones and records for the virtual network")
+	flag.Var(&dnsRecords, "dns-record",
+		"List of additional DNS records in Hostname=IP format e.g: registry.corp.example=192.168.127.1")
 	flag.Parse()
 
 	if debug {
@@ -73,13 +80,18 @@ func main() {
 		logrus.Fatal(err)
 	}
 
-	if err := runSwitch(*subnet, portForwarding); err != nil {
+	zones, err := loadZones(*subnet, dnsConfigFile, dnsRecords)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	if err := runSwitch(*subnet, portForwarding, zones); err != nil {
 		logrus.Error(err)
 		os.Exit(1)
 	}
 }
 
-func runSwitch(subnet config.Subnet, portForwarding map[string]string) error {
+func runSwitch(subnet config.Subnet, portForwarding map[string]string, zones []types.Zone) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	groupErrs, ctx := errgroup.WithContext(ctx)
@@ -88,7 +100,7 @@ func runSwitch(subnet config.Subnet, portForwarding map[string]string) error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 
-	cfg := newConfig(subnet, portForwarding, debug)
+	cfg := newConfig(subnet, portForwarding, zones, debug)
 
 	logrus.Debugf("attempting to start a virtual network with the following config: %+v", cfg)
 	vn, err := virtualnetwork.New(&cfg)
@@ -105,6 +117,8 @@ func runSwitch(subnet config.Subnet, portForwarding map[string]string) error {
 	mux.Handle("/services/forwarder/all", vn.Mux())
 	mux.Handle("/services/forwarder/expose", vn.Mux())
 	mux.Handle("/services/forwarder/unexpose", vn.Mux())
+	mux.Handle("/services/dns/all", vn.Mux())
+	mux.Handle("/services/dns/add", dnsAddHandler(subnet, vn.Mux()))
 	httpServe(ctx, groupErrs, vnLn, mux)
 	logrus.Infof("port forwarding API server is running on: %s", apiServer)
 
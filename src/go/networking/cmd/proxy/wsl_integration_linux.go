@@ -16,11 +16,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -34,11 +38,15 @@ var (
 	socketFile   string
 	upstreamAddr string
 	udpBuffer    int
+	udpIdle      time.Duration
+	udpMaxFlows  int
+	statusFile   string
 )
 
 const (
 	defaultLogPath = "/var/log/wsl-proxy.log"
 	defaultSocket  = "/run/wsl-proxy.sock"
+	defaultStatus  = "/run/wsl-proxy-status.sock"
 	bridgeIPAddr   = "192.168.143.1"
 	// Set UDP buffer size to 8 MB
 	defaultUDPBufferSize = 8 * 1024 * 1024 // 8 MB in bytes
@@ -50,6 +58,9 @@ func main() {
 	flag.StringVar(&socketFile, "socketFile", defaultSocket, "path to the .sock file for UNIX socket")
 	flag.StringVar(&upstreamAddr, "upstreamAddress", bridgeIPAddr, "IP address of the upstream server to forward to")
 	flag.IntVar(&udpBuffer, "udpBuffer", defaultUDPBufferSize, "max buffer size in bytes for UDP socket I/O")
+	flag.DurationVar(&udpIdle, "udpIdleTimeout", portproxy.DefaultUDPIdleTimeout, "how long a UDP flow is kept without traffic")
+	flag.IntVar(&udpMaxFlows, "udpMaxFlows", portproxy.DefaultUDPMaxFlows, "max number of concurrent UDP flows per port")
+	flag.StringVar(&statusFile, "statusSocket", defaultStatus, "path to the .sock file for the status endpoint, empty to disable")
 	flag.Parse()
 
 	setupLogging(logFile)
@@ -66,8 +77,13 @@ func main() {
 	proxyConfig := &portproxy.ProxyConfig{
 		UpstreamAddress: upstreamAddr,
 		UDPBufferSize:   udpBuffer,
+		UDPIdleTimeout:  udpIdle,
+		UDPMaxFlows:     udpMaxFlows,
 	}
 	proxy := portproxy.NewPortProxy(ctx, socket, proxyConfig)
+	if statusFile != "" {
+		go serveStatus(ctx, statusFile, proxy)
+	}
 
 	// Handle graceful shutdown
 	sigCh := make(chan os.Signal, 1)
@@ -89,6 +105,40 @@ func main() {
 	}
 }
 
+// serveStatus serves the counters of the forwarded ports as JSON, e.g.
+// curl --unix-socket /run/wsl-proxy-status.sock http://localhost/status
+func serveStatus(ctx context.Context, socketFile string, proxy *portproxy.PortProxy) {
+	if err := os.Remove(socketFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logrus.Errorf("failed to remove stale status socket: %s", err)
+		return
+	}
+	listenerConfig := net.ListenConfig{}
+	listener, err := listenerConfig.Listen(ctx, "unix", socketFile)
+	if err != nil {
+		logrus.Errorf("failed to create listener for status endpoint: %s", err)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		status := struct {
+			UDP []portproxy.UDPStats `json:"udp"`
+		}{
+			UDP: proxy.UDPStats(),
+		}
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			logrus.Errorf("writing status failed: %s", err)
+		}
+	})
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logrus.Errorf("status endpoint failed: %s", err)
+	}
+}
+
 func setupLogging(logFile string) {
 	if err := log.SetOutputFile(logFile, logrus.StandardLogger()); err != nil {
 		logrus.Fatalf("setting logger's output file failed: %v", err)
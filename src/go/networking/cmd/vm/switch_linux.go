@@ -21,9 +21,11 @@ import (
 	"io"
 	"math"
 	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -36,6 +38,7 @@ import (
 	"github.com/vishvananda/netlink"
 	"gvisor.dev/gvisor/pkg/tcpip/header"
 
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/capture"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/config"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/log"
 )
@@ -48,12 +51,18 @@ var (
 	logFile          string
 	subnet           string
 	tapDeviceMacAddr string
+	captureAddress   string
+	captureDir       string
 )
 
 const (
 	defaultTapDevice = "eth0"
 	defaultVsockFD   = 3
 	maxMTU           = 4000
+	// defaultCaptureAddress is within the network namespace, where it can
+	// be reached through `rdctl shell`.
+	defaultCaptureAddress = "127.0.0.1:6670"
+	defaultCaptureDir     = "/var/lib/vm-switch/capture"
 )
 
 var (
@@ -62,6 +71,12 @@ var (
 	// tracePackets gates per-packet logging. It is initialized from traceFlag
 	// and can be toggled at runtime by sending SIGUSR1 to this process.
 	tracePackets atomic.Bool
+	// packetCapture records the packets in both directions while an
+	// on-demand capture runs; it is nil if captures are disabled.
+	packetCapture *capture.Capturer
+	// captureServerOnce starts the packet capture control endpoint once
+	// the loopback device is up.
+	captureServerOnce sync.Once
 )
 
 func main() {
@@ -75,6 +90,9 @@ func main() {
 	flag.StringVar(&subnet, "subnet", config.DefaultSubnet,
 		fmt.Sprintf("Subnet range with CIDR suffix that is associated to the tap interface, e,g: %s", config.DefaultSubnet))
 	flag.StringVar(&logFile, "logfile", "/var/log/vm-switch.log", "path to vm-switch process logfile")
+	flag.StringVar(&captureAddress, "capture-address", defaultCaptureAddress,
+		"address of the packet capture control endpoint; empty to disable packet captures")
+	flag.StringVar(&captureDir, "capture-dir", defaultCaptureDir, "directory to write packet captures to")
 	flag.Parse()
 
 	if err := log.SetOutputFile(logFile, logrus.StandardLogger()); err != nil {
@@ -106,6 +124,10 @@ func main() {
 		}
 	}()
 
+	if captureAddress != "" {
+		packetCapture = capture.New(captureDir, maxMTU+header.EthernetMinimumSize)
+	}
+
 	// the FD is passed-in as an extra arg from exec.Command
 	// of the parent process. This is for the AF_VSOCK connection that
 	// is handed over from the default namespace to Rancher Desktop's
@@ -170,6 +192,12 @@ func run(ctx context.Context, cancel context.CancelFunc, connFile io.ReadWriteCl
 
 	logrus.Debugf("setup complete for tap interface %s(%s) + loopback", tapIface, tapDeviceMacAddr)
 
+	if packetCapture != nil {
+		captureServerOnce.Do(func() {
+			go serveCapture(captureAddress, packetCapture)
+		})
+	}
+
 	errCh := make(chan error, 1)
 	go tx(ctx, connFile, tap, errCh, maxMTU)
 	go rx(ctx, connFile, tap, errCh, maxMTU)
@@ -256,6 +284,8 @@ func rx(ctx context.Context, conn io.Writer, tap *water.Interface, errCh chan er
 				return
 			}
 
+			packetCapture.Packet(frame)
+
 			if tracePackets.Load() {
 				packet := gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.Default)
 				logrus.Infof("wrote packet (vm -> host %d): %s", n, packet.String())
@@ -304,6 +334,8 @@ func tx(ctx context.Context, conn io.Reader, tap *water.Interface, errCh chan er
 				return
 			}
 
+			packetCapture.Packet(buf[:size])
+
 			if tracePackets.Load() {
 				packet := gopacket.NewPacket(buf[:size], layers.LayerTypeEthernet, gopacket.Default)
 				logrus.Infof("read packet (host -> vm %d): %s", size, packet.String())
@@ -312,6 +344,20 @@ func tx(ctx context.Context, conn io.Reader, tap *water.Interface, errCh chan er
 	}
 }
 
+// serveCapture serves the packet capture control endpoints. Failing to do
+// so only disables packet captures, as they are a debugging aid.
+func serveCapture(address string, capturer *capture.Capturer) {
+	s := &http.Server{
+		Addr:              address,
+		Handler:           capturer.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	logrus.Infof("packet capture control endpoint is running on: %s", address)
+	if err := s.ListenAndServe(); err != nil {
+		logrus.Errorf("packet capture control endpoint failed: %v", err)
+	}
+}
+
 func checkForExistingIface(ifName string) error {
 	// equivalent to: `ip link show`
 	links, err := netlink.LinkList()
@@ -0,0 +1,314 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package capture implements on-demand packet captures of Ethernet frames,
+// written to size-capped, rotating pcap files.
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+const (
+	// DefaultMaxFileSize is the size of a capture file before it is rotated.
+	DefaultMaxFileSize = 16 * 1024 * 1024
+	// DefaultMaxFiles is the number of capture files kept; older files are
+	// removed as the capture rotates.
+	DefaultMaxFiles = 4
+	// pcapHeaderSize and pcapRecordHeaderSize are the sizes of the file
+	// and the per-packet headers of the pcap format.
+	pcapHeaderSize       = 24
+	pcapRecordHeaderSize = 16
+)
+
+var (
+	ErrRunning    = errors.New("a packet capture is already running")
+	ErrNotRunning = errors.New("no packet capture is running")
+	ErrNoCapture  = errors.New("no packet capture is available")
+)
+
+// Options configures a packet capture.
+type Options struct {
+	// Filter is a filter expression; see Filter.
+	Filter string
+	// MaxFileSize is the size, in bytes, of a capture file before it is
+	// rotated; it defaults to DefaultMaxFileSize.
+	MaxFileSize int64
+	// MaxFiles is the number of capture files that are kept; it defaults
+	// to DefaultMaxFiles.
+	MaxFiles int
+}
+
+// Status describes the current, or last, packet capture.
+type Status struct {
+	Running     bool      `json:"running"`
+	Filter      string    `json:"filter"`
+	MaxFileSize int64     `json:"maxFileSize"`
+	MaxFiles    int       `json:"maxFiles"`
+	Started     time.Time `json:"started,omitzero"`
+	Stopped     time.Time `json:"stopped,omitzero"`
+	// Packets and Bytes count the captured frames, including the ones
+	// that were rotated out.
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+	// Files are the capture files that are kept, oldest first.
+	Files []string `json:"files,omitempty"`
+	// Error is set if the capture stopped because it could not be written.
+	Error string `json:"error,omitempty"`
+}
+
+// Capturer captures the frames it is given while a capture is running. It
+// is safe for concurrent use, and cheap to call while no capture runs.
+type Capturer struct {
+	dir     string
+	snapLen int
+	active  atomic.Bool
+	mutex   sync.Mutex
+	filter  *Filter
+	file    *os.File
+	writer  *pcapgo.Writer
+	size    int64
+	// sequence numbers the files of the capture.
+	sequence int
+	status   Status
+}
+
+// New returns a Capturer that writes capture files to the given directory,
+// truncating frames to snapLen bytes.
+func New(dir string, snapLen int) *Capturer {
+	return &Capturer{dir: dir, snapLen: snapLen}
+}
+
+// Start starts a new capture, replacing the files of the previous one.
+func (c *Capturer) Start(opts Options) error {
+	filter, err := ParseFilter(opts.Filter)
+	if err != nil {
+		return err
+	}
+	if opts.MaxFileSize == 0 {
+		opts.MaxFileSize = DefaultMaxFileSize
+	}
+	if opts.MaxFiles == 0 {
+		opts.MaxFiles = DefaultMaxFiles
+	}
+	if opts.MaxFileSize < pcapHeaderSize+pcapRecordHeaderSize+int64(c.snapLen) || opts.MaxFiles < 1 {
+		return fmt.Errorf("capture files must allow at least %d bytes, and at least one file must be kept",
+			pcapHeaderSize+pcapRecordHeaderSize+c.snapLen)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.status.Running {
+		return ErrRunning
+	}
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return fmt.Errorf("creating capture directory: %w", err)
+	}
+	for _, file := range c.status.Files {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing previous capture: %w", err)
+		}
+	}
+	c.filter = filter
+	c.sequence = 0
+	c.status = Status{
+		Running:     true,
+		Filter:      filter.String(),
+		MaxFileSize: opts.MaxFileSize,
+		MaxFiles:    opts.MaxFiles,
+		Started:     time.Now(),
+	}
+	if err := c.rotate(); err != nil {
+		c.status.Running = false
+		return err
+	}
+	c.active.Store(true)
+	return nil
+}
+
+// Stop stops the running capture; its files are kept until the next one
+// starts.
+func (c *Capturer) Stop() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.status.Running {
+		return ErrNotRunning
+	}
+	c.active.Store(false)
+	c.status.Running = false
+	c.status.Stopped = time.Now()
+	return c.closeFile()
+}
+
+// Status returns the status of the current, or last, capture.
+func (c *Capturer) Status() Status {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	status := c.status
+	status.Files = append([]string(nil), c.status.Files...)
+	return status
+}
+
+// Packet records the Ethernet frame if a capture is running and the frame
+// matches its filter. Errors writing the capture stop it.
+func (c *Capturer) Packet(frame []byte) {
+	if c == nil || !c.active.Load() {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.status.Running || !c.filter.Match(frame) {
+		return
+	}
+	data := frame
+	if len(data) > c.snapLen {
+		data = data[:c.snapLen]
+	}
+	recordSize := int64(pcapRecordHeaderSize + len(data))
+	if c.size+recordSize > c.status.MaxFileSize {
+		if err := c.rotate(); err != nil {
+			c.fail(err)
+			return
+		}
+	}
+	info := gopacket.CaptureInfo{
+		Timestamp:     time.Now(),
+		CaptureLength: len(data),
+		Length:        len(frame),
+	}
+	if err := c.writer.WritePacket(info, data); err != nil {
+		c.fail(err)
+		return
+	}
+	c.size += recordSize
+	c.status.Packets++
+	c.status.Bytes += uint64(len(frame))
+}
+
+// WriteTo writes the frames of the current, or last, capture to w as a
+// single pcap stream. A running capture is not interrupted; the frames
+// captured while writing may or may not be included.
+func (c *Capturer) WriteTo(w io.Writer) (int64, error) {
+	// Do not hold the lock while writing, as that would block the frames
+	// being captured.
+	files := c.Status().Files
+	if len(files) == 0 {
+		return 0, ErrNoCapture
+	}
+	counter := &countingWriter{w: w}
+	writer := pcapgo.NewWriter(counter)
+	if err := writer.WriteFileHeader(uint32(c.snapLen), layers.LinkTypeEthernet); err != nil {
+		return counter.n, err
+	}
+	for _, name := range files {
+		if err := copyPackets(writer, name); err != nil {
+			return counter.n, err
+		}
+	}
+	return counter.n, nil
+}
+
+func copyPackets(writer *pcapgo.Writer, name string) error {
+	file, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		// The file was rotated out since the capture status was read.
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader, err := pcapgo.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	for {
+		data, info, err := reader.ReadPacketData()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("reading %s: %w", name, err)
+		}
+		if err := writer.WritePacket(info, data); err != nil {
+			return err
+		}
+	}
+}
+
+// rotate closes the current capture file, if any, and starts a new one,
+// removing the oldest files beyond the limit.
+func (c *Capturer) rotate() error {
+	if err := c.closeFile(); err != nil {
+		return err
+	}
+	name := filepath.Join(c.dir, fmt.Sprintf("capture-%s-%03d.pcap",
+		c.status.Started.Format("20060102T150405"), c.sequence))
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("creating capture file: %w", err)
+	}
+	writer := pcapgo.NewWriter(file)
+	if err := writer.WriteFileHeader(uint32(c.snapLen), layers.LinkTypeEthernet); err != nil {
+		file.Close()
+		return fmt.Errorf("writing capture file: %w", err)
+	}
+	c.file, c.writer, c.size = file, writer, pcapHeaderSize
+	c.sequence++
+	c.status.Files = append(c.status.Files, name)
+	for len(c.status.Files) > c.status.MaxFiles {
+		if err := os.Remove(c.status.Files[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing capture file: %w", err)
+		}
+		c.status.Files = c.status.Files[1:]
+	}
+	return nil
+}
+
+func (c *Capturer) closeFile() error {
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file, c.writer = nil, nil
+	return err
+}
+
+// fail stops the running capture after an error writing it.
+func (c *Capturer) fail(err error) {
+	c.active.Store(false)
+	c.status.Running = false
+	c.status.Stopped = time.Now()
+	_ = c.closeFile()
+	c.status.Error = err.Error()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,222 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capture_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/capture"
+)
+
+const snapLen = 128
+
+func frame(t *testing.T, transport string, srcIP, dstIP string, srcPort, dstPort int) []byte {
+	t.Helper()
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x5a, 0x94, 0xef, 0xe4, 0x0c, 0xee},
+		DstMAC:       net.HardwareAddr{0x5a, 0x94, 0xef, 0xe4, 0x0c, 0xdd},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := &layers.IPv4{
+		Version: 4,
+		TTL:     64,
+		SrcIP:   net.ParseIP(srcIP).To4(),
+		DstIP:   net.ParseIP(dstIP).To4(),
+	}
+	var transportLayer gopacket.SerializableLayer
+	switch transport {
+	case "tcp":
+		ip.Protocol = layers.IPProtocolTCP
+		tcp := &layers.TCP{SrcPort: layers.TCPPort(srcPort), DstPort: layers.TCPPort(dstPort), SYN: true}
+		require.NoError(t, tcp.SetNetworkLayerForChecksum(ip))
+		transportLayer = tcp
+	case "udp":
+		ip.Protocol = layers.IPProtocolUDP
+		udp := &layers.UDP{SrcPort: layers.UDPPort(srcPort), DstPort: layers.UDPPort(dstPort)}
+		require.NoError(t, udp.SetNetworkLayerForChecksum(ip))
+		transportLayer = udp
+	}
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	require.NoError(t, gopacket.SerializeLayers(buf, opts, eth, ip, transportLayer, gopacket.Payload("payload")))
+	return buf.Bytes()
+}
+
+func TestFilter(t *testing.T) {
+	httpFrame := frame(t, "tcp", "192.168.127.2", "10.0.0.1", 40000, 80)
+	dnsFrame := frame(t, "udp", "192.168.127.2", "192.168.127.1", 40001, 53)
+
+	tests := []struct {
+		expression string
+		http, dns  bool
+	}{
+		{"", true, true},
+		{"tcp", true, false},
+		{"udp and port 53", false, true},
+		{"not udp", true, false},
+		{"dst port 80", true, false},
+		{"src port 80", false, false},
+		{"host 10.0.0.1", true, false},
+		{"src host 192.168.127.2", true, true},
+		{"net 192.168.127.0/24 and not dst host 10.0.0.1", false, true},
+		{"ip and tcp and dst net 10.0.0.0/8", true, false},
+		{"arp", false, false},
+	}
+	for _, tt := range tests {
+		filter, err := capture.ParseFilter(tt.expression)
+		require.NoError(t, err, tt.expression)
+		assert.Equal(t, tt.http, filter.Match(httpFrame), "%q matching HTTP", tt.expression)
+		assert.Equal(t, tt.dns, filter.Match(dnsFrame), "%q matching DNS", tt.expression)
+	}
+
+	for _, expression := range []string{"tcp or udp", "port", "port 99999", "host nope", "src tcp", "tcp and", "not"} {
+		_, err := capture.ParseFilter(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func readPackets(t *testing.T, r io.Reader) [][]byte {
+	t.Helper()
+	reader, err := pcapgo.NewReader(r)
+	require.NoError(t, err)
+	assert.Equal(t, layers.LinkTypeEthernet, reader.LinkType())
+	var packets [][]byte
+	for {
+		data, _, err := reader.ReadPacketData()
+		if errors.Is(err, io.EOF) {
+			return packets
+		}
+		require.NoError(t, err)
+		packets = append(packets, data)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	capturer := capture.New(t.TempDir(), snapLen)
+	httpFrame := frame(t, "tcp", "192.168.127.2", "10.0.0.1", 40000, 80)
+	dnsFrame := frame(t, "udp", "192.168.127.2", "192.168.127.1", 40001, 53)
+
+	// Frames are ignored while no capture runs.
+	capturer.Packet(httpFrame)
+	_, err := capturer.WriteTo(io.Discard)
+	require.ErrorIs(t, err, capture.ErrNoCapture)
+	require.ErrorIs(t, capturer.Stop(), capture.ErrNotRunning)
+
+	recordSize := int64(16 + len(httpFrame))
+	require.NoError(t, capturer.Start(capture.Options{
+		Filter:      "tcp port 80",
+		MaxFileSize: 24 + 2*recordSize,
+		MaxFiles:    2,
+	}))
+	require.ErrorIs(t, capturer.Start(capture.Options{}), capture.ErrRunning)
+
+	for range 8 {
+		capturer.Packet(httpFrame)
+		capturer.Packet(dnsFrame)
+	}
+	require.NoError(t, capturer.Stop())
+
+	status := capturer.Status()
+	assert.False(t, status.Running)
+	assert.Equal(t, "tcp port 80", status.Filter)
+	assert.Equal(t, uint64(8), status.Packets)
+	assert.Equal(t, uint64(8*len(httpFrame)), status.Bytes)
+	assert.Len(t, status.Files, 2, "older files should be rotated out")
+
+	var buf bytes.Buffer
+	n, err := capturer.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	packets := readPackets(t, &buf)
+	assert.Len(t, packets, 4, "only the packets of the kept files should be written")
+	for _, packet := range packets {
+		assert.Equal(t, httpFrame, packet)
+	}
+
+	// Frames are ignored once the capture stopped.
+	capturer.Packet(httpFrame)
+	assert.Equal(t, uint64(8), capturer.Status().Packets)
+}
+
+func TestHandler(t *testing.T) {
+	capturer := capture.New(t.TempDir(), snapLen)
+	server := httptest.NewServer(capturer.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + capture.PcapPath)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.PostForm(server.URL+capture.StartPath, url.Values{"filter": {"tcp or udp"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.PostForm(server.URL+capture.StartPath, url.Values{
+		"filter":    {"udp"},
+		"max-size":  {"1MB"},
+		"max-files": {"3"},
+	})
+	require.NoError(t, err)
+	var status capture.Status
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, status.Running)
+	assert.Equal(t, int64(1000*1000), status.MaxFileSize)
+	assert.Equal(t, 3, status.MaxFiles)
+
+	resp, err = http.Post(server.URL+capture.StartPath, "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	dnsFrame := frame(t, "udp", "192.168.127.2", "192.168.127.1", 40001, 53)
+	capturer.Packet(dnsFrame)
+
+	// The capture can be fetched while it is running.
+	resp, err = http.Get(server.URL + capture.PcapPath)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, [][]byte{dnsFrame}, readPackets(t, resp.Body))
+	resp.Body.Close()
+
+	resp, err = http.Post(server.URL+capture.StopPath, "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + capture.StatusPath)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(body), `"running":false`), string(body))
+}
@@ -0,0 +1,223 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capture
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// direction restricts a filter term to the source or destination of a
+// packet; the zero value matches either.
+type direction int
+
+const (
+	either direction = iota
+	source
+	destination
+)
+
+// term is a single primitive of a filter, e.g. "tcp" or "dst port 80".
+type term struct {
+	negate    bool
+	direction direction
+	protocol  string
+	port      int
+	network   *net.IPNet
+}
+
+// Filter selects the packets to capture. It supports a subset of the
+// pcap-filter syntax: primitives joined by "and", each optionally preceded
+// by "not"; "or" and parentheses are not supported. The primitives are the
+// protocols (ip, ip6, arp, icmp, icmp6, tcp, udp), and [src|dst] host <ip>,
+// [src|dst] net <cidr> and [src|dst] port <port>. An empty filter matches
+// every packet.
+type Filter struct {
+	expression string
+	terms      []term
+}
+
+var protocols = []string{"ip", "ip6", "arp", "icmp", "icmp6", "tcp", "udp"}
+
+// ParseFilter parses a filter expression.
+func ParseFilter(expression string) (*Filter, error) {
+	filter := &Filter{expression: strings.TrimSpace(expression)}
+	words := strings.Fields(strings.ToLower(filter.expression))
+	for len(words) > 0 {
+		var t term
+		if words[0] == "not" {
+			t.negate = true
+			words = words[1:]
+		}
+		if len(words) > 0 {
+			switch words[0] {
+			case "src":
+				t.direction = source
+				words = words[1:]
+			case "dst":
+				t.direction = destination
+				words = words[1:]
+			}
+		}
+		if len(words) == 0 {
+			return nil, fmt.Errorf("invalid filter %q: unexpected end of expression", expression)
+		}
+		keyword := words[0]
+		words = words[1:]
+		switch keyword {
+		case "host", "net", "port":
+			if len(words) == 0 {
+				return nil, fmt.Errorf("invalid filter %q: %s requires a value", expression, keyword)
+			}
+			if err := t.parseValue(keyword, words[0]); err != nil {
+				return nil, fmt.Errorf("invalid filter %q: %w", expression, err)
+			}
+			words = words[1:]
+		default:
+			if !slices.Contains(protocols, keyword) {
+				return nil, fmt.Errorf("invalid filter %q: unknown primitive %q", expression, keyword)
+			}
+			if t.direction != either {
+				return nil, fmt.Errorf("invalid filter %q: src and dst do not apply to %s", expression, keyword)
+			}
+			t.protocol = keyword
+		}
+		filter.terms = append(filter.terms, t)
+		// As in pcap-filter, "and" may be omitted.
+		if len(words) > 0 && words[0] == "and" {
+			if len(words) == 1 {
+				return nil, fmt.Errorf("invalid filter %q: unexpected end of expression", expression)
+			}
+			words = words[1:]
+		}
+	}
+	return filter, nil
+}
+
+func (t *term) parseValue(keyword, value string) error {
+	switch keyword {
+	case "host":
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return fmt.Errorf("invalid host %q", value)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		t.network = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	case "net":
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return fmt.Errorf("invalid net %q: %w", value, err)
+		}
+		t.network = network
+	case "port":
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port %q", value)
+		}
+		t.port = port
+	}
+	return nil
+}
+
+// String returns the filter expression.
+func (f *Filter) String() string {
+	return f.expression
+}
+
+// Match returns whether the Ethernet frame matches the filter.
+func (f *Filter) Match(frame []byte) bool {
+	if len(f.terms) == 0 {
+		return true
+	}
+	packet := gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.DecodeOptions{Lazy: true, NoCopy: true})
+	for _, t := range f.terms {
+		if t.match(packet) == t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *term) match(packet gopacket.Packet) bool {
+	switch {
+	case t.protocol != "":
+		return matchProtocol(packet, t.protocol)
+	case t.network != nil:
+		var src, dst net.IP
+		switch layer := packet.NetworkLayer().(type) {
+		case *layers.IPv4:
+			src, dst = layer.SrcIP, layer.DstIP
+		case *layers.IPv6:
+			src, dst = layer.SrcIP, layer.DstIP
+		default:
+			if arp, ok := packet.Layer(layers.LayerTypeARP).(*layers.ARP); ok {
+				src, dst = arp.SourceProtAddress, arp.DstProtAddress
+			}
+		}
+		return t.matchDirection(
+			src != nil && t.network.Contains(src),
+			dst != nil && t.network.Contains(dst))
+	default:
+		var src, dst int
+		switch layer := packet.TransportLayer().(type) {
+		case *layers.TCP:
+			src, dst = int(layer.SrcPort), int(layer.DstPort)
+		case *layers.UDP:
+			src, dst = int(layer.SrcPort), int(layer.DstPort)
+		default:
+			return false
+		}
+		return t.matchDirection(src == t.port, dst == t.port)
+	}
+}
+
+func (t *term) matchDirection(src, dst bool) bool {
+	switch t.direction {
+	case source:
+		return src
+	case destination:
+		return dst
+	default:
+		return src || dst
+	}
+}
+
+func matchProtocol(packet gopacket.Packet, protocol string) bool {
+	switch protocol {
+	case "ip":
+		return packet.Layer(layers.LayerTypeIPv4) != nil
+	case "ip6":
+		return packet.Layer(layers.LayerTypeIPv6) != nil
+	case "arp":
+		return packet.Layer(layers.LayerTypeARP) != nil
+	case "icmp":
+		return packet.Layer(layers.LayerTypeICMPv4) != nil
+	case "icmp6":
+		return packet.Layer(layers.LayerTypeICMPv6) != nil
+	case "tcp":
+		return packet.Layer(layers.LayerTypeTCP) != nil
+	case "udp":
+		return packet.Layer(layers.LayerTypeUDP) != nil
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capture
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+)
+
+// Paths of the control endpoints served by Handler.
+const (
+	StartPath  = "/capture/start"
+	StopPath   = "/capture/stop"
+	StatusPath = "/capture/status"
+	PcapPath   = "/capture/pcap"
+)
+
+// Handler returns the control endpoints of the capturer:
+//   - POST StartPath starts a capture; the optional form values are filter,
+//     max-size (e.g. 16MB) and max-files.
+//   - POST StopPath stops the running capture.
+//   - GET StatusPath returns the Status as JSON.
+//   - GET PcapPath returns the current, or last, capture as a pcap file.
+func (c *Capturer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+StartPath, func(w http.ResponseWriter, r *http.Request) {
+		opts, err := parseOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := c.Start(opts); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		logrus.Infof("packet capture started with filter %q", opts.Filter)
+		c.writeStatus(w)
+	})
+	mux.HandleFunc("POST "+StopPath, func(w http.ResponseWriter, _ *http.Request) {
+		if err := c.Stop(); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		logrus.Info("packet capture stopped")
+		c.writeStatus(w)
+	})
+	mux.HandleFunc("GET "+StatusPath, func(w http.ResponseWriter, _ *http.Request) {
+		c.writeStatus(w)
+	})
+	mux.HandleFunc("GET "+PcapPath, func(w http.ResponseWriter, _ *http.Request) {
+		if len(c.Status().Files) == 0 {
+			http.Error(w, ErrNoCapture.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.tcpdump.pcap")
+		if _, err := c.WriteTo(w); err != nil {
+			// The headers are already sent; the client sees a truncated file.
+			logrus.Errorf("writing packet capture failed: %v", err)
+		}
+	})
+	return mux
+}
+
+func parseOptions(r *http.Request) (Options, error) {
+	var opts Options
+	if err := r.ParseForm(); err != nil {
+		return opts, err
+	}
+	opts.Filter = r.Form.Get("filter")
+	if value := r.Form.Get("max-size"); value != "" {
+		size, err := humanize.ParseBytes(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid max-size %q: %w", value, err)
+		}
+		opts.MaxFileSize = int64(size)
+	}
+	if value := r.Form.Get("max-files"); value != "" {
+		files, err := strconv.Atoi(value)
+		if err != nil || files < 1 {
+			return opts, fmt.Errorf("invalid max-files %q", value)
+		}
+		opts.MaxFiles = files
+	}
+	return opts, nil
+}
+
+func errorStatus(err error) int {
+	if errors.Is(err, ErrRunning) || errors.Is(err, ErrNotRunning) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func (c *Capturer) writeStatus(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c.Status()); err != nil {
+		logrus.Errorf("writing packet capture status failed: %v", err)
+	}
+}
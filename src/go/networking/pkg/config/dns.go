@@ -0,0 +1,230 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/containers/gvisor-tap-vsock/pkg/types"
+)
+
+// DNSConfig is the content of the DNS configuration file of the host
+// switch; it adds zones to the DNS server of the virtual network.
+type DNSConfig struct {
+	Zones []DNSZone `json:"zones"`
+}
+
+// DNSZone is a domain that is answered by the DNS server of the virtual
+// network instead of the upstream resolvers of the host.
+type DNSZone struct {
+	// Name is the domain of the zone, e.g. "corp.example".
+	Name    string      `json:"name"`
+	Records []DNSRecord `json:"records,omitempty"`
+	// DefaultIP answers the names in the zone that do not match any record;
+	// without it, those names do not resolve.
+	DefaultIP string `json:"defaultIP,omitempty"`
+}
+
+// DNSRecord is an A record within a zone; either Name or Regexp must be set.
+type DNSRecord struct {
+	// Name is relative to the zone, e.g. "registry".
+	Name string `json:"name,omitempty"`
+	// Regexp matches names relative to the zone.
+	Regexp string `json:"regexp,omitempty"`
+	IP     string `json:"ip"`
+}
+
+var dnsLabel = regexp.MustCompile(`^[a-z0-9_]([a-z0-9_-]{0,61}[a-z0-9_])?$`)
+
+// LoadDNSConfig reads the DNS configuration file and returns the zones it
+// defines.
+func LoadDNSConfig(path string) ([]types.Zone, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading DNS config: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var dnsConfig DNSConfig
+	if err := decoder.Decode(&dnsConfig); err != nil {
+		return nil, fmt.Errorf("parsing DNS config %s: %w", path, err)
+	}
+	zones := make([]types.Zone, 0, len(dnsConfig.Zones))
+	for _, zone := range dnsConfig.Zones {
+		converted, err := zone.toZone()
+		if err != nil {
+			return nil, fmt.Errorf("DNS config %s: zone %q: %w", path, zone.Name, err)
+		}
+		zones = append(zones, converted)
+	}
+	return zones, nil
+}
+
+func (z DNSZone) toZone() (types.Zone, error) {
+	zone := types.Zone{Name: z.Name}
+	if z.DefaultIP != "" {
+		zone.DefaultIP = net.ParseIP(z.DefaultIP)
+		if zone.DefaultIP == nil {
+			return zone, fmt.Errorf("invalid default IP %q", z.DefaultIP)
+		}
+	}
+	for _, r := range z.Records {
+		record := types.Record{Name: r.Name, IP: net.ParseIP(r.IP)}
+		if record.IP == nil {
+			return zone, fmt.Errorf("invalid IP %q", r.IP)
+		}
+		if r.Regexp != "" {
+			re, err := regexp.Compile(r.Regexp)
+			if err != nil {
+				return zone, fmt.Errorf("invalid regexp %q: %w", r.Regexp, err)
+			}
+			record.Regexp = re
+		}
+		zone.Records = append(zone.Records, record)
+	}
+	return zone, nil
+}
+
+// ParseDNSRecords converts the input format of Hostname=IP, e.g.
+// registry.corp.example=192.168.127.1, into zones; the first label of the
+// hostname is the record, and the rest is the zone.
+func ParseDNSRecords(records []string) ([]types.Zone, error) {
+	var zones []types.Zone
+	for _, v := range records {
+		hostname, address, found := strings.Cut(v, "=")
+		if !found {
+			return nil, fmt.Errorf("input %q not in expected format: Hostname=IP", v)
+		}
+		name, zone, found := strings.Cut(strings.TrimSuffix(hostname, "."), ".")
+		if !found || zone == "" {
+			return nil, fmt.Errorf("hostname %q must include a domain", hostname)
+		}
+		ip := net.ParseIP(address)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address provided: %s", address)
+		}
+		zones = MergeZones(zones, types.Zone{
+			Name:    zone,
+			Records: []types.Record{{Name: name, IP: ip}},
+		})
+	}
+	return zones, nil
+}
+
+// NormalizeZoneName returns the zone name as matched by the DNS server:
+// lower case and fully qualified.
+func NormalizeZoneName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+}
+
+// MergeZones adds the records of the extra zones to the zones with the same
+// name, or adds the zones if there are none. The existing records come
+// first, so they take precedence.
+func MergeZones(zones []types.Zone, extra ...types.Zone) []types.Zone {
+	for _, zone := range extra {
+		zone.Name = NormalizeZoneName(zone.Name)
+		i := slices.IndexFunc(zones, func(z types.Zone) bool {
+			return z.Name == zone.Name
+		})
+		if i < 0 {
+			zones = append(zones, zone)
+			continue
+		}
+		zones[i].Records = append(zones[i].Records, zone.Records...)
+		if zones[i].DefaultIP == nil {
+			zones[i].DefaultIP = zone.DefaultIP
+		}
+	}
+	return zones
+}
+
+// ValidateZone checks that the zone can be served by the DNS server of the
+// virtual network for the given subnet. Addresses within the subnet must be
+// assigned to the gateway, the host, or a static DHCP lease; addresses
+// outside of it are reached through the host.
+func ValidateZone(subnet Subnet, zone types.Zone) error {
+	name := strings.TrimSuffix(strings.ToLower(zone.Name), ".")
+	if name == "" {
+		// An empty zone would shadow every name resolved by the host.
+		return errors.New("zone name must not be empty")
+	}
+	if !validHostname(name) {
+		return fmt.Errorf("invalid zone name %q", zone.Name)
+	}
+	if zone.DefaultIP != nil {
+		if err := validateZoneIP(subnet, zone.DefaultIP); err != nil {
+			return fmt.Errorf("zone %q: default IP: %w", zone.Name, err)
+		}
+	}
+	for _, record := range zone.Records {
+		if (record.Name == "") == (record.Regexp == nil) {
+			return fmt.Errorf("zone %q: records must have either a name or a regexp", zone.Name)
+		}
+		if record.Name != "" && !validHostname(strings.ToLower(record.Name)) {
+			return fmt.Errorf("zone %q: invalid record name %q", zone.Name, record.Name)
+		}
+		if err := validateZoneIP(subnet, record.IP); err != nil {
+			return fmt.Errorf("zone %q: record %q: %w", zone.Name, recordName(record), err)
+		}
+	}
+	return nil
+}
+
+func validateZoneIP(subnet Subnet, ip net.IP) error {
+	// The DNS server only answers A queries.
+	if ip.To4() == nil {
+		return fmt.Errorf("%q is not an IPv4 address", ip)
+	}
+	_, network, err := net.ParseCIDR(subnet.SubnetCIDR)
+	if err != nil {
+		return fmt.Errorf("validating subnet: %w", err)
+	}
+	if !network.Contains(ip) {
+		return nil
+	}
+	assigned := []string{subnet.GatewayIP, subnet.StaticDNSHost}
+	for leaseIP := range subnet.StaticDHCPLease {
+		assigned = append(assigned, leaseIP)
+	}
+	if !slices.ContainsFunc(assigned, func(address string) bool {
+		return ip.Equal(net.ParseIP(address))
+	}) {
+		return fmt.Errorf("%s is within subnet %s but is not assigned in the virtual network", ip, subnet.SubnetCIDR)
+	}
+	return nil
+}
+
+func validHostname(name string) bool {
+	for _, label := range strings.Split(name, ".") {
+		if !dnsLabel.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+func recordName(record types.Record) string {
+	if record.Regexp != nil {
+		return record.Regexp.String()
+	}
+	return record.Name
+}
@@ -0,0 +1,128 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/containers/gvisor-tap-vsock/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/config"
+)
+
+func TestLoadDNSConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dns.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"zones": [
+			{
+				"name": "corp.example",
+				"records": [
+					{"name": "registry", "ip": "192.168.127.1"},
+					{"regexp": "^mirror-[0-9]+$", "ip": "10.0.0.5"}
+				]
+			},
+			{"name": "split.example.", "defaultIP": "10.0.0.6"}
+		]
+	}`), 0o600))
+
+	zones, err := config.LoadDNSConfig(path)
+	require.NoError(t, err)
+	require.Len(t, zones, 2)
+	assert.Equal(t, "corp.example", zones[0].Name)
+	require.Len(t, zones[0].Records, 2)
+	assert.Equal(t, "registry", zones[0].Records[0].Name)
+	assert.True(t, zones[0].Records[0].IP.Equal(net.ParseIP("192.168.127.1")))
+	require.NotNil(t, zones[0].Records[1].Regexp)
+	assert.True(t, zones[0].Records[1].Regexp.MatchString("mirror-1"))
+	assert.True(t, zones[1].DefaultIP.Equal(net.ParseIP("10.0.0.6")))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"zones": [{"name": "x", "unknown": true}]}`), 0o600))
+	_, err = config.LoadDNSConfig(path)
+	assert.Error(t, err)
+
+	_, err = config.LoadDNSConfig(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestParseDNSRecords(t *testing.T) {
+	zones, err := config.ParseDNSRecords([]string{
+		"registry.corp.example=192.168.127.1",
+		"git.Corp.Example.=10.0.0.1",
+		"proxy.other.example=10.0.0.2",
+	})
+	require.NoError(t, err)
+	require.Len(t, zones, 2)
+	assert.Equal(t, "corp.example.", zones[0].Name)
+	require.Len(t, zones[0].Records, 2)
+	assert.Equal(t, "registry", zones[0].Records[0].Name)
+	assert.Equal(t, "git", zones[0].Records[1].Name)
+	assert.Equal(t, "other.example.", zones[1].Name)
+
+	for _, input := range []string{"registry.corp.example", "registry=10.0.0.1", "registry.corp.example=nope"} {
+		_, err := config.ParseDNSRecords([]string{input})
+		assert.Error(t, err, input)
+	}
+}
+
+func TestMergeZones(t *testing.T) {
+	zones := []types.Zone{
+		{Name: "rancher-desktop.internal.", Records: []types.Record{{Name: "gateway", IP: net.ParseIP("192.168.127.1")}}},
+	}
+	zones = config.MergeZones(zones,
+		types.Zone{Name: "Rancher-Desktop.Internal", Records: []types.Record{{Name: "registry", IP: net.ParseIP("192.168.127.1")}}},
+		types.Zone{Name: "corp.example", DefaultIP: net.ParseIP("10.0.0.1")},
+	)
+	require.Len(t, zones, 2)
+	require.Len(t, zones[0].Records, 2)
+	assert.Equal(t, "gateway", zones[0].Records[0].Name, "existing records should take precedence")
+	assert.Equal(t, "registry", zones[0].Records[1].Name)
+	assert.Equal(t, "corp.example.", zones[1].Name)
+}
+
+func TestValidateZone(t *testing.T) {
+	subnet, err := config.ValidateSubnet(config.DefaultSubnet)
+	require.NoError(t, err)
+
+	valid := []types.Zone{
+		{Name: "corp.example.", Records: []types.Record{{Name: "registry", IP: net.ParseIP("192.168.127.1")}}},
+		{Name: "corp.example", Records: []types.Record{{Name: "a.b", IP: net.ParseIP("192.168.127.254")}}},
+		{Name: "corp.example", Records: []types.Record{{Name: "vm", IP: net.ParseIP("192.168.127.2")}}},
+		{Name: "corp.example", Records: []types.Record{{Regexp: regexp.MustCompile(".*"), IP: net.ParseIP("10.0.0.1")}}},
+		{Name: "corp.example", DefaultIP: net.ParseIP("10.0.0.1")},
+	}
+	for _, zone := range valid {
+		assert.NoError(t, config.ValidateZone(*subnet, zone), "%+v", zone)
+	}
+
+	invalid := map[string]types.Zone{
+		"empty zone":          {Name: "."},
+		"invalid zone":        {Name: "corp example"},
+		"unassigned IP":       {Name: "corp.example", Records: []types.Record{{Name: "registry", IP: net.ParseIP("192.168.127.50")}}},
+		"unassigned default":  {Name: "corp.example", DefaultIP: net.ParseIP("192.168.127.50")},
+		"IPv6":                {Name: "corp.example", Records: []types.Record{{Name: "registry", IP: net.ParseIP("::1")}}},
+		"missing IP":          {Name: "corp.example", Records: []types.Record{{Name: "registry"}}},
+		"missing name":        {Name: "corp.example", Records: []types.Record{{IP: net.ParseIP("10.0.0.1")}}},
+		"name and regexp":     {Name: "corp.example", Records: []types.Record{{Name: "a", Regexp: regexp.MustCompile("a"), IP: net.ParseIP("10.0.0.1")}}},
+		"invalid record name": {Name: "corp.example", Records: []types.Record{{Name: "-a", IP: net.ParseIP("10.0.0.1")}}},
+	}
+	for name, zone := range invalid {
+		assert.Error(t, config.ValidateZone(*subnet, zone), name)
+	}
+}
@@ -20,8 +20,10 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	gvisorTypes "github.com/containers/gvisor-tap-vsock/pkg/types"
 	"github.com/docker/go-connections/nat"
@@ -34,6 +36,12 @@ import (
 type ProxyConfig struct {
 	UpstreamAddress string
 	UDPBufferSize   int
+	// UDPIdleTimeout is how long a UDP flow is kept without traffic; it
+	// defaults to DefaultUDPIdleTimeout.
+	UDPIdleTimeout time.Duration
+	// UDPMaxFlows is the number of concurrent UDP flows per port; it
+	// defaults to DefaultUDPMaxFlows.
+	UDPMaxFlows int
 }
 
 type PortProxy struct {
@@ -45,21 +53,31 @@ type PortProxy struct {
 	// map of TCP port number as a key to associated listener
 	activeListeners map[int]net.Listener
 	listenerMutex   sync.Mutex
-	// map of UDP port number as a key to associated UDPConn
-	activeUDPConns map[int]*net.UDPConn
+	// map of UDP port number as a key to associated UDP port
+	activeUDPConns map[int]*udpPort
 	udpConnMutex   sync.Mutex
 	wg             sync.WaitGroup
 }
 
 func NewPortProxy(ctx context.Context, listener net.Listener, cfg *ProxyConfig) *PortProxy {
+	config := *cfg
+	if config.UDPBufferSize <= 0 {
+		config.UDPBufferSize = maxDatagramSize
+	}
+	if config.UDPIdleTimeout <= 0 {
+		config.UDPIdleTimeout = DefaultUDPIdleTimeout
+	}
+	if config.UDPMaxFlows <= 0 {
+		config.UDPMaxFlows = DefaultUDPMaxFlows
+	}
 	portProxy := &PortProxy{
 		ctx:             ctx,
-		config:          cfg,
+		config:          &config,
 		listener:        listener,
 		quit:            make(chan struct{}),
 		listenerConfig:  net.ListenConfig{},
 		activeListeners: make(map[int]net.Listener),
-		activeUDPConns:  make(map[int]*net.UDPConn),
+		activeUDPConns:  make(map[int]*udpPort),
 	}
 	return portProxy
 }
@@ -85,7 +103,27 @@ func (p *PortProxy) Start() error {
 func (p *PortProxy) UDPPortMappings() map[int]*net.UDPConn {
 	p.udpConnMutex.Lock()
 	defer p.udpConnMutex.Unlock()
-	return p.activeUDPConns
+	mappings := make(map[int]*net.UDPConn, len(p.activeUDPConns))
+	for port, u := range p.activeUDPConns {
+		mappings[port] = u.conn
+	}
+	return mappings
+}
+
+// UDPStats returns the counters of the forwarded UDP ports, sorted by port.
+func (p *PortProxy) UDPStats() []UDPStats {
+	p.udpConnMutex.Lock()
+	ports := make([]*udpPort, 0, len(p.activeUDPConns))
+	for _, u := range p.activeUDPConns {
+		ports = append(ports, u)
+	}
+	p.udpConnMutex.Unlock()
+	stats := make([]UDPStats, 0, len(ports))
+	for _, u := range ports {
+		stats = append(stats, u.stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Port < stats[j].Port })
+	return stats
 }
 
 func (p *PortProxy) handleEvent(conn net.Conn) {
@@ -124,8 +162,8 @@ func (p *PortProxy) handleUDP(portBindings []nat.PortBinding, remove bool) {
 		}
 		if remove {
 			p.udpConnMutex.Lock()
-			if udpConn, exist := p.activeUDPConns[port]; exist {
-				if err := udpConn.Close(); err != nil {
+			if u, exist := p.activeUDPConns[port]; exist {
+				if err := u.conn.Close(); err != nil {
 					logrus.Errorf("error closing UDPConn for port [%s]: %s", portBinding.HostPort, err)
 				}
 			}
@@ -157,46 +195,14 @@ func (p *PortProxy) handleUDP(portBindings []nat.PortBinding, remove bool) {
 			continue
 		}
 
+		u := newUDPPort(port, c, targetAddr)
 		p.udpConnMutex.Lock()
-		p.activeUDPConns[port] = c
+		p.activeUDPConns[port] = u
 		p.udpConnMutex.Unlock()
 		logrus.Debugf("created UDPConn for: %v", sourceAddr)
 
-		go p.acceptUDPConn(c, targetAddr)
-	}
-}
-
-func (p *PortProxy) acceptUDPConn(sourceConn *net.UDPConn, targetAddr *net.UDPAddr) {
-	targetConn, err := net.DialUDP("udp", nil, targetAddr)
-	if err != nil {
-		logrus.Errorf("failed to connect to target address: %s : %s", targetAddr, err)
-		return
-	}
-	defer targetConn.Close()
-	p.wg.Add(1)
-	for {
-		b := make([]byte, p.config.UDPBufferSize)
-		n, addr, err := sourceConn.ReadFromUDP(b)
-		if err != nil && n == 0 {
-			logrus.Errorf("error reading UDP packet from source: %s : %s", addr, err)
-			if errors.Is(err, net.ErrClosed) {
-				p.wg.Done()
-				break
-			}
-			continue
-		}
-		logrus.Debugf("received %d data from %s", n, addr)
-
-		n, err = targetConn.Write(b[:n])
-		if err != nil {
-			logrus.Errorf("error forwarding UDP packet to target: %s : %s", targetAddr, err)
-			if errors.Is(err, net.ErrClosed) {
-				p.wg.Done()
-				break
-			}
-			continue
-		}
-		logrus.Debugf("sent %d data to %s", n, targetAddr)
+		p.wg.Add(1)
+		go p.acceptUDPConn(u)
 	}
 }
 
@@ -289,7 +295,7 @@ func (p *PortProxy) cleanupListeners() {
 func (p *PortProxy) cleanupUDPConns() {
 	p.udpConnMutex.Lock()
 	defer p.udpConnMutex.Unlock()
-	for _, c := range p.activeUDPConns {
-		_ = c.Close()
+	for _, u := range p.activeUDPConns {
+		_ = u.conn.Close()
 	}
 }
@@ -109,6 +109,111 @@ func TestNewPortProxyUDP(t *testing.T) {
 	portProxy.Close()
 }
 
+func TestPortProxyUDPFlows(t *testing.T) {
+	testServerIP, err := availableIP()
+	require.NoError(t, err, "cannot continue with the test since there are no available IP addresses")
+
+	targetAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(testServerIP, "0"))
+	require.NoError(t, err)
+	targetConn, err := net.ListenUDP("udp", targetAddr)
+	require.NoError(t, err)
+	defer targetConn.Close()
+
+	// echo the packets back, so that the replies are relayed to the clients
+	go func() {
+		b := make([]byte, 1024)
+		for {
+			n, addr, err := targetConn.ReadFromUDP(b)
+			if err != nil {
+				return
+			}
+			_, _ = targetConn.WriteToUDP(b[:n], addr)
+		}
+	}()
+
+	localListener, err := nettest.NewLocalListener("unix")
+	require.NoError(t, err)
+	defer localListener.Close()
+
+	idleTimeout := 300 * time.Millisecond
+	proxyConfig := &portproxy.ProxyConfig{
+		UpstreamAddress: testServerIP,
+		UDPBufferSize:   1024,
+		UDPIdleTimeout:  idleTimeout,
+		UDPMaxFlows:     1,
+	}
+	portProxy := portproxy.NewPortProxy(t.Context(), localListener, proxyConfig)
+	go portProxy.Start()
+	defer portProxy.Close()
+
+	_, testPort, err := net.SplitHostPort(targetConn.LocalAddr().String())
+	require.NoError(t, err)
+	port, err := nat.NewPort("udp", testPort)
+	require.NoError(t, err)
+	portMapping := types.PortMapping{
+		Ports: nat.PortMap{
+			port: []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: testPort}},
+		},
+	}
+	require.NoError(t, marshalAndSend(t.Context(), localListener, portMapping))
+	require.Eventually(t, func() bool {
+		return len(portProxy.UDPPortMappings()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	sourceAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort("127.0.0.1", testPort))
+	require.NoError(t, err)
+	exchange := func(conn *net.UDPConn, message string) error {
+		if _, err := conn.Write([]byte(message)); err != nil {
+			return err
+		}
+		if err := conn.SetReadDeadline(time.Now().Add(idleTimeout / 2)); err != nil {
+			return err
+		}
+		b := make([]byte, len(message))
+		n, err := conn.Read(b)
+		if err != nil {
+			return err
+		}
+		require.Equal(t, message, string(b[:n]))
+		return nil
+	}
+	stats := func() portproxy.UDPStats {
+		stats := portProxy.UDPStats()
+		require.Len(t, stats, 1)
+		return stats[0]
+	}
+
+	firstClient, err := net.DialUDP("udp", nil, sourceAddr)
+	require.NoError(t, err)
+	defer firstClient.Close()
+	secondClient, err := net.DialUDP("udp", nil, sourceAddr)
+	require.NoError(t, err)
+	defer secondClient.Close()
+
+	require.NoError(t, exchange(firstClient, "first"))
+	require.NoError(t, exchange(firstClient, "again"))
+	current := stats()
+	require.Equal(t, 1, current.ActiveFlows)
+	require.Equal(t, uint64(1), current.Flows)
+	require.Equal(t, uint64(2), current.PacketsIn)
+	require.Equal(t, uint64(2), current.PacketsOut)
+	require.Equal(t, uint64(len("first")+len("again")), current.BytesIn)
+
+	// the flow limit is reached, so the packets of a new client are dropped
+	require.Error(t, exchange(secondClient, "dropped"))
+	require.Equal(t, uint64(1), stats().Dropped)
+
+	// once the first flow expires, the second client gets a flow
+	require.Eventually(t, func() bool {
+		return stats().ActiveFlows == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, uint64(1), stats().Expired)
+	require.NoError(t, exchange(secondClient, "second"))
+	current = stats()
+	require.Equal(t, 1, current.ActiveFlows)
+	require.Equal(t, uint64(2), current.Flows)
+}
+
 func TestNewPortProxyTCP(t *testing.T) {
 	expectedResponse := "called the upstream server"
 
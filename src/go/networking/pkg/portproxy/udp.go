@@ -0,0 +1,235 @@
+/*
+Copyright © 2026 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package portproxy
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultUDPIdleTimeout is how long a UDP flow is kept without traffic
+	// in either direction.
+	DefaultUDPIdleTimeout = 60 * time.Second
+	// DefaultUDPMaxFlows is the number of concurrent UDP flows per port.
+	DefaultUDPMaxFlows = 1024
+	// maxDatagramSize is the largest UDP payload; larger buffers are wasted.
+	maxDatagramSize = 65535
+)
+
+// UDPStats are the counters of a forwarded UDP port. Packets and bytes
+// are counted from the client to the upstream server (in), and back (out).
+type UDPStats struct {
+	Port        int    `json:"port"`
+	ActiveFlows int    `json:"activeFlows"`
+	Flows       uint64 `json:"flows"`
+	Expired     uint64 `json:"expired"`
+	// Dropped counts the packets that could not be forwarded, either
+	// because the port reached its flow limit or because of an error.
+	Dropped    uint64 `json:"dropped"`
+	PacketsIn  uint64 `json:"packetsIn"`
+	PacketsOut uint64 `json:"packetsOut"`
+	BytesIn    uint64 `json:"bytesIn"`
+	BytesOut   uint64 `json:"bytesOut"`
+}
+
+// udpPort is a forwarded UDP port; each client address is a flow with its
+// own upstream socket, so that replies can be sent back to the client.
+type udpPort struct {
+	port   int
+	conn   *net.UDPConn
+	target *net.UDPAddr
+	mutex  sync.Mutex
+	// map of client address as a key to the associated flow
+	flows      map[string]*udpFlow
+	flowCount  atomic.Uint64
+	expired    atomic.Uint64
+	dropped    atomic.Uint64
+	packetsIn  atomic.Uint64
+	packetsOut atomic.Uint64
+	bytesIn    atomic.Uint64
+	bytesOut   atomic.Uint64
+}
+
+type udpFlow struct {
+	client   *net.UDPAddr
+	upstream *net.UDPConn
+	// lastActive is the time of the last packet, in Unix nanoseconds.
+	lastActive atomic.Int64
+}
+
+func newUDPPort(port int, conn *net.UDPConn, target *net.UDPAddr) *udpPort {
+	return &udpPort{
+		port:   port,
+		conn:   conn,
+		target: target,
+		flows:  make(map[string]*udpFlow),
+	}
+}
+
+func (u *udpPort) stats() UDPStats {
+	u.mutex.Lock()
+	activeFlows := len(u.flows)
+	u.mutex.Unlock()
+	return UDPStats{
+		Port:        u.port,
+		ActiveFlows: activeFlows,
+		Flows:       u.flowCount.Load(),
+		Expired:     u.expired.Load(),
+		Dropped:     u.dropped.Load(),
+		PacketsIn:   u.packetsIn.Load(),
+		PacketsOut:  u.packetsOut.Load(),
+		BytesIn:     u.bytesIn.Load(),
+		BytesOut:    u.bytesOut.Load(),
+	}
+}
+
+func (p *PortProxy) udpBufferSize() int {
+	return min(p.config.UDPBufferSize, maxDatagramSize)
+}
+
+func (p *PortProxy) acceptUDPConn(u *udpPort) {
+	defer p.wg.Done()
+	defer p.closeUDPFlows(u)
+	b := make([]byte, p.udpBufferSize())
+	for {
+		n, addr, err := u.conn.ReadFromUDP(b)
+		if err != nil && n == 0 {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logrus.Errorf("error reading UDP packet from source: %s : %s", addr, err)
+			continue
+		}
+		logrus.Debugf("received %d data from %s", n, addr)
+
+		flow := p.udpFlow(u, addr)
+		if flow == nil {
+			u.dropped.Add(1)
+			continue
+		}
+		n, err = flow.upstream.Write(b[:n])
+		if err != nil {
+			logrus.Errorf("error forwarding UDP packet to target: %s : %s", u.target, err)
+			u.dropped.Add(1)
+			continue
+		}
+		u.packetsIn.Add(1)
+		u.bytesIn.Add(uint64(n))
+		logrus.Debugf("sent %d data to %s", n, u.target)
+	}
+}
+
+// udpFlow returns the flow of the client, creating it if needed; it returns
+// nil if the port reached its flow limit, or the upstream socket could not
+// be created.
+func (p *PortProxy) udpFlow(u *udpPort, client *net.UDPAddr) *udpFlow {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	key := client.String()
+	if flow, ok := u.flows[key]; ok {
+		flow.touch()
+		return flow
+	}
+	if len(u.flows) >= p.config.UDPMaxFlows {
+		logrus.Warnf("dropping UDP packet from %s: port %d reached the limit of %d flows", client, u.port, p.config.UDPMaxFlows)
+		return nil
+	}
+	upstream, err := net.DialUDP("udp", nil, u.target)
+	if err != nil {
+		logrus.Errorf("failed to connect to target address: %s : %s", u.target, err)
+		return nil
+	}
+	flow := &udpFlow{client: client, upstream: upstream}
+	flow.touch()
+	u.flows[key] = flow
+	u.flowCount.Add(1)
+	p.wg.Add(1)
+	go p.relayUDPReplies(u, flow)
+	return flow
+}
+
+// relayUDPReplies sends the replies of the upstream server back to the
+// client, until the flow is idle for longer than the idle timeout.
+func (p *PortProxy) relayUDPReplies(u *udpPort, flow *udpFlow) {
+	defer p.wg.Done()
+	b := make([]byte, p.udpBufferSize())
+	for {
+		deadline := time.Unix(0, flow.lastActive.Load()).Add(p.config.UDPIdleTimeout)
+		if err := flow.upstream.SetReadDeadline(deadline); err != nil {
+			logrus.Errorf("error setting UDP flow deadline for %s: %s", flow.client, err)
+		}
+		n, err := flow.upstream.Read(b)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			if p.expireUDPFlow(u, flow) {
+				return
+			}
+			continue
+		}
+		if err != nil && n == 0 {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logrus.Errorf("error reading UDP packet from target: %s : %s", u.target, err)
+			continue
+		}
+		flow.touch()
+		n, err = u.conn.WriteToUDP(b[:n], flow.client)
+		if err != nil {
+			logrus.Errorf("error forwarding UDP packet to source: %s : %s", flow.client, err)
+			u.dropped.Add(1)
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		u.packetsOut.Add(1)
+		u.bytesOut.Add(uint64(n))
+	}
+}
+
+// expireUDPFlow removes the flow if it is still idle; packets from the
+// client may have arrived since the read deadline was set.
+func (p *PortProxy) expireUDPFlow(u *udpPort, flow *udpFlow) bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if time.Since(time.Unix(0, flow.lastActive.Load())) < p.config.UDPIdleTimeout {
+		return false
+	}
+	delete(u.flows, flow.client.String())
+	_ = flow.upstream.Close()
+	u.expired.Add(1)
+	logrus.Debugf("UDP flow from %s to %s expired", flow.client, u.target)
+	return true
+}
+
+func (p *PortProxy) closeUDPFlows(u *udpPort) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	for key, flow := range u.flows {
+		_ = flow.upstream.Close()
+		delete(u.flows, key)
+	}
+}
+
+func (f *udpFlow) touch() {
+	f.lastActive.Store(time.Now().UnixNano())
+}
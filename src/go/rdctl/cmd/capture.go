@@ -0,0 +1,186 @@
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/shell"
+)
+
+// captureEndpoint is the packet capture control endpoint of the vm-switch,
+// within the network namespace of the VM.
+const captureEndpoint = "http://127.0.0.1:6670"
+
+var captureSettings struct {
+	Filter   string
+	MaxSize  string
+	MaxFiles int
+}
+
+var captureCmd = &cobra.Command{
+	Use:   "capture",
+	Short: "Capture the network traffic of the VM",
+	Long: `Capture the network traffic between the VM and the host on demand, without
+restarting networking. Captures are only available on Windows, with the
+network tunnel.`,
+}
+
+var captureStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start a packet capture",
+	Long: `Start a packet capture, replacing the previous one. The capture is written to
+rotating files in the VM; only the most recent files are kept.
+
+The filter supports a subset of the pcap-filter syntax: primitives joined by
+"and", each optionally preceded by "not". The primitives are the protocols
+(ip, ip6, arp, icmp, icmp6, tcp, udp), and [src|dst] host <ip>,
+[src|dst] net <cidr> and [src|dst] port <port>. For example:
+
+> rdctl capture start --filter "tcp and port 443 and host 10.0.0.1"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		form := url.Values{"filter": {captureSettings.Filter}}
+		if captureSettings.MaxSize != "" {
+			form.Set("max-size", captureSettings.MaxSize)
+		}
+		if captureSettings.MaxFiles != 0 {
+			form.Set("max-files", strconv.Itoa(captureSettings.MaxFiles))
+		}
+		return printCaptureStatus(cmd.Context(), "/capture/start", form)
+	},
+}
+
+var captureStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the running packet capture",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return printCaptureStatus(cmd.Context(), "/capture/stop", url.Values{})
+	},
+}
+
+var captureStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of the current, or last, packet capture",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return printCaptureStatus(cmd.Context(), "/capture/status", nil)
+	},
+}
+
+var captureFetchCmd = &cobra.Command{
+	Use:   "fetch <file>",
+	Short: "Save the current, or last, packet capture to a pcap file",
+	Long: `Save the current, or last, packet capture to a pcap file (or - for stdout),
+which can be opened with Wireshark or tcpdump. A running capture is not
+stopped.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return fetchCapture(cmd.Context(), args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(captureCmd)
+	captureCmd.AddCommand(captureStartCmd, captureStopCmd, captureStatusCmd, captureFetchCmd)
+	captureStartCmd.Flags().StringVar(&captureSettings.Filter, "filter", "", "only capture the packets matching the `expression`")
+	captureStartCmd.Flags().StringVar(&captureSettings.MaxSize, "max-size", "", "rotate capture files once they reach the given `size`, e.g. 16MB (default 16MiB)")
+	captureStartCmd.Flags().IntVar(&captureSettings.MaxFiles, "max-files", 0, "number of capture files to keep (default 4)")
+}
+
+// captureRequest sends a request to the packet capture control endpoint
+// from within the VM, writing the response body to output. A nil form
+// sends a GET request; otherwise, the form is posted.
+func captureRequest(ctx context.Context, path string, form url.Values, output io.Writer) error {
+	if runtime.GOOS != "windows" {
+		return errors.New("packet captures are only supported on Windows")
+	}
+	args := []string{"wget", "-q", "-O", "-"}
+	if form != nil {
+		args = append(args, "--post-data", form.Encode())
+	}
+	args = append(args, captureEndpoint+path)
+	wgetCmd, err := shell.SpawnCommand(ctx, args...)
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	wgetCmd.Stdout = output
+	wgetCmd.Stderr = &stderr
+	if err := wgetCmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		switch {
+		case strings.Contains(message, "400"):
+			message = "invalid capture options: " + message
+		case strings.Contains(message, "404"):
+			message = "no packet capture is available: " + message
+		case strings.Contains(message, "409"):
+			message = "a packet capture is already running, or none is running: " + message
+		}
+		return fmt.Errorf("packet capture request failed: %w: %s", err, message)
+	}
+	return nil
+}
+
+func printCaptureStatus(ctx context.Context, path string, form url.Values) error {
+	var body bytes.Buffer
+	if err := captureRequest(ctx, path, form, &body); err != nil {
+		return err
+	}
+	var status bytes.Buffer
+	if err := json.Indent(&status, body.Bytes(), "", "  "); err != nil {
+		return fmt.Errorf("failed to parse packet capture status: %w", err)
+	}
+	_, err := status.WriteTo(os.Stdout)
+	return err
+}
+
+func fetchCapture(ctx context.Context, fileName string) error {
+	if fileName == "-" {
+		return captureRequest(ctx, "/capture/pcap", nil, os.Stdout)
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to create capture file: %w", err)
+	}
+	err = captureRequest(ctx, "/capture/pcap", nil, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Don't leave partial captures behind.
+		_ = os.Remove(fileName)
+	}
+	return err
+}
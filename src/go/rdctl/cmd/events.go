@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+)
+
+var eventTypes = []string{client.EventBackendState, client.EventLock, client.EventSettings, client.EventExtension}
+
+var eventsSettings struct {
+	Filters []string
+	Until   string
+	Timeout time.Duration
+}
+
+// errEventsUntilReached is returned by the event handler to stop streaming
+// once the backend state given by --until has been reached.
+var errEventsUntilReached = errors.New("backend state reached")
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Stream backend state and progress changes",
+	Long: fmt.Sprintf(`Print changes to the backend state, the backend lock, the settings, and the
+installed extensions as they happen, one JSON object per line. The current
+backend state is always printed first.
+
+Events can be limited to some types with --filter type=<type>, which may be
+given multiple times; the types are %s.
+
+With --until, rdctl exits successfully once the backend reaches the given
+state (e.g. STARTED), waiting for Rancher Desktop to start if needed. With
+--timeout, rdctl fails if that takes longer than the given duration.`, strings.Join(eventTypes, ", ")),
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		types, err := parseEventFilters(eventsSettings.Filters)
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		return streamEvents(ctx, types)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+	eventsCmd.Flags().StringArrayVar(&eventsSettings.Filters, "filter", nil, "only print events matching `type=<type>`")
+	eventsCmd.Flags().StringVar(&eventsSettings.Until, "until", "", "exit once the backend reaches the given `state`")
+	eventsCmd.Flags().DurationVar(&eventsSettings.Timeout, "timeout", 0, "fail if no matching state is reached within the given `duration`")
+}
+
+// parseEventFilters returns the event types to print, or nil to print all of
+// them.
+func parseEventFilters(filters []string) ([]string, error) {
+	var types []string
+	for _, filter := range filters {
+		key, value, _ := strings.Cut(filter, "=")
+		if key != "type" {
+			return nil, fmt.Errorf("invalid filter %q: only type=<type> is supported", filter)
+		}
+		if !slices.Contains(eventTypes, value) {
+			return nil, fmt.Errorf("invalid event type %q: must be one of %s", value, strings.Join(eventTypes, ", "))
+		}
+		types = append(types, value)
+	}
+	return types, nil
+}
+
+func streamEvents(ctx context.Context, types []string) error {
+	if eventsSettings.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, eventsSettings.Timeout)
+		defer cancel()
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	handler := func(event client.Event) error {
+		if types == nil || slices.Contains(types, event.Type) {
+			if err := encoder.Encode(event); err != nil {
+				return err
+			}
+		}
+		if eventsSettings.Until != "" && event.Type == client.EventBackendState && event.VMState == eventsSettings.Until {
+			return errEventsUntilReached
+		}
+		return nil
+	}
+
+	for {
+		err := watchEvents(ctx, handler)
+		if errors.Is(err, errEventsUntilReached) {
+			return nil
+		}
+		// The error may be unrelated to the timeout (e.g. Rancher Desktop was
+		// not started at all), so check the context itself.
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timed out after %s", eventsSettings.Timeout)
+		}
+		if ctx.Err() != nil {
+			// Interrupted by the user.
+			return nil
+		}
+		if eventsSettings.Until == "" {
+			// Without --until, stop once Rancher Desktop closes the stream.
+			return err
+		}
+		// Wait for Rancher Desktop to (re)start.
+		if err != nil && !errors.Is(err, errEventsNotReady) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			continue
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// errEventsNotReady is returned by watchEvents when Rancher Desktop is not
+// running, or is still starting up.
+var errEventsNotReady = errors.New("Rancher Desktop is not running")
+
+// watchEvents connects to Rancher Desktop and passes the events it sends to
+// handler. The connection info is read again on every call, as the password
+// changes whenever Rancher Desktop is restarted.
+func watchEvents(ctx context.Context, handler func(client.Event) error) error {
+	connectionInfo, err := config.GetConnectionInfo(true)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %w", errEventsNotReady, err)
+	} else if err != nil {
+		return fmt.Errorf("failed to get connection info: %w", err)
+	} else if connectionInfo == nil {
+		return fmt.Errorf("%w: connection info not found", errEventsNotReady)
+	}
+	apiClient := client.NewClient(client.NewRDClient(connectionInfo))
+	err = apiClient.Events(ctx, handler)
+	// An outdated password is rejected until the connection info written by
+	// the new instance is read.
+	if errors.Is(err, client.ErrConnectionRefused) || errors.Is(err, client.ErrUnauthorized) {
+		return fmt.Errorf("%w: %w", errEventsNotReady, err)
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+)
+
+func TestWatchEventsNotReady(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "rd-engine.json")
+	savedConfigPath := config.DefaultConfigPath
+	config.DefaultConfigPath = configPath
+	t.Cleanup(func() {
+		config.DefaultConfigPath = savedConfigPath
+	})
+	handler := func(client.Event) error { return nil }
+
+	// Rancher Desktop has not written its connection info yet.
+	err := watchEvents(context.Background(), handler)
+	assert.ErrorIs(t, err, errEventsNotReady)
+
+	// The connection info is from an earlier instance of Rancher Desktop.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(serverURL.Port())
+	require.NoError(t, err)
+	contents, err := json.Marshal(config.ConnectionInfo{User: "user", Password: "outdated", Host: serverURL.Hostname(), Port: port})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(configPath, contents, 0o600))
+	err = watchEvents(context.Background(), handler)
+	assert.ErrorIs(t, err, errEventsNotReady)
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+}
+
+func TestStreamEventsTimeout(t *testing.T) {
+	// Rancher Desktop was never started, so there is no connection info.
+	savedConfigPath := config.DefaultConfigPath
+	config.DefaultConfigPath = filepath.Join(t.TempDir(), "rd-engine.json")
+	savedSettings := eventsSettings
+	eventsSettings.Until = "STARTED"
+	eventsSettings.Timeout = 100 * time.Millisecond
+	t.Cleanup(func() {
+		config.DefaultConfigPath = savedConfigPath
+		eventsSettings = savedSettings
+	})
+
+	err := streamEvents(context.Background(), nil)
+	assert.ErrorContains(t, err, "timed out")
+}
@@ -20,6 +20,7 @@ import (
 
 var snapshotDescription string
 var snapshotDescriptionFrom string
+var snapshotLabels []string
 
 var snapshotCreateCmd = &cobra.Command{
 	Use:   "create <name>",
@@ -52,6 +53,7 @@ func init() {
 	snapshotCreateCmd.Flags().BoolVar(&outputJSONFormat, "json", false, "output json format")
 	snapshotCreateCmd.Flags().StringVar(&snapshotDescription, "description", "", "snapshot description")
 	snapshotCreateCmd.Flags().StringVar(&snapshotDescriptionFrom, "description-from", "", "snapshot description from a file (or - for stdin)")
+	snapshotCreateCmd.Flags().StringArrayVarP(&snapshotLabels, "label", "l", nil, "snapshot label, as key=value (can be specified multiple times)")
 }
 
 func createSnapshot(ctx context.Context, args []string) error {
@@ -60,10 +62,16 @@ func createSnapshot(ctx context.Context, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create snapshot manager: %w", err)
 	}
-	// Report on invalid names before locking and shutting down the backend
+	// Report on invalid names and labels before locking and shutting down the backend
 	if err := manager.ValidateName(name); err != nil {
 		return err
 	}
+	labels, remove, err := snapshot.ParseLabels(snapshotLabels)
+	if err != nil {
+		return err
+	} else if len(remove) > 0 {
+		return fmt.Errorf("invalid label %q: must be of the form key=value", remove[0]+"-")
+	}
 
 	// Ideally we would not use the deprecated syscall package,
 	// but it works well with all expected scenarios and allows us
@@ -76,7 +84,7 @@ func createSnapshot(ctx context.Context, args []string) error {
 		}
 	})
 	defer stopAfterFunc()
-	_, err = manager.Create(notifyCtx, name, snapshotDescription)
+	_, err = manager.Create(notifyCtx, name, snapshotDescription, labels)
 	if err != nil && !errors.Is(err, runner.ErrContextDone) {
 		return fmt.Errorf("failed to create snapshot: %w", err)
 	}
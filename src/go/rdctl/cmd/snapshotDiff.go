@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
+)
+
+var snapshotDiffCurrent bool
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff <name> [<other name>|--current]",
+	Short: "Show the differences between two snapshots",
+	Long: `Compare the settings, the Lima configuration and the disk sizes of a
+snapshot with those of another snapshot, or with the current state of
+Rancher Desktop.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if snapshotDiffCurrent == (len(args) == 2) {
+			return errors.New("exactly one of a second snapshot name or --current must be specified")
+		}
+		cmd.SilenceUsage = true
+		return exitWithJSONOrErrorCondition(diffSnapshots(args))
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+	snapshotDiffCmd.Flags().BoolVar(&outputJSONFormat, "json", false, "output json format")
+	snapshotDiffCmd.Flags().BoolVar(&snapshotDiffCurrent, "current", false, "compare with the current state")
+}
+
+func diffSnapshots(args []string) error {
+	manager, err := snapshot.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot manager: %w", err)
+	}
+	from, err := manager.Snapshot(args[0])
+	if err != nil {
+		return err
+	}
+	var to *snapshot.Snapshot
+	if len(args) > 1 {
+		other, err := manager.Snapshot(args[1])
+		if err != nil {
+			return err
+		}
+		to = &other
+	}
+	diff, err := manager.Diff(&from, to)
+	if err != nil {
+		return fmt.Errorf("failed to compare snapshots: %w", err)
+	}
+	return outputDiff(os.Stdout, diff)
+}
+
+// outputDiff writes diff in the format selected by --json.
+func outputDiff(w io.Writer, diff snapshot.Diff) error {
+	if outputJSONFormat {
+		jsonBuffer, err := json.Marshal(diff)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(jsonBuffer))
+		return err
+	}
+	describe := func(name string) string {
+		if name == "" {
+			return "current state"
+		}
+		return fmt.Sprintf("snapshot %q", name)
+	}
+	fmt.Fprintf(w, "Comparing %s with %s.\n", describe(diff.From), describe(diff.To))
+	if !diff.HasChanges() {
+		fmt.Fprintln(w, "No differences found.")
+		return nil
+	}
+	for _, file := range diff.Files {
+		switch {
+		case !file.OldPresent:
+			fmt.Fprintf(w, "\n%s: added\n", file.Name)
+		case !file.NewPresent:
+			fmt.Fprintf(w, "\n%s: removed\n", file.Name)
+		case len(file.Changes) == 0:
+			fmt.Fprintf(w, "\n%s: unchanged\n", file.Name)
+			continue
+		default:
+			fmt.Fprintf(w, "\n%s:\n", file.Name)
+		}
+		for _, change := range file.Changes {
+			switch change.Kind {
+			case snapshot.ChangeAdded:
+				fmt.Fprintf(w, "  + %s: %s\n", change.Path, formatDiffValue(change.New))
+			case snapshot.ChangeRemoved:
+				fmt.Fprintf(w, "  - %s: %s\n", change.Path, formatDiffValue(change.Old))
+			case snapshot.ChangeChanged:
+				fmt.Fprintf(w, "  ~ %s: %s -> %s\n", change.Path, formatDiffValue(change.Old), formatDiffValue(change.New))
+			}
+		}
+	}
+	if len(diff.Disks) > 0 {
+		fmt.Fprintln(w, "\nDisks:")
+	}
+	for _, disk := range diff.Disks {
+		formatSize := func(size *int64) string {
+			if size == nil {
+				return "unknown"
+			}
+			return units.BytesSize(float64(*size))
+		}
+		line := fmt.Sprintf("  %s: %s -> %s", disk.Name, formatSize(disk.OldSize), formatSize(disk.NewSize))
+		if delta, ok := disk.Delta(); ok {
+			sign := "+"
+			if delta < 0 {
+				sign, delta = "-", -delta
+			}
+			line += fmt.Sprintf(" (%s%s)", sign, units.BytesSize(float64(delta)))
+		}
+		fmt.Fprintln(w, line)
+	}
+	return nil
+}
+
+// formatDiffValue formats a settings value compactly, as JSON.
+func formatDiffValue(value any) string {
+	jsonBuffer, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(jsonBuffer)
+}
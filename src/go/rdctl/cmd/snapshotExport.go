@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/runner"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
+)
+
+var snapshotExportCmd = &cobra.Command{
+	Use:   "export <name> <file>",
+	Short: "Export a snapshot to an archive file",
+	Long: `Export a snapshot to a single compressed archive file (or - for stdout).
+The archive can be imported on another machine with "rdctl snapshot import".`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return exitWithJSONOrErrorCondition(exportSnapshot(cmd.Context(), args[0], args[1]))
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotExportCmd)
+	snapshotExportCmd.Flags().BoolVar(&outputJSONFormat, "json", false, "output json format")
+}
+
+func exportSnapshot(ctx context.Context, name, fileName string) error {
+	manager, err := snapshot.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot manager: %w", err)
+	}
+
+	var writer io.Writer = os.Stdout
+	exported := false
+	if fileName != "-" {
+		file, err := os.Create(fileName)
+		if err != nil {
+			return fmt.Errorf("failed to create archive file: %w", err)
+		}
+		defer func() {
+			// Don't leave partial archives behind.
+			if err := file.Close(); err != nil || !exported {
+				_ = os.Remove(fileName)
+			}
+		}()
+		writer = file
+	}
+
+	// Ideally we would not use the deprecated syscall package,
+	// but it works well with all expected scenarios and allows us
+	// to avoid platform-specific signal handling code.
+	notifyCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGHUP, syscall.SIGTERM)
+	defer stop()
+	stopAfterFunc := context.AfterFunc(notifyCtx, func() {
+		if !outputJSONFormat {
+			fmt.Fprintln(os.Stderr, "Cancelling snapshot export...")
+		}
+	})
+	defer stopAfterFunc()
+	err = manager.Export(notifyCtx, name, writer)
+	if err != nil && !errors.Is(err, runner.ErrContextDone) {
+		return fmt.Errorf("failed to export snapshot %q: %w", name, err)
+	}
+	exported = err == nil
+	return nil
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/runner"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
+)
+
+var snapshotImportName string
+
+var snapshotImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a snapshot from an archive file",
+	Long: `Import a snapshot from an archive file (or - for stdin) created by
+"rdctl snapshot export". The snapshot keeps its name unless --name is given;
+importing fails if a snapshot with that name already exists.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return exitWithJSONOrErrorCondition(importSnapshot(cmd.Context(), args[0]))
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotImportCmd)
+	snapshotImportCmd.Flags().BoolVar(&outputJSONFormat, "json", false, "output json format")
+	snapshotImportCmd.Flags().StringVar(&snapshotImportName, "name", "", "name of the imported snapshot (defaults to the name in the archive)")
+}
+
+func importSnapshot(ctx context.Context, fileName string) error {
+	manager, err := snapshot.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot manager: %w", err)
+	}
+	// Report on invalid names before reading the archive
+	if snapshotImportName != "" {
+		if err := manager.ValidateName(snapshotImportName); err != nil {
+			return err
+		}
+	}
+
+	var reader io.Reader = os.Stdin
+	if fileName != "-" {
+		file, err := os.Open(fileName)
+		if err != nil {
+			return fmt.Errorf("failed to open archive file: %w", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	// Ideally we would not use the deprecated syscall package,
+	// but it works well with all expected scenarios and allows us
+	// to avoid platform-specific signal handling code.
+	notifyCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGHUP, syscall.SIGTERM)
+	defer stop()
+	stopAfterFunc := context.AfterFunc(notifyCtx, func() {
+		if !outputJSONFormat {
+			fmt.Fprintln(os.Stderr, "Cancelling snapshot import...")
+		}
+	})
+	defer stopAfterFunc()
+	imported, err := manager.Import(notifyCtx, reader, snapshotImportName)
+	if errors.Is(err, runner.ErrContextDone) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to import snapshot: %w", err)
+	}
+	if !outputJSONFormat {
+		fmt.Printf("Imported snapshot %q\n", imported.Name)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
+)
+
+var snapshotLabelCmd = &cobra.Command{
+	Use:   "label <name> <key>=<value>|<key>-...",
+	Short: "Set or remove snapshot labels",
+	Long: `Set or remove labels on an existing snapshot. Each argument after the
+snapshot name either sets a label (key=value) or removes one (key-).`,
+	Example: `  rdctl snapshot label my-snapshot ticket=ABC-123 k8s-`,
+	Args:    cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return exitWithJSONOrErrorCondition(labelSnapshot(cmd.Context(), args[0], args[1:]))
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotLabelCmd)
+	snapshotLabelCmd.Flags().BoolVar(&outputJSONFormat, "json", false, "output json format")
+}
+
+func labelSnapshot(ctx context.Context, name string, args []string) error {
+	set, remove, err := snapshot.ParseLabels(args)
+	if err != nil {
+		return err
+	}
+	manager, err := snapshot.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot manager: %w", err)
+	}
+	if _, err := manager.SetLabels(ctx, name, set, remove); err != nil {
+		return fmt.Errorf("failed to label snapshot %q: %w", name, err)
+	}
+	return nil
+}
@@ -2,13 +2,18 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"text/template"
 	"time"
 
+	"github.com/docker/go-units"
 	"github.com/spf13/cobra"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
@@ -34,12 +39,38 @@ func (snapshots SortableSnapshots) Swap(i, j int) {
 	snapshots[i], snapshots[j] = snapshots[j], snapshots[i]
 }
 
+// snapshotListEntry is the data available to templates given via
+// `rdctl snapshot list --format`.
+type snapshotListEntry struct {
+	Name        string
+	Created     time.Time
+	Description string
+	Labels      map[string]string
+	// The number of bytes the snapshot uses on disk; this is only computed
+	// when sorting by size or when the template refers to it, as it requires
+	// walking the snapshot files.
+	DiskUsage int64
+
+	snapshot snapshot.Snapshot
+}
+
+var snapshotListFlags struct {
+	selector string
+	sortBy   string
+	format   string
+}
+
 var snapshotListCmd = &cobra.Command{
 	Use:     "list",
 	Aliases: []string{"ls"},
 	Short:   "List snapshots",
-	Args:    cobra.NoArgs,
+	Example: `  rdctl snapshot list --selector engine=moby,ticket --sort-by size
+  rdctl snapshot list --format '{{.Name}}: {{index .Labels "k8s"}} ({{size .DiskUsage}})'`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if outputJSONFormat && snapshotListFlags.format != "" {
+			return errors.New(`can't specify more than one option from "--json" and "--format"`)
+		}
 		cmd.SilenceUsage = true
 		return exitWithJSONOrErrorCondition(listSnapshot())
 	},
@@ -48,9 +79,32 @@ var snapshotListCmd = &cobra.Command{
 func init() {
 	snapshotCmd.AddCommand(snapshotListCmd)
 	snapshotListCmd.Flags().BoolVar(&outputJSONFormat, "json", false, "output json format")
+	snapshotListCmd.Flags().StringVarP(&snapshotListFlags.selector, "selector", "l", "", "only list snapshots matching the label selector (e.g. key=value,key!=value,key,!key)")
+	snapshotListCmd.Flags().StringVar(&snapshotListFlags.sortBy, "sort-by", "created", "sort snapshots by one of: created, name, size (which also shows the size column)")
+	snapshotListCmd.Flags().StringVar(&snapshotListFlags.format, "format", "", "format each snapshot using a Go template")
 }
 
 func listSnapshot() error {
+	selector, err := snapshot.ParseSelector(snapshotListFlags.selector)
+	if err != nil {
+		return err
+	}
+	var tmpl *template.Template
+	if snapshotListFlags.format != "" {
+		funcs := template.FuncMap{
+			"json": func(v any) (string, error) {
+				jsonBuffer, err := json.Marshal(v)
+				return string(jsonBuffer), err
+			},
+			"size": func(v int64) string {
+				return units.BytesSize(float64(v))
+			},
+		}
+		tmpl, err = template.New("format").Funcs(funcs).Parse(snapshotListFlags.format)
+		if err != nil {
+			return fmt.Errorf("invalid format: %w", err)
+		}
+	}
 	manager, err := snapshot.NewManager()
 	if err != nil {
 		return fmt.Errorf("failed to create snapshot manager: %w", err)
@@ -59,16 +113,68 @@ func listSnapshot() error {
 	if err != nil {
 		return fmt.Errorf("failed to list snapshots: %w", err)
 	}
-	sort.Sort(SortableSnapshots(snapshots))
+	snapshots = slices.DeleteFunc(snapshots, func(aSnapshot snapshot.Snapshot) bool {
+		return !selector.Matches(aSnapshot)
+	})
+	withUsage := snapshotListFlags.sortBy == "size" ||
+		(tmpl != nil && strings.Contains(snapshotListFlags.format, ".DiskUsage"))
+	entries := make([]snapshotListEntry, 0, len(snapshots))
+	for _, aSnapshot := range snapshots {
+		var usage int64
+		if withUsage {
+			usage, err = manager.DiskUsage(aSnapshot)
+			if err != nil {
+				return err
+			}
+		}
+		entries = append(entries, snapshotListEntry{
+			Name:        aSnapshot.Name,
+			Created:     aSnapshot.Created,
+			Description: aSnapshot.Description,
+			Labels:      aSnapshot.Labels,
+			DiskUsage:   usage,
+			snapshot:    aSnapshot,
+		})
+	}
+	if err := sortSnapshots(entries, snapshotListFlags.sortBy); err != nil {
+		return err
+	}
 	if outputJSONFormat {
+		snapshots = snapshots[:0]
+		for _, entry := range entries {
+			snapshots = append(snapshots, entry.snapshot)
+		}
 		return jsonOutput(snapshots)
 	}
-	return tabularOutput(snapshots)
+	if tmpl != nil {
+		return templateOutput(tmpl, entries)
+	}
+	return tabularOutput(entries, withUsage)
+}
+
+// sortSnapshots sorts the entries by the given key.
+func sortSnapshots(entries []snapshotListEntry, sortBy string) error {
+	var less func(a, b snapshotListEntry) bool
+	switch sortBy {
+	case "created":
+		less = func(a, b snapshotListEntry) bool { return a.Created.Before(b.Created) }
+	case "name":
+		less = func(a, b snapshotListEntry) bool { return a.Name < b.Name }
+	case "size":
+		less = func(a, b snapshotListEntry) bool { return a.DiskUsage < b.DiskUsage }
+	default:
+		return fmt.Errorf("invalid sort key %q: must be one of created, name, size", sortBy)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return less(entries[i], entries[j])
+	})
+	return nil
 }
 
 func jsonOutput(snapshots []snapshot.Snapshot) error {
 	for _, aSnapshot := range snapshots {
 		aSnapshot.ID = ""
+		aSnapshot.Checksums = nil
 		jsonBuffer, err := json.Marshal(aSnapshot)
 		if err != nil {
 			return err
@@ -78,22 +184,54 @@ func jsonOutput(snapshots []snapshot.Snapshot) error {
 	return nil
 }
 
-func tabularOutput(snapshots []snapshot.Snapshot) error {
-	if len(snapshots) == 0 {
+func templateOutput(tmpl *template.Template, entries []snapshotListEntry) error {
+	for _, entry := range entries {
+		if err := tmpl.Execute(os.Stdout, entry); err != nil {
+			return fmt.Errorf("failed to format snapshot %q: %w", entry.Name, err)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// tabularOutput prints the entries as a table; the size column is only
+// included if withUsage is set, as the usage is otherwise not computed.
+func tabularOutput(entries []snapshotListEntry, withUsage bool) error {
+	if len(entries) == 0 {
 		fmt.Fprintln(os.Stderr, "No snapshots present.")
 		return nil
 	}
 	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
-	fmt.Fprintf(writer, "NAME\tCREATED\tDESCRIPTION\n")
-	for _, aSnapshot := range snapshots {
-		prettyCreated := aSnapshot.Created.Format(time.RFC1123)
-		desc := truncateAtNewlineOrMaxRunes(aSnapshot.Description, tableMaxRunes)
-		fmt.Fprintf(writer, "%s\t%s\t%s\n", aSnapshot.Name, prettyCreated, desc)
+	if withUsage {
+		fmt.Fprintf(writer, "NAME\tCREATED\tSIZE\tLABELS\tDESCRIPTION\n")
+	} else {
+		fmt.Fprintf(writer, "NAME\tCREATED\tLABELS\tDESCRIPTION\n")
+	}
+	for _, entry := range entries {
+		prettyCreated := entry.Created.Format(time.RFC1123)
+		labels := truncateAtNewlineOrMaxRunes(formatLabels(entry.Labels), tableMaxRunes)
+		desc := truncateAtNewlineOrMaxRunes(entry.Description, tableMaxRunes)
+		if withUsage {
+			size := units.BytesSize(float64(entry.DiskUsage))
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", entry.Name, prettyCreated, size, labels, desc)
+		} else {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", entry.Name, prettyCreated, labels, desc)
+		}
 	}
 	writer.Flush()
 	return nil
 }
 
+// formatLabels formats labels as a comma-separated list of key=value pairs,
+// sorted by key.
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
 // Truncates a string to either the first newline or a maximum number of
 // runes. Also removes leading and trailing whitespace.
 func truncateAtNewlineOrMaxRunes(input string, maxRunes int) string {
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
+)
+
+var snapshotPruneFlags struct {
+	keepLast     int
+	keepDaily    int
+	keepWeekly   int
+	maxTotalSize string
+	olderThan    string
+	dryRun       bool
+	save         bool
+}
+
+var snapshotPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove snapshots according to a retention policy",
+	Long: `Remove snapshots according to a retention policy.
+
+Snapshots selected by --keep-last, --keep-daily or --keep-weekly are always
+kept. If --older-than or --max-total-size are given, the remaining snapshots
+that are older than the given age are removed, followed by the oldest ones
+until the snapshots use less than the given amount of disk space. Otherwise,
+all snapshots that are not kept are removed.
+
+The policy saved with --save is used for any rule not given on the command
+line, so that pruning can run unattended.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return exitWithJSONOrErrorCondition(pruneSnapshots(cmd))
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotPruneCmd)
+	flags := snapshotPruneCmd.Flags()
+	flags.BoolVar(&outputJSONFormat, "json", false, "output json format")
+	flags.IntVar(&snapshotPruneFlags.keepLast, "keep-last", 0, "keep the given number of most recent snapshots")
+	flags.IntVar(&snapshotPruneFlags.keepDaily, "keep-daily", 0, "keep the most recent snapshot of each of the given number of days")
+	flags.IntVar(&snapshotPruneFlags.keepWeekly, "keep-weekly", 0, "keep the most recent snapshot of each of the given number of weeks")
+	flags.StringVar(&snapshotPruneFlags.maxTotalSize, "max-total-size", "", "remove the oldest snapshots until all of them use at most this much disk space (e.g. 200GiB)")
+	flags.StringVar(&snapshotPruneFlags.olderThan, "older-than", "", "remove snapshots older than the given age (e.g. 72h, 30d, 2w)")
+	flags.BoolVar(&snapshotPruneFlags.dryRun, "dry-run", false, "only report which snapshots would be removed")
+	flags.BoolVar(&snapshotPruneFlags.save, "save", false, "save the resulting policy for later runs")
+}
+
+// retentionPolicyFromFlags returns the given policy, with any rules given on
+// the command line overriding the existing ones.
+func retentionPolicyFromFlags(cmd *cobra.Command, policy snapshot.RetentionPolicy) (snapshot.RetentionPolicy, error) {
+	flags := cmd.Flags()
+	if flags.Changed("keep-last") {
+		policy.KeepLast = snapshotPruneFlags.keepLast
+	}
+	if flags.Changed("keep-daily") {
+		policy.KeepDaily = snapshotPruneFlags.keepDaily
+	}
+	if flags.Changed("keep-weekly") {
+		policy.KeepWeekly = snapshotPruneFlags.keepWeekly
+	}
+	if flags.Changed("max-total-size") {
+		policy.MaxTotalSize = 0
+		if snapshotPruneFlags.maxTotalSize != "" {
+			size, err := units.RAMInBytes(snapshotPruneFlags.maxTotalSize)
+			if err != nil {
+				return policy, fmt.Errorf("invalid value for --max-total-size: %w", err)
+			}
+			policy.MaxTotalSize = size
+		}
+	}
+	if flags.Changed("older-than") {
+		policy.OlderThan = 0
+		if snapshotPruneFlags.olderThan != "" {
+			duration, err := snapshot.ParseDuration(snapshotPruneFlags.olderThan)
+			if err != nil {
+				return policy, fmt.Errorf("invalid value for --older-than: %w", err)
+			}
+			policy.OlderThan = duration
+		}
+	}
+	return policy, nil
+}
+
+func pruneSnapshots(cmd *cobra.Command) error {
+	manager, err := snapshot.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot manager: %w", err)
+	}
+	policy, err := manager.RetentionPolicy()
+	if err != nil {
+		return err
+	}
+	if policy, err = retentionPolicyFromFlags(cmd, policy); err != nil {
+		return err
+	}
+	if snapshotPruneFlags.save {
+		if err := manager.SaveRetentionPolicy(policy); err != nil {
+			return err
+		}
+	}
+	result, err := manager.Prune(policy, time.Now(), snapshotPruneFlags.dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to prune snapshots: %w", err)
+	}
+	if outputJSONFormat {
+		return jsonPruneOutput(result)
+	}
+	return tabularPruneOutput(result)
+}
+
+type prunedSnapshot struct {
+	Name      string    `json:"name"`
+	Created   time.Time `json:"created"`
+	DiskUsage int64     `json:"diskUsage"`
+}
+
+func jsonPruneOutput(result snapshot.PruneResult) error {
+	output := struct {
+		DryRun    bool             `json:"dryRun"`
+		Removed   []prunedSnapshot `json:"removed"`
+		Reclaimed int64            `json:"reclaimed"`
+	}{
+		DryRun:    result.DryRun,
+		Removed:   make([]prunedSnapshot, 0, len(result.Removed)),
+		Reclaimed: result.Reclaimed,
+	}
+	for _, candidate := range result.Removed {
+		output.Removed = append(output.Removed, prunedSnapshot{
+			Name:      candidate.Snapshot.Name,
+			Created:   candidate.Snapshot.Created,
+			DiskUsage: candidate.DiskUsage,
+		})
+	}
+	jsonBuffer, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(jsonBuffer))
+	return nil
+}
+
+func tabularPruneOutput(result snapshot.PruneResult) error {
+	if len(result.Removed) == 0 {
+		fmt.Fprintln(os.Stderr, "No snapshots to remove.")
+		return nil
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+	fmt.Fprintf(writer, "NAME\tCREATED\tSIZE\n")
+	for _, candidate := range result.Removed {
+		prettyCreated := candidate.Snapshot.Created.Format(time.RFC1123)
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", candidate.Snapshot.Name, prettyCreated, units.BytesSize(float64(candidate.DiskUsage)))
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if result.DryRun {
+		fmt.Printf("Would remove %d snapshots, reclaiming %s.\n", len(result.Removed), units.BytesSize(float64(result.Reclaimed)))
+	} else {
+		fmt.Printf("Removed %d snapshots, reclaiming %s.\n", len(result.Removed), units.BytesSize(float64(result.Reclaimed)))
+	}
+	return nil
+}
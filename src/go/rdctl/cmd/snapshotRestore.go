@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"os/signal"
 	"syscall"
 
@@ -13,6 +14,9 @@ import (
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
 )
 
+var snapshotRestoreForce bool
+var snapshotRestoreDryRun bool
+
 var snapshotRestoreCmd = &cobra.Command{
 	Use:   "restore <id>",
 	Short: "Restore a snapshot",
@@ -26,6 +30,8 @@ var snapshotRestoreCmd = &cobra.Command{
 func init() {
 	snapshotCmd.AddCommand(snapshotRestoreCmd)
 	snapshotRestoreCmd.Flags().BoolVarP(&outputJSONFormat, "json", "", false, "output json format")
+	snapshotRestoreCmd.Flags().BoolVar(&snapshotRestoreForce, "force", false, "restore even if the snapshot fails verification")
+	snapshotRestoreCmd.Flags().BoolVar(&snapshotRestoreDryRun, "dry-run", false, "show what restoring the snapshot would change, without restoring it")
 }
 
 func restoreSnapshot(name string) error {
@@ -33,6 +39,13 @@ func restoreSnapshot(name string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create snapshot manager: %w", err)
 	}
+	if snapshotRestoreDryRun {
+		diff, err := manager.PreviewRestore(name)
+		if err != nil {
+			return fmt.Errorf("failed to preview restoring snapshot %q: %w", name, err)
+		}
+		return outputDiff(os.Stdout, diff)
+	}
 
 	// Ideally we would not use the deprecated syscall package,
 	// but it works well with all expected scenarios and allows us
@@ -45,7 +58,7 @@ func restoreSnapshot(name string) error {
 		}
 	})
 	defer stopAfterFunc()
-	err = manager.Restore(ctx, name)
+	err = manager.Restore(ctx, name, snapshotRestoreForce)
 	if err != nil && !errors.Is(err, runner.ErrContextDone) {
 		return fmt.Errorf("failed to restore snapshot %q: %w", name, err)
 	}
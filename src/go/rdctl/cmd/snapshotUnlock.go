@@ -2,13 +2,19 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/lock"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
 )
 
+var snapshotUnlockForce bool
+
 var snapshotUnlockCmd = &cobra.Command{
 	Use:   "unlock",
 	Short: "Remove snapshot lock",
@@ -16,7 +22,11 @@ var snapshotUnlockCmd = &cobra.Command{
 lock that is used to prevent simultaneous snapshot operations can be
 left behind. It then becomes impossible to run any snapshot operations.
 This command removes the filesystem lock. It should not be needed under
-normal circumstances.`,
+normal circumstances, as locks left behind by processes that no longer
+exist are taken over automatically.
+
+The process holding the lock is shown. If it is still running, the lock
+is only removed when --force is given.`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
@@ -27,6 +37,7 @@ normal circumstances.`,
 func init() {
 	snapshotCmd.AddCommand(snapshotUnlockCmd)
 	snapshotUnlockCmd.Flags().BoolVarP(&outputJSONFormat, "json", "", false, "output json format")
+	snapshotUnlockCmd.Flags().BoolVar(&snapshotUnlockForce, "force", false, "remove the lock even if the process holding it is still running")
 }
 
 func unlockSnapshot(ctx context.Context) error {
@@ -34,5 +45,33 @@ func unlockSnapshot(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to create snapshot manager: %w", err)
 	}
-	return manager.Unlock(ctx, manager.Paths, false)
+	holder, err := lock.Holder(manager.Paths)
+	if err != nil {
+		return err
+	}
+	if holder == nil {
+		if !outputJSONFormat {
+			fmt.Fprintln(os.Stderr, "The backend is not locked.")
+		}
+		return nil
+	}
+	if !outputJSONFormat {
+		fmt.Printf("The backend is locked by %s.\n", holder)
+	}
+	if holder.PID != 0 && !holder.IsStale(time.Now()) && !snapshotUnlockForce {
+		return fmt.Errorf("backend is locked by %s, which is still running; use --force to remove the lock anyway", holder)
+	}
+	if err := manager.Unlock(ctx, manager.Paths, false); err != nil {
+		return err
+	}
+	// With --json, only one document is written: the holder of the removed
+	// lock, or the error.
+	if outputJSONFormat {
+		jsonBuffer, err := json.Marshal(holder)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonBuffer))
+	}
+	return nil
 }
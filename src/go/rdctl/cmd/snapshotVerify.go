@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/runner"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
+)
+
+var snapshotVerifyAll bool
+
+var snapshotVerifyCmd = &cobra.Command{
+	Use:   "verify [<name>|--all]",
+	Short: "Verify the integrity of snapshots",
+	Long: `Recalculate the checksums of the files in a snapshot and compare them
+against the checksums recorded when the snapshot was created.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if snapshotVerifyAll == (len(args) > 0) {
+			return errors.New("exactly one of a snapshot name or --all must be specified")
+		}
+		cmd.SilenceUsage = true
+		return exitWithJSONOrErrorCondition(verifySnapshot(cmd.Context(), args))
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotVerifyCmd)
+	snapshotVerifyCmd.Flags().BoolVar(&outputJSONFormat, "json", false, "output json format")
+	snapshotVerifyCmd.Flags().BoolVar(&snapshotVerifyAll, "all", false, "verify all snapshots")
+}
+
+func verifySnapshot(ctx context.Context, args []string) error {
+	manager, err := snapshot.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot manager: %w", err)
+	}
+	var snapshots []snapshot.Snapshot
+	if snapshotVerifyAll {
+		if snapshots, err = manager.List(false); err != nil {
+			return fmt.Errorf("failed to list snapshots: %w", err)
+		}
+		sort.Sort(SortableSnapshots(snapshots))
+	} else {
+		aSnapshot, err := manager.Snapshot(args[0])
+		if err != nil {
+			return err
+		}
+		snapshots = append(snapshots, aSnapshot)
+	}
+
+	// Ideally we would not use the deprecated syscall package,
+	// but it works well with all expected scenarios and allows us
+	// to avoid platform-specific signal handling code.
+	notifyCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGHUP, syscall.SIGTERM)
+	defer stop()
+
+	verifications := make([]snapshot.Verification, 0, len(snapshots))
+	failed := 0
+	for _, aSnapshot := range snapshots {
+		verification, err := manager.Verify(notifyCtx, aSnapshot)
+		if errors.Is(err, runner.ErrContextDone) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to verify snapshot %q: %w", aSnapshot.Name, err)
+		}
+		if !verification.Valid() {
+			failed++
+		}
+		verifications = append(verifications, verification)
+	}
+	if outputJSONFormat {
+		err = jsonVerificationOutput(verifications)
+	} else {
+		err = tabularVerificationOutput(verifications)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d snapshots have mismatched files", snapshot.ErrVerificationFailed, failed, len(verifications))
+	}
+	return nil
+}
+
+func jsonVerificationOutput(verifications []snapshot.Verification) error {
+	for _, verification := range verifications {
+		jsonBuffer, err := json.Marshal(verification)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonBuffer))
+	}
+	return nil
+}
+
+func tabularVerificationOutput(verifications []snapshot.Verification) error {
+	if len(verifications) == 0 {
+		fmt.Fprintln(os.Stderr, "No snapshots present.")
+		return nil
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+	fmt.Fprintf(writer, "NAME\tFILE\tSTATUS\n")
+	for _, verification := range verifications {
+		if !verification.HasChecksums {
+			fmt.Fprintf(writer, "%s\t-\tno checksums recorded\n", verification.Name)
+			continue
+		}
+		for _, file := range verification.Files {
+			status := string(file.Status)
+			switch file.Status {
+			case snapshot.FileStatusMismatch:
+				status = fmt.Sprintf("%s (expected %s, got %s)", file.Status, file.Expected, file.Actual)
+			case snapshot.FileStatusError:
+				status = fmt.Sprintf("%s: %s", file.Status, file.Error)
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\n", verification.Name, file.Name, status)
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,399 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Errors that a ResponseError matches (using errors.Is), based on the HTTP
+// status code of the response.
+var (
+	// The request was invalid (400 or 422).
+	ErrInvalidRequest = errors.New("invalid request")
+	// The user name or password were not accepted (401).
+	ErrUnauthorized = errors.New("user/password not accepted")
+	// The requested item does not exist (404).
+	ErrNotFound = errors.New("not found")
+	// The server is not ready to handle the request yet; it may be retried
+	// later (503).
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+// ResponseError is returned by Client when the server responds with an
+// error status code. If the body of the response is a JSON error, it is
+// decoded into the embedded APIError.
+type ResponseError struct {
+	APIError
+	StatusCode int
+	// The body of the response, which usually describes the problem.
+	Body string
+}
+
+func (err *ResponseError) Error() string {
+	status := fmt.Sprintf("%d %s", err.StatusCode, http.StatusText(err.StatusCode))
+	if err.Message != nil {
+		return fmt.Sprintf("%s: %s", status, *err.Message)
+	}
+	if err.Body != "" {
+		return fmt.Sprintf("%s: %s", status, err.Body)
+	}
+	return status
+}
+
+func (err *ResponseError) Is(target error) bool {
+	switch err.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return target == ErrInvalidRequest
+	case http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusServiceUnavailable:
+		return target == ErrUnavailable
+	}
+	return false
+}
+
+// DiagnosticFix describes how a failing diagnostic check may be fixed.
+type DiagnosticFix struct {
+	Description string `json:"description"`
+}
+
+// DiagnosticCheck is the result of a single diagnostic check.
+type DiagnosticCheck struct {
+	ID            string          `json:"id"`
+	Category      string          `json:"category"`
+	Documentation string          `json:"documentation"`
+	Description   string          `json:"description"`
+	Passed        bool            `json:"passed"`
+	Mute          bool            `json:"mute"`
+	Fixes         []DiagnosticFix `json:"fixes"`
+}
+
+// Diagnostics is the result of the diagnostic checks.
+type Diagnostics struct {
+	LastUpdate time.Time         `json:"last_update"`
+	Checks     []DiagnosticCheck `json:"checks"`
+}
+
+// Extension describes an installed extension.
+type Extension struct {
+	Version  string            `json:"version"`
+	Metadata map[string]any    `json:"metadata,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// NavItem is the page (and tab) the preferences window shows.
+type NavItem struct {
+	Current     string            `json:"current,omitempty"`
+	CurrentTabs map[string]string `json:"currentTabs,omitempty"`
+}
+
+// TransientPreferences are the transient settings of the preferences window.
+type TransientPreferences struct {
+	NavItem *NavItem `json:"navItem,omitempty"`
+}
+
+// TransientSettings are settings that are not persisted across restarts.
+// When updating, only fields that are set are changed.
+type TransientSettings struct {
+	NoModalDialogs *bool                 `json:"noModalDialogs,omitempty"`
+	Preferences    *TransientPreferences `json:"preferences,omitempty"`
+}
+
+// Types of Event.
+const (
+	// The backend state changed; VMState and Locked are set.
+	EventBackendState = "backend-state"
+	// The backend was locked or unlocked; Locked and Action are set.
+	EventLock = "lock"
+	// The settings changed.
+	EventSettings = "settings"
+	// An extension was installed or uninstalled; ID and Action are set.
+	EventExtension = "extension"
+)
+
+// Event is a change reported by the events stream.
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// The new backend state, for EventBackendState.
+	VMState string `json:"vmState,omitempty"`
+	// Whether the backend is locked, for EventBackendState and EventLock.
+	Locked *bool `json:"locked,omitempty"`
+	// The action holding the lock for EventLock, or "install" or "uninstall"
+	// for EventExtension.
+	Action string `json:"action,omitempty"`
+	// The extension ID, for EventExtension.
+	ID string `json:"id,omitempty"`
+}
+
+// Client is a typed client for the Rancher Desktop HTTP API.
+type Client struct {
+	rdClient RDClient
+	// The number of times a request is retried if the connection is refused,
+	// e.g. because Rancher Desktop is still starting.
+	Retries int
+	// How long to wait between retries.
+	RetryDelay time.Duration
+	// If set, the maximum time a call (including retries) may take.
+	Timeout time.Duration
+}
+
+// NewClient creates a typed client that sends requests through rdClient.
+// By default, requests are not retried and have no timeout.
+func NewClient(rdClient RDClient) *Client {
+	return &Client{
+		rdClient:   rdClient,
+		RetryDelay: time.Second,
+	}
+}
+
+// send sends a request, retrying if the connection is refused. The payload,
+// if not nil, is sent as JSON. If the server responds with an error status
+// code, the response is closed and a *ResponseError is returned.
+func (client *Client) send(ctx context.Context, method, command string, payload any) (*http.Response, error) {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+	command = VersionCommand("", command)
+	var response *http.Response
+	for attempt := 0; ; attempt++ {
+		var err error
+		if payload != nil {
+			response, err = client.rdClient.DoRequestWithPayload(ctx, method, command, bytes.NewReader(body))
+		} else {
+			response, err = client.rdClient.DoRequest(ctx, method, command)
+		}
+		err = handleConnectionRefused(err)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrConnectionRefused) || attempt >= client.Retries {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-time.After(client.RetryDelay):
+		}
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		defer response.Body.Close()
+		contents, _ := io.ReadAll(response.Body)
+		responseErr := &ResponseError{StatusCode: response.StatusCode, Body: strings.TrimSpace(string(contents))}
+		// Most errors are plain text, but some are JSON with a message.
+		if json.Unmarshal(contents, &responseErr.APIError) != nil {
+			responseErr.APIError = APIError{}
+		}
+		return nil, responseErr
+	}
+	return response, nil
+}
+
+// do sends a request, and decodes the response into result if it is not nil;
+// if result is a *string, the response is returned as-is. The payload, if not
+// nil, is sent as JSON. It returns the status code of the response.
+func (client *Client) do(ctx context.Context, method, command string, payload, result any) (int, error) {
+	if client.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.Timeout)
+		defer cancel()
+	}
+	response, err := client.send(ctx, method, command, payload)
+	if err != nil {
+		var responseErr *ResponseError
+		if errors.As(err, &responseErr) {
+			return responseErr.StatusCode, err
+		}
+		return 0, err
+	}
+	defer response.Body.Close()
+	contents, err := io.ReadAll(response.Body)
+	if err != nil {
+		return response.StatusCode, fmt.Errorf("failed to read response: %w", err)
+	}
+	if text, ok := result.(*string); ok {
+		*text = string(contents)
+	} else if result != nil && response.StatusCode != http.StatusNoContent {
+		if err := json.Unmarshal(contents, result); err != nil {
+			return response.StatusCode, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+	return response.StatusCode, nil
+}
+
+// doText sends a request and returns the body of the response as text.
+func (client *Client) doText(ctx context.Context, method, command string, payload any) (string, error) {
+	var result string
+	_, err := client.do(ctx, method, command, payload, &result)
+	return result, err
+}
+
+// Settings decodes the current settings into settings, which is usually a
+// *options.ServerSettingsForJSON, or a map for untyped access.
+func (client *Client) Settings(ctx context.Context, settings any) error {
+	_, err := client.do(ctx, http.MethodGet, "settings", nil, settings)
+	return err
+}
+
+// LockedSettings decodes the locked settings into settings.
+func (client *Client) LockedSettings(ctx context.Context, settings any) error {
+	_, err := client.do(ctx, http.MethodGet, "settings/locked", nil, settings)
+	return err
+}
+
+// UpdateSettings changes the given settings; settings that are not included
+// are left unchanged. It returns the message from the server.
+func (client *Client) UpdateSettings(ctx context.Context, settings any) (string, error) {
+	return client.doText(ctx, http.MethodPut, "settings", settings)
+}
+
+// DiagnosticCategories returns the names of the diagnostic categories.
+func (client *Client) DiagnosticCategories(ctx context.Context) ([]string, error) {
+	var categories []string
+	_, err := client.do(ctx, http.MethodGet, "diagnostic_categories", nil, &categories)
+	return categories, err
+}
+
+// DiagnosticIDs returns the IDs of the diagnostic checks in the given
+// category. It returns an error matching ErrNotFound if the category does
+// not exist.
+func (client *Client) DiagnosticIDs(ctx context.Context, category string) ([]string, error) {
+	var ids []string
+	command := "diagnostic_ids?" + url.Values{"category": {category}}.Encode()
+	_, err := client.do(ctx, http.MethodGet, command, nil, &ids)
+	return ids, err
+}
+
+// DiagnosticChecks returns the results of the last diagnostic run,
+// optionally filtered by category and check ID.
+func (client *Client) DiagnosticChecks(ctx context.Context, category, checkID string) (Diagnostics, error) {
+	var diagnostics Diagnostics
+	query := url.Values{}
+	if category != "" {
+		query.Set("category", category)
+	}
+	if checkID != "" {
+		query.Set("checkID", checkID)
+	}
+	command := "diagnostic_checks"
+	if len(query) > 0 {
+		command += "?" + query.Encode()
+	}
+	_, err := client.do(ctx, http.MethodGet, command, nil, &diagnostics)
+	return diagnostics, err
+}
+
+// RunDiagnosticChecks runs all diagnostic checks and returns the results.
+func (client *Client) RunDiagnosticChecks(ctx context.Context) (Diagnostics, error) {
+	var diagnostics Diagnostics
+	_, err := client.do(ctx, http.MethodPost, "diagnostic_checks", nil, &diagnostics)
+	return diagnostics, err
+}
+
+// Extensions returns the installed extensions, keyed by ID. It returns an
+// error matching ErrUnavailable if the extension manager is not ready yet.
+func (client *Client) Extensions(ctx context.Context) (map[string]Extension, error) {
+	extensions := make(map[string]Extension)
+	_, err := client.do(ctx, http.MethodGet, "extensions", nil, &extensions)
+	return extensions, err
+}
+
+// InstallExtension installs the extension with the given image ID. It
+// returns false if the extension was already installed.
+func (client *Client) InstallExtension(ctx context.Context, id string) (bool, error) {
+	status, err := client.do(ctx, http.MethodPost, "extensions/install?"+url.Values{"id": {id}}.Encode(), nil, nil)
+	return status == http.StatusCreated, err
+}
+
+// UninstallExtension uninstalls the extension with the given image ID. It
+// returns false if the extension was not installed.
+func (client *Client) UninstallExtension(ctx context.Context, id string) (bool, error) {
+	status, err := client.do(ctx, http.MethodPost, "extensions/uninstall?"+url.Values{"id": {id}}.Encode(), nil, nil)
+	return status == http.StatusCreated, err
+}
+
+// TransientSettings returns the current transient settings.
+func (client *Client) TransientSettings(ctx context.Context) (TransientSettings, error) {
+	var settings TransientSettings
+	_, err := client.do(ctx, http.MethodGet, "transient_settings", nil, &settings)
+	return settings, err
+}
+
+// UpdateTransientSettings changes the transient settings that are set in
+// settings.
+func (client *Client) UpdateTransientSettings(ctx context.Context, settings TransientSettings) error {
+	_, err := client.doText(ctx, http.MethodPut, "transient_settings", settings)
+	return err
+}
+
+// Shutdown asks Rancher Desktop to shut down; it does not wait for it to
+// exit.
+func (client *Client) Shutdown(ctx context.Context) error {
+	_, err := client.doText(ctx, http.MethodPut, "shutdown", nil)
+	return err
+}
+
+// BackendState returns the current state of the backend.
+func (client *Client) BackendState(ctx context.Context) (BackendState, error) {
+	var state BackendState
+	if _, err := client.do(ctx, http.MethodGet, "backend_state", nil, &state); err != nil {
+		return state, err
+	}
+	return state, validateBackendState(state)
+}
+
+// SetBackendState requests a change of the backend state.
+func (client *Client) SetBackendState(ctx context.Context, state BackendState) error {
+	_, err := client.doText(ctx, http.MethodPut, "backend_state", state)
+	return err
+}
+
+// Events streams events from the server, calling handler for each one,
+// starting with the current backend state. It returns when the server closes
+// the stream (with a nil error), when ctx is done, or when handler returns an
+// error. Client.Timeout does not apply to the stream itself.
+func (client *Client) Events(ctx context.Context, handler func(Event) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	response, err := client.send(ctx, http.MethodGet, "events", nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			return fmt.Errorf("failed to unmarshal event %q: %w", line, err)
+		}
+		if err := handler(event); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to read events: %w", err)
+	}
+	return nil
+}
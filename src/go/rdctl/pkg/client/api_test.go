@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("settings", func(t *testing.T) {
+		fake := NewFakeRDClient()
+		fake.Settings["kubernetes"] = map[string]any{"enabled": true, "version": "1.29.0"}
+		client := NewClient(fake)
+		message, err := client.UpdateSettings(ctx, map[string]any{"kubernetes": map[string]any{"version": "1.30.2"}})
+		require.NoError(t, err)
+		assert.NotEmpty(t, message)
+		var settings struct {
+			Kubernetes struct {
+				Enabled bool   `json:"enabled"`
+				Version string `json:"version"`
+			} `json:"kubernetes"`
+		}
+		require.NoError(t, client.Settings(ctx, &settings))
+		assert.True(t, settings.Kubernetes.Enabled)
+		assert.Equal(t, "1.30.2", settings.Kubernetes.Version)
+	})
+
+	t.Run("extensions", func(t *testing.T) {
+		client := NewClient(NewFakeRDClient())
+		installed, err := client.InstallExtension(ctx, "example/extension:1.0")
+		require.NoError(t, err)
+		assert.True(t, installed)
+		installed, err = client.InstallExtension(ctx, "example/extension:1.0")
+		require.NoError(t, err)
+		assert.False(t, installed, "installing twice should be a no-op")
+		extensions, err := client.Extensions(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]Extension{"example/extension:1.0": {Version: "1.0"}}, extensions)
+		uninstalled, err := client.UninstallExtension(ctx, "example/extension:1.0")
+		require.NoError(t, err)
+		assert.True(t, uninstalled)
+		_, err = client.InstallExtension(ctx, "")
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+	})
+
+	t.Run("diagnostics", func(t *testing.T) {
+		fake := NewFakeRDClient()
+		fake.Diagnostics.Checks = []DiagnosticCheck{
+			{ID: "PATH_MANAGEMENT", Category: "Utilities"},
+			{ID: "CONNECTED_TO_INTERNET", Category: "Networking", Passed: true},
+		}
+		client := NewClient(fake)
+		categories, err := client.DiagnosticCategories(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Utilities", "Networking"}, categories)
+		checks, err := client.DiagnosticChecks(ctx, "Networking", "")
+		require.NoError(t, err)
+		require.Len(t, checks.Checks, 1)
+		assert.True(t, checks.Checks[0].Passed)
+		_, err = client.DiagnosticIDs(ctx, "Nonexistent")
+		var responseErr *ResponseError
+		require.ErrorAs(t, err, &responseErr)
+		assert.Equal(t, 404, responseErr.StatusCode)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("transient settings", func(t *testing.T) {
+		client := NewClient(NewFakeRDClient())
+		noModalDialogs := true
+		require.NoError(t, client.UpdateTransientSettings(ctx, TransientSettings{NoModalDialogs: &noModalDialogs}))
+		settings, err := client.TransientSettings(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, settings.NoModalDialogs)
+		assert.True(t, *settings.NoModalDialogs)
+	})
+
+	t.Run("backend state and shutdown", func(t *testing.T) {
+		fake := NewFakeRDClient()
+		client := NewClient(fake)
+		require.NoError(t, client.SetBackendState(ctx, BackendState{VMState: "STOPPED", Locked: true}))
+		state, err := client.BackendState(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, BackendState{VMState: "STOPPED", Locked: true}, state)
+		assert.ErrorIs(t, client.SetBackendState(ctx, BackendState{VMState: "BOGUS"}), ErrInvalidRequest)
+		require.NoError(t, client.Shutdown(ctx))
+		assert.True(t, fake.ShutdownRequested)
+	})
+
+	t.Run("events", func(t *testing.T) {
+		fake := NewFakeRDClient()
+		fake.Events = []Event{
+			{Type: EventSettings},
+			{Type: EventExtension, ID: "example/extension:1.0", Action: "install"},
+			{Type: EventBackendState, VMState: "STOPPING"},
+		}
+		client := NewClient(fake)
+		var events []Event
+		require.NoError(t, client.Events(ctx, func(event Event) error {
+			events = append(events, event)
+			return nil
+		}))
+		require.Len(t, events, 4)
+		assert.Equal(t, EventBackendState, events[0].Type)
+		assert.Equal(t, "STARTED", events[0].VMState)
+		require.NotNil(t, events[0].Locked)
+		assert.False(t, *events[0].Locked)
+		assert.Equal(t, fake.Events, events[1:])
+
+		errStop := errors.New("stop")
+		count := 0
+		err := client.Events(ctx, func(event Event) error {
+			count++
+			return errStop
+		})
+		assert.ErrorIs(t, err, errStop)
+		assert.Equal(t, 1, count, "no events should be delivered after the handler fails")
+	})
+
+	t.Run("retries refused connections", func(t *testing.T) {
+		fake := NewFakeRDClient()
+		fake.RefuseConnections = 2
+		client := NewClient(fake)
+		client.RetryDelay = time.Millisecond
+		_, err := client.BackendState(ctx)
+		assert.ErrorIs(t, err, ErrConnectionRefused, "requests should not be retried by default")
+		client.Retries = 1
+		_, err = client.BackendState(ctx)
+		assert.NoError(t, err)
+	})
+
+	t.Run("error responses", func(t *testing.T) {
+		respond := func(body string) error {
+			client := NewClient(errorRDClient{status: http.StatusUnprocessableEntity, body: body})
+			_, err := client.BackendState(ctx)
+			return err
+		}
+		err := respond(`{"message": "invalid state", "documentation_url": "https://docs.invalid/state"}` + "\n")
+		var responseErr *ResponseError
+		require.ErrorAs(t, err, &responseErr)
+		require.NotNil(t, responseErr.Message)
+		assert.Equal(t, "invalid state", *responseErr.Message)
+		require.NotNil(t, responseErr.DocumentationURL)
+		assert.Equal(t, "https://docs.invalid/state", *responseErr.DocumentationURL)
+		assert.EqualError(t, err, "422 Unprocessable Entity: invalid state")
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+
+		err = respond("invalid state\n")
+		require.ErrorAs(t, err, &responseErr)
+		assert.Nil(t, responseErr.Message)
+		assert.EqualError(t, err, "422 Unprocessable Entity: invalid state")
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		fake := NewFakeRDClient()
+		fake.RefuseConnections = 100
+		client := NewClient(fake)
+		client.Retries = 100
+		client.RetryDelay = time.Hour
+		client.Timeout = 10 * time.Millisecond
+		_, err := client.BackendState(ctx)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error %v", err)
+		assert.ErrorIs(t, err, ErrConnectionRefused)
+	})
+}
+
+// errorRDClient is an RDClient that responds to every request with the given
+// status code and body.
+type errorRDClient struct {
+	RDClient
+	status int
+	body   string
+}
+
+func (client errorRDClient) DoRequest(ctx context.Context, method, command string) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: client.status,
+		Status:     fmt.Sprintf("%d %s", client.status, http.StatusText(client.status)),
+		Body:       io.NopCloser(strings.NewReader(client.body)),
+	}, nil
+}
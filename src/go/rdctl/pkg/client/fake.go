@@ -0,0 +1,255 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// FakeRDClient is an RDClient that serves the Rancher Desktop HTTP API from
+// memory, for use in tests. Its fields may be changed between requests;
+// Mutex must be held while doing so if requests may be in flight.
+type FakeRDClient struct {
+	Mutex sync.Mutex
+	// The current settings; PUT requests are merged into them.
+	Settings       map[string]any
+	LockedSettings map[string]any
+	Diagnostics    Diagnostics
+	Extensions     map[string]Extension
+	Transient      TransientSettings
+	State          BackendState
+	// Events sent on the events stream after the current backend state; the
+	// stream is closed once they have been sent.
+	Events []Event
+	// Whether Rancher Desktop was asked to shut down.
+	ShutdownRequested bool
+	// If greater than zero, the given number of requests fail with
+	// ErrConnectionRefused before the server starts responding.
+	RefuseConnections int
+}
+
+// NewFakeRDClient returns a FakeRDClient with a running backend.
+func NewFakeRDClient() *FakeRDClient {
+	return &FakeRDClient{
+		Settings:       make(map[string]any),
+		LockedSettings: make(map[string]any),
+		Extensions:     make(map[string]Extension),
+		State:          BackendState{VMState: "STARTED"},
+	}
+}
+
+func (fake *FakeRDClient) DoRequest(ctx context.Context, method, command string) (*http.Response, error) {
+	return fake.DoRequestWithPayload(ctx, method, command, http.NoBody)
+}
+
+func (fake *FakeRDClient) DoRequestWithPayload(ctx context.Context, method, command string, payload io.Reader) (*http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	fake.Mutex.Lock()
+	defer fake.Mutex.Unlock()
+	if fake.RefuseConnections > 0 {
+		fake.RefuseConnections--
+		return nil, ErrConnectionRefused
+	}
+	target, err := url.Parse("/" + strings.TrimPrefix(command, "/"))
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(payload)
+	if err != nil {
+		return nil, err
+	}
+	recorder := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+	fake.serve(recorder, method, target, body)
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", recorder.status, http.StatusText(recorder.status)),
+		StatusCode: recorder.status,
+		Header:     recorder.header,
+		Body:       io.NopCloser(&recorder.body),
+	}, nil
+}
+
+func (fake *FakeRDClient) GetBackendState(ctx context.Context) (BackendState, error) {
+	return NewClient(fake).BackendState(ctx)
+}
+
+func (fake *FakeRDClient) UpdateBackendState(ctx context.Context, state BackendState) error {
+	return NewClient(fake).SetBackendState(ctx, state)
+}
+
+// serve handles a single request; the mutex must be held.
+func (fake *FakeRDClient) serve(w http.ResponseWriter, method string, target *url.URL, body []byte) {
+	writeJSON := func(value any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(value)
+	}
+	accepted := func(message string) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = io.WriteString(w, message)
+	}
+	decode := func(value any) bool {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(value); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+			return false
+		}
+		return true
+	}
+	id := target.Query().Get("id")
+
+	switch method + " " + target.Path {
+	case "GET /v1/settings":
+		writeJSON(fake.Settings)
+	case "GET /v1/settings/locked":
+		writeJSON(fake.LockedSettings)
+	case "PUT /v1/settings":
+		var changes map[string]any
+		if !decode(&changes) {
+			return
+		}
+		mergeSettings(fake.Settings, changes)
+		accepted("reconfiguring Rancher Desktop to apply changes (this may take a while)")
+	case "GET /v1/diagnostic_categories":
+		categories := []string{}
+		for _, check := range fake.Diagnostics.Checks {
+			if !slices.Contains(categories, check.Category) {
+				categories = append(categories, check.Category)
+			}
+		}
+		writeJSON(categories)
+	case "GET /v1/diagnostic_ids":
+		ids := []string{}
+		found := false
+		for _, check := range fake.Diagnostics.Checks {
+			if check.Category == target.Query().Get("category") {
+				found = true
+				ids = append(ids, check.ID)
+			}
+		}
+		if !found {
+			http.NotFound(w, nil)
+			return
+		}
+		writeJSON(ids)
+	case "GET /v1/diagnostic_checks", "POST /v1/diagnostic_checks":
+		result := Diagnostics{LastUpdate: fake.Diagnostics.LastUpdate, Checks: []DiagnosticCheck{}}
+		category, checkID := target.Query().Get("category"), target.Query().Get("checkID")
+		for _, check := range fake.Diagnostics.Checks {
+			if (category == "" || check.Category == category) && (checkID == "" || check.ID == checkID) {
+				result.Checks = append(result.Checks, check)
+			}
+		}
+		writeJSON(result)
+	case "GET /v1/extensions":
+		writeJSON(fake.Extensions)
+	case "POST /v1/extensions/install":
+		if id == "" {
+			http.Error(w, "no extension id given", http.StatusBadRequest)
+		} else if _, ok := fake.Extensions[id]; ok {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			_, tag, _ := strings.Cut(id, ":")
+			if tag == "" {
+				tag = "latest"
+			}
+			fake.Extensions[id] = Extension{Version: tag}
+			w.WriteHeader(http.StatusCreated)
+		}
+	case "POST /v1/extensions/uninstall":
+		if id == "" {
+			http.Error(w, "no extension id given", http.StatusBadRequest)
+		} else if _, ok := fake.Extensions[id]; !ok {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			delete(fake.Extensions, id)
+			w.WriteHeader(http.StatusCreated)
+		}
+	case "GET /v1/transient_settings":
+		writeJSON(fake.Transient)
+	case "PUT /v1/transient_settings":
+		var changes TransientSettings
+		if !decode(&changes) {
+			return
+		}
+		if changes.NoModalDialogs != nil {
+			fake.Transient.NoModalDialogs = changes.NoModalDialogs
+		}
+		if changes.Preferences != nil {
+			fake.Transient.Preferences = changes.Preferences
+		}
+		accepted("")
+	case "PUT /v1/shutdown":
+		fake.ShutdownRequested = true
+		accepted("Shutting down.")
+	case "GET /v1/backend_state":
+		writeJSON(fake.State)
+	case "GET /v1/events":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(w)
+		locked := fake.State.Locked
+		_ = encoder.Encode(Event{Type: EventBackendState, VMState: fake.State.VMState, Locked: &locked})
+		for _, event := range fake.Events {
+			_ = encoder.Encode(event)
+		}
+	case "PUT /v1/backend_state":
+		var state BackendState
+		if !decode(&state) {
+			return
+		}
+		if err := validateBackendState(state); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fake.State = state
+		accepted("")
+	default:
+		http.NotFound(w, nil)
+	}
+}
+
+// mergeSettings recursively merges changes into settings.
+func mergeSettings(settings, changes map[string]any) {
+	for key, value := range changes {
+		changedMap, changedIsMap := value.(map[string]any)
+		existingMap, existingIsMap := settings[key].(map[string]any)
+		if changedIsMap && existingIsMap {
+			mergeSettings(existingMap, changedMap)
+		} else {
+			settings[key] = value
+		}
+	}
+}
+
+// responseRecorder is a minimal http.ResponseWriter that keeps the response
+// in memory.
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (recorder *responseRecorder) Header() http.Header {
+	return recorder.header
+}
+
+func (recorder *responseRecorder) WriteHeader(status int) {
+	if !recorder.wroteHeader {
+		recorder.status = status
+		recorder.wroteHeader = true
+	}
+}
+
+func (recorder *responseRecorder) Write(data []byte) (int, error) {
+	recorder.WriteHeader(http.StatusOK)
+	return recorder.body.Write(data)
+}
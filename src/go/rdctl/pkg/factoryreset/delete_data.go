@@ -51,6 +51,7 @@ func cleanupDockerContextFiles() {
 func clearDockerContext() error {
 	// Ignore failure to delete this next file:
 	os.Remove(path.Join(dockerconfig.Dir(), "plaintext-credentials.config.json"))
+	os.Remove(path.Join(dockerconfig.Dir(), "encrypted-credentials.json"))
 
 	cleanupDockerContextFiles()
 
@@ -0,0 +1,28 @@
+//go:build unix
+
+package lock
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
+)
+
+// lockGuard takes an exclusive flock on the guard file, waiting for other
+// processes to release it. It returns a function that releases it.
+func lockGuard(appPaths *paths.Paths) (func(), error) {
+	file, err := os.OpenFile(guardPath(appPaths), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Flock(int(file.Fd()), unix.LOCK_EX); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return func() {
+		_ = unix.Flock(int(file.Fd()), unix.LOCK_UN)
+		_ = file.Close()
+	}, nil
+}
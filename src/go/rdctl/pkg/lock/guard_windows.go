@@ -0,0 +1,28 @@
+package lock
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
+)
+
+// lockGuard takes an exclusive LockFileEx lock on the guard file, waiting for
+// other processes to release it. It returns a function that releases it.
+func lockGuard(appPaths *paths.Paths) (func(), error) {
+	file, err := os.OpenFile(guardPath(appPaths), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	handle := windows.Handle(file.Fd())
+	overlapped := &windows.Overlapped{}
+	if err := windows.LockFileEx(handle, windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, overlapped); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return func() {
+		_ = windows.UnlockFileEx(handle, 0, 1, 0, overlapped)
+		_ = file.Close()
+	}, nil
+}
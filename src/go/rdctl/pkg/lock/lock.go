@@ -13,68 +13,300 @@ import (
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/process"
 )
 
-const backendLockName = "backend.lock"
+const (
+	backendLockName      = "backend.lock"
+	backendLockGuardName = "backend.lock.guard"
+)
 
 type BackendLocker interface {
 	Lock(ctx context.Context, appPaths *paths.Paths, action string) error
+	// LockStore takes the lock without stopping the VM, for operations that
+	// only change the snapshot store; it is released with Unlock, without
+	// restarting the VM.
+	LockStore(ctx context.Context, appPaths *paths.Paths, action string) error
 	Unlock(ctx context.Context, appPaths *paths.Paths, restart bool) error
 }
 
+const (
+	// How often the holder of the backend lock refreshes the heartbeat.
+	heartbeatInterval = 30 * time.Second
+	// How old the heartbeat of a lock held by a process on another host may
+	// be before the lock is considered stale. This is only used when the
+	// owning process cannot be checked directly.
+	staleHeartbeatAge = 5 * heartbeatInterval
+)
+
+// BackendLock is a BackendLocker that uses a lock file in the application
+// home directory. While the lock is held, a heartbeat in the lock file is
+// refreshed periodically.
 type BackendLock struct {
+	stopHeartbeat func()
 }
 
+// LockData is the content of the lock file.
 type LockData struct {
 	Action string `json:"action"`
+	// The process holding the lock.
+	PID int `json:"pid,omitempty"`
+	// Identifies when the process holding the lock was started, as returned
+	// by process.StartMarker; this is used to detect PID reuse.
+	ProcessStart uint64 `json:"processStart,omitempty"`
+	// The host the process holding the lock runs on.
+	Hostname string `json:"hostname,omitempty"`
+	// The time the lock was acquired.
+	Created time.Time `json:"created,omitzero"`
+	// The last time the holder of the lock showed it was still alive.
+	Heartbeat time.Time `json:"heartbeat,omitzero"`
+}
+
+// IsStale returns whether the process holding the lock is known to be gone,
+// so that the lock can be taken over. Lock files without owner information
+// (written by older versions) are never considered stale.
+func (data LockData) IsStale(now time.Time) bool {
+	if data.PID == 0 {
+		return false
+	}
+	if hostname, err := os.Hostname(); err != nil || hostname != data.Hostname {
+		// We can't check processes on other hosts (e.g. with a shared home
+		// directory); rely on the heartbeat instead.
+		return now.Sub(data.Heartbeat) > staleHeartbeatAge
+	}
+	marker, err := process.StartMarker(data.PID)
+	if errors.Is(err, process.ErrNotFound) {
+		return true
+	} else if err != nil {
+		return false
+	}
+	// Otherwise, the lock is stale if the PID was reused by another process.
+	return marker != data.ProcessStart
+}
+
+// String describes the holder of the lock.
+func (data LockData) String() string {
+	if data.PID == 0 {
+		return fmt.Sprintf("an unknown process (%s)", data.Action)
+	}
+	return fmt.Sprintf("process %d on %s (%s) since %s, last seen %s",
+		data.PID, data.Hostname, data.Action,
+		data.Created.Local().Format(time.DateTime), data.Heartbeat.Local().Format(time.DateTime))
+}
+
+func lockPath(appPaths *paths.Paths) string {
+	return filepath.Join(appPaths.AppHome, backendLockName)
+}
+
+// guardPath returns the path of the file that is locked (with an OS lock)
+// while the lock file is being checked or changed. Unlike the lock file, it
+// is never removed, and the OS lock is released if its holder exits.
+func guardPath(appPaths *paths.Paths) string {
+	return filepath.Join(appPaths.AppHome, backendLockGuardName)
+}
+
+// Holder returns the contents of the lock file, or nil if the backend is not
+// locked.
+func Holder(appPaths *paths.Paths) (*LockData, error) {
+	contents, err := os.ReadFile(lockPath(appPaths))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read backend lock file: %w", err)
+	}
+	// The lock file may be empty or invalid if the holder crashed while
+	// writing it; it is then treated as having an unknown holder.
+	var lockData LockData
+	_ = json.Unmarshal(contents, &lockData)
+	return &lockData, nil
 }
 
 // Lock the backend by creating the lock file and shutting down the VM.
 // The lock file will be deleted if Lock returns an error (e.g. the backend couldn't be stopped).
+// If the lock file belongs to a process that no longer exists, it is taken over.
 func (lock *BackendLock) Lock(ctx context.Context, appPaths *paths.Paths, action string) error {
+	if err := lock.LockStore(ctx, appPaths, action); err != nil {
+		return err
+	}
+	err := ensureBackendStopped(ctx, action)
+	if err != nil {
+		lock.stop()
+		_ = release(appPaths)
+	}
+	return err
+}
+
+// LockStore creates the lock file like Lock, but leaves the VM running.
+func (lock *BackendLock) LockStore(ctx context.Context, appPaths *paths.Paths, action string) error {
 	if err := os.MkdirAll(appPaths.AppHome, 0o755); err != nil {
 		return fmt.Errorf("failed to create backend lock parent directory %q: %w", appPaths.AppHome, err)
 	}
-	// Create an empty file whose presence signifies that the backend is locked.
-	lockPath := filepath.Join(appPaths.AppHome, backendLockName)
-	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0o644)
+	lockData, err := newLockData(action)
+	if err != nil {
+		return err
+	}
+	if err := acquire(appPaths, lockData); err != nil {
+		return err
+	}
+	lock.startHeartbeat(appPaths, lockData)
+	return nil
+}
+
+// acquire creates the lock file with the given contents, taking it over if
+// it is stale. This is done while holding the guard, so that no other
+// process can take over the lock file we create.
+func acquire(appPaths *paths.Paths, lockData LockData) error {
+	unlockGuard, err := lockGuard(appPaths)
+	if err != nil {
+		return fmt.Errorf("failed to acquire backend lock guard: %w", err)
+	}
+	defer unlockGuard()
+
+	// Create a file whose presence signifies that the backend is locked.
+	path := lockPath(appPaths)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		holder, holderErr := Holder(appPaths)
+		if holderErr != nil || holder == nil || !holder.IsStale(time.Now()) {
+			if holder != nil && holder.PID != 0 {
+				return fmt.Errorf("backend is locked by %s; if it is no longer running, you can remove the lock with `rdctl snapshot unlock`", holder)
+			}
+			return errors.New("backend lock file already exists; if there is no snapshot operation in progress, you can remove this error with `rdctl snapshot unlock`")
+		}
+		// The holder is gone; take over the lock. Other processes only
+		// replace the lock file while holding the guard, so it is still the
+		// stale one.
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove stale backend lock held by %s: %w", holder, err)
+		}
+		file, err = os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0o644)
+	}
 	if errors.Is(err, os.ErrExist) {
 		return errors.New("backend lock file already exists; if there is no snapshot operation in progress, you can remove this error with `rdctl snapshot unlock`")
 	} else if err != nil {
 		return fmt.Errorf("unexpected error acquiring backend lock: %w", err)
 	}
 
-	lockData := LockData{
-		Action: action,
-	}
 	encoder := json.NewEncoder(file)
 	encoder.SetIndent("", "  ")
 	if err := encoder.Encode(lockData); err != nil {
 		_ = file.Close()
-		_ = os.Remove(lockPath)
+		_ = os.Remove(path)
 		return fmt.Errorf("failed to write metadata file: %w", err)
 	}
 
 	if err := file.Close(); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "failed to close backend lock file descriptor: %s", err)
 	}
-	err = ensureBackendStopped(ctx, action)
+	return nil
+}
+
+// release removes the lock file.
+func release(appPaths *paths.Paths) error {
+	unlockGuard, err := lockGuard(appPaths)
 	if err != nil {
-		_ = os.Remove(lockPath)
+		return fmt.Errorf("failed to acquire backend lock guard: %w", err)
 	}
-	return err
+	defer unlockGuard()
+	return os.RemoveAll(lockPath(appPaths))
 }
 
 // Unlock the backend by removing the lock file. Restart the VM if the file was deleted and `restart` is true.
 func (lock *BackendLock) Unlock(ctx context.Context, appPaths *paths.Paths, restart bool) error {
-	lockPath := filepath.Join(appPaths.AppHome, backendLockName)
-	err := os.RemoveAll(lockPath)
+	lock.stop()
+	err := release(appPaths)
 	if err == nil && restart {
 		err = ensureBackendStarted(ctx)
 	}
 	return err
 }
 
+func newLockData(action string) (LockData, error) {
+	now := time.Now()
+	lockData := LockData{
+		Action:    action,
+		PID:       os.Getpid(),
+		Created:   now,
+		Heartbeat: now,
+	}
+	var err error
+	if lockData.ProcessStart, err = process.StartMarker(lockData.PID); err != nil {
+		return lockData, fmt.Errorf("failed to determine process start time: %w", err)
+	}
+	if lockData.Hostname, err = os.Hostname(); err != nil {
+		return lockData, fmt.Errorf("failed to determine host name: %w", err)
+	}
+	return lockData, nil
+}
+
+// startHeartbeat periodically refreshes the heartbeat in the lock file until
+// the lock is released.
+func (lock *BackendLock) startHeartbeat(appPaths *paths.Paths, lockData LockData) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lock.stopHeartbeat = func() {
+		cancel()
+		<-done
+	}
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				lockData.Heartbeat = now
+				if err := writeHeartbeat(appPaths, lockData); err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "failed to update backend lock heartbeat: %s\n", err)
+				}
+			}
+		}
+	}()
+}
+
+// stop stops refreshing the heartbeat, if it is running.
+func (lock *BackendLock) stop() {
+	if lock.stopHeartbeat != nil {
+		lock.stopHeartbeat()
+		lock.stopHeartbeat = nil
+	}
+}
+
+// writeHeartbeat replaces the lock file with lockData, as long as the lock
+// is still held by this process.
+func writeHeartbeat(appPaths *paths.Paths, lockData LockData) error {
+	unlockGuard, err := lockGuard(appPaths)
+	if err != nil {
+		return fmt.Errorf("failed to acquire backend lock guard: %w", err)
+	}
+	defer unlockGuard()
+	holder, err := Holder(appPaths)
+	if err != nil {
+		return err
+	}
+	if holder == nil || holder.PID != lockData.PID || !holder.Created.Equal(lockData.Created) {
+		return errors.New("backend lock is no longer held by this process")
+	}
+	contents, err := json.MarshalIndent(lockData, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it, so that readers never see a
+	// partially written file.
+	path := lockPath(appPaths)
+	tempPath := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tempPath, append(contents, '\n'), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
 func ensureBackendStarted(ctx context.Context) error {
 	connectionInfo, err := config.GetConnectionInfo(true)
 	if err != nil || connectionInfo == nil {
@@ -0,0 +1,106 @@
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
+)
+
+func TestLockData(t *testing.T) {
+	now := time.Now()
+	live, err := newLockData("testing")
+	require.NoError(t, err)
+
+	t.Run("a lock held by this process is not stale", func(t *testing.T) {
+		assert.False(t, live.IsStale(now))
+	})
+
+	t.Run("a lock held by a reused PID is stale", func(t *testing.T) {
+		reused := live
+		reused.ProcessStart = live.ProcessStart - 1
+		assert.True(t, reused.IsStale(now))
+	})
+
+	t.Run("a lock without owner information is not stale", func(t *testing.T) {
+		assert.False(t, LockData{Action: "testing"}.IsStale(now))
+	})
+
+	t.Run("a lock held on another host is stale once the heartbeat stops", func(t *testing.T) {
+		remote := live
+		remote.Hostname = live.Hostname + ".invalid"
+		assert.False(t, remote.IsStale(now))
+		remote.Heartbeat = now.Add(-2 * staleHeartbeatAge)
+		assert.True(t, remote.IsStale(now))
+	})
+}
+
+func TestHolder(t *testing.T) {
+	appPaths := &paths.Paths{AppHome: t.TempDir()}
+	holder, err := Holder(appPaths)
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+
+	lockData, err := newLockData("testing")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(lockPath(appPaths), []byte(`{"action": "testing"}`), 0o644))
+	// The heartbeat must not overwrite a lock file held by someone else.
+	assert.Error(t, writeHeartbeat(appPaths, lockData))
+
+	require.NoError(t, os.WriteFile(filepath.Join(appPaths.AppHome, backendLockName), nil, 0o644))
+	holder, err = Holder(appPaths)
+	require.NoError(t, err)
+	require.NotNil(t, holder)
+	assert.Equal(t, LockData{}, *holder)
+}
+
+func TestAcquire(t *testing.T) {
+	appPaths := &paths.Paths{AppHome: t.TempDir()}
+	stale, err := newLockData("stale")
+	require.NoError(t, err)
+	stale.ProcessStart--
+	contents, err := json.Marshal(stale)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(lockPath(appPaths), contents, 0o644))
+
+	// Several processes taking over the same stale lock at once must not
+	// remove each other's locks: exactly one of them gets it.
+	const count = 10
+	results := make([]error, count)
+	datas := make([]LockData, count)
+	var wg sync.WaitGroup
+	for i := range count {
+		datas[i], err = newLockData(fmt.Sprintf("taker %d", i))
+		require.NoError(t, err)
+		datas[i].Created = datas[i].Created.Add(time.Duration(i) * time.Millisecond)
+		wg.Go(func() {
+			results[i] = acquire(appPaths, datas[i])
+		})
+	}
+	wg.Wait()
+	winner := -1
+	for i, result := range results {
+		if result == nil {
+			assert.Equal(t, -1, winner, "both %d and %d acquired the lock", winner, i)
+			winner = i
+		}
+	}
+	require.NotEqual(t, -1, winner, "nobody acquired the lock: %v", results)
+	holder, err := Holder(appPaths)
+	require.NoError(t, err)
+	require.NotNil(t, holder)
+	assert.Equal(t, datas[winner].Action, holder.Action)
+
+	require.NoError(t, release(appPaths))
+	holder, err = Holder(appPaths)
+	require.NoError(t, err)
+	assert.Nil(t, holder)
+}
@@ -13,6 +13,10 @@ func (lock *MockBackendLock) Lock(ctx context.Context, appPaths *paths.Paths, ac
 	return nil
 }
 
+func (lock *MockBackendLock) LockStore(ctx context.Context, appPaths *paths.Paths, action string) error {
+	return nil
+}
+
 func (lock *MockBackendLock) Unlock(ctx context.Context, appPaths *paths.Paths, restart bool) error {
 	return nil
 }
@@ -87,3 +87,21 @@ func WaitForProcess(pid int) error {
 	logrus.Tracef("got %d kqueue events: %+v", n, events[:n])
 	return nil
 }
+
+// StartMarker returns a value identifying when the given process was
+// started, to tell it apart from later processes reusing its PID; this is
+// the start time in microseconds, as recorded by the kernel when the process
+// was created.  It returns ErrNotFound if the process does not exist.
+func StartMarker(pid int) (uint64, error) {
+	if err := unix.Kill(pid, 0); errors.Is(err, unix.ESRCH) {
+		return 0, ErrNotFound
+	}
+	proc, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get information on process %d: %w", pid, err)
+	}
+	if int(proc.Proc.P_pid) != pid {
+		return 0, ErrNotFound
+	}
+	return uint64(proc.Proc.P_starttime.Sec)*1_000_000 + uint64(proc.Proc.P_starttime.Usec), nil
+}
@@ -17,10 +17,13 @@ limitations under the License.
 package process
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"golang.org/x/sys/unix"
 )
@@ -73,3 +76,34 @@ func WaitForProcess(pid int) error {
 	}
 	return nil
 }
+
+// StartMarker returns a value identifying when the given process was
+// started, to tell it apart from later processes reusing its PID; this is
+// the start time in clock ticks since boot from /proc/<pid>/stat, which,
+// unlike a wall-clock time, does not change when the system clock is set.
+// It returns ErrNotFound if the process does not exist.
+func StartMarker(pid int) (uint64, error) {
+	//nolint:gocritic // filepathJoin doesn't like absolute paths
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, ErrNotFound
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to read status of process %d: %w", pid, err)
+	}
+	// The second field is the command name in parentheses, which may contain
+	// spaces and parentheses itself; the remaining fields start at the state
+	// (field 3), so the start time (field 22) is at index 19.
+	index := bytes.LastIndexByte(stat, ')')
+	if index < 0 {
+		return 0, fmt.Errorf("failed to parse status of process %d", pid)
+	}
+	fields := strings.Fields(string(stat[index+1:]))
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("failed to parse status of process %d", pid)
+	}
+	ticks, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse start time of process %d: %w", pid, err)
+	}
+	return ticks, nil
+}
@@ -17,3 +17,12 @@ func TestFindPidOfProcess(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, os.Getpid(), pid)
 }
+
+func TestStartMarker(t *testing.T) {
+	marker, err := process.StartMarker(os.Getpid())
+	require.NoError(t, err)
+	assert.NotZero(t, marker)
+	again, err := process.StartMarker(os.Getpid())
+	require.NoError(t, err)
+	assert.Equal(t, marker, again, "start marker should be stable")
+}
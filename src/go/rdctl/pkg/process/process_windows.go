@@ -453,3 +453,31 @@ func TerminateProcessInDirectory(directory string, force bool) error {
 		return nil
 	})
 }
+
+// StartMarker returns a value identifying when the given process was
+// started, to tell it apart from later processes reusing its PID; this is
+// the creation time recorded for the process, in 100ns intervals.  It returns
+// ErrNotFound if the process does not exist (or has exited).
+func StartMarker(pid int) (uint64, error) {
+	proc, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if errors.Is(err, windows.ERROR_INVALID_PARAMETER) {
+		return 0, ErrNotFound
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to open process %d: %w", pid, err)
+	}
+	defer func() { _ = windows.CloseHandle(proc) }()
+	var exitCode uint32
+	if err := windows.GetExitCodeProcess(proc, &exitCode); err != nil {
+		return 0, fmt.Errorf("failed to get exit code of process %d: %w", pid, err)
+	}
+	// STILL_ACTIVE is the exit code of a process that has not exited.
+	const stillActive = 259
+	if exitCode != stillActive {
+		return 0, ErrNotFound
+	}
+	var creationTime, exitTime, kernelTime, userTime windows.Filetime
+	if err := windows.GetProcessTimes(proc, &creationTime, &exitTime, &kernelTime, &userTime); err != nil {
+		return 0, fmt.Errorf("failed to get start time of process %d: %w", pid, err)
+	}
+	return uint64(creationTime.HighDateTime)<<32 | uint64(creationTime.LowDateTime), nil
+}
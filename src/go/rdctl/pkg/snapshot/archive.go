@@ -0,0 +1,408 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/runner"
+)
+
+// The version of the snapshot archive format. It must be incremented
+// whenever a change is made that older versions of rdctl cannot import.
+const archiveFormatVersion = 1
+
+// The name of the first entry in a snapshot archive.
+const archiveMetadataName = "metadata.json"
+
+// The maximum size of the metadata entry in a snapshot archive; this
+// guards against reading arbitrarily large (corrupt) archives into memory.
+const archiveMetadataMaxSize = 1 << 20
+
+// The size of the blocks that are checked for zeroes when extracting files
+// from an archive; all-zero blocks are skipped to keep disk images sparse.
+const sparseBlockSize = 64 * 1024
+
+// ErrUnsupportedArchive is returned by Manager.Import when the archive was
+// created by an incompatible version of rdctl.
+var ErrUnsupportedArchive = errors.New("unsupported snapshot archive format")
+
+// archiveMetadata is the first entry of a snapshot archive, and describes
+// the rest of its contents.
+type archiveMetadata struct {
+	// The version of the archive format.
+	FormatVersion int `json:"formatVersion"`
+	// The snapshot that was exported; the ID is not included, as a new
+	// one is assigned on import.
+	Snapshot Snapshot `json:"snapshot"`
+	// The files in the archive, in the order they appear.
+	Files []archiveFile `json:"files"`
+}
+
+// archiveFile describes a file in a snapshot archive.
+type archiveFile struct {
+	// The name of the file, relative to the snapshot directory.
+	Name string `json:"name"`
+	// The (apparent) size of the file, in bytes.
+	Size int64 `json:"size"`
+	// The permissions the file should have.
+	FileMode os.FileMode `json:"mode"`
+	// The hex-encoded SHA-256 checksum of the file contents.
+	SHA256 string `json:"sha256"`
+}
+
+// Export writes the snapshot with the given name to w as a zstd-compressed
+// tar archive that can be imported on another machine.
+func (manager *Manager) Export(ctx context.Context, name string, w io.Writer) error {
+	snapshot, err := manager.Snapshot(name)
+	if err != nil {
+		return err
+	}
+	snapshotDir := manager.SnapshotDirectory(snapshot)
+	files, err := manager.archiveFiles(ctx, snapshotDir)
+	if err != nil {
+		return err
+	}
+	metadata := archiveMetadata{
+		FormatVersion: archiveFormatVersion,
+		Snapshot:      snapshot,
+		Files:         files,
+	}
+	metadata.Snapshot.ID = ""
+	metadataBytes, err := json.MarshalIndent(&metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal archive metadata: %w", err)
+	}
+
+	zstdWriter, err := zstd.NewWriter(w)
+	if err != nil {
+		return fmt.Errorf("failed to create zstd writer: %w", err)
+	}
+	tarWriter := tar.NewWriter(zstdWriter)
+	err = tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     archiveMetadataName,
+		Mode:     0o644,
+		Size:     int64(len(metadataBytes)),
+		ModTime:  snapshot.Created,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s header: %w", archiveMetadataName, err)
+	}
+	if _, err := tarWriter.Write(metadataBytes); err != nil {
+		return fmt.Errorf("failed to write %s: %w", archiveMetadataName, err)
+	}
+	for _, file := range files {
+		if contextIsDone(ctx) {
+			return runner.ErrContextDone
+		}
+		if err := manager.writeArchiveFile(tarWriter, snapshotDir, file, snapshot); err != nil {
+			return err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := zstdWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish compression: %w", err)
+	}
+	return nil
+}
+
+// archiveFiles describes the files of the snapshot in snapshotDir that
+// should be included in an archive, including their checksums.
+func (manager *Manager) archiveFiles(ctx context.Context, snapshotDir string) ([]archiveFile, error) {
+	var files []archiveFile
+	for _, file := range manager.Files(manager.Paths, snapshotDir) {
+		fd, size, err := openSnapshotFile(manager.Paths, file.SnapshotPath)
+		if errors.Is(err, os.ErrNotExist) && file.MissingOk {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to open %q: %w", filepath.Base(file.SnapshotPath), err)
+		}
+		fd.Close()
+		files = append(files, archiveFile{
+			Name:     filepath.Base(file.SnapshotPath),
+			Size:     size,
+			FileMode: file.FileMode,
+		})
+	}
+	taskRunner := runner.NewTaskRunner(ctx)
+	for i := range files {
+		taskRunner.Add(func() error {
+			checksum, err := manager.fileChecksum(filepath.Join(snapshotDir, files[i].Name))
+			if err != nil {
+				return fmt.Errorf("failed to calculate checksum of %q: %w", files[i].Name, err)
+			}
+			files[i].SHA256 = checksum
+			return nil
+		})
+	}
+	if err := taskRunner.Wait(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (manager *Manager) writeArchiveFile(tarWriter *tar.Writer, snapshotDir string, file archiveFile, snapshot Snapshot) error {
+	srcFd, _, err := openSnapshotFile(manager.Paths, filepath.Join(snapshotDir, file.Name))
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %w", file.Name, err)
+	}
+	defer srcFd.Close()
+	err = tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     file.Name,
+		Mode:     int64(file.FileMode.Perm()),
+		Size:     file.Size,
+		ModTime:  snapshot.Created,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write header for %q: %w", file.Name, err)
+	}
+	// Holes in sparse files are read back as zeroes; these compress well, and
+	// are turned back into holes on import.
+	if _, err := io.CopyN(tarWriter, srcFd, file.Size); err != nil {
+		return fmt.Errorf("failed to write %q: %w", file.Name, err)
+	}
+	return nil
+}
+
+// Import reads a snapshot archive created by Export from r and adds it to
+// the snapshots on this machine. If name is not empty, it is used instead of
+// the name stored in the archive. The imported snapshot is assigned a new ID.
+func (manager *Manager) Import(ctx context.Context, r io.Reader, name string) (snapshot Snapshot, err error) {
+	zstdReader, err := zstd.NewReader(r)
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to create zstd reader: %w", err)
+	}
+	defer zstdReader.Close()
+	tarReader := tar.NewReader(zstdReader)
+
+	metadata, err := readArchiveMetadata(tarReader)
+	if err != nil {
+		return snapshot, err
+	}
+	if err := manager.checkArchiveFiles(metadata.Files); err != nil {
+		return snapshot, err
+	}
+	if name == "" {
+		name = metadata.Snapshot.Name
+	}
+	if err := manager.ValidateName(name); err != nil {
+		return snapshot, err
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to generate ID for snapshot: %w", err)
+	}
+	snapshot = Snapshot{
+		Created:     metadata.Snapshot.Created,
+		Name:        name,
+		ID:          id.String(),
+		Description: metadata.Snapshot.Description,
+		Labels:      metadata.Snapshot.Labels,
+		Checksums:   make(map[string]string),
+	}
+	if err := ValidateLabels(snapshot.Labels); err != nil {
+		return snapshot, fmt.Errorf("invalid snapshot archive: %w", err)
+	}
+	for _, file := range metadata.Files {
+		snapshot.Checksums[file.Name] = file.SHA256
+	}
+	action := fmt.Sprintf("Importing snapshot %q", name)
+	// Importing only adds to the snapshot store, so the VM can keep running.
+	if err := manager.LockStore(ctx, manager.Paths, action); err != nil {
+		return snapshot, err
+	}
+	snapshotDir := manager.SnapshotDirectory(snapshot)
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(snapshotDir)
+		}
+		unlockErr := manager.Unlock(ctx, manager.Paths, false)
+		if err == nil {
+			err = unlockErr
+		}
+	}()
+	// (Re)validate the name after acquiring the lock in case another process created a snapshot with the same name
+	if err = manager.ValidateName(name); err != nil {
+		return snapshot, err
+	}
+	if err = manager.writeMetadataFile(snapshot); err != nil {
+		return snapshot, err
+	}
+
+	for _, file := range metadata.Files {
+		if contextIsDone(ctx) {
+			return snapshot, runner.ErrContextDone
+		}
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return snapshot, fmt.Errorf("archive is truncated: %q is missing", file.Name)
+		} else if err != nil {
+			return snapshot, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Name != file.Name || header.Typeflag != tar.TypeReg {
+			return snapshot, fmt.Errorf("unexpected archive entry %q (expected %q)", header.Name, file.Name)
+		}
+		if err := extractArchiveFile(tarReader, snapshotDir, file); err != nil {
+			return snapshot, err
+		}
+	}
+	if _, err := tarReader.Next(); !errors.Is(err, io.EOF) {
+		return snapshot, errors.New("archive contains unexpected trailing entries")
+	}
+
+	if err := writeCompleteFile(snapshotDir); err != nil {
+		return snapshot, err
+	}
+	return snapshot, nil
+}
+
+// readArchiveMetadata reads and validates the metadata entry, which must be
+// the first entry of a snapshot archive.
+func readArchiveMetadata(tarReader *tar.Reader) (archiveMetadata, error) {
+	var metadata archiveMetadata
+	header, err := tarReader.Next()
+	if err != nil {
+		return metadata, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if header.Name != archiveMetadataName {
+		return metadata, fmt.Errorf("%w: first entry is %q, not %q", ErrUnsupportedArchive, header.Name, archiveMetadataName)
+	}
+	if header.Size > archiveMetadataMaxSize {
+		return metadata, fmt.Errorf("%w: %s is too large", ErrUnsupportedArchive, archiveMetadataName)
+	}
+	decoder := json.NewDecoder(tarReader)
+	if err := decoder.Decode(&metadata); err != nil {
+		return metadata, fmt.Errorf("failed to read %s: %w", archiveMetadataName, err)
+	}
+	if metadata.FormatVersion != archiveFormatVersion {
+		return metadata, fmt.Errorf("%w: version %d (expected %d)", ErrUnsupportedArchive, metadata.FormatVersion, archiveFormatVersion)
+	}
+	seen := make(map[string]bool)
+	for _, file := range metadata.Files {
+		if file.Name != filepath.Base(file.Name) || file.Name == "." || file.Name == ".." {
+			return metadata, fmt.Errorf("invalid file name %q in archive", file.Name)
+		}
+		if file.Name == archiveMetadataName || file.Name == completeFileName {
+			return metadata, fmt.Errorf("reserved file name %q in archive", file.Name)
+		}
+		if seen[file.Name] {
+			return metadata, fmt.Errorf("duplicate file name %q in archive", file.Name)
+		}
+		seen[file.Name] = true
+	}
+	return metadata, nil
+}
+
+// checkArchiveFiles checks that the files in an archive are the ones that
+// make up a snapshot on this machine: every file must be known, and only
+// files that are allowed to be missing may be absent.
+func (manager *Manager) checkArchiveFiles(files []archiveFile) error {
+	archived := make(map[string]bool)
+	for _, file := range files {
+		archived[file.Name] = true
+	}
+	expected := make(map[string]bool)
+	for _, file := range manager.Files(manager.Paths, "") {
+		name := filepath.Base(file.SnapshotPath)
+		expected[name] = true
+		if !archived[name] && !file.MissingOk {
+			return fmt.Errorf("archive is missing file %q", name)
+		}
+	}
+	for _, file := range files {
+		if !expected[file.Name] {
+			return fmt.Errorf("unexpected file %q in archive", file.Name)
+		}
+	}
+	return nil
+}
+
+// extractArchiveFile writes the current entry of tarReader into snapshotDir,
+// verifying its size and checksum against file.
+func extractArchiveFile(tarReader *tar.Reader, snapshotDir string, file archiveFile) error {
+	dstPath := filepath.Join(snapshotDir, file.Name)
+	dstFd, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.FileMode.Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", file.Name, err)
+	}
+	defer dstFd.Close()
+	hash := sha256.New()
+	size, err := writeSparse(dstFd, io.TeeReader(tarReader, hash))
+	if err != nil {
+		return fmt.Errorf("failed to extract %q: %w", file.Name, err)
+	}
+	if size != file.Size {
+		return fmt.Errorf("size mismatch for %q: got %d bytes, expected %d", file.Name, size, file.Size)
+	}
+	if checksum := hex.EncodeToString(hash.Sum(nil)); checksum != file.SHA256 {
+		return fmt.Errorf("checksum mismatch for %q: got %s, expected %s", file.Name, checksum, file.SHA256)
+	}
+	return dstFd.Close()
+}
+
+// writeSparse copies src to dst, seeking over blocks that consist entirely
+// of zeroes instead of writing them, so that sparse files stay sparse on
+// filesystems that support it. Returns the number of bytes copied.
+func writeSparse(dst *os.File, src io.Reader) (int64, error) {
+	buf := make([]byte, sparseBlockSize)
+	var offset int64
+	for {
+		n, err := io.ReadFull(src, buf)
+		if n > 0 {
+			block := buf[:n]
+			if isZero(block) {
+				if _, err := dst.Seek(int64(n), io.SeekCurrent); err != nil {
+					return offset, err
+				}
+			} else if _, err := dst.Write(block); err != nil {
+				return offset, err
+			}
+			offset += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		} else if err != nil {
+			return offset, err
+		}
+	}
+	// Extend the file in case it ends with a hole.
+	if err := dst.Truncate(offset); err != nil {
+		return offset, err
+	}
+	return offset, nil
+}
+
+func isZero(block []byte) bool {
+	var zeroes [sparseBlockSize]byte
+	return bytes.Equal(block, zeroes[:len(block)])
+}
+
+// fileChecksum returns the hex-encoded SHA-256 checksum of the file at path
+// in a snapshot directory.
+func (manager *Manager) fileChecksum(path string) (string, error) {
+	fd, _, err := openSnapshotFile(manager.Paths, path)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, fd); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
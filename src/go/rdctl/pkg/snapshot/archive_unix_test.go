@@ -0,0 +1,208 @@
+//go:build unix
+
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchive(t *testing.T) {
+	t.Run("Import should recreate an exported snapshot under a new ID", func(t *testing.T) {
+		appPaths, testFiles := populateFiles(t, true)
+		manager := newTestManager(appPaths)
+		original, err := manager.Create(context.Background(), "test-snapshot", "exported", nil)
+		require.NoError(t, err)
+
+		var archive bytes.Buffer
+		require.NoError(t, manager.Export(context.Background(), original.Name, &archive))
+
+		imported, err := manager.Import(context.Background(), &archive, "test-snapshot-imported")
+		require.NoError(t, err)
+		assert.NotEqual(t, original.ID, imported.ID)
+		assert.Equal(t, "exported", imported.Description)
+		assert.Equal(t, original.Created.Unix(), imported.Created.Unix())
+
+		snapshots, err := manager.List(false)
+		require.NoError(t, err)
+		assert.Len(t, snapshots, 2)
+
+		for testFileName, testFile := range testFiles {
+			contents, err := os.ReadFile(filepath.Join(manager.SnapshotDirectory(imported), testFileName))
+			require.NoError(t, err)
+			assert.Equal(t, testFile.Contents, string(contents), "contents of %s", testFileName)
+		}
+		info, err := os.Stat(filepath.Join(manager.SnapshotDirectory(imported), "user"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("Import should keep the labels of an exported snapshot", func(t *testing.T) {
+		appPaths, _ := populateFiles(t, true)
+		manager := newTestManager(appPaths)
+		labels := map[string]string{"team": "qa", LabelContainerEngine: "moby"}
+		_, err := manager.Create(context.Background(), "test-snapshot", "", labels)
+		require.NoError(t, err)
+
+		var archive bytes.Buffer
+		require.NoError(t, manager.Export(context.Background(), "test-snapshot", &archive))
+		imported, err := manager.Import(context.Background(), &archive, "test-snapshot-imported")
+		require.NoError(t, err)
+		assert.Equal(t, labels, imported.Labels)
+
+		reread, err := manager.Snapshot(imported.Name)
+		require.NoError(t, err)
+		assert.Equal(t, labels, reread.Labels)
+	})
+
+	t.Run("Import should refuse names that already exist", func(t *testing.T) {
+		appPaths, _ := populateFiles(t, true)
+		manager := newTestManager(appPaths)
+		_, err := manager.Create(context.Background(), "test-snapshot", "", nil)
+		require.NoError(t, err)
+
+		var archive bytes.Buffer
+		require.NoError(t, manager.Export(context.Background(), "test-snapshot", &archive))
+		_, err = manager.Import(context.Background(), &archive, "")
+		assert.ErrorContains(t, err, "already exists")
+
+		snapshots, err := manager.List(true)
+		require.NoError(t, err)
+		assert.Len(t, snapshots, 1)
+	})
+
+	t.Run("Import should reject archives with mismatched checksums", func(t *testing.T) {
+		appPaths, _ := populateFiles(t, true)
+		manager := newTestManager(appPaths)
+		_, err := manager.Create(context.Background(), "test-snapshot", "", nil)
+		require.NoError(t, err)
+
+		var archive bytes.Buffer
+		require.NoError(t, manager.Export(context.Background(), "test-snapshot", &archive))
+		corrupted := rewriteArchive(t, &archive, func(name string, contents []byte) []byte {
+			if name == "diffdisk" {
+				contents = bytes.ToUpper(contents)
+			}
+			return contents
+		})
+		_, err = manager.Import(context.Background(), corrupted, "test-snapshot-corrupted")
+		assert.ErrorContains(t, err, `checksum mismatch for "diffdisk"`)
+
+		snapshots, err := manager.List(true)
+		require.NoError(t, err)
+		assert.Len(t, snapshots, 1, "the partially imported snapshot should be removed")
+	})
+
+	t.Run("Import should reject unknown format versions", func(t *testing.T) {
+		appPaths, _ := populateFiles(t, true)
+		manager := newTestManager(appPaths)
+		_, err := manager.Create(context.Background(), "test-snapshot", "", nil)
+		require.NoError(t, err)
+
+		var archive bytes.Buffer
+		require.NoError(t, manager.Export(context.Background(), "test-snapshot", &archive))
+		future := rewriteArchive(t, &archive, func(name string, contents []byte) []byte {
+			if name == archiveMetadataName {
+				contents = bytes.Replace(contents, []byte(`"formatVersion": 1`), []byte(`"formatVersion": 99`), 1)
+			}
+			return contents
+		})
+		_, err = manager.Import(context.Background(), future, "test-snapshot-future")
+		assert.ErrorIs(t, err, ErrUnsupportedArchive)
+	})
+
+	t.Run("Import should reject archives with unexpected or missing files", func(t *testing.T) {
+		appPaths, _ := populateFiles(t, true)
+		manager := newTestManager(appPaths)
+		_, err := manager.Create(context.Background(), "test-snapshot", "", nil)
+		require.NoError(t, err)
+
+		var archive bytes.Buffer
+		require.NoError(t, manager.Export(context.Background(), "test-snapshot", &archive))
+		exported := archive.Bytes()
+		renameFile := func(from, to string) io.Reader {
+			return rewriteArchive(t, bytes.NewReader(exported), func(name string, contents []byte) []byte {
+				if name == archiveMetadataName {
+					contents = bytes.Replace(contents, []byte(`"name": "`+from+`"`), []byte(`"name": "`+to+`"`), 1)
+				}
+				return contents
+			})
+		}
+		_, err = manager.Import(context.Background(), renameFile("override.yaml", "extra.yaml"), "test-snapshot-unexpected")
+		assert.ErrorContains(t, err, `unexpected file "extra.yaml"`)
+		_, err = manager.Import(context.Background(), renameFile("user.pub", "override.yaml.bak"), "test-snapshot-missing")
+		assert.ErrorContains(t, err, `archive is missing file "user.pub"`)
+
+		snapshots, err := manager.List(true)
+		require.NoError(t, err)
+		assert.Len(t, snapshots, 1)
+	})
+
+	t.Run("Import should keep disk images sparse", func(t *testing.T) {
+		appPaths, testFiles := populateFiles(t, true)
+		const diskSize = 64 * 1024 * 1024
+		fd, err := os.OpenFile(testFiles["diffdisk"].Path, os.O_WRONLY|os.O_TRUNC, 0o644)
+		require.NoError(t, err)
+		_, err = fd.WriteAt([]byte("data in the middle"), diskSize/2)
+		require.NoError(t, err)
+		require.NoError(t, fd.Truncate(diskSize))
+		require.NoError(t, fd.Close())
+
+		manager := newTestManager(appPaths)
+		_, err = manager.Create(context.Background(), "test-snapshot", "", nil)
+		require.NoError(t, err)
+		var archive bytes.Buffer
+		require.NoError(t, manager.Export(context.Background(), "test-snapshot", &archive))
+		imported, err := manager.Import(context.Background(), &archive, "test-snapshot-imported")
+		require.NoError(t, err)
+
+		info, err := os.Stat(filepath.Join(manager.SnapshotDirectory(imported), "diffdisk"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(diskSize), info.Size())
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		require.True(t, ok)
+		assert.Less(t, stat.Blocks*512, int64(diskSize/2), "imported disk image is not sparse")
+	})
+}
+
+// rewriteArchive decompresses a snapshot archive, passes the contents of each
+// entry through modify, and returns the re-compressed result.
+func rewriteArchive(t *testing.T, archive io.Reader, modify func(name string, contents []byte) []byte) io.Reader {
+	zstdReader, err := zstd.NewReader(archive)
+	require.NoError(t, err)
+	defer zstdReader.Close()
+	tarReader := tar.NewReader(zstdReader)
+
+	var result bytes.Buffer
+	zstdWriter, err := zstd.NewWriter(&result)
+	require.NoError(t, err)
+	tarWriter := tar.NewWriter(zstdWriter)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		contents, err := io.ReadAll(tarReader)
+		require.NoError(t, err)
+		contents = modify(header.Name, contents)
+		header.Size = int64(len(contents))
+		require.NoError(t, tarWriter.WriteHeader(header))
+		_, err = tarWriter.Write(contents)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, zstdWriter.Close())
+	return &result
+}
@@ -0,0 +1,483 @@
+//go:build unix
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// The name of the directory, in the snapshots directory, that holds the
+	// chunks of disk images that could not be cloned.
+	chunkStoreDirName = "chunks"
+	// The suffix of the file, in a snapshot directory, that lists the chunks
+	// a disk image is made of. It is stored in place of the disk image.
+	chunkManifestSuffix = ".chunks"
+	// The size of the chunks disk images are split into. Disk images change
+	// in small scattered writes, so this needs to be small enough that most
+	// chunks are unchanged between snapshots.
+	chunkSize = 4 << 20
+)
+
+// errCloneNotSupported is returned by cloneFile when the filesystem does not
+// support copy-on-write clones.
+var errCloneNotSupported = errors.New("copy-on-write clones are not supported")
+
+// chunkManifest describes a file that is stored in the chunk store.
+type chunkManifest struct {
+	Size      int64 `json:"size"`
+	ChunkSize int64 `json:"chunkSize"`
+	// The hex-encoded SHA-256 checksums of the chunks the file is made of, in
+	// order. Chunks that only contain zeroes are not stored, and have an
+	// empty checksum.
+	Chunks []string `json:"chunks"`
+}
+
+// chunkStore is a content-addressed store of file chunks that is shared by
+// all snapshots. Chunks are removed once no snapshot refers to them.
+type chunkStore struct {
+	dir string
+}
+
+func newChunkStore(appPaths *paths.Paths) chunkStore {
+	return chunkStore{dir: filepath.Join(appPaths.Snapshots, chunkStoreDirName)}
+}
+
+func (store chunkStore) chunkPath(checksum string) string {
+	return filepath.Join(store.dir, checksum[:2], checksum)
+}
+
+// lock takes a lock on the chunk store. Storing files takes a shared lock,
+// and removing unreferenced chunks takes an exclusive one, so that chunks
+// are never removed before the manifest referring to them is written. The
+// returned function releases the lock.
+func (store chunkStore) lock(how int) (func(), error) {
+	if err := os.MkdirAll(store.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create chunk store: %w", err)
+	}
+	fd, err := os.OpenFile(filepath.Join(store.dir, "lock"), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chunk store lock: %w", err)
+	}
+	if err := unix.Flock(int(fd.Fd()), how); err != nil {
+		fd.Close()
+		return nil, fmt.Errorf("failed to lock chunk store: %w", err)
+	}
+	return func() {
+		_ = unix.Flock(int(fd.Fd()), unix.LOCK_UN)
+		fd.Close()
+	}, nil
+}
+
+// storeFile splits the file at src into chunks, adds the ones that are not
+// yet present to the store, and writes the manifest for dst.
+func (store chunkStore) storeFile(ctx context.Context, dst, src string) error {
+	unlock, err := store.lock(unix.LOCK_SH)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	srcFd, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer srcFd.Close()
+	info, err := srcFd.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat source file: %w", err)
+	}
+	manifest := chunkManifest{Size: info.Size(), ChunkSize: chunkSize}
+	buf := make([]byte, chunkSize)
+	for offset := int64(0); offset < manifest.Size; offset += chunkSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chunk := buf[:min(chunkSize, manifest.Size-offset)]
+		if _, err := io.ReadFull(srcFd, chunk); err != nil {
+			return fmt.Errorf("failed to read source file: %w", err)
+		}
+		checksum, err := store.addChunk(chunk)
+		if err != nil {
+			return err
+		}
+		manifest.Chunks = append(manifest.Chunks, checksum)
+	}
+	contents, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chunk manifest: %w", err)
+	}
+	if err := os.WriteFile(dst+chunkManifestSuffix, contents, 0o644); err != nil {
+		return fmt.Errorf("failed to write chunk manifest: %w", err)
+	}
+	return nil
+}
+
+// addChunk adds a chunk to the store, unless it is already present, and
+// returns its checksum. Chunks that only contain zeroes are not stored.
+func (store chunkStore) addChunk(chunk []byte) (string, error) {
+	if isZeroChunk(chunk) {
+		return "", nil
+	}
+	sum := sha256.Sum256(chunk)
+	checksum := hex.EncodeToString(sum[:])
+	chunkPath := store.chunkPath(checksum)
+	// Only reuse an existing chunk if it is intact; otherwise, it is replaced
+	// with the chunk at hand.
+	if _, err := store.readChunk(checksum); err == nil {
+		return checksum, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(chunkPath), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create chunk directory: %w", err)
+	}
+	// Write to a temporary file first, so that a partially written chunk is
+	// never mistaken for a complete one.
+	tempFd, err := os.CreateTemp(filepath.Dir(chunkPath), checksum+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create chunk: %w", err)
+	}
+	defer os.Remove(tempFd.Name())
+	if _, err := tempFd.Write(chunk); err != nil {
+		tempFd.Close()
+		return "", fmt.Errorf("failed to write chunk: %w", err)
+	}
+	if err := tempFd.Close(); err != nil {
+		return "", fmt.Errorf("failed to write chunk: %w", err)
+	}
+	if err := os.Rename(tempFd.Name(), chunkPath); err != nil {
+		return "", fmt.Errorf("failed to write chunk: %w", err)
+	}
+	return checksum, nil
+}
+
+// readManifest reads the manifest of a file stored in the chunk store.
+func readManifest(manifestPath string) (chunkManifest, error) {
+	var manifest chunkManifest
+	contents, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(contents, &manifest); err != nil {
+		return manifest, fmt.Errorf("failed to parse chunk manifest %q: %w", manifestPath, err)
+	}
+	if manifest.ChunkSize <= 0 || int64(len(manifest.Chunks)) != (manifest.Size+manifest.ChunkSize-1)/manifest.ChunkSize {
+		return manifest, fmt.Errorf("invalid chunk manifest %q", manifestPath)
+	}
+	return manifest, nil
+}
+
+// restoreFile reconstructs the file described by the manifest at
+// manifestPath into dst. Zero chunks are left as holes.
+func (store chunkStore) restoreFile(ctx context.Context, dst, manifestPath string, fileMode os.FileMode) error {
+	manifest, err := readManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("failed to create destination parent dir: %w", err)
+	}
+	dstFd, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileMode)
+	if err != nil {
+		return fmt.Errorf("failed to open destination file: %w", err)
+	}
+	defer dstFd.Close()
+	for i, checksum := range manifest.Chunks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if checksum == "" {
+			continue
+		}
+		if _, err := dstFd.Seek(int64(i)*manifest.ChunkSize, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek destination file: %w", err)
+		}
+		contents, err := store.readChunk(checksum)
+		if err != nil {
+			return err
+		}
+		if _, err := dstFd.Write(contents); err != nil {
+			return fmt.Errorf("failed to write destination file: %w", err)
+		}
+	}
+	if err := dstFd.Truncate(manifest.Size); err != nil {
+		return fmt.Errorf("failed to set size of destination file: %w", err)
+	}
+	return nil
+}
+
+// readChunk returns the contents of the chunk with the given checksum,
+// after checking that they still match it.
+func (store chunkStore) readChunk(checksum string) ([]byte, error) {
+	contents, err := os.ReadFile(store.chunkPath(checksum))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("chunk %s is missing from the chunk store", checksum)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read chunk %s: %w", checksum, err)
+	}
+	sum := sha256.Sum256(contents)
+	if hex.EncodeToString(sum[:]) != checksum {
+		return nil, fmt.Errorf("chunk %s is corrupt", checksum)
+	}
+	return contents, nil
+}
+
+// chunkReader reads the contents of a file stored in the chunk store.
+type chunkReader struct {
+	store    chunkStore
+	manifest chunkManifest
+	next     int
+	current  io.Reader
+}
+
+func (reader *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if reader.current != nil {
+			n, err := reader.current.Read(p)
+			if !errors.Is(err, io.EOF) || n > 0 {
+				return n, err
+			}
+			reader.current = nil
+		}
+		if reader.next >= len(reader.manifest.Chunks) {
+			return 0, io.EOF
+		}
+		checksum := reader.manifest.Chunks[reader.next]
+		length := min(reader.manifest.ChunkSize, reader.manifest.Size-int64(reader.next)*reader.manifest.ChunkSize)
+		reader.next++
+		if checksum == "" {
+			reader.current = io.LimitReader(zeroReader{}, length)
+			continue
+		}
+		contents, err := reader.store.readChunk(checksum)
+		if err != nil {
+			return 0, err
+		}
+		if int64(len(contents)) != length {
+			return 0, fmt.Errorf("chunk %s has unexpected size %d", checksum, len(contents))
+		}
+		reader.current = bytes.NewReader(contents)
+	}
+}
+
+func (reader *chunkReader) Close() error {
+	return nil
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func isZeroChunk(chunk []byte) bool {
+	for offset := 0; offset < len(chunk); offset += sparseBlockSize {
+		if !isZero(chunk[offset:min(offset+sparseBlockSize, len(chunk))]) {
+			return false
+		}
+	}
+	return true
+}
+
+// referenceCounts returns, for every chunk, the number of manifests in the
+// snapshots directory that refer to it. Incomplete snapshots are included,
+// as they may still be being created.
+func referenceCounts(snapshotsDir string) (map[string]int, error) {
+	counts := make(map[string]int)
+	manifestPaths, err := filepath.Glob(filepath.Join(snapshotsDir, "*", "*"+chunkManifestSuffix))
+	if err != nil {
+		return nil, err
+	}
+	for _, manifestPath := range manifestPaths {
+		manifest, err := readManifest(manifestPath)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, checksum := range manifest.Chunks {
+			if checksum != "" {
+				counts[checksum]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+// collectGarbage removes the chunks that are no longer referred to by any
+// snapshot.
+func (store chunkStore) collectGarbage(snapshotsDir string) error {
+	if _, err := os.Stat(store.dir); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	unlock, err := store.lock(unix.LOCK_EX)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	counts, err := referenceCounts(snapshotsDir)
+	if err != nil {
+		return fmt.Errorf("failed to count chunk references: %w", err)
+	}
+	var errs []error
+	err = filepath.WalkDir(store.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Dir(path) == store.dir {
+			return err
+		}
+		// Leftover temporary files are removed as well.
+		if checksum := entry.Name(); counts[checksum] == 0 || strings.HasSuffix(checksum, ".tmp") {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to remove unreferenced chunks: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// exclusiveChunkUsage returns the number of bytes used by chunks that are
+// only referred to by the snapshots in snapshotDirs, i.e. the space that is
+// reclaimed when those snapshots are deleted.
+func exclusiveChunkUsage(appPaths *paths.Paths, snapshotDirs ...string) (int64, error) {
+	var manifestPaths []string
+	for _, snapshotDir := range snapshotDirs {
+		matches, err := filepath.Glob(filepath.Join(snapshotDir, "*"+chunkManifestSuffix))
+		if err != nil {
+			return 0, err
+		}
+		manifestPaths = append(manifestPaths, matches...)
+	}
+	if len(manifestPaths) == 0 {
+		return 0, nil
+	}
+	counts, err := referenceCounts(appPaths.Snapshots)
+	if err != nil {
+		return 0, err
+	}
+	own := make(map[string]int)
+	for _, manifestPath := range manifestPaths {
+		manifest, err := readManifest(manifestPath)
+		if err != nil {
+			return 0, err
+		}
+		for _, checksum := range manifest.Chunks {
+			if checksum != "" {
+				own[checksum]++
+			}
+		}
+	}
+	store := newChunkStore(appPaths)
+	var total int64
+	for checksum, count := range own {
+		if counts[checksum] > count {
+			continue
+		}
+		chunkPath := store.chunkPath(checksum)
+		info, err := os.Stat(chunkPath)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		total += diskUsage(chunkPath, info)
+	}
+	return total, nil
+}
+
+// storeCopyOnWriteFile copies a disk image into a snapshot. A clone is used
+// if the filesystem supports it; otherwise, the disk image is added to the
+// chunk store, so that only chunks that changed since earlier snapshots take
+// up space.
+func storeCopyOnWriteFile(ctx context.Context, appPaths *paths.Paths, file snapshotFile) error {
+	err := cloneFile(file.SnapshotPath, file.WorkingPath, file.FileMode)
+	if !errors.Is(err, errCloneNotSupported) {
+		return err
+	}
+	return newChunkStore(appPaths).storeFile(ctx, file.SnapshotPath, file.WorkingPath)
+}
+
+// restoreCopyOnWriteFile restores a disk image stored by
+// storeCopyOnWriteFile.
+func restoreCopyOnWriteFile(ctx context.Context, appPaths *paths.Paths, file snapshotFile) error {
+	manifestPath := file.SnapshotPath + chunkManifestSuffix
+	if _, err := os.Stat(manifestPath); err == nil {
+		return newChunkStore(appPaths).restoreFile(ctx, file.WorkingPath, manifestPath, file.FileMode)
+	}
+	return copyFile(file.WorkingPath, file.SnapshotPath, true, file.FileMode)
+}
+
+// openSnapshotFile opens a file in a snapshot directory for reading, and
+// returns its size. Files in the chunk store are reassembled as they are
+// read.
+func openSnapshotFile(appPaths *paths.Paths, path string) (io.ReadCloser, int64, error) {
+	manifest, err := readManifest(path + chunkManifestSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		fd, err := os.Open(path)
+		if err != nil {
+			return nil, 0, err
+		}
+		info, err := fd.Stat()
+		if err != nil {
+			fd.Close()
+			return nil, 0, err
+		}
+		return fd, info.Size(), nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+	return &chunkReader{store: newChunkStore(appPaths), manifest: manifest}, manifest.Size, nil
+}
+
+// allocatedSnapshotFileSize returns the number of bytes allocated for a file
+// in a snapshot directory, or for a file in its working location. For files
+// in the chunk store, this is the space used by their chunks.
+func allocatedSnapshotFileSize(appPaths *paths.Paths, path string) (int64, error) {
+	manifest, err := readManifest(path + chunkManifestSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, err
+		}
+		return allocatedSize(info), nil
+	} else if err != nil {
+		return 0, err
+	}
+	store := newChunkStore(appPaths)
+	var total int64
+	for _, checksum := range manifest.Chunks {
+		if checksum == "" {
+			continue
+		}
+		info, err := os.Stat(store.chunkPath(checksum))
+		if err != nil {
+			return 0, err
+		}
+		total += allocatedSize(info)
+	}
+	return total, nil
+}
+
+// removeUnreferencedChunks removes the chunks that are no longer referred to
+// by any snapshot.
+func removeUnreferencedChunks(appPaths *paths.Paths) error {
+	return newChunkStore(appPaths).collectGarbage(appPaths.Snapshots)
+}
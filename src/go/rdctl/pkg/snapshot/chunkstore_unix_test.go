@@ -0,0 +1,179 @@
+//go:build unix
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
+)
+
+func TestChunkStore(t *testing.T) {
+	// Returns the number of chunks in the store.
+	countChunks := func(t *testing.T, store chunkStore) int {
+		chunks, err := filepath.Glob(filepath.Join(store.dir, "*", "*"))
+		require.NoError(t, err)
+		return len(chunks)
+	}
+	// Returns disk image contents of three and a half chunks, where the
+	// second chunk is all zeroes.
+	diskContents := func(first, last byte) []byte {
+		contents := make([]byte, 3*chunkSize+chunkSize/2)
+		for i := range chunkSize {
+			contents[i] = first
+		}
+		for i := 2 * chunkSize; i < len(contents); i++ {
+			contents[i] = last
+		}
+		return contents
+	}
+
+	t.Run("should only store changed chunks and remove unreferenced ones", func(t *testing.T) {
+		appPaths := &paths.Paths{Snapshots: filepath.Join(t.TempDir(), "snapshots")}
+		store := newChunkStore(appPaths)
+		workingPath := filepath.Join(t.TempDir(), "diffdisk")
+		var snapshotPaths []string
+		for i, contents := range [][]byte{diskContents('a', 'b'), diskContents('a', 'c')} {
+			require.NoError(t, os.WriteFile(workingPath, contents, 0o644))
+			snapshotDir := filepath.Join(appPaths.Snapshots, []string{"first", "second"}[i])
+			require.NoError(t, os.MkdirAll(snapshotDir, 0o755))
+			snapshotPath := filepath.Join(snapshotDir, "diffdisk")
+			require.NoError(t, store.storeFile(context.Background(), snapshotPath, workingPath))
+			snapshotPaths = append(snapshotPaths, snapshotPath)
+		}
+		// The first chunk is shared; the last two chunks differ, and the
+		// zero chunk is not stored at all.
+		assert.Equal(t, 5, countChunks(t, store))
+
+		require.NoError(t, os.RemoveAll(filepath.Dir(snapshotPaths[0])))
+		require.NoError(t, store.collectGarbage(appPaths.Snapshots))
+		assert.Equal(t, 3, countChunks(t, store))
+
+		restoredPath := filepath.Join(t.TempDir(), "restored")
+		require.NoError(t, store.restoreFile(context.Background(), restoredPath, snapshotPaths[1]+chunkManifestSuffix, 0o644))
+		restored, err := os.ReadFile(restoredPath)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(diskContents('a', 'c'), restored), "restored file does not match")
+
+		reader, size, err := openSnapshotFile(appPaths, snapshotPaths[1])
+		require.NoError(t, err)
+		defer reader.Close()
+		assert.EqualValues(t, len(restored), size)
+		read, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(restored, read), "read file does not match")
+	})
+
+	t.Run("should detect and replace corrupt chunks", func(t *testing.T) {
+		appPaths := &paths.Paths{Snapshots: filepath.Join(t.TempDir(), "snapshots")}
+		store := newChunkStore(appPaths)
+		workingPath := filepath.Join(t.TempDir(), "diffdisk")
+		contents := diskContents('a', 'b')
+		require.NoError(t, os.WriteFile(workingPath, contents, 0o644))
+		snapshotPath := filepath.Join(appPaths.Snapshots, "first", "diffdisk")
+		require.NoError(t, os.MkdirAll(filepath.Dir(snapshotPath), 0o755))
+		require.NoError(t, store.storeFile(context.Background(), snapshotPath, workingPath))
+		manifest, err := readManifest(snapshotPath + chunkManifestSuffix)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(store.chunkPath(manifest.Chunks[0]), []byte("corrupt"), 0o644))
+
+		restoredPath := filepath.Join(t.TempDir(), "restored")
+		err = store.restoreFile(context.Background(), restoredPath, snapshotPath+chunkManifestSuffix, 0o644)
+		assert.ErrorContains(t, err, "is corrupt")
+		reader, _, err := openSnapshotFile(appPaths, snapshotPath)
+		require.NoError(t, err)
+		_, err = io.ReadAll(reader)
+		assert.ErrorContains(t, err, "is corrupt")
+		reader.Close()
+
+		// Storing the same contents again should replace the corrupt chunk.
+		secondPath := filepath.Join(appPaths.Snapshots, "second", "diffdisk")
+		require.NoError(t, os.MkdirAll(filepath.Dir(secondPath), 0o755))
+		require.NoError(t, store.storeFile(context.Background(), secondPath, workingPath))
+		require.NoError(t, store.restoreFile(context.Background(), restoredPath, snapshotPath+chunkManifestSuffix, 0o644))
+		restored, err := os.ReadFile(restoredPath)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(contents, restored), "restored file does not match")
+	})
+
+	t.Run("should report chunks only used by one snapshot", func(t *testing.T) {
+		appPaths := &paths.Paths{Snapshots: filepath.Join(t.TempDir(), "snapshots")}
+		store := newChunkStore(appPaths)
+		workingPath := filepath.Join(t.TempDir(), "diffdisk")
+		for i, contents := range [][]byte{diskContents('a', 'b'), diskContents('a', 'c')} {
+			require.NoError(t, os.WriteFile(workingPath, contents, 0o644))
+			snapshotDir := filepath.Join(appPaths.Snapshots, []string{"first", "second"}[i])
+			require.NoError(t, os.MkdirAll(snapshotDir, 0o755))
+			require.NoError(t, store.storeFile(context.Background(), filepath.Join(snapshotDir, "diffdisk"), workingPath))
+		}
+		usage, err := exclusiveChunkUsage(appPaths, filepath.Join(appPaths.Snapshots, "first"))
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, usage, int64(chunkSize+chunkSize/2))
+		assert.Less(t, usage, int64(3*chunkSize))
+
+		usage, err = exclusiveChunkUsage(appPaths, filepath.Join(appPaths.Snapshots, "first"), filepath.Join(appPaths.Snapshots, "second"))
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, usage, int64(4*chunkSize), "chunks shared by both snapshots should be counted")
+	})
+}
+
+func TestChunkedSnapshots(t *testing.T) {
+	t.Run("Prune should count chunks only shared by pruned snapshots", func(t *testing.T) {
+		appPaths, testFiles := populateFiles(t, true)
+		manager := newTestManager(appPaths)
+		now := time.Now()
+		for i, name := range []string{"first", "second", "third"} {
+			if name == "third" {
+				require.NoError(t, os.WriteFile(testFiles["diffdisk"].Path, []byte("changed diffdisk contents"), 0o644))
+			}
+			snapshot, err := manager.Create(context.Background(), name, "", nil)
+			require.NoError(t, err)
+			snapshot.Created = now.Add(time.Duration(i-3) * time.Hour)
+			require.NoError(t, manager.writeMetadataFile(snapshot))
+		}
+		if manifests, _ := filepath.Glob(filepath.Join(appPaths.Snapshots, "*", "*"+chunkManifestSuffix)); len(manifests) == 0 {
+			t.Skip("the file system supports cloning, so no chunks are stored")
+		}
+
+		result, err := manager.Prune(RetentionPolicy{KeepLast: 1}, now, true)
+		require.NoError(t, err)
+		require.Len(t, result.Removed, 2)
+		var separateUsage int64
+		for _, candidate := range result.Removed {
+			separateUsage += candidate.DiskUsage
+		}
+		assert.Greater(t, result.Reclaimed, separateUsage, "the diffdisk chunk shared by the pruned snapshots should be counted")
+	})
+
+	t.Run("Delete should keep chunks used by other snapshots", func(t *testing.T) {
+		appPaths, testFiles := populateFiles(t, true)
+		manager := newTestManager(appPaths)
+		_, err := manager.Create(context.Background(), "first", "", nil)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(testFiles["diffdisk"].Path, []byte("changed diffdisk contents"), 0o644))
+		_, err = manager.Create(context.Background(), "second", "", nil)
+		require.NoError(t, err)
+		require.NoError(t, manager.Delete("first"))
+
+		require.NoError(t, os.WriteFile(testFiles["basedisk"].Path, []byte("something else"), 0o644))
+		require.NoError(t, manager.Restore(context.Background(), "second", false))
+		for _, name := range []string{"basedisk", "diffdisk"} {
+			contents, err := os.ReadFile(testFiles[name].Path)
+			require.NoError(t, err)
+			expected := testFiles[name].Contents
+			if name == "diffdisk" {
+				expected = "changed diffdisk contents"
+			}
+			assert.Equal(t, expected, string(contents))
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package snapshot
+
+import (
+	"io"
+	"os"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
+)
+
+// On Windows, the WSL distributions are exported as tar files, which do not
+// share blocks between snapshots; there is no chunk store.
+
+// exclusiveChunkUsage returns the number of bytes used by chunks that are
+// only referred to by the snapshots in snapshotDirs.
+func exclusiveChunkUsage(_ *paths.Paths, _ ...string) (int64, error) {
+	return 0, nil
+}
+
+// openSnapshotFile opens a file in a snapshot directory for reading, and
+// returns its size.
+func openSnapshotFile(_ *paths.Paths, path string) (io.ReadCloser, int64, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := fd.Stat()
+	if err != nil {
+		fd.Close()
+		return nil, 0, err
+	}
+	return fd, info.Size(), nil
+}
+
+// allocatedSnapshotFileSize returns the number of bytes allocated for a file
+// in a snapshot directory, or for a file in its working location.
+func allocatedSnapshotFileSize(_ *paths.Paths, path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return allocatedSize(info), nil
+}
+
+// removeUnreferencedChunks removes the chunks that are no longer referred to
+// by any snapshot.
+func removeUnreferencedChunks(_ *paths.Paths) error {
+	return nil
+}
@@ -16,17 +16,9 @@ import (
 // drives, falls back to a plain copy. If copyOnWrite is false, does a
 // plain copy.
 func copyFile(dst, src string, copyOnWrite bool, fileMode os.FileMode) error {
-	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
-		return fmt.Errorf("failed to create destination parent dir: %w", err)
-	}
 	if copyOnWrite {
-		if err := os.RemoveAll(dst); err != nil {
-			return fmt.Errorf("failed to remove existing destination file: %w", err)
-		}
-		if err := unix.Clonefile(src, dst, 0); err == nil {
-			return nil
-		} else if !errors.Is(err, unix.ENOTSUP) && !errors.Is(err, unix.EXDEV) {
-			return fmt.Errorf("failed to clone src to dest: %w", err)
+		if err := cloneFile(dst, src, fileMode); !errors.Is(err, errCloneNotSupported) {
+			return err
 		}
 	}
 	srcFd, err := os.Open(src)
@@ -44,3 +36,21 @@ func copyFile(dst, src string, copyOnWrite bool, fileMode os.FileMode) error {
 	}
 	return nil
 }
+
+// Clones src to dst using the clonefile syscall. Returns
+// errCloneNotSupported if the underlying filesystem does not support it,
+// or src and dst are on different drives.
+func cloneFile(dst, src string, _ os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("failed to create destination parent dir: %w", err)
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return fmt.Errorf("failed to remove existing destination file: %w", err)
+	}
+	if err := unix.Clonefile(src, dst, 0); errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EXDEV) {
+		return errCloneNotSupported
+	} else if err != nil {
+		return fmt.Errorf("failed to clone src to dest: %w", err)
+	}
+	return nil
+}
@@ -16,6 +16,11 @@ import (
 // copyOnWrite is false, does a plain copy. fileMode specifies the
 // permissions that are applied to the destination file.
 func copyFile(dst, src string, copyOnWrite bool, fileMode os.FileMode) error {
+	if copyOnWrite {
+		if err := cloneFile(dst, src, fileMode); !errors.Is(err, errCloneNotSupported) {
+			return err
+		}
+	}
 	srcFd, err := os.Open(src)
 	if err != nil {
 		return fmt.Errorf("failed to open source file: %w", err)
@@ -29,15 +34,36 @@ func copyFile(dst, src string, copyOnWrite bool, fileMode os.FileMode) error {
 		return fmt.Errorf("failed to open destination file: %w", err)
 	}
 	defer dstFd.Close()
-	if copyOnWrite {
-		if err := unix.IoctlFileClone(int(dstFd.Fd()), int(srcFd.Fd())); err == nil {
-			return nil
-		} else if !errors.Is(err, unix.ENOTSUP) {
-			return fmt.Errorf("failed to ioctl_ficlone file: %w", err)
-		}
-	}
 	if _, err := io.Copy(dstFd, srcFd); err != nil {
 		return fmt.Errorf("failed to copy contents of src to dst: %w", err)
 	}
 	return nil
 }
+
+// Clones src to dst using ioctl FICLONE. Returns errCloneNotSupported if
+// the underlying filesystem does not support it, or if src and dst are on
+// different filesystems (EXDEV) or the filesystem refuses to clone them
+// (EINVAL, e.g. for mismatched block alignment).
+func cloneFile(dst, src string, fileMode os.FileMode) error {
+	srcFd, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer srcFd.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("failed to create destination parent dir: %w", err)
+	}
+	dstFd, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileMode)
+	if err != nil {
+		return fmt.Errorf("failed to open destination file: %w", err)
+	}
+	defer dstFd.Close()
+	if err := unix.IoctlFileClone(int(dstFd.Fd()), int(srcFd.Fd())); errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EXDEV) || errors.Is(err, unix.EINVAL) {
+		_ = dstFd.Close()
+		_ = os.Remove(dst)
+		return errCloneNotSupported
+	} else if err != nil {
+		return fmt.Errorf("failed to ioctl_ficlone file: %w", err)
+	}
+	return nil
+}
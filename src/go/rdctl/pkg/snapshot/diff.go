@@ -0,0 +1,262 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChangeKind describes how a single setting differs between two states.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change is a difference in a single value of a structured file.
+type Change struct {
+	// The path of the value, such as "kubernetes.version" or
+	// "containerEngine.allowedImages.patterns[0]".
+	Path string     `json:"path"`
+	Kind ChangeKind `json:"kind"`
+	Old  any        `json:"old,omitempty"`
+	New  any        `json:"new,omitempty"`
+}
+
+// FileDiff describes the differences in a settings or configuration file.
+type FileDiff struct {
+	Name string `json:"name"`
+	// Whether the file is present in the states being compared.
+	OldPresent bool     `json:"oldPresent"`
+	NewPresent bool     `json:"newPresent"`
+	Changes    []Change `json:"changes,omitempty"`
+}
+
+// DiskDiff describes the difference in size of a disk image. Sizes are the
+// space allocated on disk, and are nil if they are not known, e.g. because
+// the image is not present.
+type DiskDiff struct {
+	Name    string `json:"name"`
+	OldSize *int64 `json:"oldSize,omitempty"`
+	NewSize *int64 `json:"newSize,omitempty"`
+}
+
+// Delta returns the change in size of the disk image, and whether it is
+// known.
+func (diff DiskDiff) Delta() (int64, bool) {
+	if diff.OldSize == nil || diff.NewSize == nil {
+		return 0, false
+	}
+	return *diff.NewSize - *diff.OldSize, true
+}
+
+// Diff describes the differences between two snapshots, or between a
+// snapshot and the current state. An empty name stands for the current
+// state.
+type Diff struct {
+	From  string     `json:"from"`
+	To    string     `json:"to"`
+	Files []FileDiff `json:"files"`
+	Disks []DiskDiff `json:"disks"`
+}
+
+// HasChanges returns whether any of the files or disk images differ.
+func (diff Diff) HasChanges() bool {
+	for _, file := range diff.Files {
+		if len(file.Changes) > 0 || file.OldPresent != file.NewPresent {
+			return true
+		}
+	}
+	for _, disk := range diff.Disks {
+		if delta, ok := disk.Delta(); !ok || delta != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff compares the files of two snapshots. A nil snapshot stands for the
+// current state of Rancher Desktop, so that Diff(nil, &snapshot) describes
+// what restoring snapshot would change. settings.json and YAML files are
+// compared value by value; for disk images, only the sizes are compared.
+// No lock is taken.
+func (manager *Manager) Diff(from, to *Snapshot) (Diff, error) {
+	var diff Diff
+	if from != nil {
+		diff.From = from.Name
+	}
+	if to != nil {
+		diff.To = to.Name
+	}
+	fromFiles := manager.diffFiles(from)
+	toFiles := manager.diffFiles(to)
+	for i, file := range manager.Files(manager.Paths, "") {
+		name := filepath.Base(file.SnapshotPath)
+		fromPath, toPath := fromFiles[i], toFiles[i]
+		if file.CopyOnWrite || strings.HasSuffix(name, ".tar") {
+			disk := DiskDiff{Name: name}
+			var err error
+			if disk.OldSize, err = manager.fileSize(fromPath); err != nil {
+				return diff, err
+			}
+			if disk.NewSize, err = manager.fileSize(toPath); err != nil {
+				return diff, err
+			}
+			diff.Disks = append(diff.Disks, disk)
+			continue
+		}
+		var unmarshal func([]byte, any) error
+		switch filepath.Ext(name) {
+		case ".json":
+			unmarshal = json.Unmarshal
+		case ".yaml":
+			unmarshal = yaml.Unmarshal
+		default:
+			continue
+		}
+		fileDiff := FileDiff{Name: name}
+		oldValue, oldPresent, err := readStructuredFile(fromPath, unmarshal)
+		if err != nil {
+			return diff, err
+		}
+		newValue, newPresent, err := readStructuredFile(toPath, unmarshal)
+		if err != nil {
+			return diff, err
+		}
+		if !oldPresent && !newPresent {
+			continue
+		}
+		fileDiff.OldPresent, fileDiff.NewPresent = oldPresent, newPresent
+		// A missing file is treated as empty, so that every value of the file
+		// that is present is listed.
+		if !oldPresent {
+			oldValue = map[string]any{}
+		}
+		if !newPresent {
+			newValue = map[string]any{}
+		}
+		fileDiff.Changes = diffValues("", oldValue, newValue)
+		diff.Files = append(diff.Files, fileDiff)
+	}
+	return diff, nil
+}
+
+// diffFiles returns the paths of the files of a snapshot, in the order
+// returned by Files. For the current state (a nil snapshot), these are the
+// working paths; they are empty for files that have no working path.
+func (manager *Manager) diffFiles(snapshot *Snapshot) []string {
+	var result []string
+	if snapshot == nil {
+		for _, file := range manager.Files(manager.Paths, "") {
+			result = append(result, file.WorkingPath)
+		}
+		return result
+	}
+	for _, file := range manager.Files(manager.Paths, manager.SnapshotDirectory(*snapshot)) {
+		result = append(result, file.SnapshotPath)
+	}
+	return result
+}
+
+// fileSize returns the number of bytes allocated for the file at path, or
+// nil if the path is empty or the file does not exist. The allocated size is
+// used rather than the apparent size, as disk images are sparse files whose
+// apparent size rarely changes.
+func (manager *Manager) fileSize(path string) (*int64, error) {
+	if path == "" {
+		return nil, nil
+	}
+	size, err := allocatedSnapshotFileSize(manager.Paths, path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to determine size of %q: %w", path, err)
+	}
+	return &size, nil
+}
+
+// readStructuredFile reads and parses the file at path. It returns false if
+// the file does not exist.
+func readStructuredFile(path string, unmarshal func([]byte, any) error) (any, bool, error) {
+	if path == "" {
+		return nil, false, nil
+	}
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, fmt.Errorf("failed to read %q: %w", path, err)
+	}
+	var value any
+	if err := unmarshal(contents, &value); err != nil {
+		return nil, false, fmt.Errorf("failed to parse %q: %w", path, err)
+	}
+	return value, true, nil
+}
+
+// diffValues returns the differences between two decoded JSON or YAML
+// values, recursing into objects and arrays.
+func diffValues(path string, oldValue, newValue any) []Change {
+	oldMap, oldIsMap := oldValue.(map[string]any)
+	newMap, newIsMap := newValue.(map[string]any)
+	if oldIsMap && newIsMap {
+		keys := make([]string, 0, len(oldMap)+len(newMap))
+		for key := range oldMap {
+			keys = append(keys, key)
+		}
+		for key := range newMap {
+			if _, ok := oldMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+		var changes []Change
+		for _, key := range keys {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			oldChild, oldOk := oldMap[key]
+			newChild, newOk := newMap[key]
+			switch {
+			case !oldOk:
+				changes = append(changes, Change{Path: childPath, Kind: ChangeAdded, New: newChild})
+			case !newOk:
+				changes = append(changes, Change{Path: childPath, Kind: ChangeRemoved, Old: oldChild})
+			default:
+				changes = append(changes, diffValues(childPath, oldChild, newChild)...)
+			}
+		}
+		return changes
+	}
+	oldList, oldIsList := oldValue.([]any)
+	newList, newIsList := newValue.([]any)
+	if oldIsList && newIsList {
+		var changes []Change
+		for i := range max(len(oldList), len(newList)) {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(oldList):
+				changes = append(changes, Change{Path: childPath, Kind: ChangeAdded, New: newList[i]})
+			case i >= len(newList):
+				changes = append(changes, Change{Path: childPath, Kind: ChangeRemoved, Old: oldList[i]})
+			default:
+				changes = append(changes, diffValues(childPath, oldList[i], newList[i])...)
+			}
+		}
+		return changes
+	}
+	if reflect.DeepEqual(oldValue, newValue) {
+		return nil
+	}
+	return []Change{{Path: path, Kind: ChangeChanged, Old: oldValue, New: newValue}}
+}
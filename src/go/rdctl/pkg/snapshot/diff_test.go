@@ -0,0 +1,57 @@
+package snapshot
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffValues(t *testing.T) {
+	oldValue := map[string]any{
+		"kubernetes": map[string]any{"enabled": true, "version": "1.29.0"},
+		"removed":    "value",
+		"list":       []any{"a", "b"},
+	}
+	newValue := map[string]any{
+		"kubernetes": map[string]any{"enabled": true, "version": "1.30.2"},
+		"added":      1.0,
+		"list":       []any{"a", "c", "d"},
+	}
+	expected := []Change{
+		{Path: "added", Kind: ChangeAdded, New: 1.0},
+		{Path: "kubernetes.version", Kind: ChangeChanged, Old: "1.29.0", New: "1.30.2"},
+		{Path: "list[1]", Kind: ChangeChanged, Old: "b", New: "c"},
+		{Path: "list[2]", Kind: ChangeAdded, New: "d"},
+		{Path: "removed", Kind: ChangeRemoved, Old: "value"},
+	}
+	assert.Equal(t, expected, diffValues("", oldValue, newValue))
+	assert.Empty(t, diffValues("", oldValue, oldValue))
+}
+
+func TestDiff(t *testing.T) {
+	t.Run("PreviewRestore should describe the changes a restore would make", func(t *testing.T) {
+		appPaths, testFiles := populateFiles(t, true)
+		settingsPath := testFiles["settings.json"].Path
+		require.NoError(t, os.WriteFile(settingsPath, []byte(`{"kubernetes": {"version": "1.29.0"}}`), 0o644))
+		manager := newTestManager(appPaths)
+		_, err := manager.Create(context.Background(), "test-snapshot", "", nil)
+		require.NoError(t, err)
+
+		diff, err := manager.PreviewRestore("test-snapshot")
+		require.NoError(t, err)
+		assert.False(t, diff.HasChanges(), "unexpected changes: %+v", diff)
+
+		require.NoError(t, os.WriteFile(settingsPath, []byte(`{"kubernetes": {"version": "1.30.2"}}`), 0o644))
+		diff, err = manager.PreviewRestore("test-snapshot")
+		require.NoError(t, err)
+		assert.Empty(t, diff.From)
+		assert.Equal(t, "test-snapshot", diff.To)
+		assert.True(t, diff.HasChanges())
+		require.NotEmpty(t, diff.Files)
+		assert.Equal(t, "settings.json", diff.Files[0].Name)
+		assert.Equal(t, []Change{{Path: "kubernetes.version", Kind: ChangeChanged, Old: "1.30.2", New: "1.29.0"}}, diff.Files[0].Changes)
+	})
+}
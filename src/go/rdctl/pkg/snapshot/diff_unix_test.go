@@ -0,0 +1,49 @@
+//go:build unix
+
+package snapshot
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffSparseDisks(t *testing.T) {
+	appPaths, testFiles := populateFiles(t, true)
+	const diskSize = 64 * 1024 * 1024
+	diskPath := testFiles["diffdisk"].Path
+	writeAt := func(offset int64) {
+		fd, err := os.OpenFile(diskPath, os.O_WRONLY, 0o644)
+		require.NoError(t, err)
+		_, err = fd.WriteAt([]byte("data"), offset)
+		require.NoError(t, err)
+		require.NoError(t, fd.Truncate(diskSize))
+		require.NoError(t, fd.Close())
+	}
+	writeAt(0)
+	manager := newTestManager(appPaths)
+	before, err := manager.Create(context.Background(), "before", "", nil)
+	require.NoError(t, err)
+	// The apparent size of the disk image stays the same, but more of it is
+	// allocated.
+	writeAt(diskSize / 2)
+	after, err := manager.Create(context.Background(), "after", "", nil)
+	require.NoError(t, err)
+
+	diff, err := manager.Diff(&before, &after)
+	require.NoError(t, err)
+	var disk *DiskDiff
+	for i := range diff.Disks {
+		if diff.Disks[i].Name == "diffdisk" {
+			disk = &diff.Disks[i]
+		}
+	}
+	require.NotNil(t, disk)
+	delta, ok := disk.Delta()
+	require.True(t, ok)
+	assert.Positive(t, delta)
+	assert.Less(t, *disk.NewSize, int64(diskSize/2))
+}
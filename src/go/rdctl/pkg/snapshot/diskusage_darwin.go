@@ -0,0 +1,49 @@
+package snapshot
+
+import (
+	"encoding/binary"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ATTR_CMNEXT_PRIVATESIZE from sys/attr.h, which golang.org/x/sys does not
+// provide.
+const attrCmnExtPrivateSize = 0x00000008
+
+// unsharedDiskUsage returns the number of bytes allocated for the file at
+// path that are not shared with other files (e.g. through clonefile), as
+// reported by APFS.
+func unsharedDiskUsage(path string) (int64, error) {
+	pathBytes, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	attrList := unix.Attrlist{
+		Bitmapcount: unix.ATTR_BIT_MAP_COUNT,
+		Forkattr:    attrCmnExtPrivateSize,
+	}
+	// The result is a uint32 length followed by the off_t private size; the
+	// attributes are packed with 4-byte alignment.
+	var buf [16]byte
+	_, _, errno := unix.Syscall6(unix.SYS_GETATTRLIST,
+		uintptr(unsafe.Pointer(pathBytes)),
+		uintptr(unsafe.Pointer(&attrList)),
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(len(buf)),
+		uintptr(unix.FSOPT_ATTR_CMN_EXTENDED|unix.FSOPT_NOFOLLOW),
+		0)
+	if errno != 0 {
+		return 0, errno
+	}
+	if binary.NativeEndian.Uint32(buf[0:4]) < 12 {
+		return 0, unix.ENOTSUP
+	}
+	return int64(binary.NativeEndian.Uint64(buf[4:12])), nil
+}
+
+// unsharedFilesDiskUsage is not supported, as APFS does not report which
+// files share blocks.
+func unsharedFilesDiskUsage(_, _ []string) (int64, error) {
+	return 0, unix.ENOTSUP
+}
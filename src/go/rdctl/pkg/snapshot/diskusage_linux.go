@@ -0,0 +1,177 @@
+package snapshot
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"os"
+	"slices"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Definitions from linux/fiemap.h and linux/fs.h, which golang.org/x/sys
+// does not provide.
+const (
+	fsIocFiemap        = 0xC020660B // _IOWR('f', 11, struct fiemap)
+	fiemapFlagSync     = 0x0001
+	fiemapExtentLast   = 0x0001
+	fiemapExtentShared = 0x2000
+	// The number of extents requested per ioctl call.
+	fiemapExtentCount = 256
+)
+
+type fiemapExtent struct {
+	Logical  uint64
+	Physical uint64
+	Length   uint64
+	_        [2]uint64
+	Flags    uint32
+	_        [3]uint32
+}
+
+type fiemap struct {
+	Start         uint64
+	Length        uint64
+	Flags         uint32
+	MappedExtents uint32
+	ExtentCount   uint32
+	_             uint32
+	Extents       [fiemapExtentCount]fiemapExtent
+}
+
+// unsharedDiskUsage returns the number of bytes in the extents of the file
+// at path that are not shared with other files (e.g. through FICLONE).
+func unsharedDiskUsage(path string) (int64, error) {
+	var total int64
+	err := walkExtents(path, func(extent fiemapExtent) {
+		if extent.Flags&fiemapExtentShared == 0 {
+			total += int64(extent.Length)
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// physicalRange is a range of bytes on the underlying device, from start
+// (inclusive) to end (exclusive).
+type physicalRange struct {
+	start, end uint64
+}
+
+// unsharedFilesDiskUsage returns the number of bytes in the extents of the
+// files in removed that are not shared with any of the files in retained.
+// Extents shared between files in removed are only counted once.
+func unsharedFilesDiskUsage(removed, retained []string) (int64, error) {
+	var total int64
+	var shared []physicalRange
+	for _, path := range removed {
+		err := walkExtents(path, func(extent fiemapExtent) {
+			if extent.Flags&fiemapExtentShared == 0 {
+				total += int64(extent.Length)
+			} else {
+				shared = append(shared, physicalRange{extent.Physical, extent.Physical + extent.Length})
+			}
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	if len(shared) == 0 {
+		return total, nil
+	}
+	var kept []physicalRange
+	for _, path := range retained {
+		err := walkExtents(path, func(extent fiemapExtent) {
+			if extent.Flags&fiemapExtentShared != 0 {
+				kept = append(kept, physicalRange{extent.Physical, extent.Physical + extent.Length})
+			}
+		})
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	for _, r := range subtractRanges(mergeRanges(shared), mergeRanges(kept)) {
+		total += int64(r.end - r.start)
+	}
+	return total, nil
+}
+
+// mergeRanges sorts ranges and merges the ones that overlap.
+func mergeRanges(ranges []physicalRange) []physicalRange {
+	slices.SortFunc(ranges, func(a, b physicalRange) int {
+		return cmp.Compare(a.start, b.start)
+	})
+	var merged []physicalRange
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r.start <= merged[n-1].end {
+			merged[n-1].end = max(merged[n-1].end, r.end)
+		} else {
+			merged = append(merged, r)
+		}
+	}
+	return merged
+}
+
+// subtractRanges returns the parts of ranges that are not covered by
+// removed; both must be sorted and merged.
+func subtractRanges(ranges, removed []physicalRange) []physicalRange {
+	var result []physicalRange
+	for _, r := range ranges {
+		for len(removed) > 0 && removed[0].end <= r.start {
+			removed = removed[1:]
+		}
+		start := r.start
+		for _, cut := range removed {
+			if cut.start >= r.end {
+				break
+			}
+			if cut.start > start {
+				result = append(result, physicalRange{start, cut.start})
+			}
+			start = max(start, cut.end)
+		}
+		if start < r.end {
+			result = append(result, physicalRange{start, r.end})
+		}
+	}
+	return result
+}
+
+// walkExtents calls fn with each of the extents of the file at path.
+func walkExtents(path string, fn func(extent fiemapExtent)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	request := &fiemap{}
+	var start uint64
+	for {
+		*request = fiemap{
+			Start:       start,
+			Length:      math.MaxUint64 - start,
+			Flags:       fiemapFlagSync,
+			ExtentCount: fiemapExtentCount,
+		}
+		_, _, errno := unix.Syscall(unix.SYS_IOCTL, file.Fd(), fsIocFiemap, uintptr(unsafe.Pointer(request)))
+		if errno != 0 {
+			return errno
+		}
+		if request.MappedExtents == 0 {
+			return nil
+		}
+		for _, extent := range request.Extents[:request.MappedExtents] {
+			fn(extent)
+			if extent.Flags&fiemapExtentLast != 0 {
+				return nil
+			}
+			start = extent.Logical + extent.Length
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtractRanges(t *testing.T) {
+	ranges := mergeRanges([]physicalRange{{40, 50}, {0, 10}, {5, 20}})
+	assert.Equal(t, []physicalRange{{0, 20}, {40, 50}}, ranges)
+	removed := mergeRanges([]physicalRange{{15, 42}, {2, 4}})
+	assert.Equal(t, []physicalRange{{0, 2}, {4, 15}, {42, 50}}, subtractRanges(ranges, removed))
+	assert.Equal(t, ranges, subtractRanges(ranges, nil))
+}
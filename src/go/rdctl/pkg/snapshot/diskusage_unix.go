@@ -0,0 +1,50 @@
+//go:build unix
+
+package snapshot
+
+import (
+	"os"
+	"syscall"
+)
+
+// diskUsage returns the number of bytes allocated for the file at path that
+// are not shared with other files. This is less than its size for sparse
+// files, and for copy-on-write clones where blocks are still shared with the
+// file they were cloned from. If the filesystem can't tell which blocks are
+// shared, all allocated blocks are counted.
+func diskUsage(path string, info os.FileInfo) int64 {
+	if usage, err := unsharedDiskUsage(path); err == nil {
+		return usage
+	}
+	return allocatedSize(info)
+}
+
+// filesDiskUsage returns the number of bytes that are freed by removing the
+// files in removed while keeping the ones in retained. Unlike the sum of
+// their diskUsage, this includes blocks the removed files only share with
+// each other, if the filesystem can tell which blocks are shared.
+func filesDiskUsage(removed, retained []string) (int64, error) {
+	if usage, err := unsharedFilesDiskUsage(removed, retained); err == nil {
+		return usage, nil
+	}
+	var total int64
+	for _, path := range removed {
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, err
+		}
+		total += diskUsage(path, info)
+	}
+	return total, nil
+}
+
+// allocatedSize returns the number of bytes allocated for a file, including
+// blocks shared with other files. This is less than its size for sparse
+// files.
+func allocatedSize(info os.FileInfo) int64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		// st_blocks is always in units of 512 bytes.
+		return int64(stat.Blocks) * 512
+	}
+	return info.Size()
+}
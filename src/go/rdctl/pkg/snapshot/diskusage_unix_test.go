@@ -0,0 +1,33 @@
+//go:build unix
+
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskUsage(t *testing.T) {
+	const fileSize = 64 * 1024 * 1024
+	path := filepath.Join(t.TempDir(), "diffdisk")
+	fd, err := os.Create(path)
+	require.NoError(t, err)
+	data := make([]byte, 1024*1024)
+	for i := range data {
+		data[i] = 1
+	}
+	_, err = fd.WriteAt(data, fileSize/2)
+	require.NoError(t, err)
+	require.NoError(t, fd.Truncate(fileSize))
+	require.NoError(t, fd.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	usage := diskUsage(path, info)
+	assert.GreaterOrEqual(t, usage, int64(len(data)), "written data should be counted")
+	assert.Less(t, usage, int64(fileSize/2), "holes should not be counted")
+}
@@ -0,0 +1,32 @@
+package snapshot
+
+import (
+	"os"
+)
+
+// diskUsage returns the number of bytes used by a file. On Windows, snapshots
+// consist of exported WSL distros, which are not sparse, so this is the same
+// as the file size.
+func diskUsage(_ string, info os.FileInfo) int64 {
+	return info.Size()
+}
+
+// filesDiskUsage returns the number of bytes that are freed by removing the
+// files in removed; as above, nothing is shared, so this is their total size.
+func filesDiskUsage(removed, _ []string) (int64, error) {
+	var total int64
+	for _, path := range removed {
+		info, err := os.Stat(path)
+		if err != nil {
+			return 0, err
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
+// allocatedSize returns the number of bytes allocated for a file; as above,
+// this is the same as the file size.
+func allocatedSize(info os.FileInfo) int64 {
+	return info.Size()
+}
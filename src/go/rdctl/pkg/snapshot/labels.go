@@ -0,0 +1,216 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	// The label recording the Kubernetes version in use when the snapshot
+	// was created; it is not set if Kubernetes was disabled.
+	LabelKubernetesVersion = "k8s"
+	// The label recording the container engine in use when the snapshot
+	// was created.
+	LabelContainerEngine = "engine"
+)
+
+const maxLabelValueLength = 250
+
+var labelKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_./]{0,61}[A-Za-z0-9])?$`)
+
+func validateLabel(key, value string) error {
+	if !labelKeyRegexp.MatchString(key) {
+		return fmt.Errorf("invalid label key %q: must be at most 63 alphanumeric characters, '-', '_', '.' or '/', starting and ending with an alphanumeric character", key)
+	}
+	if len([]rune(value)) > maxLabelValueLength {
+		return fmt.Errorf("invalid value for label %q: max length is %d", key, maxLabelValueLength)
+	}
+	for idx, c := range value {
+		if !unicode.IsPrint(c) {
+			return fmt.Errorf("invalid character %q at position %d in value for label %q: all characters must be printable or a space", c, idx, key)
+		}
+	}
+	return nil
+}
+
+// ValidateLabels checks that all of the given labels have valid keys and
+// values.
+func ValidateLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if err := validateLabel(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseLabels parses label arguments of the form "key=value" (which sets a
+// label) and "key-" (which removes one).
+func ParseLabels(args []string) (set map[string]string, remove []string, err error) {
+	set = make(map[string]string)
+	for _, arg := range args {
+		if key, value, ok := strings.Cut(arg, "="); ok {
+			if err := validateLabel(key, value); err != nil {
+				return nil, nil, err
+			}
+			set[key] = value
+		} else if key, ok := strings.CutSuffix(arg, "-"); ok {
+			if err := validateLabel(key, ""); err != nil {
+				return nil, nil, err
+			}
+			remove = append(remove, key)
+		} else {
+			return nil, nil, fmt.Errorf("invalid label %q: must be of the form key=value or key-", arg)
+		}
+	}
+	return set, remove, nil
+}
+
+// settingsLabels returns the labels that are derived from the settings.json
+// file at settingsPath.
+func settingsLabels(settingsPath string) (map[string]string, error) {
+	contents, err := os.ReadFile(settingsPath)
+	if err != nil {
+		return nil, err
+	}
+	var settings struct {
+		ContainerEngine struct {
+			Name string `json:"name"`
+		} `json:"containerEngine"`
+		Kubernetes struct {
+			Enabled bool   `json:"enabled"`
+			Version string `json:"version"`
+		} `json:"kubernetes"`
+	}
+	if err := json.Unmarshal(contents, &settings); err != nil {
+		return nil, err
+	}
+	labels := make(map[string]string)
+	if settings.ContainerEngine.Name != "" {
+		labels[LabelContainerEngine] = settings.ContainerEngine.Name
+	}
+	if settings.Kubernetes.Enabled && settings.Kubernetes.Version != "" {
+		labels[LabelKubernetesVersion] = settings.Kubernetes.Version
+	}
+	return labels, nil
+}
+
+// SetLabels sets and removes labels on an existing snapshot.
+func (manager *Manager) SetLabels(ctx context.Context, name string, set map[string]string, remove []string) (snapshot Snapshot, err error) {
+	if err := ValidateLabels(set); err != nil {
+		return snapshot, err
+	}
+	action := fmt.Sprintf("Labelling snapshot %q", name)
+	// Labels only live in the snapshot metadata, so the VM can keep running.
+	if err := manager.LockStore(ctx, manager.Paths, action); err != nil {
+		return snapshot, err
+	}
+	defer func() {
+		unlockErr := manager.Unlock(ctx, manager.Paths, false)
+		if err == nil {
+			err = unlockErr
+		}
+	}()
+	// Read the metadata while holding the lock, so that concurrent changes
+	// are not lost.
+	snapshot, err = manager.Snapshot(name)
+	if err != nil {
+		return snapshot, err
+	}
+	if snapshot.Labels == nil {
+		snapshot.Labels = make(map[string]string)
+	}
+	for _, key := range remove {
+		delete(snapshot.Labels, key)
+	}
+	for key, value := range set {
+		snapshot.Labels[key] = value
+	}
+	if len(snapshot.Labels) == 0 {
+		snapshot.Labels = nil
+	}
+	err = manager.writeMetadataFile(snapshot)
+	return snapshot, err
+}
+
+// selectorOperator is the kind of test a selector requirement performs.
+type selectorOperator int
+
+const (
+	selectorEquals selectorOperator = iota
+	selectorNotEquals
+	selectorExists
+	selectorNotExists
+)
+
+type selectorRequirement struct {
+	key      string
+	operator selectorOperator
+	value    string
+}
+
+// Selector filters snapshots by their labels. The zero value matches all
+// snapshots.
+type Selector struct {
+	requirements []selectorRequirement
+}
+
+// ParseSelector parses a comma-separated list of label requirements, each
+// of which is one of "key=value", "key==value", "key!=value", "key" (the
+// label is set) or "!key" (the label is not set).
+func ParseSelector(input string) (Selector, error) {
+	var selector Selector
+	if strings.TrimSpace(input) == "" {
+		return selector, nil
+	}
+	for term := range strings.SplitSeq(input, ",") {
+		term = strings.TrimSpace(term)
+		var requirement selectorRequirement
+		if key, value, ok := strings.Cut(term, "!="); ok {
+			requirement = selectorRequirement{key: key, operator: selectorNotEquals, value: value}
+		} else if key, value, ok := strings.Cut(term, "=="); ok {
+			requirement = selectorRequirement{key: key, operator: selectorEquals, value: value}
+		} else if key, value, ok := strings.Cut(term, "="); ok {
+			requirement = selectorRequirement{key: key, operator: selectorEquals, value: value}
+		} else if key, ok := strings.CutPrefix(term, "!"); ok {
+			requirement = selectorRequirement{key: key, operator: selectorNotExists}
+		} else {
+			requirement = selectorRequirement{key: term, operator: selectorExists}
+		}
+		requirement.key = strings.TrimSpace(requirement.key)
+		requirement.value = strings.TrimSpace(requirement.value)
+		if err := validateLabel(requirement.key, requirement.value); err != nil {
+			return selector, fmt.Errorf("invalid selector %q: %w", term, err)
+		}
+		selector.requirements = append(selector.requirements, requirement)
+	}
+	return selector, nil
+}
+
+// Matches returns whether the given snapshot satisfies all of the
+// requirements of the selector.
+func (selector Selector) Matches(snapshot Snapshot) bool {
+	for _, requirement := range selector.requirements {
+		value, ok := snapshot.Labels[requirement.key]
+		var matched bool
+		switch requirement.operator {
+		case selectorEquals:
+			matched = ok && value == requirement.value
+		case selectorNotEquals:
+			matched = !ok || value != requirement.value
+		case selectorExists:
+			matched = ok
+		case selectorNotExists:
+			matched = !ok
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
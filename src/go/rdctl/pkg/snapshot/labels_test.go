@@ -0,0 +1,82 @@
+package snapshot
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabels(t *testing.T) {
+	set, remove, err := ParseLabels([]string{"k8s=1.30", "ticket=ABC-123", "empty=", "old-"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"k8s": "1.30", "ticket": "ABC-123", "empty": ""}, set)
+	assert.Equal(t, []string{"old"}, remove)
+
+	for _, invalid := range []string{"no-separator", "=value", "-bad=key", "key=tab\tvalue"} {
+		_, _, err := ParseLabels([]string{invalid})
+		assert.Error(t, err, "label %q should be invalid", invalid)
+	}
+}
+
+func TestSelector(t *testing.T) {
+	snapshot := Snapshot{Labels: map[string]string{"engine": "moby", "k8s": "1.30"}}
+	testCases := []struct {
+		Selector string
+		Matches  bool
+	}{
+		{Selector: "", Matches: true},
+		{Selector: "engine=moby", Matches: true},
+		{Selector: "engine==moby", Matches: true},
+		{Selector: "engine=containerd", Matches: false},
+		{Selector: "engine!=containerd", Matches: true},
+		{Selector: "ticket!=ABC-123", Matches: true},
+		{Selector: "k8s", Matches: true},
+		{Selector: "ticket", Matches: false},
+		{Selector: "!ticket", Matches: true},
+		{Selector: "!k8s", Matches: false},
+		{Selector: "engine=moby, k8s=1.30", Matches: true},
+		{Selector: "engine=moby,k8s=1.29", Matches: false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Selector, func(t *testing.T) {
+			selector, err := ParseSelector(testCase.Selector)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.Matches, selector.Matches(snapshot))
+		})
+	}
+
+	_, err := ParseSelector("engine=moby,,k8s")
+	assert.Error(t, err)
+}
+
+func TestLabels(t *testing.T) {
+	t.Run("Create should add labels derived from settings", func(t *testing.T) {
+		appPaths, testFiles := populateFiles(t, true)
+		settings := `{"containerEngine": {"name": "moby"}, "kubernetes": {"enabled": true, "version": "1.30.2"}}`
+		require.NoError(t, os.WriteFile(testFiles["settings.json"].Path, []byte(settings), 0o644))
+		manager := newTestManager(appPaths)
+		snapshot, err := manager.Create(context.Background(), "test-snapshot", "", map[string]string{"ticket": "ABC-123", "engine": "override"})
+		require.NoError(t, err)
+		expected := map[string]string{"engine": "override", "k8s": "1.30.2", "ticket": "ABC-123"}
+		assert.Equal(t, expected, snapshot.Labels)
+
+		listed, err := manager.Snapshot("test-snapshot")
+		require.NoError(t, err)
+		assert.Equal(t, expected, listed.Labels)
+	})
+
+	t.Run("SetLabels should set and remove labels", func(t *testing.T) {
+		appPaths, _ := populateFiles(t, true)
+		manager := newTestManager(appPaths)
+		_, err := manager.Create(context.Background(), "test-snapshot", "", map[string]string{"a": "1", "b": "2"})
+		require.NoError(t, err)
+		_, err = manager.SetLabels(context.Background(), "test-snapshot", map[string]string{"b": "3", "c": "4"}, []string{"a"})
+		require.NoError(t, err)
+		snapshot, err := manager.Snapshot("test-snapshot")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"b": "3", "c": "4"}, snapshot.Labels)
+	})
+}
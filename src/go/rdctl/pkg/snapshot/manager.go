@@ -99,27 +99,54 @@ func (manager *Manager) ValidateName(name string) error {
 	return nil
 }
 
+// writeMetadataFile writes metadata.json for snapshot. The file is replaced
+// atomically, so that readers never see a partially written file.
 func (manager *Manager) writeMetadataFile(snapshot Snapshot) error {
 	snapshotDir := manager.SnapshotDirectory(snapshot)
 	if err := os.MkdirAll(snapshotDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create snapshot directory: %w", err)
 	}
 	metadataPath := filepath.Join(snapshotDir, "metadata.json")
-	metadataFile, err := os.Create(metadataPath)
+	metadataFile, err := os.CreateTemp(snapshotDir, "metadata.*.json.tmp")
 	if err != nil {
 		return fmt.Errorf("failed to create metadata file: %w", err)
 	}
+	defer os.Remove(metadataFile.Name())
 	defer metadataFile.Close()
 	encoder := json.NewEncoder(metadataFile)
 	encoder.SetIndent("", "  ")
 	if err := encoder.Encode(snapshot); err != nil {
 		return fmt.Errorf("failed to write metadata file: %w", err)
 	}
+	if err := metadataFile.Chmod(0o644); err != nil {
+		return fmt.Errorf("failed to write metadata file: %w", err)
+	}
+	if err := metadataFile.Close(); err != nil {
+		return fmt.Errorf("failed to write metadata file: %w", err)
+	}
+	if err := os.Rename(metadataFile.Name(), metadataPath); err != nil {
+		return fmt.Errorf("failed to write metadata file: %w", err)
+	}
+	return nil
+}
+
+// Writes complete.txt to snapshotDir. This must be done last, because its
+// presence signifies a complete and valid snapshot.
+func writeCompleteFile(snapshotDir string) error {
+	completeFilePath := filepath.Join(snapshotDir, completeFileName)
+	if err := os.WriteFile(completeFilePath, []byte(completeFileContents), 0o644); err != nil {
+		return fmt.Errorf("failed to write %q: %w", completeFileName, err)
+	}
 	return nil
 }
 
-// Create a new snapshot.
-func (manager *Manager) Create(ctx context.Context, name, description string) (Snapshot, error) {
+// Create a new snapshot. In addition to the given labels, labels describing
+// the Kubernetes version and container engine in use are added, unless the
+// given labels already contain them.
+func (manager *Manager) Create(ctx context.Context, name, description string, labels map[string]string) (Snapshot, error) {
+	if err := ValidateLabels(labels); err != nil {
+		return Snapshot{}, err
+	}
 	id, err := uuid.NewRandom()
 	if err != nil {
 		return Snapshot{}, fmt.Errorf("failed to generate ID for snapshot: %w", err)
@@ -137,6 +164,7 @@ func (manager *Manager) Create(ctx context.Context, name, description string) (S
 	defer func() {
 		if err != nil {
 			os.RemoveAll(manager.SnapshotDirectory(snapshot))
+			_ = removeUnreferencedChunks(manager.Paths)
 		}
 		unlockErr := manager.Unlock(ctx, manager.Paths, true)
 		if err == nil {
@@ -147,9 +175,35 @@ func (manager *Manager) Create(ctx context.Context, name, description string) (S
 	if err := manager.ValidateName(name); err != nil {
 		return snapshot, err
 	}
-	if err = manager.writeMetadataFile(snapshot); err == nil {
-		err = manager.CreateFiles(ctx, manager.Paths, manager.SnapshotDirectory(snapshot))
+	if err = manager.writeMetadataFile(snapshot); err != nil {
+		return snapshot, err
+	}
+	snapshotDir := manager.SnapshotDirectory(snapshot)
+	if err = manager.CreateFiles(ctx, manager.Paths, snapshotDir); err != nil {
+		return snapshot, err
+	}
+	// Record the checksums of the copied files so that the snapshot
+	// can be verified before it is restored.
+	if snapshot.Checksums, err = manager.calculateChecksums(ctx, snapshotDir); err != nil {
+		return snapshot, err
+	}
+	// Failing to read the settings should not prevent creating a snapshot;
+	// it only means that the derived labels are missing.
+	if settingsLabels, settingsErr := settingsLabels(filepath.Join(snapshotDir, "settings.json")); settingsErr == nil {
+		snapshot.Labels = settingsLabels
 	}
+	for key, value := range labels {
+		if snapshot.Labels == nil {
+			snapshot.Labels = make(map[string]string)
+		}
+		snapshot.Labels[key] = value
+	}
+	if err = manager.writeMetadataFile(snapshot); err != nil {
+		return snapshot, err
+	}
+	// Create complete.txt file. This is done last because its presence
+	// signifies a complete and valid snapshot.
+	err = writeCompleteFile(snapshotDir)
 	return snapshot, err
 }
 
@@ -201,13 +255,29 @@ func (manager *Manager) Delete(name string) error {
 	// Remove complete.txt file. This must be done first because restoring
 	// from a partially-deleted snapshot could result in errors.
 	err = os.RemoveAll(filepath.Join(snapshotDir, completeFileName))
-	return errors.Join(err, os.RemoveAll(snapshotDir))
+	if err = errors.Join(err, os.RemoveAll(snapshotDir)); err != nil {
+		return err
+	}
+	// Chunks that were only used by this snapshot are no longer needed.
+	return removeUnreferencedChunks(manager.Paths)
 }
 
-// Restore Rancher Desktop to the state saved in a snapshot.
-func (manager *Manager) Restore(ctx context.Context, name string) (err error) {
+// PreviewRestore describes what restoring a snapshot would change, without
+// taking the backend lock or changing anything.
+func (manager *Manager) PreviewRestore(name string) (Diff, error) {
 	snapshot, err := manager.Snapshot(name)
 	if err != nil {
+		return Diff{}, err
+	}
+	return manager.Diff(nil, &snapshot)
+}
+
+// Restore Rancher Desktop to the state saved in a snapshot. Unless force is
+// true, the snapshot is verified first, and is not restored if any of its
+// files do not match the checksums recorded when it was created.
+func (manager *Manager) Restore(ctx context.Context, name string, force bool) (err error) {
+	// Check that the snapshot exists before stopping the backend.
+	if _, err := manager.Snapshot(name); err != nil {
 		return err
 	}
 
@@ -222,6 +292,21 @@ func (manager *Manager) Restore(ctx context.Context, name string) (err error) {
 			err = unlockErr
 		}
 	}()
+	// Look up and verify the snapshot while holding the lock, so that it
+	// can't be changed or deleted between verifying and restoring it.
+	snapshot, err := manager.Snapshot(name)
+	if err != nil {
+		return err
+	}
+	if !force {
+		verification, err := manager.Verify(ctx, snapshot)
+		if err != nil {
+			return err
+		}
+		if err := verification.Err(); err != nil {
+			return err
+		}
+	}
 	// If the context is marked done (i.e. the user cancelled the
 	// operation) we can avoid running RestoreFiles() and thus avoid
 	// an unnecessary data reset.
@@ -26,7 +26,7 @@ func TestManager(t *testing.T) {
 		if err := manager.ValidateName(snapshotName); err != nil {
 			t.Fatalf("failed to validate first snapshot: %s", err)
 		}
-		snapshot, err := manager.Create(context.Background(), snapshotName, "")
+		snapshot, err := manager.Create(context.Background(), snapshotName, "", nil)
 		if err != nil {
 			t.Fatalf("failed to create first snapshot: %s", err)
 		}
@@ -156,7 +156,7 @@ func TestManager(t *testing.T) {
 			var lastSnapshot Snapshot
 			for i := range []int{1, 2, 3} {
 				snapshotName := fmt.Sprintf("test-snapshot-%d", i)
-				snapshot, err := manager.Create(context.Background(), snapshotName, "")
+				snapshot, err := manager.Create(context.Background(), snapshotName, "", nil)
 				if err != nil {
 					t.Fatalf("failed to create snapshot %q: %s", snapshotName, err)
 				}
@@ -186,7 +186,7 @@ func TestManager(t *testing.T) {
 	t.Run("Delete", func(t *testing.T) {
 		paths, _ := populateFiles(t, true)
 		manager := newTestManager(paths)
-		snapshot, err := manager.Create(context.Background(), "test-snapshot-delete", "")
+		snapshot, err := manager.Create(context.Background(), "test-snapshot-delete", "", nil)
 		if err != nil {
 			t.Fatalf("failed to create snapshot: %s", err)
 		}
@@ -212,7 +212,7 @@ func TestManager(t *testing.T) {
 	t.Run("Restore should return an error if asked to restore a nonexistent snapshot", func(t *testing.T) {
 		paths, _ := populateFiles(t, true)
 		manager := newTestManager(paths)
-		if err := manager.Restore(context.Background(), "no-such-snapshot-id"); err == nil {
+		if err := manager.Restore(context.Background(), "no-such-snapshot-id", false); err == nil {
 			t.Errorf("Failed to complain when asked to restore a nonexistent snapshot")
 		}
 	})
@@ -220,7 +220,7 @@ func TestManager(t *testing.T) {
 	t.Run("Restore should return the proper error if asked to restore from an incomplete snapshot", func(t *testing.T) {
 		paths, _ := populateFiles(t, true)
 		manager := newTestManager(paths)
-		snapshot, err := manager.Create(context.Background(), "test-snapshot-restore-incomplete", "")
+		snapshot, err := manager.Create(context.Background(), "test-snapshot-restore-incomplete", "", nil)
 		if err != nil {
 			t.Fatalf("failed to create snapshot: %s", err)
 		}
@@ -228,7 +228,7 @@ func TestManager(t *testing.T) {
 		if err := os.Remove(completeFilePath); err != nil {
 			t.Fatalf("failed to remove %q: %s", completeFileName, err)
 		}
-		if err := manager.Restore(context.Background(), snapshot.Name); err == nil {
+		if err := manager.Restore(context.Background(), snapshot.Name, false); err == nil {
 			t.Errorf("Failed to complain when asked to restore an incomplete snapshot")
 		}
 	})
@@ -237,13 +237,13 @@ func TestManager(t *testing.T) {
 		paths, _ := populateFiles(t, true)
 		manager := newTestManager(paths)
 		snapshotName := "test-snapshot-restore-cancelled"
-		_, err := manager.Create(context.Background(), snapshotName, "")
+		_, err := manager.Create(context.Background(), snapshotName, "", nil)
 		if err != nil {
 			t.Fatalf("failed to create snapshot: %s", err)
 		}
 		ctx, cancel := context.WithCancel(context.Background())
 		cancel()
-		if err := manager.Restore(ctx, snapshotName); !errors.Is(err, runner.ErrContextDone) {
+		if err := manager.Restore(ctx, snapshotName, false); !errors.Is(err, runner.ErrContextDone) {
 			t.Errorf("Error is of unexpected type: %q", err)
 		}
 	})
@@ -252,7 +252,7 @@ func TestManager(t *testing.T) {
 		paths, _ := populateFiles(t, true)
 		manager := newTestManager(paths)
 		snapshotName := "test-snapshot-error"
-		snapshot, err := manager.Create(context.Background(), snapshotName, "")
+		snapshot, err := manager.Create(context.Background(), snapshotName, "", nil)
 		if err != nil {
 			t.Fatalf("failed to create snapshot: %s", err)
 		}
@@ -260,8 +260,67 @@ func TestManager(t *testing.T) {
 		if err := os.RemoveAll(snapshotSettingsPath); err != nil {
 			t.Fatalf("failed to remove settings.json: %s", err)
 		}
-		if err := manager.Restore(context.Background(), snapshotName); !errors.Is(err, ErrDataReset) {
+		if err := manager.Restore(context.Background(), snapshotName, true); !errors.Is(err, ErrDataReset) {
 			t.Errorf("Error is of unexpected type: %q", err)
 		}
 	})
+
+	t.Run("Restore should refuse to restore a snapshot that fails verification", func(t *testing.T) {
+		paths, _ := populateFiles(t, true)
+		manager := newTestManager(paths)
+		snapshotName := "test-snapshot-corrupted"
+		snapshot, err := manager.Create(context.Background(), snapshotName, "", nil)
+		if err != nil {
+			t.Fatalf("failed to create snapshot: %s", err)
+		}
+		snapshotSettingsPath := filepath.Join(paths.Snapshots, snapshot.ID, "settings.json")
+		if err := os.WriteFile(snapshotSettingsPath, []byte(`{"corrupted": true}`), 0o644); err != nil {
+			t.Fatalf("failed to modify settings.json: %s", err)
+		}
+		err = manager.Restore(context.Background(), snapshotName, false)
+		if !errors.Is(err, ErrVerificationFailed) {
+			t.Errorf("Error is of unexpected type: %q", err)
+		} else if !strings.Contains(err.Error(), `"settings.json": checksum mismatch`) {
+			t.Errorf("Error does not describe the mismatched file: %q", err)
+		}
+		if err := manager.Restore(context.Background(), snapshotName, true); err != nil {
+			t.Errorf("Failed to restore with force: %s", err)
+		}
+	})
+
+	t.Run("Verify should report each file that does not match", func(t *testing.T) {
+		paths, _ := populateFiles(t, true)
+		manager := newTestManager(paths)
+		snapshot, err := manager.Create(context.Background(), "test-snapshot-verify", "", nil)
+		if err != nil {
+			t.Fatalf("failed to create snapshot: %s", err)
+		}
+		verification, err := manager.Verify(context.Background(), snapshot)
+		if err != nil {
+			t.Fatalf("failed to verify snapshot: %s", err)
+		}
+		if !verification.HasChecksums || !verification.Valid() {
+			t.Fatalf("unexpected verification result for a fresh snapshot: %+v", verification)
+		}
+		snapshotDir := manager.SnapshotDirectory(snapshot)
+		if err := os.WriteFile(filepath.Join(snapshotDir, "settings.json"), []byte("{}"), 0o644); err != nil {
+			t.Fatalf("failed to modify settings.json: %s", err)
+		}
+		verification, err = manager.Verify(context.Background(), snapshot)
+		if err != nil {
+			t.Fatalf("failed to verify snapshot: %s", err)
+		}
+		if verification.Valid() {
+			t.Fatalf("verification unexpectedly succeeded")
+		}
+		for _, file := range verification.Files {
+			expected := FileStatusOK
+			if file.Name == "settings.json" {
+				expected = FileStatusMismatch
+			}
+			if file.Status != expected {
+				t.Errorf("file %q has status %q (expected %q)", file.Name, file.Status, expected)
+			}
+		}
+	})
 }
@@ -82,7 +82,7 @@ func TestManagerUnix(t *testing.T) {
 
 			// create snapshot
 			testManager := newTestManager(appPaths)
-			snapshot, err := testManager.Create(context.Background(), "test-snapshot", "")
+			snapshot, err := testManager.Create(context.Background(), "test-snapshot", "", nil)
 			if err != nil {
 				t.Fatalf("unexpected error creating snapshot: %s", err)
 			}
@@ -99,6 +99,10 @@ func TestManagerUnix(t *testing.T) {
 				snapshotFiles = append(snapshotFiles, filepath.Join(snapshotDir, "override.yaml"))
 			}
 			for _, file := range snapshotFiles {
+				// Disk images may be stored in the chunk store instead.
+				if _, err := os.ReadFile(file + chunkManifestSuffix); err == nil {
+					continue
+				}
 				if _, err := os.ReadFile(file); err != nil {
 					t.Errorf("file %q does not exist in snapshot: %s", file, err)
 				}
@@ -110,7 +114,7 @@ func TestManagerUnix(t *testing.T) {
 		t.Run(fmt.Sprintf("Restore with includeOverrideYaml %t", includeOverrideYaml), func(t *testing.T) {
 			appPaths, testFiles := populateFiles(t, includeOverrideYaml)
 			manager := newTestManager(appPaths)
-			snapshot, err := manager.Create(context.Background(), "test-snapshot", "")
+			snapshot, err := manager.Create(context.Background(), "test-snapshot", "", nil)
 			if err != nil {
 				t.Fatalf("failed to create snapshot: %s", err)
 			}
@@ -119,7 +123,7 @@ func TestManagerUnix(t *testing.T) {
 					t.Fatalf("failed to modify %s: %s", testFileName, err)
 				}
 			}
-			if err := manager.Restore(context.Background(), snapshot.Name); err != nil {
+			if err := manager.Restore(context.Background(), snapshot.Name, false); err != nil {
 				t.Fatalf("failed to restore snapshot: %s", err)
 			}
 			for testFileName, testFile := range testFiles {
@@ -140,7 +144,7 @@ func TestManagerUnix(t *testing.T) {
 		if err := os.Remove(testFiles["override.yaml"].Path); err != nil {
 			t.Fatalf("failed to delete override.yaml: %s", err)
 		}
-		snapshot, err := manager.Create(context.Background(), "test-snapshot", "")
+		snapshot, err := manager.Create(context.Background(), "test-snapshot", "", nil)
 		if err != nil {
 			t.Fatalf("failed to create snapshot: %s", err)
 		}
@@ -149,7 +153,7 @@ func TestManagerUnix(t *testing.T) {
 				t.Fatalf("failed to modify %s: %s", testFileName, err)
 			}
 		}
-		if err := manager.Restore(context.Background(), snapshot.Name); err != nil {
+		if err := manager.Restore(context.Background(), snapshot.Name, false); err != nil {
 			t.Fatalf("failed to restore snapshot: %s", err)
 		}
 		overrideYamlPath := testFiles["override.yaml"].Path
@@ -171,7 +175,7 @@ func TestManagerUnix(t *testing.T) {
 	t.Run("Restore should create any needed parent directories", func(t *testing.T) {
 		appPaths, _ := populateFiles(t, true)
 		manager := newTestManager(appPaths)
-		snapshot, err := manager.Create(context.Background(), "test-snapshot", "")
+		snapshot, err := manager.Create(context.Background(), "test-snapshot", "", nil)
 		if err != nil {
 			t.Fatalf("failed to create snapshot: %s", err)
 		}
@@ -180,7 +184,7 @@ func TestManagerUnix(t *testing.T) {
 				t.Fatalf("failed to remove directory: %s", err)
 			}
 		}
-		if err := manager.Restore(context.Background(), snapshot.Name); err != nil {
+		if err := manager.Restore(context.Background(), snapshot.Name, false); err != nil {
 			t.Fatalf("failed to restore snapshot: %s", err)
 		}
 	})
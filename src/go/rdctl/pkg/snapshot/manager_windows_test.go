@@ -55,7 +55,7 @@ func TestManagerWindows(t *testing.T) {
 
 		// create snapshot
 		testManager := newTestManager(appPaths)
-		snapshot, err := testManager.Create(context.Background(), "test-snapshot", "")
+		snapshot, err := testManager.Create(context.Background(), "test-snapshot", "", nil)
 		if err != nil {
 			t.Fatalf("unexpected error creating snapshot: %s", err)
 		}
@@ -75,7 +75,7 @@ func TestManagerWindows(t *testing.T) {
 	t.Run("Restore should work properly", func(t *testing.T) {
 		appPaths, testFiles := populateFiles(t, false)
 		manager := newTestManager(appPaths)
-		snapshot, err := manager.Create(context.Background(), "test-snapshot", "")
+		snapshot, err := manager.Create(context.Background(), "test-snapshot", "", nil)
 		if err != nil {
 			t.Fatalf("failed to create snapshot: %s", err)
 		}
@@ -84,7 +84,7 @@ func TestManagerWindows(t *testing.T) {
 				t.Fatalf("failed to modify %s: %s", testFileName, err)
 			}
 		}
-		if err := manager.Restore(context.Background(), snapshot.Name); err != nil {
+		if err := manager.Restore(context.Background(), snapshot.Name, false); err != nil {
 			t.Fatalf("failed to restore snapshot: %s", err)
 		}
 		for testFileName, testFile := range testFiles {
@@ -101,7 +101,7 @@ func TestManagerWindows(t *testing.T) {
 	t.Run("Restore should create any needed parent directories", func(t *testing.T) {
 		appPaths, _ := populateFiles(t, true)
 		manager := newTestManager(appPaths)
-		snapshot, err := manager.Create(context.Background(), "test-snapshot", "")
+		snapshot, err := manager.Create(context.Background(), "test-snapshot", "", nil)
 		if err != nil {
 			t.Fatalf("failed to create snapshot: %s", err)
 		}
@@ -115,7 +115,7 @@ func TestManagerWindows(t *testing.T) {
 				t.Fatalf("failed to remove test directory %q: %s", testDir, err)
 			}
 		}
-		if err := manager.Restore(context.Background(), snapshot.Name); err != nil {
+		if err := manager.Restore(context.Background(), snapshot.Name, false); err != nil {
 			t.Fatalf("failed to restore snapshot: %s", err)
 		}
 		for _, testDir := range testDirs {
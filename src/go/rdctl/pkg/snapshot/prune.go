@@ -0,0 +1,352 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The name of the file, in the snapshots directory, that holds the
+// retention policy used by `rdctl snapshot prune`.
+const retentionPolicyFileName = "retention.json"
+
+// Duration is a time.Duration that is stored in JSON as a string, and
+// additionally accepts "d" (day) and "w" (week) units.
+type Duration time.Duration
+
+// ParseDuration parses a duration such as "36h", "30d" or "2w".
+func ParseDuration(input string) (Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(input, suffix); ok {
+			count, err := strconv.ParseFloat(number, 64)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration %q", input)
+			}
+			return Duration(count * float64(unit)), nil
+		}
+	}
+	duration, err := time.ParseDuration(input)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid duration %q", input)
+	}
+	return Duration(duration), nil
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var input string
+	if err := json.Unmarshal(data, &input); err != nil {
+		return err
+	}
+	duration, err := ParseDuration(input)
+	if err != nil {
+		return err
+	}
+	*d = duration
+	return nil
+}
+
+// RetentionPolicy describes which snapshots `rdctl snapshot prune` removes.
+//
+// KeepLast, KeepDaily and KeepWeekly select snapshots that are always kept.
+// If OlderThan or MaxTotalSize are set, they decide which of the remaining
+// snapshots are removed: first those older than OlderThan, then the oldest
+// ones until the total size is below MaxTotalSize. Otherwise, every snapshot
+// that is not selected by a keep rule is removed.
+type RetentionPolicy struct {
+	// Keep the given number of most recent snapshots.
+	KeepLast int `json:"keepLast,omitempty"`
+	// Keep the most recent snapshot of each of the given number of days.
+	KeepDaily int `json:"keepDaily,omitempty"`
+	// Keep the most recent snapshot of each of the given number of weeks.
+	KeepWeekly int `json:"keepWeekly,omitempty"`
+	// Remove snapshots when all snapshots together use more than the given
+	// number of bytes on disk.
+	MaxTotalSize int64 `json:"maxTotalSize,omitempty"`
+	// Remove snapshots that are older than the given duration.
+	OlderThan Duration `json:"olderThan,omitempty"`
+}
+
+// IsEmpty returns whether the policy has no rules at all.
+func (policy RetentionPolicy) IsEmpty() bool {
+	return policy == RetentionPolicy{}
+}
+
+func (policy RetentionPolicy) validate() error {
+	if policy.KeepLast < 0 || policy.KeepDaily < 0 || policy.KeepWeekly < 0 {
+		return errors.New("keep counts must not be negative")
+	}
+	if policy.MaxTotalSize < 0 {
+		return errors.New("maximum total size must not be negative")
+	}
+	if policy.OlderThan < 0 {
+		return errors.New("age must not be negative")
+	}
+	return nil
+}
+
+// PruneCandidate is a snapshot along with the disk space it uses.
+type PruneCandidate struct {
+	Snapshot Snapshot `json:"snapshot"`
+	// The number of bytes the snapshot uses on disk. For sparse files, this
+	// only includes allocated blocks; blocks shared with copy-on-write clones
+	// are not included.
+	DiskUsage int64 `json:"diskUsage"`
+}
+
+// PruneResult describes the outcome of Manager.Prune.
+type PruneResult struct {
+	// The snapshots that were (or, for a dry run, would be) removed.
+	Removed []PruneCandidate `json:"removed"`
+	// The snapshots that were kept.
+	Kept []PruneCandidate `json:"kept"`
+	// The number of bytes reclaimed by removing the snapshots; unlike the sum
+	// of their DiskUsage, this includes data they only share with each other.
+	Reclaimed int64 `json:"reclaimed"`
+	// Whether this was a dry run, in which case nothing was removed.
+	DryRun bool `json:"dryRun"`
+}
+
+func (manager *Manager) retentionPolicyPath() string {
+	return filepath.Join(manager.Snapshots, retentionPolicyFileName)
+}
+
+// RetentionPolicy returns the retention policy stored next to the snapshots.
+// If none has been saved, an empty policy is returned.
+func (manager *Manager) RetentionPolicy() (RetentionPolicy, error) {
+	var policy RetentionPolicy
+	contents, err := os.ReadFile(manager.retentionPolicyPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return policy, nil
+	} else if err != nil {
+		return policy, fmt.Errorf("failed to read retention policy: %w", err)
+	}
+	if err := json.Unmarshal(contents, &policy); err != nil {
+		return policy, fmt.Errorf("failed to parse retention policy %q: %w", manager.retentionPolicyPath(), err)
+	}
+	return policy, policy.validate()
+}
+
+// SaveRetentionPolicy stores the retention policy next to the snapshots, so
+// that later runs of `rdctl snapshot prune` use it.
+func (manager *Manager) SaveRetentionPolicy(policy RetentionPolicy) error {
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(manager.Snapshots, 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshots directory: %w", err)
+	}
+	contents, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal retention policy: %w", err)
+	}
+	if err := os.WriteFile(manager.retentionPolicyPath(), append(contents, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write retention policy: %w", err)
+	}
+	return nil
+}
+
+// DiskUsage returns the number of bytes the snapshot uses on disk. This
+// includes the chunks in the chunk store that no other snapshot refers to,
+// but not the ones it shares with other snapshots.
+func (manager *Manager) DiskUsage(snapshot Snapshot) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(manager.SnapshotDirectory(snapshot), func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		total += diskUsage(path, info)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine disk usage of snapshot %q: %w", snapshot.Name, err)
+	}
+	chunkUsage, err := exclusiveChunkUsage(manager.Paths, manager.SnapshotDirectory(snapshot))
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine disk usage of snapshot %q: %w", snapshot.Name, err)
+	}
+	return total + chunkUsage, nil
+}
+
+// Prune removes the snapshots that are not retained by policy. If dryRun is
+// true, nothing is removed, but the result describes what would be.
+func (manager *Manager) Prune(policy RetentionPolicy, now time.Time, dryRun bool) (PruneResult, error) {
+	result := PruneResult{DryRun: dryRun}
+	if err := policy.validate(); err != nil {
+		return result, err
+	}
+	if policy.IsEmpty() {
+		return result, errors.New("no retention policy configured")
+	}
+	snapshots, err := manager.List(false)
+	if err != nil {
+		return result, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	candidates := make([]PruneCandidate, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		usage, err := manager.DiskUsage(snapshot)
+		if err != nil {
+			return result, err
+		}
+		candidates = append(candidates, PruneCandidate{Snapshot: snapshot, DiskUsage: usage})
+	}
+	result.Removed, result.Kept = selectPrunable(candidates, policy, now)
+	// The removed snapshots may share data with each other, which the
+	// DiskUsage of each of them does not include.
+	result.Reclaimed, err = manager.reclaimableDiskUsage(result.Removed, result.Kept)
+	if err != nil {
+		return result, err
+	}
+	if dryRun {
+		return result, nil
+	}
+	for _, candidate := range result.Removed {
+		if err := manager.Delete(candidate.Snapshot.Name); err != nil {
+			return result, fmt.Errorf("failed to delete snapshot %q: %w", candidate.Snapshot.Name, err)
+		}
+	}
+	return result, nil
+}
+
+// reclaimableDiskUsage returns the number of bytes that are freed by
+// deleting all of the removed snapshots while keeping the kept ones. This
+// includes the chunks and blocks that the removed snapshots share only with
+// each other.
+func (manager *Manager) reclaimableDiskUsage(removed, kept []PruneCandidate) (int64, error) {
+	if len(removed) == 0 {
+		return 0, nil
+	}
+	var removedDirs, removedFiles, keptFiles []string
+	for _, candidate := range removed {
+		snapshotDir := manager.SnapshotDirectory(candidate.Snapshot)
+		files, err := listFiles(snapshotDir)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list files of snapshot %q: %w", candidate.Snapshot.Name, err)
+		}
+		removedDirs = append(removedDirs, snapshotDir)
+		removedFiles = append(removedFiles, files...)
+	}
+	for _, candidate := range kept {
+		files, err := listFiles(manager.SnapshotDirectory(candidate.Snapshot))
+		if err != nil {
+			return 0, fmt.Errorf("failed to list files of snapshot %q: %w", candidate.Snapshot.Name, err)
+		}
+		keptFiles = append(keptFiles, files...)
+	}
+	// Snapshot files may also share blocks with the files they were cloned
+	// from, which are not removed either.
+	for _, file := range manager.Files(manager.Paths, "") {
+		if file.WorkingPath != "" {
+			keptFiles = append(keptFiles, file.WorkingPath)
+		}
+	}
+	fileUsage, err := filesDiskUsage(removedFiles, keptFiles)
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine reclaimable disk usage: %w", err)
+	}
+	chunkUsage, err := exclusiveChunkUsage(manager.Paths, removedDirs...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine reclaimable disk usage: %w", err)
+	}
+	return fileUsage + chunkUsage, nil
+}
+
+// listFiles returns the paths of all files under dir.
+func listFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		files = append(files, path)
+		return nil
+	})
+	return files, err
+}
+
+// selectPrunable splits candidates into the snapshots that should be removed
+// and those that should be kept according to policy. Both are returned
+// sorted from newest to oldest.
+func selectPrunable(candidates []PruneCandidate, policy RetentionPolicy, now time.Time) (removed, kept []PruneCandidate) {
+	candidates = append([]PruneCandidate(nil), candidates...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Snapshot.Created.After(candidates[j].Snapshot.Created)
+	})
+
+	protected := make([]bool, len(candidates))
+	for i := range candidates {
+		if i < policy.KeepLast {
+			protected[i] = true
+		}
+	}
+	keepPerPeriod := func(count int, period func(time.Time) string) {
+		seen := make(map[string]bool)
+		for i, candidate := range candidates {
+			if len(seen) >= count {
+				break
+			}
+			key := period(candidate.Snapshot.Created.Local())
+			if !seen[key] {
+				seen[key] = true
+				protected[i] = true
+			}
+		}
+	}
+	keepPerPeriod(policy.KeepDaily, func(t time.Time) string {
+		return t.Format(time.DateOnly)
+	})
+	keepPerPeriod(policy.KeepWeekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%d", year, week)
+	})
+
+	remove := make([]bool, len(candidates))
+	if policy.OlderThan == 0 && policy.MaxTotalSize == 0 {
+		for i := range candidates {
+			remove[i] = !protected[i]
+		}
+	} else {
+		var total int64
+		for i, candidate := range candidates {
+			if !protected[i] && policy.OlderThan > 0 && now.Sub(candidate.Snapshot.Created) > time.Duration(policy.OlderThan) {
+				remove[i] = true
+			} else {
+				total += candidate.DiskUsage
+			}
+		}
+		if policy.MaxTotalSize > 0 {
+			for i := len(candidates) - 1; i >= 0 && total > policy.MaxTotalSize; i-- {
+				if !protected[i] && !remove[i] {
+					remove[i] = true
+					total -= candidates[i].DiskUsage
+				}
+			}
+		}
+	}
+
+	for i, candidate := range candidates {
+		if remove[i] {
+			removed = append(removed, candidate)
+		} else {
+			kept = append(kept, candidate)
+		}
+	}
+	return removed, kept
+}
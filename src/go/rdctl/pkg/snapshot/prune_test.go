@@ -0,0 +1,148 @@
+package snapshot
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
+)
+
+func TestParseDuration(t *testing.T) {
+	testCases := []struct {
+		Input    string
+		Expected time.Duration
+		Error    bool
+	}{
+		{Input: "36h", Expected: 36 * time.Hour},
+		{Input: "30d", Expected: 30 * 24 * time.Hour},
+		{Input: "1.5d", Expected: 36 * time.Hour},
+		{Input: "2w", Expected: 14 * 24 * time.Hour},
+		{Input: "-1d", Error: true},
+		{Input: "d", Error: true},
+		{Input: "soon", Error: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Input, func(t *testing.T) {
+			duration, err := ParseDuration(testCase.Input)
+			if testCase.Error {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.Expected, time.Duration(duration))
+			}
+		})
+	}
+}
+
+func TestSelectPrunable(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.Local)
+	// Two snapshots per day for the last ten days, each 10 bytes in size;
+	// named "day-<days ago>-<am|pm>".
+	var candidates []PruneCandidate
+	for day := range 10 {
+		for _, halfDay := range []string{"am", "pm"} {
+			created := now.AddDate(0, 0, -day).Add(-time.Hour)
+			if halfDay == "am" {
+				created = created.Add(-6 * time.Hour)
+			}
+			candidates = append(candidates, PruneCandidate{
+				Snapshot: Snapshot{
+					Name:    fmt.Sprintf("day-%d-%s", day, halfDay),
+					Created: created,
+				},
+				DiskUsage: 10,
+			})
+		}
+	}
+	names := func(candidates []PruneCandidate) []string {
+		result := make([]string, 0, len(candidates))
+		for _, candidate := range candidates {
+			result = append(result, candidate.Snapshot.Name)
+		}
+		return result
+	}
+
+	testCases := []struct {
+		Description string
+		Policy      RetentionPolicy
+		Kept        []string
+	}{
+		{
+			Description: "keep last",
+			Policy:      RetentionPolicy{KeepLast: 3},
+			Kept:        []string{"day-0-pm", "day-0-am", "day-1-pm"},
+		},
+		{
+			Description: "keep daily",
+			Policy:      RetentionPolicy{KeepDaily: 3},
+			Kept:        []string{"day-0-pm", "day-1-pm", "day-2-pm"},
+		},
+		{
+			Description: "keep last and daily overlap",
+			Policy:      RetentionPolicy{KeepLast: 2, KeepDaily: 2},
+			Kept:        []string{"day-0-pm", "day-0-am", "day-1-pm"},
+		},
+		{
+			Description: "older than",
+			Policy:      RetentionPolicy{OlderThan: Duration(48 * time.Hour)},
+			Kept:        []string{"day-0-pm", "day-0-am", "day-1-pm", "day-1-am"},
+		},
+		{
+			Description: "older than keeps protected snapshots",
+			// 2024-06-15 is a Saturday; day-6 is the Sunday of the previous week.
+			Policy: RetentionPolicy{KeepWeekly: 2, OlderThan: Duration(24 * time.Hour)},
+			Kept:   []string{"day-0-pm", "day-0-am", "day-6-pm"},
+		},
+		{
+			Description: "max total size removes oldest first",
+			Policy:      RetentionPolicy{MaxTotalSize: 45},
+			Kept:        []string{"day-0-pm", "day-0-am", "day-1-pm", "day-1-am"},
+		},
+		{
+			Description: "max total size keeps protected snapshots",
+			Policy:      RetentionPolicy{KeepLast: 1, KeepDaily: 10, MaxTotalSize: 45},
+			Kept:        []string{"day-0-pm", "day-1-pm", "day-2-pm", "day-3-pm", "day-4-pm", "day-5-pm", "day-6-pm", "day-7-pm", "day-8-pm", "day-9-pm"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.Description, func(t *testing.T) {
+			removed, kept := selectPrunable(candidates, testCase.Policy, now)
+			assert.Equal(t, testCase.Kept, names(kept))
+			assert.Len(t, removed, len(candidates)-len(testCase.Kept))
+		})
+	}
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	t.Run("should return an empty policy when none is saved", func(t *testing.T) {
+		manager := &Manager{Paths: &paths.Paths{Snapshots: filepath.Join(t.TempDir(), "snapshots")}}
+		policy, err := manager.RetentionPolicy()
+		require.NoError(t, err)
+		assert.True(t, policy.IsEmpty())
+	})
+
+	t.Run("should round-trip a saved policy", func(t *testing.T) {
+		manager := &Manager{Paths: &paths.Paths{Snapshots: filepath.Join(t.TempDir(), "snapshots")}}
+		expected := RetentionPolicy{
+			KeepLast:     2,
+			KeepWeekly:   4,
+			MaxTotalSize: 100 << 30,
+			OlderThan:    Duration(90 * 24 * time.Hour),
+		}
+		require.NoError(t, manager.SaveRetentionPolicy(expected))
+		policy, err := manager.RetentionPolicy()
+		require.NoError(t, err)
+		assert.Equal(t, expected, policy)
+	})
+
+	t.Run("should refuse to prune without a policy", func(t *testing.T) {
+		manager := &Manager{Paths: &paths.Paths{Snapshots: filepath.Join(t.TempDir(), "snapshots")}}
+		_, err := manager.Prune(RetentionPolicy{}, time.Now(), true)
+		assert.ErrorContains(t, err, "no retention policy")
+	})
+}
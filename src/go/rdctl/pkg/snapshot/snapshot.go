@@ -10,6 +10,11 @@ type Snapshot struct {
 	Name        string    `json:"name"`
 	ID          string    `json:"id,omitempty"`
 	Description string    `json:"description"`
+	// Arbitrary key/value pairs that can be used to find snapshots.
+	Labels map[string]string `json:"labels,omitempty"`
+	// SHA-256 checksums of the files in the snapshot, keyed by file name.
+	// Snapshots created by older versions of rdctl do not have these.
+	Checksums map[string]string `json:"checksums,omitempty"`
 }
 
 func (s *Snapshot) getTimeString() string {
@@ -3,14 +3,33 @@ package snapshot
 import (
 	"context"
 	"errors"
+	"os"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
 )
 
+// Represents a file that is included in a snapshot.
+type snapshotFile struct {
+	// The path that Rancher Desktop uses. This is empty for files that
+	// do not have a single working location, such as exported WSL distros.
+	WorkingPath string
+	// The path that the file is put at in a snapshot.
+	SnapshotPath string
+	// Whether clonefile (macOS) or ioctl_ficlone (Linux) should be used
+	// when copying the file around.
+	CopyOnWrite bool
+	// Whether it is ok for the file to not be present.
+	MissingOk bool
+	// The permissions the file should have.
+	FileMode os.FileMode
+}
+
 // Types that implement Snapshotter are responsible for copying/creating
 // files that need to be copied/created for the creation and restoration of
 // snapshots.
 type Snapshotter interface {
+	// Files returns the files that make up a snapshot stored in snapshotDir.
+	Files(appPaths *paths.Paths, snapshotDir string) []snapshotFile
 	// Does all of the things that can fail when creating a snapshot,
 	// so that the snapshot creation can easily be rolled back upon
 	// a failure.
@@ -13,21 +13,6 @@ import (
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/runner"
 )
 
-// Represents a file that is included in a snapshot.
-type snapshotFile struct {
-	// The path that Rancher Desktop uses.
-	WorkingPath string
-	// The path that the file is put at in a snapshot.
-	SnapshotPath string
-	// Whether clonefile (macOS) or ioctl_ficlone (Linux) should be used
-	// when copying the file around.
-	CopyOnWrite bool
-	// Whether it is ok for the file to not be present.
-	MissingOk bool
-	// The permissions the file should have.
-	FileMode os.FileMode
-}
-
 // SnapshotterImpl also works as a *Manager receiver
 type SnapshotterImpl struct {
 }
@@ -96,7 +81,12 @@ func (snapshotter SnapshotterImpl) CreateFiles(ctx context.Context, appPaths *pa
 	files := snapshotter.Files(appPaths, snapshotDir)
 	for _, file := range files {
 		taskRunner.Add(func() error {
-			err := copyFile(file.SnapshotPath, file.WorkingPath, file.CopyOnWrite, file.FileMode)
+			var err error
+			if file.CopyOnWrite {
+				err = storeCopyOnWriteFile(ctx, appPaths, file)
+			} else {
+				err = copyFile(file.SnapshotPath, file.WorkingPath, false, file.FileMode)
+			}
 			if errors.Is(err, os.ErrNotExist) && file.MissingOk {
 				return nil
 			} else if err != nil {
@@ -106,16 +96,6 @@ func (snapshotter SnapshotterImpl) CreateFiles(ctx context.Context, appPaths *pa
 		})
 	}
 
-	// Create complete.txt file. This is done last because its presence
-	// signifies a complete and valid snapshot.
-	taskRunner.Add(func() error {
-		completeFilePath := filepath.Join(snapshotDir, completeFileName)
-		if err := os.WriteFile(completeFilePath, []byte(completeFileContents), 0o644); err != nil {
-			return fmt.Errorf("failed to write %q: %w", completeFileName, err)
-		}
-		return nil
-	})
-
 	return taskRunner.Wait()
 }
 
@@ -127,7 +107,12 @@ func (snapshotter SnapshotterImpl) RestoreFiles(ctx context.Context, appPaths *p
 	for _, file := range files {
 		taskRunner.Add(func() error {
 			filename := filepath.Base(file.WorkingPath)
-			err := copyFile(file.WorkingPath, file.SnapshotPath, file.CopyOnWrite, file.FileMode)
+			var err error
+			if file.CopyOnWrite {
+				err = restoreCopyOnWriteFile(ctx, appPaths, file)
+			} else {
+				err = copyFile(file.WorkingPath, file.SnapshotPath, false, file.FileMode)
+			}
 			if errors.Is(err, os.ErrNotExist) && file.MissingOk {
 				if err := os.RemoveAll(file.WorkingPath); err != nil {
 					return fmt.Errorf("failed to remove %q: %w", filename, err)
@@ -38,6 +38,25 @@ func (snapshotter SnapshotterImpl) WSLDistros(appPaths *paths.Paths) []wslDistro
 	}
 }
 
+func (snapshotter SnapshotterImpl) Files(appPaths *paths.Paths, snapshotDir string) []snapshotFile {
+	files := []snapshotFile{
+		{
+			WorkingPath:  filepath.Join(appPaths.Config, "settings.json"),
+			SnapshotPath: filepath.Join(snapshotDir, "settings.json"),
+			MissingOk:    false,
+			FileMode:     0o644,
+		},
+	}
+	for _, distro := range snapshotter.WSLDistros(appPaths) {
+		files = append(files, snapshotFile{
+			SnapshotPath: filepath.Join(snapshotDir, distro.Name+".tar"),
+			MissingOk:    false,
+			FileMode:     0o644,
+		})
+	}
+	return files
+}
+
 // Note: on Windows, there are system calls such as CopyFile and CopyFileEx
 // that may speed up the process of copying a file, but they appear to require
 // loading DLL's. This approach works fine for copying smaller files, but if
@@ -92,16 +111,6 @@ func (snapshotter SnapshotterImpl) CreateFiles(ctx context.Context, appPaths *pa
 		return nil
 	})
 
-	// Create complete.txt file. This is done last because its presence
-	// signifies a complete and valid snapshot.
-	taskRunner.Add(func() error {
-		completeFilePath := filepath.Join(snapshotDir, completeFileName)
-		if err := os.WriteFile(completeFilePath, []byte(completeFileContents), 0o644); err != nil {
-			return fmt.Errorf("failed to write %q: %w", completeFileName, err)
-		}
-		return nil
-	})
-
 	return taskRunner.Wait()
 }
 
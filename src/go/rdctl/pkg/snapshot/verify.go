@@ -0,0 +1,157 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/runner"
+)
+
+// ErrVerificationFailed is returned when the contents of a snapshot do not
+// match the checksums recorded when it was created.
+var ErrVerificationFailed = errors.New("snapshot verification failed")
+
+// FileStatus describes the outcome of verifying a single snapshot file.
+type FileStatus string
+
+const (
+	// The file matches its recorded checksum.
+	FileStatusOK FileStatus = "ok"
+	// The file does not match its recorded checksum.
+	FileStatusMismatch FileStatus = "mismatch"
+	// The file has a recorded checksum, but is not present.
+	FileStatusMissing FileStatus = "missing"
+	// The file could not be read.
+	FileStatusError FileStatus = "error"
+)
+
+// FileVerification is the result of verifying a single snapshot file.
+type FileVerification struct {
+	Name     string     `json:"name"`
+	Status   FileStatus `json:"status"`
+	Expected string     `json:"expected,omitempty"`
+	Actual   string     `json:"actual,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// Verification is the result of verifying a snapshot.
+type Verification struct {
+	Name string `json:"name"`
+	// Whether the snapshot has any recorded checksums; snapshots created
+	// by older versions of rdctl do not, and can't be verified.
+	HasChecksums bool               `json:"hasChecksums"`
+	Files        []FileVerification `json:"files"`
+}
+
+// Valid returns whether all of the files in the snapshot match their
+// recorded checksums.
+func (v Verification) Valid() bool {
+	for _, file := range v.Files {
+		if file.Status != FileStatusOK {
+			return false
+		}
+	}
+	return true
+}
+
+// Err returns an error wrapping ErrVerificationFailed that describes every
+// file that failed verification, or nil if the snapshot is valid.
+func (v Verification) Err() error {
+	var errs []error
+	for _, file := range v.Files {
+		switch file.Status {
+		case FileStatusOK:
+		case FileStatusMismatch:
+			errs = append(errs, fmt.Errorf("%q: checksum mismatch (expected %s, got %s)", file.Name, file.Expected, file.Actual))
+		case FileStatusMissing:
+			errs = append(errs, fmt.Errorf("%q: file is missing", file.Name))
+		default:
+			errs = append(errs, fmt.Errorf("%q: %s", file.Name, file.Error))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrVerificationFailed, errors.Join(errs...))
+}
+
+// calculateChecksums returns the checksums of the files of the snapshot
+// stored in snapshotDir, keyed by file name. Files that are not present
+// are omitted.
+func (manager *Manager) calculateChecksums(ctx context.Context, snapshotDir string) (map[string]string, error) {
+	checksums := make(map[string]string)
+	taskRunner := runner.NewTaskRunner(ctx)
+	for _, file := range manager.Files(manager.Paths, snapshotDir) {
+		taskRunner.Add(func() error {
+			name := filepath.Base(file.SnapshotPath)
+			checksum, err := manager.fileChecksum(file.SnapshotPath)
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("failed to calculate checksum of %q: %w", name, err)
+			}
+			checksums[name] = checksum
+			return nil
+		})
+	}
+	if err := taskRunner.Wait(); err != nil {
+		return nil, err
+	}
+	return checksums, nil
+}
+
+// Verify recalculates the checksums of the files of a snapshot and compares
+// them against the checksums recorded when the snapshot was created. Files
+// are hashed concurrently, each with its own TaskRunner so that hashing stops
+// once the context is cancelled.
+func (manager *Manager) Verify(ctx context.Context, snapshot Snapshot) (Verification, error) {
+	verification := Verification{
+		Name:         snapshot.Name,
+		HasChecksums: len(snapshot.Checksums) > 0,
+	}
+	snapshotDir := manager.SnapshotDirectory(snapshot)
+	var taskRunners []*runner.TaskRunner
+	for _, file := range manager.Files(manager.Paths, snapshotDir) {
+		name := filepath.Base(file.SnapshotPath)
+		expected, ok := snapshot.Checksums[name]
+		if !ok {
+			continue
+		}
+		verification.Files = append(verification.Files, FileVerification{
+			Name:     name,
+			Expected: expected,
+		})
+	}
+	for i := range verification.Files {
+		taskRunner := runner.NewTaskRunner(ctx)
+		taskRunner.Add(func() error {
+			result := &verification.Files[i]
+			actual, err := manager.fileChecksum(filepath.Join(snapshotDir, result.Name))
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				result.Status = FileStatusMissing
+			case err != nil:
+				result.Status = FileStatusError
+				result.Error = err.Error()
+			case actual != result.Expected:
+				result.Status = FileStatusMismatch
+				result.Actual = actual
+			default:
+				result.Status = FileStatusOK
+				result.Actual = actual
+			}
+			return nil
+		})
+		taskRunners = append(taskRunners, taskRunner)
+	}
+	var firstErr error
+	for _, taskRunner := range taskRunners {
+		if err := taskRunner.Wait(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return verification, firstErr
+}
@@ -1,6 +1,8 @@
-// Command startup-profile generates a Chrome devtools profile format.
-// The output can be loaded via https://profiler.firefox.com/ or via Chrome
-// devtools (Performance tab).
+// Command startup-profile generates a profile of Rancher Desktop startup.
+// By default, the output is in Chrome devtools profile format, which can be
+// loaded via https://profiler.firefox.com/ or via Chrome devtools (Performance
+// tab).  It can also be written in Chrome Trace Event format (for Perfetto) or
+// as OpenTelemetry spans (for Jaeger).
 package main
 
 import (
@@ -10,6 +12,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/startup-profile/render"
 )
 
 // marshalledPath represents a path.  It implements [encoding.TextMarshaler] and
@@ -34,12 +38,25 @@ func (p *marshalledPath) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// options are the command line options.
+type options struct {
+	outPath marshalledPath
+	format  render.Format
+	debug   bool
+}
+
 func main() {
-	outPath := marshalledPath("rancher-desktop.cpuprofile")
-	flag.TextVar(&outPath, "out", &outPath, "File name to write the output to")
+	opts := options{format: render.FormatCPUProfile}
+	flag.TextVar(&opts.outPath, "out", &opts.outPath, "File name to write the output to (default rancher-desktop.<format extension>)")
+	flag.TextVar(&opts.format, "format", opts.format, "Output format (cpuprofile, trace, or otlp)")
+	flag.BoolVar(&opts.debug, "debug", false, "Write intermediate events to JSON files in the current directory")
 	flag.Parse()
 
-	if err := run(context.Background(), outPath); err != nil {
+	if opts.outPath == "" {
+		opts.outPath = marshalledPath("rancher-desktop" + opts.format.Extension())
+	}
+
+	if err := run(context.Background(), opts); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,52 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Format is an output format.  It implements [encoding.TextMarshaler] and
+// [encoding.TextUnmarshaler].
+type Format string
+
+const (
+	// Chrome devtools CPU profile; this can be loaded in Chrome devtools or
+	// https://profiler.firefox.com/.
+	FormatCPUProfile = Format("cpuprofile")
+	// Chrome Trace Event format; this can be loaded in https://ui.perfetto.dev/
+	// or chrome://tracing.
+	FormatTrace = Format("trace")
+	// OpenTelemetry (OTLP) JSON spans; this can be loaded in Jaeger.
+	FormatOTLP = Format("otlp")
+)
+
+// Formats lists all supported output formats.
+var Formats = []Format{FormatCPUProfile, FormatTrace, FormatOTLP}
+
+func (f Format) MarshalText() ([]byte, error) {
+	return []byte(f), nil
+}
+
+func (f *Format) UnmarshalText(text []byte) error {
+	if !slices.Contains(Formats, Format(text)) {
+		names := make([]string, 0, len(Formats))
+		for _, format := range Formats {
+			names = append(names, string(format))
+		}
+		return fmt.Errorf("unknown format %q (must be one of %s)", text, strings.Join(names, ", "))
+	}
+	*f = Format(text)
+	return nil
+}
+
+// Extension returns the default file name extension for the format.
+func (f Format) Extension() string {
+	switch f {
+	case FormatTrace:
+		return ".trace.json"
+	case FormatOTLP:
+		return ".otlp.json"
+	}
+	return ".cpuprofile"
+}
@@ -0,0 +1,212 @@
+package render
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/startup-profile/model"
+)
+
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+// This is the root object of the document (ExportTraceServiceRequest).
+type otlpDocument struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID      string          `json:"traceId"`
+	SpanID       string          `json:"spanId"`
+	ParentSpanID string          `json:"parentSpanId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         int             `json:"kind"`
+	StartTime    string          `json:"startTimeUnixNano"`
+	EndTime      string          `json:"endTimeUnixNano"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+	Events       []otlpEvent     `json:"events,omitempty"`
+}
+
+type otlpEvent struct {
+	Time       string          `json:"timeUnixNano"`
+	Name       string          `json:"name"`
+	Attributes []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// SPAN_KIND_INTERNAL
+const otlpSpanKindInternal = 1
+
+// Render the events as OpenTelemetry spans, in the OTLP JSON encoding.  All
+// spans are part of a single trace, with a root span covering the whole
+// startup; each category (i.e. parser) has a span covering its events, with
+// begin/end pairs as child spans and instant events as span events.
+// processEvents must have been called already.
+func renderOTLP(events []*model.Event) (otlpDocument, error) {
+	traceID, err := randomID(16)
+	if err != nil {
+		return otlpDocument{}, err
+	}
+	root, err := newOTLPSpan(traceID, "", "startup")
+	if err != nil {
+		return otlpDocument{}, err
+	}
+
+	categorySpans := make(map[string]*otlpSpan)
+	categoryStart, categoryEnd := make(map[string]time.Time), make(map[string]time.Time)
+	var spans []otlpSpan
+	var start, end time.Time
+	for _, category := range eventCategories(events) {
+		span, err := newOTLPSpan(traceID, root.SpanID, category)
+		if err != nil {
+			return otlpDocument{}, err
+		}
+		categorySpans[category] = &span
+	}
+
+	for _, event := range events {
+		eventStart := event.TimeStamp
+		eventEnd := eventStart
+		categorySpan := categorySpans[event.Category]
+		attributes := otlpAttributes(event)
+		switch event.Phase {
+		case model.EventPhaseBegin:
+			eventEnd = eventStart.Add(max(event.Duration, time.Microsecond))
+			span, err := newOTLPSpan(traceID, categorySpan.SpanID, event.Name)
+			if err != nil {
+				return otlpDocument{}, err
+			}
+			span.StartTime = otlpTime(eventStart)
+			span.EndTime = otlpTime(eventEnd)
+			span.Attributes = attributes
+			spans = append(spans, span)
+		case model.EventPhaseInstant:
+			categorySpan.Events = append(categorySpan.Events, otlpEvent{
+				Time:       otlpTime(eventStart),
+				Name:       event.Name,
+				Attributes: attributes,
+			})
+		default:
+			// End events are emitted together with the begin events.
+			continue
+		}
+		if existing, ok := categoryStart[event.Category]; !ok || eventStart.Before(existing) {
+			categoryStart[event.Category] = eventStart
+		}
+		if existing, ok := categoryEnd[event.Category]; !ok || eventEnd.After(existing) {
+			categoryEnd[event.Category] = eventEnd
+		}
+		if start.IsZero() || eventStart.Before(start) {
+			start = eventStart
+		}
+		if eventEnd.After(end) {
+			end = eventEnd
+		}
+	}
+
+	root.StartTime = otlpTime(start)
+	root.EndTime = otlpTime(end)
+	allSpans := []otlpSpan{root}
+	for _, category := range eventCategories(events) {
+		span := categorySpans[category]
+		if _, ok := categoryStart[category]; !ok {
+			continue
+		}
+		span.StartTime = otlpTime(categoryStart[category])
+		span.EndTime = otlpTime(categoryEnd[category])
+		allSpans = append(allSpans, *span)
+	}
+	allSpans = append(allSpans, spans...)
+
+	return otlpDocument{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: []otlpAttribute{
+						{Key: "service.name", Value: otlpValue{StringValue: "rancher-desktop"}},
+					},
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{Name: "startup-profile"},
+						Spans: allSpans,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// Create a new span with a random span ID.  The times must be filled in by the
+// caller.
+func newOTLPSpan(traceID, parentSpanID, name string) (otlpSpan, error) {
+	spanID, err := randomID(8)
+	if err != nil {
+		return otlpSpan{}, err
+	}
+	return otlpSpan{
+		TraceID:      traceID,
+		SpanID:       spanID,
+		ParentSpanID: parentSpanID,
+		Name:         name,
+		Kind:         otlpSpanKindInternal,
+	}, nil
+}
+
+// Convert the category and arguments of the event into span attributes.
+func otlpAttributes(event *model.Event) []otlpAttribute {
+	attributes := []otlpAttribute{
+		{Key: "category", Value: otlpValue{StringValue: event.Category}},
+	}
+	for _, key := range slices.Sorted(maps.Keys(event.Args)) {
+		attributes = append(attributes, otlpAttribute{
+			Key:   key,
+			Value: otlpValue{StringValue: fmt.Sprint(event.Args[key])},
+		})
+	}
+	return attributes
+}
+
+// Format a time as nanoseconds since the epoch; 64-bit integers are encoded as
+// strings in OTLP JSON.
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// Generate a random, hex-encoded ID of the given size in bytes.
+func randomID(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -18,7 +18,8 @@ import (
 // - All begin events have a duration set (i.e. not zero-time).
 // - All instant events have a duration set (i.e. not zero-time).
 // - Any events following zero-time events have been moved back.
-func ProcessSource(ctx context.Context, name string, events []*model.Event) error {
+// If debug is set, the normalized events are written to <name>.json.
+func ProcessSource(ctx context.Context, name string, events []*model.Event, debug bool) error {
 	// If we have no events, don't touch anything.
 	if len(events) == 0 {
 		return nil
@@ -78,17 +79,28 @@ func ProcessSource(ctx context.Context, name string, events []*model.Event) erro
 		}
 	}
 
-	if f, err := os.Create(name + ".json"); err == nil {
-		encoder := json.NewEncoder(f)
-		encoder.SetIndent("", "  ")
-		if err := encoder.Encode(events); err != nil {
-			slog.ErrorContext(ctx, "error writing debug logs", "processor", name, "error", err)
-		}
+	if debug {
+		writeDebugFile(ctx, name+".json", events)
 	}
 
 	return nil
 }
 
+// Write the given events to a file, for debugging.
+func writeDebugFile(ctx context.Context, path string, events []*model.Event) {
+	f, err := os.Create(path)
+	if err != nil {
+		slog.ErrorContext(ctx, "error creating debug log", "path", path, "error", err)
+		return
+	}
+	defer f.Close()
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(events); err != nil {
+		slog.ErrorContext(ctx, "error writing debug log", "path", path, "error", err)
+	}
+}
+
 // Process the events to normalize them.  At this point, ProcessSource must have
 // been called already (but the events may be out of order).
 func processEvents(events []*model.Event) error {
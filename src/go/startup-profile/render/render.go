@@ -2,18 +2,36 @@ package render
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log/slog"
-	"os"
 	"slices"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/startup-profile/model"
 )
 
 // Render the events into a data structure suitable to be JSON-encoded into a
-// file as a Chrome CPU profile.
-func Render(ctx context.Context, events []*model.Event) (any, error) {
+// file of the given format.  If debug is set, the processed events are also
+// written to processed.json.
+func Render(ctx context.Context, events []*model.Event, format Format, debug bool) (any, error) {
+	switch format {
+	case FormatCPUProfile:
+		return renderCPUProfile(ctx, events, debug)
+	case FormatTrace, FormatOTLP:
+		if err := processEvents(events); err != nil {
+			return nil, err
+		}
+		if debug {
+			writeDebugFile(ctx, "processed.json", events)
+		}
+		if format == FormatTrace {
+			return renderTrace(events), nil
+		}
+		return renderOTLP(events)
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// Render the events into a Chrome CPU profile.
+func renderCPUProfile(ctx context.Context, events []*model.Event, debug bool) (any, error) {
 	// Insert a fake root event at the start
 	events = append([]*model.Event{
 		{
@@ -43,12 +61,8 @@ func Render(ctx context.Context, events []*model.Event) (any, error) {
 		Time:     profile.EndTime - profile.StartTime,
 	})
 
-	if f, err := os.Create("processed.json"); err == nil {
-		encoder := json.NewEncoder(f)
-		encoder.SetIndent("", "  ")
-		if err := encoder.Encode(events); err != nil {
-			slog.ErrorContext(ctx, "error writing debug logs", "error", err)
-		}
+	if debug {
+		writeDebugFile(ctx, "processed.json", events)
 	}
 
 	var stack []*profileNode
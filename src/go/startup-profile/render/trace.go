@@ -0,0 +1,117 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/startup-profile/model"
+)
+
+// The process ID used for all events in the trace.
+const tracePID = 1
+
+// https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU/preview
+// This is the root object of the document.
+type traceDocument struct {
+	TraceEvents     []traceEvent   `json:"traceEvents"`
+	DisplayTimeUnit string         `json:"displayTimeUnit"`
+	OtherData       map[string]any `json:"otherData,omitempty"`
+}
+
+type traceEvent struct {
+	Name     string `json:"name"`
+	Category string `json:"cat,omitempty"`
+	Phase    string `json:"ph"`
+	Time     int64  `json:"ts"`
+	PID      int    `json:"pid"`
+	TID      int    `json:"tid"`
+	// ID of async events; begin and end events with the same ID are paired.
+	ID string `json:"id,omitempty"`
+	// Scope of instant events.
+	Scope string         `json:"s,omitempty"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+// Render the events in the Chrome Trace Event format.  Each category (i.e.
+// parser) is shown as a separate thread; begin/end pairs are emitted as async
+// spans, so that overlapping spans within a category are displayed correctly.
+// processEvents must have been called already.
+func renderTrace(events []*model.Event) traceDocument {
+	categories := eventCategories(events)
+	threads := make(map[string]int, len(categories))
+	document := traceDocument{
+		DisplayTimeUnit: "ms",
+		TraceEvents: []traceEvent{
+			{
+				Name:  "process_name",
+				Phase: "M",
+				PID:   tracePID,
+				Args:  map[string]any{"name": "Rancher Desktop startup"},
+			},
+		},
+	}
+	if len(events) > 0 {
+		document.OtherData = map[string]any{"startTime": events[0].TimeStamp.Format(time.RFC3339Nano)}
+	}
+
+	for i, category := range categories {
+		threads[category] = i + 1
+		document.TraceEvents = append(document.TraceEvents,
+			traceEvent{
+				Name:  "thread_name",
+				Phase: "M",
+				PID:   tracePID,
+				TID:   i + 1,
+				Args:  map[string]any{"name": category},
+			},
+			traceEvent{
+				Name:  "thread_sort_index",
+				Phase: "M",
+				PID:   tracePID,
+				TID:   i + 1,
+				Args:  map[string]any{"sort_index": i},
+			})
+	}
+
+	for i, event := range events {
+		base := traceEvent{
+			Name:     event.Name,
+			Category: event.Category,
+			Time:     event.Time,
+			PID:      tracePID,
+			TID:      threads[event.Category],
+			Args:     event.Args,
+		}
+		switch event.Phase {
+		case model.EventPhaseBegin:
+			// Emit both ends of the span here, as the end event may not be
+			// unambiguously matched to this begin event later.
+			base.ID = fmt.Sprintf("0x%x", i+1)
+			begin, end := base, base
+			begin.Phase = "b"
+			end.Phase = "e"
+			end.Args = nil
+			end.Time += max(int64(event.Duration/time.Microsecond), 1)
+			document.TraceEvents = append(document.TraceEvents, begin, end)
+		case model.EventPhaseInstant:
+			base.Phase = "i"
+			base.Scope = "t"
+			document.TraceEvents = append(document.TraceEvents, base)
+		}
+	}
+
+	return document
+}
+
+// Return the sorted list of categories of the given events.
+func eventCategories(events []*model.Event) []string {
+	var categories []string
+	for _, event := range events {
+		if !slices.Contains(categories, event.Category) {
+			categories = append(categories, event.Category)
+		}
+	}
+	slices.Sort(categories)
+	return categories
+}
@@ -15,7 +15,8 @@ import (
 	"github.com/rancher-sandbox/rancher-desktop/src/go/startup-profile/render"
 )
 
-func run(ctx context.Context, outPath marshalledPath) error {
+func run(ctx context.Context, opts options) error {
+	outPath := opts.outPath
 	// Normalize the output path, in case it's still the default value.
 	if err := outPath.UnmarshalText([]byte(outPath)); err != nil {
 		return fmt.Errorf("error normalizing output path %s: %w", outPath, err)
@@ -45,7 +46,7 @@ func run(ctx context.Context, outPath marshalledPath) error {
 			if err != nil {
 				return err
 			}
-			if err := render.ProcessSource(ctx, name, results); err != nil {
+			if err := render.ProcessSource(ctx, name, results, opts.debug); err != nil {
 				return err
 			}
 			mutex.Lock()
@@ -64,7 +65,7 @@ func run(ctx context.Context, outPath marshalledPath) error {
 	}
 
 	// Emit the output
-	data, err := render.Render(ctx, events)
+	data, err := render.Render(ctx, events, opts.format, opts.debug)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,129 @@
+//go:build linux || windows
+
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/audit"
+)
+
+// defaultAuditDir is the directory of the docker proxy audit log.
+var defaultAuditDir = filepath.Join(xdg.StateHome, "rancher-desktop", "docker-proxy-audit")
+
+var dockerproxyAuditViper = viper.New()
+
+// dockerproxyAuditCmd is the `wsl-helper docker-proxy audit` command.
+var dockerproxyAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "List the Docker API calls recorded by the docker socket proxy",
+	Long: `List the Docker API calls recorded by the docker socket proxy, oldest first.
+The --since and --until flags take either an RFC 3339 time, or a duration
+before now (e.g. 15m).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		now := time.Now()
+		since, err := parseAuditTime(dockerproxyAuditViper.GetString("since"), now)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		until, err := parseAuditTime(dockerproxyAuditViper.GetString("until"), now)
+		if err != nil {
+			return fmt.Errorf("invalid --until: %w", err)
+		}
+		records, err := audit.Query(dockerproxyAuditViper.GetString("audit-dir"), since, until)
+		if err != nil {
+			return err
+		}
+		if dockerproxyAuditViper.GetBool("json") {
+			encoder := json.NewEncoder(os.Stdout)
+			for _, record := range records {
+				if err := encoder.Encode(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "TIME\tMETHOD\tPATH\tSTATUS\tCONTAINER\tLATENCY\tDURATION")
+		for _, record := range records {
+			container := record.ContainerID
+			if len(container) > 12 {
+				container = container[:12]
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+				record.Time.Local().Format(time.RFC3339), record.Method, record.Path,
+				record.Status, container, record.Latency.Round(time.Millisecond),
+				record.Duration.Round(time.Millisecond))
+		}
+		return writer.Flush()
+	},
+}
+
+// parseAuditTime parses either an RFC 3339 time, or a duration before now;
+// an empty value is the zero time.
+func parseAuditTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// addAuditLogFlags adds the flags configuring the audit log of the
+// `docker-proxy serve` command.
+func addAuditLogFlags(flags *pflag.FlagSet) {
+	flags.String("audit-dir", defaultAuditDir, "Directory of the Docker API audit log; empty to disable it")
+	flags.Int64("audit-max-size", audit.DefaultMaxSize, "Size in bytes at which the audit log is rotated")
+	flags.Int("audit-max-files", audit.DefaultMaxFiles, "Number of audit log files to keep")
+}
+
+// openAuditLog opens the audit log configured by the flags, if enabled.
+func openAuditLog(v *viper.Viper) (*audit.Log, error) {
+	dir := v.GetString("audit-dir")
+	if dir == "" {
+		return nil, nil
+	}
+	return audit.Open(dir, v.GetInt64("audit-max-size"), v.GetInt("audit-max-files"))
+}
+
+func init() {
+	dockerproxyAuditCmd.Flags().String("audit-dir", defaultAuditDir, "Directory of the Docker API audit log")
+	dockerproxyAuditCmd.Flags().String("since", "", "Only list the calls received at or after this time")
+	dockerproxyAuditCmd.Flags().String("until", "", "Only list the calls received before this time")
+	dockerproxyAuditCmd.Flags().Bool("json", false, "Output the calls as JSON lines")
+	dockerproxyAuditViper.AutomaticEnv()
+	if err := dockerproxyAuditViper.BindPFlags(dockerproxyAuditCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	dockerproxyCmd.AddCommand(dockerproxyAuditCmd)
+}
@@ -22,8 +22,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy"
-	// Pull in to register the mungers
-	_ "github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/mungers"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/mungers"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/platform"
 )
 
@@ -43,7 +42,20 @@ var dockerproxyServeCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		err = dockerproxy.Serve(cmd.Context(), endpoint, dialer)
+		mungers.SetPolicyFile(dockerproxyServeViper.GetString("policy-file"))
+		mungers.SetMirrorsFile(dockerproxyServeViper.GetString("mirrors-file"))
+		auditLog, err := openAuditLog(dockerproxyServeViper)
+		if err != nil {
+			return err
+		}
+		if auditLog != nil {
+			defer auditLog.Close()
+		}
+		err = dockerproxy.Serve(cmd.Context(), endpoint, dialer, dockerproxy.Options{
+			AuditLog:         auditLog,
+			APISpecDir:       dockerproxyServeViper.GetString("api-spec-dir"),
+			APICompatibility: dockerproxyServeViper.GetBool("api-compatibility"),
+		})
 		if err != nil {
 			return err
 		}
@@ -54,6 +66,11 @@ var dockerproxyServeCmd = &cobra.Command{
 func init() {
 	dockerproxyServeCmd.Flags().String("endpoint", platform.DefaultEndpoint, "Endpoint to listen on")
 	dockerproxyServeCmd.Flags().Uint32("port", dockerproxy.DefaultPort, "Vsock port docker is listening on")
+	dockerproxyServeCmd.Flags().String("policy-file", mungers.DefaultPolicyFile, "YAML file describing the policy enforced on container creation and exec")
+	dockerproxyServeCmd.Flags().String("mirrors-file", mungers.DefaultMirrorsFile, "YAML file describing the registry mirrors images are pulled from; the Dockerfile images of BuildKit builds (version=2) are not rewritten")
+	dockerproxyServeCmd.Flags().String("api-spec-dir", "", "Directory with additional docker API specifications (e.g. v1.47.yaml)")
+	dockerproxyServeCmd.Flags().Bool("api-compatibility", false, "Report the oldest of the client, proxy and engine API versions")
+	addAuditLogFlags(dockerproxyServeCmd.Flags())
 	dockerproxyServeViper.AutomaticEnv()
 	if err := dockerproxyServeViper.BindPFlags(dockerproxyServeCmd.Flags()); err != nil {
 		logrus.WithError(err).Fatal("Failed to set up flags")
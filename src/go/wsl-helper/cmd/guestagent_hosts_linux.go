@@ -0,0 +1,112 @@
+//go:build linux
+
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// guestagentHostnamesPath is the status API endpoint of the guest agent
+// that lists the hostnames routed by Kubernetes objects.
+const guestagentHostnamesPath = "/v1/hostnames"
+
+// guestagentHostnameStatus matches types.HostnameStatus from the guest agent.
+type guestagentHostnameStatus struct {
+	ID        string   `json:"id"`
+	Source    string   `json:"source"`
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	Hostnames []string `json:"hostnames"`
+}
+
+var guestagentHostsViper = viper.New()
+
+// guestagentHostsCmd represents the `guestagent hosts` command.
+var guestagentHostsCmd = &cobra.Command{
+	Use:   "hosts",
+	Short: "List the hostnames routed by Kubernetes Ingress and HTTPRoute objects",
+	Long: `List the hostnames routed by Kubernetes Ingress and Gateway API HTTPRoute
+objects, in hosts file format, so that they can be resolved on the host.
+Wildcard hostnames cannot be expressed in a hosts file, and are listed as
+comments instead.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		address := guestagentHostsViper.GetString("address")
+		if net.ParseIP(address) == nil {
+			return fmt.Errorf("invalid address %q", address)
+		}
+		body, err := fetchGuestagentStatus(cmd.Context(), guestagentHostsViper.GetString("socket"), guestagentHostnamesPath)
+		if err != nil {
+			return err
+		}
+		if guestagentHostsViper.GetBool("json") {
+			var buf bytes.Buffer
+			if err := json.Indent(&buf, body, "", "  "); err != nil {
+				return fmt.Errorf("failed to format guest agent hostnames: %w", err)
+			}
+			_, err = buf.WriteTo(os.Stdout)
+			return err
+		}
+		var statuses []guestagentHostnameStatus
+		if err := json.Unmarshal(body, &statuses); err != nil {
+			return fmt.Errorf("failed to parse guest agent hostnames: %w", err)
+		}
+		return writeGuestagentHosts(os.Stdout, address, statuses)
+	},
+}
+
+// writeGuestagentHosts renders the hostnames as hosts file entries
+// resolving to the given address.
+func writeGuestagentHosts(w io.Writer, address string, statuses []guestagentHostnameStatus) error {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "# Kubernetes hostnames reported by the Rancher Desktop guest agent")
+	for _, status := range statuses {
+		fmt.Fprintf(&buf, "# %s %s/%s\n", status.Source, status.Namespace, status.Name)
+		for _, hostname := range status.Hostnames {
+			if strings.Contains(hostname, "*") {
+				fmt.Fprintf(&buf, "# %s %s (wildcard)\n", address, hostname)
+			} else {
+				fmt.Fprintf(&buf, "%s %s\n", address, hostname)
+			}
+		}
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func init() {
+	guestagentHostsCmd.Flags().String("socket", "/run/rancher-desktop-guestagent.sock", "Path to the guest agent status socket")
+	guestagentHostsCmd.Flags().String("address", "127.0.0.1", "Address the hostnames should resolve to")
+	guestagentHostsCmd.Flags().Bool("json", false, "Output the hostnames as JSON")
+	guestagentHostsViper.AutomaticEnv()
+	if err := guestagentHostsViper.BindPFlags(guestagentHostsCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	guestagentCmd.AddCommand(guestagentHostsCmd)
+}
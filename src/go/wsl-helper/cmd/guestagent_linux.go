@@ -0,0 +1,33 @@
+//go:build linux
+
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// guestagentCmd represents the guestagent command
+var guestagentCmd = &cobra.Command{
+	Use:   "guestagent",
+	Short: "Commands for interacting with the Rancher Desktop guest agent",
+}
+
+func init() {
+	rootCmd.AddCommand(guestagentCmd)
+}
@@ -0,0 +1,213 @@
+//go:build linux
+
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// guestagentPortMappingsPath is the status API endpoint of the guest agent
+// that lists the port mappings it is tracking.
+const guestagentPortMappingsPath = "/v1/port_mappings"
+
+// guestagentPortBinding matches nat.PortBinding, as reported by the guest agent.
+type guestagentPortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+// guestagentPortRemap matches types.PortRemap and types.PortDenial from
+// the guest agent.
+type guestagentPortRemap struct {
+	Port      string                `json:"port"`
+	Requested guestagentPortBinding `json:"requested"`
+	Host      guestagentPortBinding `json:"host"`
+	Reason    string                `json:"reason"`
+}
+
+// guestagentPortMappingStatus matches types.PortMappingStatus from the
+// guest agent.
+type guestagentPortMappingStatus struct {
+	ID        string                             `json:"id"`
+	Source    string                             `json:"source"`
+	Requested map[string][]guestagentPortBinding `json:"requested"`
+	Ports     map[string][]guestagentPortBinding `json:"ports"`
+	Remaps    []guestagentPortRemap              `json:"remaps"`
+	Denied    []guestagentPortRemap              `json:"denied"`
+	Errors    []string                           `json:"errors"`
+	Created   time.Time                          `json:"created"`
+	Updated   time.Time                          `json:"updated"`
+}
+
+var guestagentStatusViper = viper.New()
+
+// guestagentStatusCmd represents the `guestagent status` command.
+var guestagentStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the port mappings tracked by the guest agent",
+	Long: `Show the port mappings that the guest agent is tracking, which component
+reported them, and whether they could be exposed on the host.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		body, err := fetchGuestagentStatus(cmd.Context(), guestagentStatusViper.GetString("socket"), guestagentPortMappingsPath)
+		if err != nil {
+			return err
+		}
+		if guestagentStatusViper.GetBool("json") {
+			var buf bytes.Buffer
+			if err := json.Indent(&buf, body, "", "  "); err != nil {
+				return fmt.Errorf("failed to format guest agent status: %w", err)
+			}
+			_, err = buf.WriteTo(os.Stdout)
+			return err
+		}
+		var statuses []guestagentPortMappingStatus
+		if err := json.Unmarshal(body, &statuses); err != nil {
+			return fmt.Errorf("failed to parse guest agent status: %w", err)
+		}
+		return writeGuestagentStatus(os.Stdout, statuses)
+	},
+}
+
+// fetchGuestagentStatus returns the raw response for the given endpoint of
+// the guest agent status API listening on the given socket.
+func fetchGuestagentStatus(ctx context.Context, socketPath, path string) ([]byte, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://guestagent"+path, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to guest agent at %s: %w", socketPath, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read guest agent status: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("guest agent returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
+
+// writeGuestagentStatus renders the port mapping status as a table, with
+// one row per requested port binding showing the address it is exposed on
+// the host as, followed by any remaps, denials and errors.
+func writeGuestagentStatus(w io.Writer, statuses []guestagentPortMappingStatus) error {
+	if len(statuses) == 0 {
+		_, err := fmt.Fprintln(w, "No port mappings are being tracked.")
+		return err
+	}
+	writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tSOURCE\tPORT\tHOST\tSTATE\tUPDATED")
+	for _, status := range statuses {
+		id := status.ID
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		source := status.Source
+		if source == "" {
+			source = "-"
+		}
+		for _, port := range slices.Sorted(maps.Keys(status.Requested)) {
+			for _, binding := range status.Requested[port] {
+				host := binding
+				state := "failed"
+				if slices.Contains(status.Ports[port], binding) {
+					state = "exposed"
+				}
+				for _, remap := range status.Remaps {
+					if remap.Port == port && remap.Requested == binding {
+						host = remap.Host
+						state = "remapped"
+					}
+				}
+				for _, denial := range status.Denied {
+					if denial.Port == port && denial.Requested == binding {
+						state = "denied"
+					}
+				}
+				fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n",
+					id, source, port, net.JoinHostPort(host.HostIP, host.HostPort),
+					state, status.Updated.Local().Format(time.DateTime))
+			}
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	separator := "\nDetails:"
+	for _, status := range statuses {
+		var messages []string
+		for _, remap := range status.Remaps {
+			messages = append(messages, fmt.Sprintf("%s on %s remapped to %s by %s", remap.Port,
+				net.JoinHostPort(remap.Requested.HostIP, remap.Requested.HostPort),
+				net.JoinHostPort(remap.Host.HostIP, remap.Host.HostPort), remap.Reason))
+		}
+		for _, denial := range status.Denied {
+			messages = append(messages, fmt.Sprintf("%s on %s denied by %s", denial.Port,
+				net.JoinHostPort(denial.Requested.HostIP, denial.Requested.HostPort), denial.Reason))
+		}
+		for _, message := range append(messages, status.Errors...) {
+			if _, err := fmt.Fprintf(w, "%s\n%s: %s", separator, status.ID, message); err != nil {
+				return err
+			}
+			separator = ""
+		}
+	}
+	if separator == "" {
+		_, err := fmt.Fprintln(w)
+		return err
+	}
+	return nil
+}
+
+func init() {
+	guestagentStatusCmd.Flags().String("socket", "/run/rancher-desktop-guestagent.sock", "Path to the guest agent status socket")
+	guestagentStatusCmd.Flags().Bool("json", false, "Output the status as JSON")
+	guestagentStatusViper.AutomaticEnv()
+	if err := guestagentStatusViper.BindPFlags(guestagentStatusCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	guestagentCmd.AddCommand(guestagentStatusCmd)
+}
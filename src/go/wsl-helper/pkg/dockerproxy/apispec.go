@@ -0,0 +1,178 @@
+//go:build linux || windows
+
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// apiSpec is one version of the docker API specification.
+type apiSpec struct {
+	version *semver.Version
+	// routes are the API path templates in this version of the spec.
+	routes *routeTable
+}
+
+// apiSpecDocument is the part of a docker API specification we look at; the
+// embedded spec is JSON, while the ones published by moby are YAML.
+type apiSpecDocument struct {
+	Info struct {
+		Version string `json:"version" yaml:"version"`
+	} `json:"info" yaml:"info"`
+	// Paths maps API path templates to the operations by (lowercase) method.
+	Paths map[string]map[string]any `json:"paths" yaml:"paths"`
+}
+
+// apiSpecs are the known versions of the docker API spec, oldest first.  The
+// spec embedded in the models is always present.
+var apiSpecs struct {
+	sync.RWMutex
+	specs []*apiSpec
+}
+
+// parseAPISpec parses a docker API specification, in either JSON or YAML.
+func parseAPISpec(buf []byte) (*apiSpec, error) {
+	var document apiSpecDocument
+	var err error
+	if json.Valid(buf) {
+		err = json.Unmarshal(buf, &document)
+	} else {
+		err = yaml.Unmarshal(buf, &document)
+	}
+	if err != nil {
+		return nil, err
+	}
+	version, err := semver.NewVersion(document.Info.Version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API version %q: %w", document.Info.Version, err)
+	}
+	spec := &apiSpec{version: version, routes: newRouteTable()}
+	for apiPath, operations := range document.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+			spec.routes.add(strings.ToUpper(method), apiPath)
+		}
+	}
+	return spec, nil
+}
+
+// registerAPISpec adds a version of the docker API spec, replacing any
+// existing spec for the same version.
+func registerAPISpec(spec *apiSpec) {
+	apiSpecs.Lock()
+	defer apiSpecs.Unlock()
+	index := sort.Search(len(apiSpecs.specs), func(i int) bool {
+		return !apiSpecs.specs[i].version.LessThan(spec.version)
+	})
+	if index < len(apiSpecs.specs) && apiSpecs.specs[index].version.Equal(spec.version) {
+		apiSpecs.specs[index] = spec
+		return
+	}
+	apiSpecs.specs = append(apiSpecs.specs, nil)
+	copy(apiSpecs.specs[index+1:], apiSpecs.specs[index:])
+	apiSpecs.specs[index] = spec
+}
+
+// LoadAPISpecs loads additional versions of the docker API spec (as
+// published by moby, e.g. v1.47.yaml) from the given directory, so that
+// requests from newer clients are matched against the routes they use.
+func LoadAPISpecs(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	jsonFiles, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range append(files, jsonFiles...) {
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		spec, err := parseAPISpec(buf)
+		if err != nil {
+			return fmt.Errorf("could not parse docker API spec %s: %w", file, err)
+		}
+		registerAPISpec(spec)
+		logrus.WithFields(logrus.Fields{
+			"path":    file,
+			"version": spec.version,
+		}).Debug("loaded docker API spec")
+	}
+	return nil
+}
+
+// specForVersion returns the newest spec that is not newer than the given API
+// version, or the oldest spec if they are all newer.  Unversioned requests
+// (with a nil version) use the newest spec.
+func specForVersion(version *semver.Version) *apiSpec {
+	apiSpecs.RLock()
+	defer apiSpecs.RUnlock()
+	specs := apiSpecs.specs
+	if version == nil {
+		return specs[len(specs)-1]
+	}
+	index := sort.Search(len(specs), func(i int) bool {
+		return specs[i].version.GreaterThan(version)
+	})
+	if index == 0 {
+		return specs[0]
+	}
+	return specs[index-1]
+}
+
+// proxyAPIVersion returns the newest API version the proxy knows about.
+func proxyAPIVersion() *semver.Version {
+	return specForVersion(nil).version
+}
+
+// reportedAPIVersion returns the API version to report to the client, given
+// the one reported by the engine: it is never newer than the proxy knows
+// about.  This may be newer than the models the mungers decode request bodies
+// with; they keep the fields they don't know about.  In compatibility mode,
+// it is also never newer than the version the client requested, so that the
+// client keeps to what it understands.
+func reportedAPIVersion(engine, client *semver.Version, compatibility bool) *semver.Version {
+	result := engine
+	if proxy := proxyAPIVersion(); result.GreaterThan(proxy) {
+		result = proxy
+	}
+	if compatibility && client != nil && result.GreaterThan(client) {
+		result = client
+	}
+	return result
+}
+
+// formatAPIVersion formats an API version the way dockerd does, e.g. "1.41".
+func formatAPIVersion(version *semver.Version) string {
+	return fmt.Sprintf("%d.%d", version.Major(), version.Minor())
+}
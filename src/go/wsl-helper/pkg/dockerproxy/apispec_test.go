@@ -0,0 +1,177 @@
+//go:build linux || windows
+
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Masterminds/semver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTestAPISpecs replaces the known API specs with the reduced ones in
+// testdata (v1.43 and v1.47), and the registered mungers with none, for the
+// duration of the test.
+func useTestAPISpecs(t *testing.T) {
+	apiSpecs.Lock()
+	savedSpecs := apiSpecs.specs
+	apiSpecs.specs = nil
+	apiSpecs.Unlock()
+	mungerMapping.Lock()
+	savedMungers := mungerMapping.mungers
+	mungerMapping.mungers = make(map[string]*mungerMethodMapping)
+	mungerMapping.Unlock()
+	savedRoutes := mungerRoutes
+	mungerRoutes = newRouteTable()
+	t.Cleanup(func() {
+		apiSpecs.Lock()
+		apiSpecs.specs = savedSpecs
+		apiSpecs.Unlock()
+		mungerMapping.Lock()
+		mungerMapping.mungers = savedMungers
+		mungerMapping.Unlock()
+		mungerRoutes = savedRoutes
+	})
+	require.NoError(t, LoadAPISpecs(filepath.Join("testdata", "api-versions", "specs")))
+}
+
+func TestSpecForVersion(t *testing.T) {
+	useTestAPISpecs(t)
+
+	assert.Equal(t, "1.47", formatAPIVersion(proxyAPIVersion()))
+	testCases := map[string]string{
+		"1.41": "1.43",
+		"1.43": "1.43",
+		"1.45": "1.43",
+		"1.47": "1.47",
+		"1.51": "1.47",
+	}
+	for version, expected := range testCases {
+		spec := specForVersion(semver.MustParse(version))
+		assert.Equal(t, expected, formatAPIVersion(spec.version), version)
+	}
+	assert.Equal(t, "1.47", formatAPIVersion(specForVersion(nil).version))
+
+	// Loading a spec for a known version replaces it.
+	spec, err := parseAPISpec([]byte(`{"info":{"version":"1.43"},"paths":{"/build/prune":{"post":{}}}}`))
+	require.NoError(t, err)
+	registerAPISpec(spec)
+	template, _ := matchRoute(semver.MustParse("1.44"), http.MethodPost, "/build/prune")
+	assert.Equal(t, "/build/prune", template)
+	template, _ = matchRoute(semver.MustParse("1.44"), http.MethodGet, "/containers/json")
+	assert.Empty(t, template)
+}
+
+// recordedRequest is a request recorded from a docker client, along with the
+// API version the engine reported for it.
+type recordedRequest struct {
+	Name          string `json:"name"`
+	Method        string `json:"method"`
+	Path          string `json:"path"`
+	UserAgent     string `json:"userAgent"`
+	EngineVersion string `json:"engineVersion"`
+	Expected      struct {
+		// SpecVersion is the version of the spec the request is matched against.
+		SpecVersion string `json:"specVersion"`
+		Template    string `json:"template"`
+		// Version is the API version reported to the client.
+		Version string `json:"version"`
+		// CompatibleVersion is the API version reported in compatibility mode.
+		CompatibleVersion string `json:"compatibleVersion"`
+	} `json:"expected"`
+}
+
+func TestRecordedRequests(t *testing.T) {
+	useTestAPISpecs(t)
+
+	buf, err := os.ReadFile(filepath.Join("testdata", "api-versions", "requests.json"))
+	require.NoError(t, err)
+	var recorded []recordedRequest
+	require.NoError(t, json.Unmarshal(buf, &recorded))
+	require.NotEmpty(t, recorded)
+
+	munger := newRequestMunger()
+	for _, request := range recorded {
+		t.Run(request.Name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(t.Context(), request.Method, "http://proxy.invalid"+request.Path, http.NoBody)
+			require.NoError(t, err)
+			req.Header.Set("User-Agent", request.UserAgent)
+
+			version := munger.getRequestVersion(req)
+			spec := specForVersion(version)
+			assert.Equal(t, request.Expected.SpecVersion, formatAPIVersion(spec.version))
+			template, _ := matchRoute(version, req.Method, munger.getRequestPath(req))
+			assert.Equal(t, request.Expected.Template, template)
+
+			engine := semver.MustParse(request.EngineVersion)
+			assert.Equal(t, request.Expected.Version, formatAPIVersion(reportedAPIVersion(engine, version, false)))
+			assert.Equal(t, request.Expected.CompatibleVersion, formatAPIVersion(reportedAPIVersion(engine, version, true)))
+		})
+	}
+}
+
+func TestVersionedMungers(t *testing.T) {
+	useTestAPISpecs(t)
+
+	var called []string
+	record := func(name string) requestMungerFunc {
+		return func(req *http.Request, contextValue *RequestContextValue, templates map[string]string) error {
+			called = append(called, name+templates["name"]+templates["container"])
+			return nil
+		}
+	}
+	RegisterRequestMunger(http.MethodPost, "/containers/create", record("all"))
+	RegisterVersionedRequestMunger(">= 1.44", http.MethodPost, "/containers/create", record("new"))
+	// The same API path, with templates named differently in each version.
+	RegisterVersionedRequestMunger("< 1.44", http.MethodPost, "/containers/{name}/rename", record("old:"))
+	RegisterVersionedRequestMunger(">= 1.44", http.MethodPost, "/containers/{container}/rename", record("new:"))
+	assert.Panics(t, func() {
+		RegisterVersionedRequestMunger("newer", http.MethodPost, "/containers/create", record("invalid"))
+	})
+
+	dialer := func(context.Context) (net.Conn, error) {
+		return nil, net.ErrClosed
+	}
+	testCases := []struct {
+		path     string
+		expected []string
+	}{
+		{"/v1.43/containers/create", []string{"all"}},
+		{"/v1.44/containers/create", []string{"all", "new"}},
+		{"/containers/create", []string{"all", "new"}},
+		{"/v1.41/containers/web/rename", []string{"old:web"}},
+		{"/v1.51/containers/web/rename", []string{"new:web"}},
+	}
+	munger := newRequestMunger()
+	for _, testCase := range testCases {
+		called = nil
+		ctx := context.WithValue(t.Context(), requestContext, &RequestContextValue{})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://proxy.invalid"+testCase.path, http.NoBody)
+		require.NoError(t, err)
+		require.NoError(t, munger.MungeRequest(req, dialer))
+		assert.Equal(t, testCase.expected, called, testCase.path)
+	}
+}
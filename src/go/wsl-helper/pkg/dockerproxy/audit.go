@@ -0,0 +1,146 @@
+//go:build linux || windows
+
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/audit"
+)
+
+// auditContainerIDKey is the RequestContextValue key holding the ID of the
+// container created by the request, for the audit log.
+type auditContainerIDKey struct{}
+
+// auditResponseWriter wraps the response writer to record the status, and
+// the times at which the response headers were sent and the connection was
+// hijacked.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status     int
+	headerTime time.Time
+	hijackTime time.Time
+}
+
+func (w *auditResponseWriter) WriteHeader(statusCode int) {
+	// Informational responses are followed by the actual one, except when
+	// switching protocols.
+	final := statusCode >= http.StatusOK || statusCode == http.StatusSwitchingProtocols
+	if w.status == 0 && final {
+		w.status = statusCode
+		w.headerTime = time.Now()
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+		w.headerTime = time.Now()
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush is needed to stream responses such as logs and events.
+func (w *auditResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack is needed for upgraded connections, such as attach and exec.
+func (w *auditResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijackTime = time.Now()
+	}
+	return conn, rw, err
+}
+
+func (w *auditResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// recordCreatedContainer remembers the ID of the container created by a
+// successful container create request, as it is not part of the path.
+func (m *requestMunger) recordCreatedContainer(resp *http.Response) {
+	if resp.Request.Method != http.MethodPost || resp.StatusCode != http.StatusCreated {
+		return
+	}
+	contextValue, _ := resp.Request.Context().Value(requestContext).(*RequestContextValue)
+	if contextValue == nil {
+		return
+	}
+	version := m.getRequestVersion(resp.Request)
+	if template, _ := matchRoute(version, http.MethodPost, m.getRequestPath(resp.Request)); template != "/containers/create" {
+		return
+	}
+	buf, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewBuffer(buf))
+	if err != nil {
+		logrus.WithError(err).Debug("could not read container create response")
+		return
+	}
+	var body containerInspectResponseBody
+	if err := json.Unmarshal(buf, &body); err == nil {
+		(*contextValue)[auditContainerIDKey{}] = body.ID
+	}
+}
+
+// auditRecord describes a completed request for the audit log.
+func (m *requestMunger) auditRecord(req *http.Request, contextValue *RequestContextValue, w *auditResponseWriter, start time.Time) audit.Record {
+	end := time.Now()
+	requestPath := m.getRequestPath(req)
+	record := audit.Record{
+		Time:      start.UTC(),
+		Method:    req.Method,
+		Path:      requestPath,
+		UserAgent: req.UserAgent(),
+		Status:    w.status,
+		Duration:  end.Sub(start),
+	}
+	if prefix := m.apiDetectPattern.FindString(req.URL.Path); prefix != "" {
+		record.APIVersion = strings.Trim(prefix, "/v")
+	}
+	template, templates := matchRoute(m.getRequestVersion(req), req.Method, requestPath)
+	record.Template = template
+	if strings.HasPrefix(template, "/containers/") {
+		record.ContainerID = templates["id"]
+	}
+	if id, ok := (*contextValue)[auditContainerIDKey{}].(string); ok {
+		record.ContainerID = id
+	}
+	if !w.headerTime.IsZero() {
+		record.Latency = w.headerTime.Sub(start)
+	}
+	if !w.hijackTime.IsZero() {
+		record.UpgradeDuration = end.Sub(w.hijackTime)
+	}
+	return record
+}
@@ -0,0 +1,221 @@
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit records the Docker API calls passing through the docker
+// proxy as JSON lines, in size-capped rotating files.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxSize is the size of the audit log before it is rotated.
+	DefaultMaxSize = 10 * 1024 * 1024
+	// DefaultMaxFiles is the number of audit log files kept, including the
+	// current one.
+	DefaultMaxFiles = 5
+
+	// fileName is the current audit log; rotated files are numbered, with
+	// audit.1.jsonl being the most recent one.
+	fileName = "audit.jsonl"
+)
+
+// Record is a single Docker API call.
+type Record struct {
+	// Time is when the request was received.
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	// Path is the request path, without the API version and query.
+	Path string `json:"path"`
+	// Template is the API path template the request matched, e.g.
+	// /containers/{id}/start, if any.
+	Template    string `json:"template,omitempty"`
+	APIVersion  string `json:"apiVersion,omitempty"`
+	ContainerID string `json:"containerId,omitempty"`
+	UserAgent   string `json:"userAgent,omitempty"`
+	Status      int    `json:"status"`
+	// Latency is the time until the response headers were sent.
+	Latency time.Duration `json:"latency"`
+	// Duration is the time until the call completed, including streamed
+	// responses and upgraded connections.
+	Duration time.Duration `json:"duration"`
+	// UpgradeDuration is how long the connection was hijacked for, after
+	// an upgrade (e.g. for attach or exec).
+	UpgradeDuration time.Duration `json:"upgradeDuration,omitempty"`
+}
+
+// Log writes records to the audit log files in a directory.
+type Log struct {
+	dir      string
+	maxSize  int64
+	maxFiles int
+
+	mutex sync.Mutex
+	file  *os.File
+	size  int64
+}
+
+// Open opens the audit log in the given directory, creating it if needed.
+// The log is rotated once it reaches maxSize bytes, keeping maxFiles files.
+func Open(dir string, maxSize int64, maxFiles int) (*Log, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxFiles < 1 {
+		maxFiles = DefaultMaxFiles
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("could not create audit log directory %s: %w", dir, err)
+	}
+	l := &Log{dir: dir, maxSize: maxSize, maxFiles: maxFiles}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) open() error {
+	file, err := os.OpenFile(filepath.Join(l.dir, fileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("could not open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("could not open audit log: %w", err)
+	}
+	l.file, l.size = file, info.Size()
+	return nil
+}
+
+// Write appends a record to the audit log, rotating it if needed.
+func (l *Log) Write(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.file == nil {
+		return os.ErrClosed
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// rotate shifts the numbered files, dropping the oldest one, and starts a
+// new current file; this must be called with the lock held.
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+	for i := l.maxFiles - 1; i > 0; i-- {
+		source := filepath.Join(l.dir, fileName)
+		if i > 1 {
+			source = rotatedName(l.dir, i-1)
+		}
+		err := os.Rename(source, rotatedName(l.dir, i))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not rotate audit log: %w", err)
+		}
+	}
+	if l.maxFiles == 1 {
+		if err := os.Remove(filepath.Join(l.dir, fileName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not rotate audit log: %w", err)
+		}
+	}
+	return l.open()
+}
+
+// Close closes the audit log; further writes fail.
+func (l *Log) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func rotatedName(dir string, index int) string {
+	return filepath.Join(dir, fmt.Sprintf("audit.%d.jsonl", index))
+}
+
+// Query returns the records of the audit log in the given directory that
+// were received within [since, until), sorted by time. A zero since or
+// until leaves that end of the window open. Lines that can't be parsed,
+// such as a partial last line, are skipped.
+func Query(dir string, since, until time.Time) ([]Record, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "audit.*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	names = append(names, filepath.Join(dir, fileName))
+	var records []Record
+	for _, name := range names {
+		file, err := os.Open(name)
+		if errors.Is(err, os.ErrNotExist) {
+			// The file may have been rotated out since it was listed.
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var record Record
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				continue
+			}
+			if !since.IsZero() && record.Time.Before(since) {
+				continue
+			}
+			if !until.IsZero() && !record.Time.Before(until) {
+				continue
+			}
+			records = append(records, record)
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not read audit log %s: %w", name, err)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	return records, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogRotation(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := func(i int) Record {
+		return Record{
+			Time:     start.Add(time.Duration(i) * time.Minute),
+			Method:   "POST",
+			Path:     "/containers/abc/start",
+			Template: "/containers/{id}/start",
+			Status:   204,
+		}
+	}
+	line, err := json.Marshal(record(0))
+	require.NoError(t, err)
+
+	// Each file holds two records.
+	log, err := Open(dir, int64(2*(len(line)+1)), 3)
+	require.NoError(t, err)
+	for i := range 7 {
+		require.NoError(t, log.Write(record(i)))
+	}
+	require.NoError(t, log.Close())
+	assert.ErrorIs(t, log.Write(record(7)), os.ErrClosed)
+
+	names, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, "audit.jsonl"),
+		filepath.Join(dir, "audit.1.jsonl"),
+		filepath.Join(dir, "audit.2.jsonl"),
+	}, names)
+
+	records, err := Query(dir, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, records, 5, "the oldest file should be rotated out")
+	for i, record := range records {
+		assert.Equal(t, start.Add(time.Duration(i+2)*time.Minute), record.Time)
+	}
+
+	// Reopening appends to the current file.
+	log, err = Open(dir, int64(2*(len(line)+1)), 3)
+	require.NoError(t, err)
+	require.NoError(t, log.Write(record(7)))
+	require.NoError(t, log.Close())
+	records, err = Query(dir, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, records, 6)
+}
+
+func TestQueryWindow(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	log, err := Open(dir, 0, 0)
+	require.NoError(t, err)
+	// Records are written once the call completes, so they may be out of order.
+	for _, minutes := range []int{3, 1, 2, 0, 4} {
+		require.NoError(t, log.Write(Record{
+			Time:   start.Add(time.Duration(minutes) * time.Minute),
+			Method: "GET",
+			Path:   "/containers/json",
+			Status: 200,
+		}))
+	}
+	require.NoError(t, log.Close())
+
+	// A partial line, as left by a crash, is skipped.
+	file, err := os.OpenFile(filepath.Join(dir, "audit.jsonl"), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"time":"2026-01-01T00:0`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	records, err := Query(dir, start.Add(time.Minute), start.Add(3*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, start.Add(time.Minute), records[0].Time)
+	assert.Equal(t, start.Add(2*time.Minute), records[1].Time)
+
+	records, err = Query(dir, start.Add(3*time.Minute), time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	records, err = Query(t.TempDir(), time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
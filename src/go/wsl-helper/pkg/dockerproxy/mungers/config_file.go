@@ -0,0 +1,96 @@
+//go:build linux || windows
+
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mungers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is a YAML configuration file of the mungers, which is reloaded
+// whenever it changes so that the proxy doesn't need to be restarted.
+type configFile[T any] struct {
+	// path of the file; if empty, there is no configuration.
+	path string
+	// validate, if set, checks the configuration after it is parsed.
+	validate func(*T) error
+
+	// The configuration, as loaded from the file with the given modification
+	// time and size.
+	value   *T
+	modTime time.Time
+	size    int64
+
+	sync.Mutex
+}
+
+// setPath sets the path of the file, which is (re)loaded on next use.
+func (c *configFile[T]) setPath(configPath string) {
+	c.Lock()
+	defer c.Unlock()
+	c.path = configPath
+	c.value = nil
+}
+
+// current returns the current configuration, or nil if the file doesn't
+// exist.
+func (c *configFile[T]) current() (*T, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.path == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		c.value = nil
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if c.value != nil && info.ModTime().Equal(c.modTime) && info.Size() == c.size {
+		return c.value, nil
+	}
+	buf, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, err
+	}
+	var loaded T
+	decoder := yaml.NewDecoder(bytes.NewReader(buf))
+	// Typos in the configuration should not go unnoticed.
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&loaded); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("could not parse %s: %w", c.path, err)
+	}
+	if c.validate != nil {
+		if err := c.validate(&loaded); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", c.path, err)
+		}
+	}
+	c.value, c.modTime, c.size = &loaded, info.ModTime(), info.Size()
+	logrus.WithField("path", c.path).Info("loaded docker proxy configuration")
+	return c.value, nil
+}
@@ -17,11 +17,9 @@ limitations under the License.
 package mungers
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path"
@@ -267,14 +265,10 @@ func (b *bindManager) mungeContainersCreateRequest(req *http.Request, contextVal
 	}
 
 	(*contextValue)[contextKey] = &binds
-	buf, err := json.Marshal(&body)
-	if err != nil {
+	if err := writeRequestBodyJSON(req, &body); err != nil {
 		logrus.WithError(err).Error("could not re-serialize modified body")
 		return err
 	}
-	req.Body = io.NopCloser(bytes.NewBuffer(buf))
-	req.ContentLength = int64(len(buf))
-	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(buf)))
 	logrus.WithField("binds", fmt.Sprintf("%+v", binds)).Debug("modified binds")
 
 	return nil
@@ -441,8 +435,14 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	// The policy is checked against the original request, before the image
+	// and the bind paths are rewritten.
+	dockerproxy.RegisterRequestMunger(http.MethodPost, "/containers/create", policy.mungeContainersCreateRequest)
+	dockerproxy.RegisterRequestMunger(http.MethodPost, "/containers/{id}/exec", policy.mungeContainersExecRequest)
+	dockerproxy.RegisterRequestMunger(http.MethodPost, "/containers/create", mirrors.mungeContainersCreateRequest)
 	dockerproxy.RegisterRequestMunger(http.MethodPost, "/containers/create", b.mungeContainersCreateRequest)
 	dockerproxy.RegisterResponseMunger(http.MethodPost, "/containers/create", b.mungeContainersCreateResponse)
+	dockerproxy.RegisterResponseMunger(http.MethodPost, "/containers/create", mirrors.mungeResponse)
 	dockerproxy.RegisterRequestMunger(http.MethodPost, "/containers/{id}/start", b.mungeContainersStartRequest)
 	dockerproxy.RegisterRequestMunger(http.MethodPost, "/containers/{id}/restart", b.mungeContainersStartRequest)
 	dockerproxy.RegisterResponseMunger(http.MethodPost, "/containers/{id}/start", b.mungeContainersStartResponse)
@@ -22,9 +22,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"os"
 	"path"
+	"slices"
 	"strings"
 	"testing"
 
@@ -224,6 +226,71 @@ func TestContainersCreate(t *testing.T) {
 		}, requestBody.HostConfig.Mounts)
 		assert.Equal(t, "hello", responseBody.ID)
 	})
+
+	t.Run("unknown fields", func(t *testing.T) {
+		bindManager := &bindManager{
+			mountRoot: t.TempDir(),
+			entries:   make(map[string]bindManagerEntry),
+			statePath: path.Join(t.TempDir(), "state.json"),
+		}
+
+		// Fields from API versions newer than the models must be kept.
+		bindPath := t.TempDir()
+		original := fmt.Sprintf(`{
+			"Image": "alpine",
+			"StopTimeout": 12345678901234567,
+			"HostConfig": {
+				"Binds": ["/foo:/foo"],
+				"Annotations": {"key": "value"},
+				"Mounts": [{"Type": "bind", "Source": %q, "Target": "/host", "BindOptions": {"ReadOnlyNonRecursive": true}}]
+			},
+			"NetworkingConfig": {"EndpointsConfig": {"web": {"GwPriority": 10}}}
+		}`, bindPath)
+		req, err := http.NewRequestWithContext(
+			context.Background(),
+			http.MethodPost,
+			"http://nowhere.invalid/",
+			io.NopCloser(strings.NewReader(original)))
+		require.NoError(t, err)
+		contextValue := &dockerproxy.RequestContextValue{}
+		err = bindManager.mungeContainersCreateRequest(req, contextValue, make(map[string]string))
+		require.NoError(t, err)
+
+		buf, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(buf)), req.ContentLength)
+		var body struct {
+			Image       string
+			StopTimeout json.Number
+			HostConfig  struct {
+				Binds       []string
+				Annotations map[string]string
+				Mounts      []map[string]any
+			}
+			NetworkingConfig map[string]any
+		}
+		decoder := json.NewDecoder(bytes.NewReader(buf))
+		decoder.UseNumber()
+		require.NoError(t, decoder.Decode(&body))
+		assert.Equal(t, "alpine", body.Image)
+		assert.Equal(t, json.Number("12345678901234567"), body.StopTimeout)
+		assert.Equal(t, map[string]string{"key": "value"}, body.HostConfig.Annotations)
+		require.Len(t, body.HostConfig.Binds, 1)
+		assert.True(t, strings.HasPrefix(body.HostConfig.Binds[0], bindManager.mountRoot), body.HostConfig.Binds[0])
+		require.Len(t, body.HostConfig.Mounts, 1)
+		assert.True(t, strings.HasPrefix(body.HostConfig.Mounts[0]["Source"].(string), bindManager.mountRoot))
+		assert.Equal(t, map[string]any{"ReadOnlyNonRecursive": true}, body.HostConfig.Mounts[0]["BindOptions"])
+		assert.Equal(t, map[string]any{"EndpointsConfig": map[string]any{"web": map[string]any{"GwPriority": json.Number("10")}}}, body.NetworkingConfig)
+
+		// Fields that the client didn't send must not be added.
+		var members map[string]any
+		require.NoError(t, json.Unmarshal(buf, &members))
+		assert.ElementsMatch(t, []string{"Image", "StopTimeout", "HostConfig", "NetworkingConfig"}, slices.Collect(maps.Keys(members)))
+		hostConfig := members["HostConfig"].(map[string]any)
+		assert.ElementsMatch(t, []string{"Binds", "Annotations", "Mounts"}, slices.Collect(maps.Keys(hostConfig)))
+		mount := hostConfig["Mounts"].([]any)[0].(map[string]any)
+		assert.ElementsMatch(t, []string{"Type", "Source", "Target", "BindOptions"}, slices.Collect(maps.Keys(mount)))
+	})
 }
 
 func TestContainersStart(t *testing.T) {
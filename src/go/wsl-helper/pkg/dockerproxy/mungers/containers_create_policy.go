@@ -0,0 +1,256 @@
+//go:build linux || windows
+
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mungers
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/models"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/platform"
+)
+
+// containerPolicy is the local policy enforced on POST /containers/create,
+// and on POST /containers/{id}/exec for privileged exec sessions.  It is read from a YAML file, for example:
+//
+//	denyPrivileged: true
+//	denyHostNetwork: true
+//	denyHostPID: true
+//	deniedCapabilities: [SYS_ADMIN, NET_ADMIN]
+//	allowedBindPaths: [/home/user/src]
+//	allowedRegistries: [docker.io/library, ghcr.io/example]
+//
+// Empty lists don't restrict anything.
+type containerPolicy struct {
+	DenyPrivileged  bool `yaml:"denyPrivileged"`
+	DenyHostNetwork bool `yaml:"denyHostNetwork"`
+	DenyHostPID     bool `yaml:"denyHostPID"`
+	// DeniedCapabilities are the capabilities that can't be added; ALL
+	// denies adding any capability.
+	DeniedCapabilities []string `yaml:"deniedCapabilities"`
+	// AllowedBindPaths are the host directories that can be bind mounted,
+	// including their subdirectories.
+	AllowedBindPaths []string `yaml:"allowedBindPaths"`
+	// AllowedRegistries are the registries images can come from, optionally
+	// followed by a repository path prefix.  Full image IDs are allowed, as
+	// they refer to images that are already present.
+	AllowedRegistries []string `yaml:"allowedRegistries"`
+}
+
+// containersCreatePolicyBody is the part of a /containers/create request the
+// policy is checked against.
+type containersCreatePolicyBody struct {
+	models.ContainerConfig
+	HostConfig models.HostConfig
+}
+
+// containersExecPolicyBody is the part of a /containers/{id}/exec request the
+// policy is checked against.
+type containersExecPolicyBody struct {
+	Privileged bool
+}
+
+// check returns the reason the container is denied, or an empty string if it
+// is allowed.
+func (p *containerPolicy) check(body *containersCreatePolicyBody) string {
+	if p.DenyPrivileged && body.HostConfig.Privileged {
+		return "privileged containers are not allowed"
+	}
+	if p.DenyHostNetwork && body.HostConfig.NetworkMode == "host" {
+		return "the host network mode is not allowed"
+	}
+	if p.DenyHostPID && body.HostConfig.PidMode == "host" {
+		return "the host PID mode is not allowed"
+	}
+	if reason := p.checkCapabilities(body.HostConfig.CapAdd); reason != "" {
+		return reason
+	}
+	if reason := p.checkBindPaths(&body.HostConfig); reason != "" {
+		return reason
+	}
+	return p.checkImage(body.Image)
+}
+
+// checkExec returns the reason the exec session is denied, or an empty string
+// if it is allowed.
+func (p *containerPolicy) checkExec(body *containersExecPolicyBody) string {
+	if p.DenyPrivileged && body.Privileged {
+		return "privileged exec sessions are not allowed"
+	}
+	return ""
+}
+
+func (p *containerPolicy) checkCapabilities(capAdd []string) string {
+	if len(p.DeniedCapabilities) == 0 {
+		return ""
+	}
+	denied := make(map[string]bool)
+	for _, capability := range p.DeniedCapabilities {
+		denied[normalizeCapability(capability)] = true
+	}
+	for _, capability := range capAdd {
+		capability = normalizeCapability(capability)
+		if capability == "ALL" {
+			return "adding all capabilities is not allowed"
+		}
+		if denied["ALL"] || denied[capability] {
+			return fmt.Sprintf("adding the %s capability is not allowed", capability)
+		}
+	}
+	return ""
+}
+
+// normalizeCapability returns the capability name as dockerd understands
+// it: case-insensitive, and with an optional CAP_ prefix.
+func normalizeCapability(capability string) string {
+	return strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(capability)), "CAP_")
+}
+
+func (p *containerPolicy) checkBindPaths(hostConfig *models.HostConfig) string {
+	if len(p.AllowedBindPaths) == 0 {
+		return ""
+	}
+	var sources []string
+	for _, bind := range hostConfig.Binds {
+		if host, _, _, isPath := platform.ParseBindString(bind); isPath {
+			sources = append(sources, host)
+		}
+	}
+	for _, mount := range hostConfig.Mounts {
+		if mount != nil && mount.Type == models.MountTypeBind {
+			sources = append(sources, mount.Source)
+		}
+	}
+	for _, source := range sources {
+		normalized := normalizeHostPath(source)
+		allowed := slices.ContainsFunc(p.AllowedBindPaths, func(allowedPath string) bool {
+			return hasPathPrefix(normalized, normalizeHostPath(allowedPath))
+		})
+		if !allowed {
+			return fmt.Sprintf("bind mounting %s is not allowed", source)
+		}
+	}
+	return ""
+}
+
+// normalizeHostPath returns a cleaned, slash-separated host path, so that
+// e.g. "/home/user/../../etc" is not mistaken for a path under /home.  Paths
+// are compared case-insensitively on Windows.
+func normalizeHostPath(hostPath string) string {
+	hostPath = path.Clean(strings.ReplaceAll(hostPath, `\`, "/"))
+	if runtime.GOOS == "windows" {
+		hostPath = strings.ToLower(hostPath)
+	}
+	return hostPath
+}
+
+func (p *containerPolicy) checkImage(image string) string {
+	if len(p.AllowedRegistries) == 0 || imageIDPattern.MatchString(image) {
+		return ""
+	}
+	registry, repository, _ := splitImageReference(image)
+	for _, allowed := range p.AllowedRegistries {
+		allowedRegistry, prefix, _ := strings.Cut(strings.TrimSpace(allowed), "/")
+		if normalizeRegistry(allowedRegistry) == registry && hasPathPrefix(repository, prefix) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("images from %s/%s are not allowed", registry, repository)
+}
+
+// policyManager loads the policy file, reloading it whenever it changes.
+type policyManager struct {
+	configFile[containerPolicy]
+}
+
+// policy is the policy enforced by the docker proxy.
+var policy = &policyManager{}
+
+// DefaultPolicyFile is the default location of the policy file.
+var DefaultPolicyFile = filepath.Join(xdg.ConfigHome, "rancher-desktop", "docker-proxy-policy.yaml")
+
+// SetPolicyFile sets the path of the YAML file describing the policy
+// enforced on container creation; the policy is not enforced if the file
+// doesn't exist.  The file is reloaded whenever it changes.
+func SetPolicyFile(policyPath string) {
+	policy.setPath(policyPath)
+}
+
+// load returns the current policy, or nil if there is none.
+func (m *policyManager) load() (*containerPolicy, error) {
+	current, err := m.current()
+	if err != nil {
+		// Don't let requests through if the policy can't be enforced.
+		logrus.WithError(err).Error("could not load docker proxy policy")
+		return nil, &dockerproxy.RequestRejectedError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    fmt.Sprintf("could not load the docker proxy policy: %s", err),
+		}
+	}
+	return current, nil
+}
+
+// munge incoming request for POST /containers/create to enforce the policy;
+// this must be registered before the mungers that rewrite the request.
+func (m *policyManager) mungeContainersCreateRequest(req *http.Request, contextValue *dockerproxy.RequestContextValue, templates map[string]string) error {
+	current, err := m.load()
+	if err != nil || current == nil {
+		return err
+	}
+	body := containersCreatePolicyBody{}
+	if err := readRequestBodyJSON(req, &body); err != nil {
+		return err
+	}
+	if reason := current.check(&body); reason != "" {
+		return &dockerproxy.RequestRejectedError{
+			StatusCode: http.StatusForbidden,
+			Message:    fmt.Sprintf("container creation denied by policy: %s", reason),
+		}
+	}
+	return nil
+}
+
+// munge incoming request for POST /containers/{id}/exec to enforce the policy,
+// so that a privileged shell can't be opened in an unprivileged container.
+func (m *policyManager) mungeContainersExecRequest(req *http.Request, contextValue *dockerproxy.RequestContextValue, templates map[string]string) error {
+	current, err := m.load()
+	if err != nil || current == nil {
+		return err
+	}
+	body := containersExecPolicyBody{}
+	if err := readRequestBodyJSON(req, &body); err != nil {
+		return err
+	}
+	if reason := current.checkExec(&body); reason != "" {
+		return &dockerproxy.RequestRejectedError{
+			StatusCode: http.StatusForbidden,
+			Message:    fmt.Sprintf("exec denied by policy: %s", reason),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mungers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/models"
+)
+
+func TestContainerPolicyCheck(t *testing.T) {
+	t.Parallel()
+
+	strict := containerPolicy{
+		DenyPrivileged:     true,
+		DenyHostNetwork:    true,
+		DenyHostPID:        true,
+		DeniedCapabilities: []string{"SYS_ADMIN", "cap_net_admin"},
+		AllowedBindPaths:   []string{"/home/user/src", "/tmp/"},
+		AllowedRegistries:  []string{"docker.io/library", "ghcr.io/example", "registry.internal:5000"},
+	}
+	body := func(modify func(*containersCreatePolicyBody)) *containersCreatePolicyBody {
+		result := &containersCreatePolicyBody{}
+		result.Image = "alpine:3.20"
+		if modify != nil {
+			modify(result)
+		}
+		return result
+	}
+
+	testCases := []struct {
+		name   string
+		policy containerPolicy
+		body   *containersCreatePolicyBody
+		// reason is the expected denial reason; empty if allowed.
+		reason string
+	}{
+		{
+			name:   "empty policy allows everything",
+			policy: containerPolicy{},
+			body: body(func(b *containersCreatePolicyBody) {
+				b.Image = "evil.example/miner"
+				b.HostConfig.Privileged = true
+				b.HostConfig.NetworkMode = "host"
+				b.HostConfig.CapAdd = []string{"ALL"}
+				b.HostConfig.Binds = []string{"/:/host"}
+			}),
+		},
+		{
+			name:   "plain container",
+			policy: strict,
+			body:   body(nil),
+		},
+		{
+			name:   "privileged",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.HostConfig.Privileged = true }),
+			reason: "privileged containers are not allowed",
+		},
+		{
+			name:   "host network",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.HostConfig.NetworkMode = "host" }),
+			reason: "the host network mode is not allowed",
+		},
+		{
+			name:   "bridge network",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.HostConfig.NetworkMode = "bridge" }),
+		},
+		{
+			name:   "host PID",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.HostConfig.PidMode = "host" }),
+			reason: "the host PID mode is not allowed",
+		},
+		{
+			name:   "denied capability with prefix",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.HostConfig.CapAdd = []string{"CAP_SYS_ADMIN"} }),
+			reason: "adding the SYS_ADMIN capability is not allowed",
+		},
+		{
+			name:   "denied capability in lower case",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.HostConfig.CapAdd = []string{"net_admin"} }),
+			reason: "adding the NET_ADMIN capability is not allowed",
+		},
+		{
+			name:   "all capabilities",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.HostConfig.CapAdd = []string{"all"} }),
+			reason: "adding all capabilities is not allowed",
+		},
+		{
+			name:   "allowed capability",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.HostConfig.CapAdd = []string{"NET_RAW"} }),
+		},
+		{
+			name:   "all capabilities denied",
+			policy: containerPolicy{DeniedCapabilities: []string{"ALL"}},
+			body:   body(func(b *containersCreatePolicyBody) { b.HostConfig.CapAdd = []string{"NET_RAW"} }),
+			reason: "adding the NET_RAW capability is not allowed",
+		},
+		{
+			name:   "allowed binds",
+			policy: strict,
+			body: body(func(b *containersCreatePolicyBody) {
+				b.HostConfig.Binds = []string{"/home/user/src:/src:ro", "/tmp:/tmp", "data:/data"}
+			}),
+		},
+		{
+			name:   "bind outside allowed paths",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.HostConfig.Binds = []string{"/home/user/.ssh:/ssh"} }),
+			reason: "bind mounting /home/user/.ssh is not allowed",
+		},
+		{
+			name:   "bind sharing a prefix with an allowed path",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.HostConfig.Binds = []string{"/home/user/src2:/src"} }),
+			reason: "bind mounting /home/user/src2 is not allowed",
+		},
+		{
+			name:   "bind escaping an allowed path",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.HostConfig.Binds = []string{"/tmp/../etc:/etc2"} }),
+			reason: "bind mounting /tmp/../etc is not allowed",
+		},
+		{
+			name:   "bind mount outside allowed paths",
+			policy: strict,
+			body: body(func(b *containersCreatePolicyBody) {
+				b.HostConfig.Mounts = []*models.Mount{
+					{Type: models.MountTypeVolume, Source: "data", Target: "/data"},
+					{Type: models.MountTypeBind, Source: "/var/run/docker.sock", Target: "/var/run/docker.sock"},
+				}
+			}),
+			reason: "bind mounting /var/run/docker.sock is not allowed",
+		},
+		{
+			name:   "official image by digest",
+			policy: strict,
+			body: body(func(b *containersCreatePolicyBody) {
+				b.Image = "docker.io/library/alpine@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+			}),
+		},
+		{
+			name:   "docker hub user image",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.Image = "someone/alpine" }),
+			reason: "images from docker.io/someone/alpine are not allowed",
+		},
+		{
+			name:   "allowed repository prefix",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.Image = "ghcr.io/example/app:v1" }),
+		},
+		{
+			name:   "other repository on an allowed registry",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.Image = "ghcr.io/examples/app" }),
+			reason: "images from ghcr.io/examples/app are not allowed",
+		},
+		{
+			name:   "registry with a port",
+			policy: strict,
+			body:   body(func(b *containersCreatePolicyBody) { b.Image = "Registry.Internal:5000/team/app:latest" }),
+		},
+		{
+			name:   "image ID",
+			policy: strict,
+			body: body(func(b *containersCreatePolicyBody) {
+				b.Image = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+			}),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.reason, testCase.policy.check(testCase.body))
+		})
+	}
+}
+
+func TestSplitImageReference(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		image, registry, repository, suffix string
+	}{
+		{"alpine", "docker.io", "library/alpine", ""},
+		{"alpine:3.20", "docker.io", "library/alpine", ":3.20"},
+		{"user/app@sha256:abcd", "docker.io", "user/app", "@sha256:abcd"},
+		{"index.docker.io/user/app", "docker.io", "user/app", ""},
+		{"localhost/app:v1", "localhost", "app", ":v1"},
+		{"localhost:5000/app", "localhost:5000", "app", ""},
+		{"ghcr.io/org/team/app:v1@sha256:abcd", "ghcr.io", "org/team/app", ":v1@sha256:abcd"},
+	}
+	for _, testCase := range testCases {
+		registry, repository, suffix := splitImageReference(testCase.image)
+		assert.Equal(t, testCase.registry, registry, testCase.image)
+		assert.Equal(t, testCase.repository, repository, testCase.image)
+		assert.Equal(t, testCase.suffix, suffix, testCase.image)
+	}
+}
+
+func TestContainersCreatePolicyMunger(t *testing.T) {
+	t.Parallel()
+
+	policyPath := filepath.Join(t.TempDir(), "policy.yaml")
+	manager := &policyManager{}
+	manager.setPath(policyPath)
+	munge := func(t *testing.T, body containersCreatePolicyBody) error {
+		buf, err := json.Marshal(&body)
+		require.NoError(t, err)
+		req, err := http.NewRequestWithContext(
+			t.Context(),
+			http.MethodPost,
+			"http://nowhere.invalid/containers/create",
+			io.NopCloser(bytes.NewReader(buf)))
+		require.NoError(t, err)
+		err = manager.mungeContainersCreateRequest(req, &dockerproxy.RequestContextValue{}, map[string]string{})
+		if err == nil {
+			// The body must be left for the next munger.
+			var remaining containersCreatePolicyBody
+			require.NoError(t, readRequestBodyJSON(req, &remaining))
+			assert.Equal(t, body, remaining)
+		}
+		return err
+	}
+	writePolicy := func(t *testing.T, contents string, modTime time.Time) {
+		require.NoError(t, os.WriteFile(policyPath, []byte(contents), 0o600))
+		require.NoError(t, os.Chtimes(policyPath, modTime, modTime))
+	}
+	privileged := containersCreatePolicyBody{}
+	privileged.Image = "alpine"
+	privileged.HostConfig.Privileged = true
+
+	// Without a policy file, everything is allowed.
+	assert.NoError(t, munge(t, privileged))
+
+	start := time.Now().Add(-time.Hour)
+	writePolicy(t, "denyPrivileged: true\n", start)
+	err := munge(t, privileged)
+	var rejected *dockerproxy.RequestRejectedError
+	require.ErrorAs(t, err, &rejected)
+	assert.Equal(t, http.StatusForbidden, rejected.StatusCode)
+	assert.Equal(t, "container creation denied by policy: privileged containers are not allowed", rejected.Message)
+
+	// Changes to the file are picked up.
+	writePolicy(t, "denyPrivileged: false\n", start.Add(time.Minute))
+	assert.NoError(t, munge(t, privileged))
+
+	// Unknown keys fail closed.
+	writePolicy(t, "denyPriviledged: true\n", start.Add(2*time.Minute))
+	err = munge(t, privileged)
+	require.ErrorAs(t, err, &rejected)
+	assert.Equal(t, http.StatusInternalServerError, rejected.StatusCode)
+	assert.Contains(t, rejected.Message, "denyPriviledged")
+
+	// An empty file is an empty policy.
+	writePolicy(t, "", start.Add(3*time.Minute))
+	assert.NoError(t, munge(t, privileged))
+
+	require.NoError(t, os.Remove(policyPath))
+	assert.NoError(t, munge(t, privileged))
+}
+
+func TestContainersExecPolicyMunger(t *testing.T) {
+	t.Parallel()
+
+	policyPath := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(policyPath, []byte("denyPrivileged: true\n"), 0o600))
+	manager := &policyManager{}
+	manager.setPath(policyPath)
+	munge := func(t *testing.T, body string) error {
+		req, err := http.NewRequestWithContext(
+			t.Context(),
+			http.MethodPost,
+			"http://nowhere.invalid/containers/abcd/exec",
+			io.NopCloser(bytes.NewReader([]byte(body))))
+		require.NoError(t, err)
+		err = manager.mungeContainersExecRequest(req, &dockerproxy.RequestContextValue{}, map[string]string{"id": "abcd"})
+		if err == nil {
+			// The body must be left for dockerd.
+			remaining, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, body, string(remaining))
+		}
+		return err
+	}
+
+	assert.NoError(t, munge(t, `{"Cmd":["sh"],"AttachStdin":true,"Tty":true}`))
+	assert.NoError(t, munge(t, `{"Cmd":["sh"],"Privileged":false}`))
+
+	err := munge(t, `{"Cmd":["sh"],"Privileged":true}`)
+	var rejected *dockerproxy.RequestRejectedError
+	require.ErrorAs(t, err, &rejected)
+	assert.Equal(t, http.StatusForbidden, rejected.StatusCode)
+	assert.Equal(t, "exec denied by policy: privileged exec sessions are not allowed", rejected.Message)
+}
@@ -17,10 +17,7 @@ limitations under the License.
 package mungers
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 
 	"github.com/sirupsen/logrus"
@@ -94,17 +91,15 @@ func mungeContainersCreate(req *http.Request, contextValue *dockerproxy.RequestC
 		return nil
 	}
 
-	buf, err := json.Marshal(body)
-	if err != nil {
-		return fmt.Errorf("could not re-marshal parameters: %w", err)
-	}
-	req.Body = io.NopCloser(bytes.NewBuffer(buf))
-	req.ContentLength = int64(len(buf))
-	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(buf)))
-
-	return nil
+	return writeRequestBodyJSON(req, &body)
 }
 
 func init() {
+	// The policy is checked against the original request, before the image
+	// and the bind paths are rewritten.
+	dockerproxy.RegisterRequestMunger(http.MethodPost, "/containers/create", policy.mungeContainersCreateRequest)
+	dockerproxy.RegisterRequestMunger(http.MethodPost, "/containers/{id}/exec", policy.mungeContainersExecRequest)
+	dockerproxy.RegisterRequestMunger(http.MethodPost, "/containers/create", mirrors.mungeContainersCreateRequest)
 	dockerproxy.RegisterRequestMunger(http.MethodPost, "/containers/create", mungeContainersCreate)
+	dockerproxy.RegisterResponseMunger(http.MethodPost, "/containers/create", mirrors.mungeResponse)
 }
@@ -23,7 +23,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"reflect"
 )
 
 // readRequestBodyJSON reads the incoming HTTP request body as if it was JSON,
@@ -43,3 +45,112 @@ func readRequestBodyJSON(req *http.Request, data interface{}) error {
 
 	return nil
 }
+
+// writeRequestBodyJSON replaces the request body with the JSON encoding of
+// the provided object, which must be a pointer to the value that the body was
+// read into.  Only the members that the caller changed are replaced; the rest
+// of the body is forwarded as it was sent, so that neither the fields the
+// models don't have (such as those added in newer API versions) are lost, nor
+// the zero values of the fields the client omitted are added.
+func writeRequestBodyJSON(req *http.Request, data any) error {
+	original, err := io.ReadAll(req.Body)
+	if err != nil {
+		return fmt.Errorf("could not read request body: %w", err)
+	}
+	modified, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("could not re-marshal parameters: %w", err)
+	}
+	// Round-trip the original body through the same type, so that it can be
+	// compared with the modified one.
+	unchangedData := reflect.New(reflect.TypeOf(data).Elem()).Interface()
+	if err := json.Unmarshal(original, unchangedData); err != nil {
+		return fmt.Errorf("could not unmarshal request body: %w", err)
+	}
+	unchanged, err := json.Marshal(unchangedData)
+	if err != nil {
+		return fmt.Errorf("could not re-marshal request body: %w", err)
+	}
+
+	originalValue, err := decodeJSON(original)
+	if err != nil {
+		return fmt.Errorf("could not unmarshal request body: %w", err)
+	}
+	unchangedValue, err := decodeJSON(unchanged)
+	if err != nil {
+		return fmt.Errorf("could not unmarshal request body: %w", err)
+	}
+	modifiedValue, err := decodeJSON(modified)
+	if err != nil {
+		return fmt.Errorf("could not unmarshal parameters: %w", err)
+	}
+	buf, err := json.Marshal(mergeJSON(originalValue, unchangedValue, modifiedValue))
+	if err != nil {
+		return fmt.Errorf("could not re-marshal parameters: %w", err)
+	}
+
+	req.Body = io.NopCloser(bytes.NewBuffer(buf))
+	req.ContentLength = int64(len(buf))
+	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(buf)))
+	return nil
+}
+
+// decodeJSON decodes a JSON document, keeping numbers as they are written.
+func decodeJSON(buf []byte) (any, error) {
+	var result any
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// mergeJSON applies the changes from the unchanged value to the modified one
+// onto the original value.  The unchanged value is the original one as the
+// models see it, so object members that are the same in both are kept as they
+// are in the original value (including being absent).  Arrays of the same
+// length are merged element by element.
+func mergeJSON(original, unchanged, modified any) any {
+	if reflect.DeepEqual(unchanged, modified) {
+		return original
+	}
+	switch modifiedValue := modified.(type) {
+	case map[string]any:
+		originalValue, ok := original.(map[string]any)
+		if !ok {
+			return modified
+		}
+		unchangedValue, _ := unchanged.(map[string]any)
+		result := maps.Clone(originalValue)
+		for key := range unchangedValue {
+			if _, ok := modifiedValue[key]; !ok {
+				delete(result, key)
+			}
+		}
+		for key, value := range modifiedValue {
+			unchangedMember, ok := unchangedValue[key]
+			if ok && reflect.DeepEqual(unchangedMember, value) {
+				continue
+			}
+			if originalMember, ok := originalValue[key]; ok {
+				result[key] = mergeJSON(originalMember, unchangedMember, value)
+			} else {
+				result[key] = value
+			}
+		}
+		return result
+	case []any:
+		originalValue, ok := original.([]any)
+		unchangedValue, _ := unchanged.([]any)
+		if !ok || len(originalValue) != len(modifiedValue) || len(unchangedValue) != len(modifiedValue) {
+			return modified
+		}
+		result := make([]any, len(modifiedValue))
+		for i := range modifiedValue {
+			result[i] = mergeJSON(originalValue[i], unchangedValue[i], modifiedValue[i])
+		}
+		return result
+	}
+	return modified
+}
@@ -0,0 +1,77 @@
+//go:build linux || windows
+
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mungers
+
+import (
+	"regexp"
+	"strings"
+)
+
+// defaultRegistry is the registry of image references without one.
+const defaultRegistry = "docker.io"
+
+// imageIDPattern matches full image IDs, which don't name a registry.
+var imageIDPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// splitImageReference splits an image reference into its registry and
+// repository path, following the defaults of the docker CLI: "alpine" is
+// docker.io/library/alpine.  The tag and digest, if any, are returned
+// unchanged (including the leading ":" or "@") as the suffix.
+func splitImageReference(image string) (registry, repository, suffix string) {
+	name := image
+	if index := strings.Index(name, "@"); index >= 0 {
+		name, suffix = name[:index], name[index:]
+	}
+	if index := strings.LastIndex(name, ":"); index > strings.LastIndex(name, "/") {
+		name, suffix = name[:index], name[index:]+suffix
+	}
+	registry, repository, found := strings.Cut(name, "/")
+	if !found || (!strings.ContainsAny(registry, ".:") && registry != "localhost") {
+		registry, repository = defaultRegistry, name
+		if !found {
+			repository = "library/" + name
+		}
+	}
+	return normalizeRegistry(registry), repository, suffix
+}
+
+// normalizeRegistry returns the canonical name of a registry host.
+func normalizeRegistry(registry string) string {
+	registry = strings.ToLower(registry)
+	if registry == "index.docker.io" || registry == "registry-1.docker.io" {
+		return defaultRegistry
+	}
+	return registry
+}
+
+// familiarImageName is the inverse of splitImageReference (without the
+// suffix), using the short form of the docker CLI for the default registry.
+func familiarImageName(registry, repository string) string {
+	if registry == defaultRegistry {
+		return strings.TrimPrefix(repository, "library/")
+	}
+	return registry + "/" + repository
+}
+
+// hasPathPrefix returns whether the slash-separated path is prefix, or is
+// under it.
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
@@ -0,0 +1,520 @@
+//go:build linux || windows
+
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mungers
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/adrg/xdg"
+	"github.com/sirupsen/logrus"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy"
+)
+
+// Registry mirrors rewrite the image references of pulls, builds and
+// container creation, so that images are pulled from an internal mirror:
+// - in POST /images/create, the fromImage query parameter.
+// - in POST /build, the FROM and COPY --from images of the Dockerfile in the
+//   build context, for the classic builder; BuildKit reads the Dockerfile
+//   through its own session, which is left alone.
+// - in POST /containers/create, the image of the container, unless the
+//   original reference resolves to an image that is already present (for
+//   example, one that was built or tagged locally).
+// The original names are put back into the responses (pull and build
+// progress, and error messages); the images are however stored under their
+// mirrored name.
+
+// mirrorConfig is the registry mirror configuration, read from a YAML file
+// mapping image prefixes (a registry, optionally followed by a repository
+// path) to their mirror, for example:
+//
+//	mirrors:
+//	  docker.io/library/*: mirror.corp/dockerhub/*
+//	  ghcr.io/*: mirror.corp/ghcr/*
+//
+// The longest matching prefix applies.
+type mirrorConfig struct {
+	Mirrors map[string]string `yaml:"mirrors"`
+
+	// rules are the parsed mirrors, longest prefix first.
+	rules []mirrorRule
+}
+
+type mirrorRule struct {
+	fromRegistry, fromPrefix string
+	toRegistry, toPrefix     string
+}
+
+// parse checks the mirrors, and prepares them for rewriting.
+func (c *mirrorConfig) parse() error {
+	c.rules = nil
+	for from, to := range c.Mirrors {
+		fromRegistry, fromPrefix, err := parseMirrorPrefix(from)
+		if err != nil {
+			return err
+		}
+		toRegistry, toPrefix, err := parseMirrorPrefix(to)
+		if err != nil {
+			return err
+		}
+		c.rules = append(c.rules, mirrorRule{fromRegistry, fromPrefix, toRegistry, toPrefix})
+	}
+	sort.Slice(c.rules, func(i, j int) bool {
+		if len(c.rules[i].fromPrefix) != len(c.rules[j].fromPrefix) {
+			return len(c.rules[i].fromPrefix) > len(c.rules[j].fromPrefix)
+		}
+		return c.rules[i].fromRegistry+"/"+c.rules[i].fromPrefix < c.rules[j].fromRegistry+"/"+c.rules[j].fromPrefix
+	})
+	return nil
+}
+
+// parseMirrorPrefix splits a prefix such as "docker.io/library/*" into its
+// registry and repository path prefix.
+func parseMirrorPrefix(value string) (string, string, error) {
+	trimmed := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(value), "*"), "/")
+	registry, prefix, _ := strings.Cut(trimmed, "/")
+	if registry == "" || strings.ContainsAny(trimmed, "*@ ") {
+		return "", "", fmt.Errorf("invalid mirror prefix %q", value)
+	}
+	return normalizeRegistry(registry), prefix, nil
+}
+
+// imageRename is an image reference rewritten to a mirror, without its tag
+// or digest.
+type imageRename struct {
+	registry, repository             string
+	mirrorRegistry, mirrorRepository string
+}
+
+// rewrite returns the reference of the image on its mirror; ok is false if
+// no mirror applies.
+func (c *mirrorConfig) rewrite(image string) (mirrored string, rename imageRename, ok bool) {
+	if imageIDPattern.MatchString(image) {
+		return image, rename, false
+	}
+	registry, repository, suffix := splitImageReference(image)
+	for _, rule := range c.rules {
+		if rule.fromRegistry != registry || !hasPathPrefix(repository, rule.fromPrefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(strings.TrimPrefix(repository, rule.fromPrefix), "/")
+		mirrorRepository := strings.Trim(rule.toPrefix+"/"+rest, "/")
+		rename = imageRename{registry, repository, rule.toRegistry, mirrorRepository}
+		return rule.toRegistry + "/" + mirrorRepository + suffix, rename, true
+	}
+	return image, rename, false
+}
+
+// imageRenames are the images rewritten for a request, used to present their
+// original names in the response.  Builds may find more images while the
+// response is being streamed, hence the lock.
+type imageRenames struct {
+	renames  []imageRename
+	replacer *strings.Replacer
+	sync.Mutex
+}
+
+func (r *imageRenames) add(rename imageRename) {
+	r.Lock()
+	defer r.Unlock()
+	for _, existing := range r.renames {
+		if existing == rename {
+			return
+		}
+	}
+	r.renames = append(r.renames, rename)
+	r.replacer = nil
+}
+
+// replace puts the original names back into the given text; full names are
+// replaced first, then the repository paths, as shown in "Pulling from".
+func (r *imageRenames) replace(text string) string {
+	r.Lock()
+	defer r.Unlock()
+	if r.replacer == nil {
+		var full, repositories [][2]string
+		for _, rename := range r.renames {
+			full = append(full, [2]string{
+				rename.mirrorRegistry + "/" + rename.mirrorRepository,
+				familiarImageName(rename.registry, rename.repository),
+			})
+			if rename.mirrorRepository != rename.repository {
+				repositories = append(repositories, [2]string{rename.mirrorRepository, rename.repository})
+			}
+		}
+		var pairs []string
+		for _, group := range [][][2]string{full, repositories} {
+			// Longest first, so that a name isn't replaced by a shorter one.
+			sort.SliceStable(group, func(i, j int) bool { return len(group[i][0]) > len(group[j][0]) })
+			for _, pair := range group {
+				pairs = append(pairs, pair[0], pair[1])
+			}
+		}
+		r.replacer = strings.NewReplacer(pairs...)
+	}
+	return r.replacer.Replace(text)
+}
+
+// replacingReader replaces the mirrored names in a response body line by
+// line, so that streamed progress is passed through as it arrives.
+type replacingReader struct {
+	source  *bufio.Reader
+	closer  io.Closer
+	renames *imageRenames
+	pending []byte
+	err     error
+}
+
+func (r *replacingReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		var line []byte
+		line, r.err = r.source.ReadBytes('\n')
+		r.pending = []byte(r.renames.replace(string(line)))
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *replacingReader) Close() error {
+	return r.closer.Close()
+}
+
+// mirrorManager loads the mirror configuration, reloading it whenever it
+// changes.
+type mirrorManager struct {
+	configFile[mirrorConfig]
+}
+
+// mirrors are the registry mirrors used by the docker proxy.
+var mirrors = newMirrorManager()
+
+// DefaultMirrorsFile is the default location of the registry mirror file.
+var DefaultMirrorsFile = filepath.Join(xdg.ConfigHome, "rancher-desktop", "docker-proxy-mirrors.yaml")
+
+func newMirrorManager() *mirrorManager {
+	return &mirrorManager{configFile[mirrorConfig]{validate: (*mirrorConfig).parse}}
+}
+
+// SetMirrorsFile sets the path of the YAML file describing the registry
+// mirrors; no image is rewritten if the file doesn't exist.  The file is
+// reloaded whenever it changes.
+func SetMirrorsFile(mirrorsPath string) {
+	mirrors.setPath(mirrorsPath)
+}
+
+// mirrorsContextKey is the key used to locate the image renames in the
+// request/response context.
+type mirrorsContextKey struct{}
+
+// load returns the current mirror configuration, or nil if there is none.
+func (m *mirrorManager) load() (*mirrorConfig, error) {
+	config, err := m.current()
+	if err != nil {
+		// Pulling from the original registry would likely fail anyway.
+		logrus.WithError(err).Error("could not load docker proxy registry mirrors")
+		return nil, &dockerproxy.RequestRejectedError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    fmt.Sprintf("could not load the docker proxy registry mirrors: %s", err),
+		}
+	}
+	if config == nil || len(config.rules) == 0 {
+		return nil, nil
+	}
+	return config, nil
+}
+
+// imageRenamesFor returns the image renames of the request, creating them if
+// needed.
+func imageRenamesFor(contextValue *dockerproxy.RequestContextValue) *imageRenames {
+	result, ok := (*contextValue)[mirrorsContextKey{}].(*imageRenames)
+	if !ok {
+		result = &imageRenames{}
+		(*contextValue)[mirrorsContextKey{}] = result
+	}
+	return result
+}
+
+// munge incoming request for POST /images/create
+func (m *mirrorManager) mungeImagesCreateRequest(req *http.Request, contextValue *dockerproxy.RequestContextValue, templates map[string]string) error {
+	config, err := m.load()
+	if err != nil || config == nil {
+		return err
+	}
+	query := req.URL.Query()
+	// fromImage is empty when importing an image (fromSrc).
+	fromImage := query.Get("fromImage")
+	if fromImage == "" {
+		return nil
+	}
+	mirrored, rename, ok := config.rewrite(fromImage)
+	if !ok {
+		return nil
+	}
+	query.Set("fromImage", mirrored)
+	req.URL.RawQuery = query.Encode()
+	imageRenamesFor(contextValue).add(rename)
+	logrus.WithFields(logrus.Fields{"image": fromImage, "mirror": mirrored}).Debug("pulling from mirror")
+	return nil
+}
+
+// munge incoming request for POST /containers/create; only the image is
+// changed, the rest of the body is passed through as is.
+func (m *mirrorManager) mungeContainersCreateRequest(req *http.Request, contextValue *dockerproxy.RequestContextValue, templates map[string]string) error {
+	config, err := m.load()
+	if err != nil || config == nil {
+		return err
+	}
+	var body map[string]json.RawMessage
+	if err := readRequestBodyJSON(req, &body); err != nil {
+		return err
+	}
+	var image string
+	if err := json.Unmarshal(body["Image"], &image); err != nil {
+		return nil
+	}
+	mirrored, rename, ok := config.rewrite(image)
+	if !ok {
+		return nil
+	}
+	if imageIsLocal(req, contextValue, image) {
+		logrus.WithField("image", image).Debug("not rewriting local image")
+		return nil
+	}
+	body["Image"], err = json.Marshal(mirrored)
+	if err != nil {
+		return err
+	}
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("could not re-marshal parameters: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewBuffer(buf))
+	req.ContentLength = int64(len(buf))
+	req.Header.Set("Content-Length", fmt.Sprintf("%d", len(buf)))
+	imageRenamesFor(contextValue).add(rename)
+	return nil
+}
+
+// imageIsLocal returns whether the image reference resolves to an image that
+// is already present in dockerd.  If that can't be determined, the image is
+// assumed not to be present, so that it is pulled from the mirror.
+func imageIsLocal(req *http.Request, contextValue *dockerproxy.RequestContextValue, image string) bool {
+	client := dockerproxy.BackendClient(req.Context(), contextValue)
+	if client == nil {
+		return false
+	}
+	inspectURL := url.URL{Scheme: "http", Host: "proxy.invalid", Path: "/images/" + image + "/json"}
+	inspectRequest, err := http.NewRequestWithContext(req.Context(), http.MethodGet, inspectURL.String(), http.NoBody)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(inspectRequest)
+	if err != nil {
+		logrus.WithError(err).WithField("image", image).Warn("could not inspect image")
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// munge incoming request for POST /build
+func (m *mirrorManager) mungeBuildRequest(req *http.Request, contextValue *dockerproxy.RequestContextValue, templates map[string]string) error {
+	config, err := m.load()
+	if err != nil || config == nil {
+		return err
+	}
+	query := req.URL.Query()
+	if query.Get("remote") != "" || query.Get("version") == "2" {
+		return nil
+	}
+	dockerfile := query.Get("dockerfile")
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	buildRenames := imageRenamesFor(contextValue)
+	body, err := rewriteBuildContext(req.Body, dockerfile, func(image string) string {
+		mirrored, rename, ok := config.rewrite(image)
+		if ok {
+			buildRenames.add(rename)
+		}
+		return mirrored
+	})
+	if err != nil {
+		return err
+	}
+	// The length of the rewritten context is unknown; send it chunked.
+	req.Body = body
+	req.ContentLength = -1
+	req.Header.Del("Content-Length")
+	return nil
+}
+
+// munge outgoing response for POST /images/create, /build and
+// /containers/create to present the original image names.
+func (m *mirrorManager) mungeResponse(resp *http.Response, contextValue *dockerproxy.RequestContextValue, templates map[string]string) error {
+	renames, ok := (*contextValue)[mirrorsContextKey{}].(*imageRenames)
+	if !ok {
+		return nil
+	}
+	resp.Body = &replacingReader{
+		source:  bufio.NewReader(resp.Body),
+		closer:  resp.Body,
+		renames: renames,
+	}
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	return nil
+}
+
+// rewriteBuildContext returns the build context with the images of the
+// Dockerfile rewritten.  Contexts that are not tar archives, or are
+// compressed with something other than gzip, are returned unchanged; the
+// rewritten context is not compressed.
+func rewriteBuildContext(body io.ReadCloser, dockerfile string, rewrite func(string) string) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(body)
+	source := buffered
+	compressed := false
+	if magic, _ := buffered.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		decompressed, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("could not read build context: %w", err)
+		}
+		source, compressed = bufio.NewReader(decompressed), true
+	}
+	// The tar header has the "ustar" magic at offset 257.
+	if magic, _ := source.Peek(262); !bytes.HasPrefix(magic[min(len(magic), 257):], []byte("ustar")) {
+		if compressed {
+			return nil, errors.New("could not read build context: not a tar archive")
+		}
+		logrus.Debug("not rewriting build context: not an uncompressed or gzip tar archive")
+		return struct {
+			io.Reader
+			io.Closer
+		}{buffered, body}, nil
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		defer body.Close()
+		writer.CloseWithError(copyBuildContext(writer, source, path.Clean(dockerfile), rewrite))
+	}()
+	return reader, nil
+}
+
+// maxDockerfileSize is the size of the largest Dockerfile that is rewritten.
+const maxDockerfileSize = 1024 * 1024
+
+func copyBuildContext(w io.Writer, r io.Reader, dockerfile string, rewrite func(string) string) error {
+	tarReader := tar.NewReader(r)
+	tarWriter := tar.NewWriter(w)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return tarWriter.Close()
+		} else if err != nil {
+			return fmt.Errorf("could not read build context: %w", err)
+		}
+		isDockerfile := path.Clean(header.Name) == dockerfile &&
+			header.Typeflag == tar.TypeReg && header.Size <= maxDockerfileSize
+		if !isDockerfile {
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return err
+			}
+			if _, err := io.Copy(tarWriter, tarReader); err != nil {
+				return err
+			}
+			continue
+		}
+		contents, err := io.ReadAll(tarReader)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", header.Name, err)
+		}
+		contents = []byte(rewriteDockerfile(string(contents), rewrite))
+		header.Size = int64(len(contents))
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tarWriter.Write(contents); err != nil {
+			return err
+		}
+	}
+}
+
+var (
+	dockerfileFromPattern      = regexp.MustCompile(`(?i)^(\s*FROM\s+(?:--\S+\s+)*)(\S+)(.*)$`)
+	dockerfileStageNamePattern = regexp.MustCompile(`(?i)^\s+AS\s+(\S+)`)
+	dockerfileCopyFromPattern  = regexp.MustCompile(`(?i)^(\s*COPY\s+(?:--\S+\s+)*?--from=)(\S+)(.*)$`)
+)
+
+// rewriteDockerfile rewrites the images of the FROM and COPY --from
+// instructions; build stages, scratch, and images using build arguments are
+// left alone.
+func rewriteDockerfile(contents string, rewrite func(string) string) string {
+	stages := make(map[string]bool)
+	rewriteImage := func(image string) string {
+		if stages[strings.ToLower(image)] || strings.EqualFold(image, "scratch") || strings.Contains(image, "$") {
+			return image
+		}
+		if _, err := strconv.Atoi(image); err == nil {
+			// COPY --from may refer to a stage by index.
+			return image
+		}
+		return rewrite(image)
+	}
+	lines := strings.SplitAfter(contents, "\n")
+	for i, line := range lines {
+		instruction := strings.TrimRight(line, "\r\n")
+		ending := line[len(instruction):]
+		if match := dockerfileFromPattern.FindStringSubmatch(instruction); match != nil {
+			lines[i] = match[1] + rewriteImage(match[2]) + match[3] + ending
+			if stage := dockerfileStageNamePattern.FindStringSubmatch(match[3]); stage != nil {
+				stages[strings.ToLower(stage[1])] = true
+			}
+		} else if match := dockerfileCopyFromPattern.FindStringSubmatch(instruction); match != nil {
+			lines[i] = match[1] + rewriteImage(match[2]) + match[3] + ending
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+func init() {
+	dockerproxy.RegisterRequestMunger(http.MethodPost, "/images/create", mirrors.mungeImagesCreateRequest)
+	dockerproxy.RegisterResponseMunger(http.MethodPost, "/images/create", mirrors.mungeResponse)
+	dockerproxy.RegisterRequestMunger(http.MethodPost, "/build", mirrors.mungeBuildRequest)
+	dockerproxy.RegisterResponseMunger(http.MethodPost, "/build", mirrors.mungeResponse)
+}
@@ -0,0 +1,417 @@
+/*
+Copyright © 2026 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mungers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy"
+)
+
+const testMirrors = `
+mirrors:
+  docker.io/library/*: mirror.corp/dockerhub/*
+  docker.io/*: mirror.corp/hub/*
+  ghcr.io/example: mirror.corp/ghcr-example
+`
+
+// newTestMirrors returns a mirror manager using the given configuration.
+func newTestMirrors(t *testing.T, contents string) *mirrorManager {
+	mirrorsPath := filepath.Join(t.TempDir(), "mirrors.yaml")
+	require.NoError(t, os.WriteFile(mirrorsPath, []byte(contents), 0o600))
+	manager := newMirrorManager()
+	manager.setPath(mirrorsPath)
+	return manager
+}
+
+func TestMirrorRewrite(t *testing.T) {
+	t.Parallel()
+
+	config, err := newTestMirrors(t, testMirrors).current()
+	require.NoError(t, err)
+	testCases := []struct {
+		image    string
+		expected string
+	}{
+		{"alpine", "mirror.corp/dockerhub/alpine"},
+		{"alpine:3.20", "mirror.corp/dockerhub/alpine:3.20"},
+		{"docker.io/library/alpine@sha256:abcd", "mirror.corp/dockerhub/alpine@sha256:abcd"},
+		{"someone/app:v1", "mirror.corp/hub/someone/app:v1"},
+		{"index.docker.io/someone/app", "mirror.corp/hub/someone/app"},
+		{"ghcr.io/example/app", "mirror.corp/ghcr-example/app"},
+		{"ghcr.io/example", "mirror.corp/ghcr-example"},
+		{"ghcr.io/examples/app", ""},
+		{"quay.io/app", ""},
+		{"sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", ""},
+	}
+	for _, testCase := range testCases {
+		mirrored, _, ok := config.rewrite(testCase.image)
+		if testCase.expected == "" {
+			assert.False(t, ok, testCase.image)
+			assert.Equal(t, testCase.image, mirrored)
+		} else {
+			assert.True(t, ok, testCase.image)
+			assert.Equal(t, testCase.expected, mirrored)
+		}
+	}
+}
+
+func TestMirrorConfigInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, contents := range []string{
+		"mirrors:\n  /library: mirror.corp\n",
+		"mirrors:\n  docker.io/*/app: mirror.corp\n",
+		"mirrors: []\n",
+		"mirror:\n  docker.io: mirror.corp\n",
+	} {
+		_, err := newTestMirrors(t, contents).current()
+		assert.Error(t, err, contents)
+	}
+}
+
+func TestDockerfileRewrite(t *testing.T) {
+	t.Parallel()
+
+	config, err := newTestMirrors(t, testMirrors).current()
+	require.NoError(t, err)
+	rewrite := func(image string) string {
+		mirrored, _, _ := config.rewrite(image)
+		return mirrored
+	}
+	testCases := []struct {
+		name, dockerfile, expected string
+	}{
+		{
+			name:       "simple",
+			dockerfile: "FROM alpine:3.20\nRUN true\n",
+			expected:   "FROM mirror.corp/dockerhub/alpine:3.20\nRUN true\n",
+		},
+		{
+			name:       "platform flag and CRLF",
+			dockerfile: "from --platform=linux/amd64 golang AS build\r\nRUN go build\r\n",
+			expected:   "from --platform=linux/amd64 mirror.corp/dockerhub/golang AS build\r\nRUN go build\r\n",
+		},
+		{
+			name: "stages",
+			dockerfile: "FROM golang:1.24 AS build\n" +
+				"FROM build AS test\n" +
+				"FROM scratch\n" +
+				"COPY --from=build /app /app\n" +
+				"COPY --from=0 /app /app2\n" +
+				"COPY --chown=1:1 --from=someone/tools:v1 /bin/tool /bin/tool\n",
+			expected: "FROM mirror.corp/dockerhub/golang:1.24 AS build\n" +
+				"FROM build AS test\n" +
+				"FROM scratch\n" +
+				"COPY --from=build /app /app\n" +
+				"COPY --from=0 /app /app2\n" +
+				"COPY --chown=1:1 --from=mirror.corp/hub/someone/tools:v1 /bin/tool /bin/tool\n",
+		},
+		{
+			name:       "build arguments",
+			dockerfile: "ARG BASE=alpine\nFROM ${BASE}\nFROM quay.io/app\n",
+			expected:   "ARG BASE=alpine\nFROM ${BASE}\nFROM quay.io/app\n",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.expected, rewriteDockerfile(testCase.dockerfile, rewrite))
+		})
+	}
+}
+
+func TestMirrorImagesCreate(t *testing.T) {
+	t.Parallel()
+
+	manager := newTestMirrors(t, testMirrors)
+	req, err := http.NewRequestWithContext(
+		t.Context(),
+		http.MethodPost,
+		"http://nowhere.invalid/v1.41/images/create?fromImage=alpine&tag=3.20",
+		http.NoBody)
+	require.NoError(t, err)
+	contextValue := &dockerproxy.RequestContextValue{}
+	require.NoError(t, manager.mungeImagesCreateRequest(req, contextValue, nil))
+	assert.Equal(t, "mirror.corp/dockerhub/alpine", req.URL.Query().Get("fromImage"))
+	assert.Equal(t, "3.20", req.URL.Query().Get("tag"))
+
+	progress := strings.Join([]string{
+		`{"status":"Pulling from dockerhub/alpine","id":"3.20"}`,
+		`{"status":"Digest: sha256:abcd"}`,
+		`{"status":"Status: Downloaded newer image for mirror.corp/dockerhub/alpine:3.20"}`,
+	}, "\r\n") + "\r\n"
+	resp := &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Length": []string{"1000"}},
+		Body:          io.NopCloser(strings.NewReader(progress)),
+		ContentLength: 1000,
+		Request:       req,
+	}
+	require.NoError(t, manager.mungeResponse(resp, contextValue, nil))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		`{"status":"Pulling from library/alpine","id":"3.20"}`,
+		`{"status":"Digest: sha256:abcd"}`,
+		`{"status":"Status: Downloaded newer image for alpine:3.20"}`,
+	}, "\r\n")+"\r\n", string(body))
+	assert.Empty(t, resp.Header.Get("Content-Length"))
+	assert.EqualValues(t, -1, resp.ContentLength)
+
+	// Images without a mirror are left alone.
+	req.URL.RawQuery = "fromImage=quay.io%2Fapp"
+	contextValue = &dockerproxy.RequestContextValue{}
+	require.NoError(t, manager.mungeImagesCreateRequest(req, contextValue, nil))
+	assert.Equal(t, "quay.io/app", req.URL.Query().Get("fromImage"))
+	assert.Empty(t, *contextValue)
+}
+
+func TestMirrorContainersCreate(t *testing.T) {
+	t.Parallel()
+
+	manager := newTestMirrors(t, testMirrors)
+	original := `{"Image":"alpine","Cmd":["true"],"HostConfig":{"Privileged":false},"Custom":{"Kept":1}}`
+	req, err := http.NewRequestWithContext(
+		t.Context(),
+		http.MethodPost,
+		"http://nowhere.invalid/containers/create",
+		io.NopCloser(strings.NewReader(original)))
+	require.NoError(t, err)
+	contextValue := &dockerproxy.RequestContextValue{}
+	require.NoError(t, manager.mungeContainersCreateRequest(req, contextValue, nil))
+
+	var body map[string]any
+	require.NoError(t, readRequestBodyJSON(req, &body))
+	assert.Equal(t, "mirror.corp/dockerhub/alpine", body["Image"])
+	assert.Equal(t, []any{"true"}, body["Cmd"])
+	assert.Equal(t, map[string]any{"Kept": float64(1)}, body["Custom"])
+
+	resp := &http.Response{
+		StatusCode: http.StatusNotFound,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"message":"No such image: mirror.corp/dockerhub/alpine:latest"}`)),
+		Request:    req,
+	}
+	require.NoError(t, manager.mungeResponse(resp, contextValue, nil))
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"message":"No such image: alpine:latest"}`, string(buf))
+}
+
+func TestMirrorContainersCreateLocalImage(t *testing.T) {
+	t.Parallel()
+
+	// dockerd has a locally built image with a name that has a mirror.
+	var (
+		inspected []string
+		lock      sync.Mutex
+	)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		inspected = append(inspected, r.URL.Path)
+		lock.Unlock()
+		if r.URL.Path == "/images/someone/app:dev/json" {
+			_, _ = w.Write([]byte(`{"Id":"sha256:abcd"}`))
+			return
+		}
+		http.Error(w, `{"message":"No such image"}`, http.StatusNotFound)
+	}))
+	t.Cleanup(backend.Close)
+	dialer := func(ctx context.Context) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "tcp", backend.Listener.Addr().String())
+	}
+
+	manager := newTestMirrors(t, testMirrors)
+	createImage := func(image string) string {
+		original := `{"Image":"` + image + `"}`
+		req, err := http.NewRequestWithContext(
+			t.Context(),
+			http.MethodPost,
+			"http://nowhere.invalid/containers/create",
+			io.NopCloser(strings.NewReader(original)))
+		require.NoError(t, err)
+		contextValue := &dockerproxy.RequestContextValue{dockerproxy.BackendDialerKey{}: dialer}
+		require.NoError(t, manager.mungeContainersCreateRequest(req, contextValue, nil))
+		var body map[string]any
+		require.NoError(t, readRequestBodyJSON(req, &body))
+		return body["Image"].(string)
+	}
+
+	assert.Equal(t, "someone/app:dev", createImage("someone/app:dev"))
+	assert.Equal(t, "mirror.corp/hub/someone/app:v1", createImage("someone/app:v1"))
+	assert.Equal(t, "quay.io/app", createImage("quay.io/app"))
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{"/images/someone/app:dev/json", "/images/someone/app:v1/json"}, inspected)
+}
+
+func TestMirrorBuild(t *testing.T) {
+	t.Parallel()
+
+	manager := newTestMirrors(t, testMirrors)
+	files := map[string]string{
+		"Dockerfile":        "FROM alpine\nCOPY app /app\n",
+		"app":               strings.Repeat("x", 5000),
+		"docker/Dockerfile": "FROM golang\n",
+	}
+	makeContext := func(t *testing.T, compress bool) io.Reader {
+		var buf bytes.Buffer
+		var w io.Writer = &buf
+		var gz *gzip.Writer
+		if compress {
+			gz = gzip.NewWriter(&buf)
+			w = gz
+		}
+		tw := tar.NewWriter(w)
+		for _, name := range []string{"Dockerfile", "app", "docker/Dockerfile"} {
+			require.NoError(t, tw.WriteHeader(&tar.Header{
+				Name:     name,
+				Mode:     0o644,
+				Size:     int64(len(files[name])),
+				Typeflag: tar.TypeReg,
+			}))
+			_, err := tw.Write([]byte(files[name]))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+		if gz != nil {
+			require.NoError(t, gz.Close())
+		}
+		return &buf
+	}
+	readContext := func(t *testing.T, r io.Reader) map[string]string {
+		result := make(map[string]string)
+		tr := tar.NewReader(r)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				return result
+			}
+			require.NoError(t, err)
+			contents, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			result[header.Name] = string(contents)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		query    string
+		compress bool
+		expected map[string]string
+	}{
+		{
+			name:  "default Dockerfile",
+			query: "t=app",
+			expected: map[string]string{
+				"Dockerfile":        "FROM mirror.corp/dockerhub/alpine\nCOPY app /app\n",
+				"app":               files["app"],
+				"docker/Dockerfile": files["docker/Dockerfile"],
+			},
+		},
+		{
+			name:     "custom Dockerfile, compressed",
+			query:    "dockerfile=.%2Fdocker%2FDockerfile",
+			compress: true,
+			expected: map[string]string{
+				"Dockerfile":        files["Dockerfile"],
+				"app":               files["app"],
+				"docker/Dockerfile": "FROM mirror.corp/dockerhub/golang\n",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			req, err := http.NewRequestWithContext(
+				t.Context(),
+				http.MethodPost,
+				"http://nowhere.invalid/build?"+testCase.query,
+				io.NopCloser(makeContext(t, testCase.compress)))
+			require.NoError(t, err)
+			contextValue := &dockerproxy.RequestContextValue{}
+			require.NoError(t, manager.mungeBuildRequest(req, contextValue, nil))
+			assert.EqualValues(t, -1, req.ContentLength)
+			assert.Equal(t, testCase.expected, readContext(t, req.Body))
+		})
+	}
+
+	t.Run("BuildKit", func(t *testing.T) {
+		t.Parallel()
+		body := io.NopCloser(strings.NewReader(""))
+		req, err := http.NewRequestWithContext(
+			t.Context(),
+			http.MethodPost,
+			"http://nowhere.invalid/build?version=2",
+			body)
+		require.NoError(t, err)
+		require.NoError(t, manager.mungeBuildRequest(req, &dockerproxy.RequestContextValue{}, nil))
+		assert.True(t, req.Body == body, "the build context should not be rewritten")
+	})
+}
+
+func TestMirrorReload(t *testing.T) {
+	t.Parallel()
+
+	manager := newTestMirrors(t, "mirrors:\n  docker.io: first.corp\n")
+	config, err := manager.load()
+	require.NoError(t, err)
+	mirrored, _, _ := config.rewrite("alpine")
+	assert.Equal(t, "first.corp/library/alpine", mirrored)
+
+	// The file is reloaded once it changes; the size differs, so that the
+	// change is noticed even if the modification time doesn't.
+	require.NoError(t, os.WriteFile(manager.path, []byte("mirrors:\n  docker.io: second.corp/hub\n"), 0o600))
+	config, err = manager.load()
+	require.NoError(t, err)
+	mirrored, _, _ = config.rewrite("alpine")
+	assert.Equal(t, "second.corp/hub/library/alpine", mirrored)
+
+	require.NoError(t, os.WriteFile(manager.path, []byte("mirrors: {}\n"), 0o600))
+	config, err = manager.load()
+	require.NoError(t, err)
+	assert.Nil(t, config, "an empty configuration should not rewrite anything")
+
+	require.NoError(t, os.WriteFile(manager.path, []byte("mirrors: [\n"), 0o600))
+	_, err = manager.load()
+	var rejected *dockerproxy.RequestRejectedError
+	require.ErrorAs(t, err, &rejected)
+	assert.Equal(t, http.StatusInternalServerError, rejected.StatusCode)
+
+	body, err := json.Marshal(map[string]string{"Image": "alpine"})
+	require.NoError(t, err)
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, "http://nowhere.invalid/containers/create", bytes.NewReader(body))
+	require.NoError(t, err)
+	assert.ErrorAs(t, manager.mungeContainersCreateRequest(req, &dockerproxy.RequestContextValue{}, nil), &rejected)
+}
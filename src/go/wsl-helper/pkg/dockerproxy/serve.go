@@ -19,8 +19,10 @@ limitations under the License.
 package dockerproxy
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -29,12 +31,16 @@ import (
 	"os"
 	"os/signal"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/sirupsen/logrus"
 
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/audit"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/models"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/platform"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/util"
@@ -49,15 +55,75 @@ type RequestContextValue map[interface{}]interface{}
 // requestContext is the context key for requestContextValue
 var requestContext = requestContextKeyType{}
 
+// BackendDialerKey is the RequestContextValue key holding the function used
+// to connect to dockerd.
+type BackendDialerKey struct{}
+
+// BackendClient returns an HTTP client connecting to dockerd, for mungers
+// that need to query it; it returns nil if the request context has no
+// dialer.
+func BackendClient(ctx context.Context, contextValue *RequestContextValue) *http.Client {
+	if contextValue == nil {
+		return nil
+	}
+	dialer, ok := (*contextValue)[BackendDialerKey{}].(func(ctx context.Context) (net.Conn, error))
+	if !ok {
+		return nil
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(context.Context, string, string) (net.Conn, error) {
+				return dialer(ctx)
+			},
+			// The client is only used for the current request.
+			DisableKeepAlives: true,
+		},
+	}
+}
+
 type containerInspectResponseBody struct {
 	ID string `json:"Id"`
 }
 
 const dockerAPIVersion = "v1.41.0"
 
+// RequestRejectedError is returned by a request munger to reject the request;
+// it is not forwarded to dockerd, and the client gets an error response with
+// the given status code instead.
+type RequestRejectedError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *RequestRejectedError) Error() string {
+	return e.Message
+}
+
+// rejectionKey is the RequestContextValue key holding the
+// RequestRejectedError of a rejected request.
+type rejectionKey struct{}
+
+// Options are the optional settings of the docker proxy.
+type Options struct {
+	// AuditLog records every API call passing through the proxy, if set.
+	AuditLog *audit.Log
+	// APISpecDir is a directory with additional versions of the docker API
+	// spec to load, if set.
+	APISpecDir string
+	// APICompatibility reports the oldest of the API versions of the client,
+	// the proxy and the engine, rather than only limiting the engine version
+	// to the one the proxy knows.
+	APICompatibility bool
+}
+
 // Serve up the docker proxy at the given endpoint, using the given function to
 // create a connection to the real dockerd.
-func Serve(ctx context.Context, endpoint string, dialer func(ctx context.Context) (net.Conn, error)) error {
+func Serve(ctx context.Context, endpoint string, dialer func(ctx context.Context) (net.Conn, error), opts Options) error {
+	if opts.APISpecDir != "" {
+		if err := LoadAPISpecs(opts.APISpecDir); err != nil {
+			return err
+		}
+	}
 	listener, err := platform.Listen(ctx, endpoint)
 	if err != nil {
 		return err
@@ -82,8 +148,7 @@ func Serve(ctx context.Context, endpoint string, dialer func(ctx context.Context
 			return dialer(ctx)
 		},
 		Director: func(req *http.Request) {
-			logrus.WithField("request", req).
-				WithField("headers", req.Header).
+			logrus.WithField("method", req.Method).
 				WithField("url", req.URL).
 				Debug("got proxy request")
 			// The incoming URL is relative (to the root of the server); we need
@@ -95,15 +160,36 @@ func Serve(ctx context.Context, endpoint string, dialer func(ctx context.Context
 			originalURL := *req.URL
 			originalReq.URL = &originalURL
 			err := munger.MungeRequest(req, dialer)
-			if err != nil {
+			var rejected *RequestRejectedError
+			if errors.As(err, &rejected) {
+				logrus.WithFields(logrus.Fields{
+					"method": req.Method,
+					"url":    req.URL,
+					"reason": rejected.Message,
+				}).Info("rejected request")
+				contextValue, _ := req.Context().Value(requestContext).(*RequestContextValue)
+				(*contextValue)[rejectionKey{}] = rejected
+			} else if err != nil {
 				logrus.WithError(err).
 					WithField("original request", originalReq).
 					WithField("modified request", req).
 					Error("could not munge request")
 			}
 		},
+		Intercept: func(req *http.Request) *http.Response {
+			contextValue, _ := req.Context().Value(requestContext).(*RequestContextValue)
+			rejected, ok := (*contextValue)[rejectionKey{}].(*RequestRejectedError)
+			if !ok {
+				return nil
+			}
+			return rejectedResponse(req, rejected)
+		},
 		ModifyResponse: func(resp *http.Response) error {
-			logEntry := logrus.WithField("response", resp)
+			logEntry := logrus.WithFields(logrus.Fields{
+				"method": resp.Request.Method,
+				"url":    resp.Request.URL,
+				"status": resp.StatusCode,
+			})
 			defer func() { logEntry.Debug("got backend response") }()
 
 			// Check the API version response, and if there is one, make sure
@@ -111,13 +197,19 @@ func Serve(ctx context.Context, endpoint string, dialer func(ctx context.Context
 			backendVersion, err := semver.NewVersion(resp.Header.Get("API-Version"))
 			if err == nil {
 				logEntry = logEntry.WithField("backend version", backendVersion)
-				if backendVersion.GreaterThan(&dockerSpec.Info.Version) {
-					overrideVersion := fmt.Sprintf("v%s", dockerSpec.Info.Version.Original())
+				clientVersion := munger.getRequestVersion(resp.Request)
+				reported := reportedAPIVersion(backendVersion, clientVersion, opts.APICompatibility)
+				if !reported.Equal(backendVersion) {
+					overrideVersion := formatAPIVersion(reported)
 					resp.Header.Set("API-Version", overrideVersion)
 					logEntry = logEntry.WithField("override version", overrideVersion)
 				}
 			}
 
+			if opts.AuditLog != nil {
+				munger.recordCreatedContainer(resp)
+			}
+
 			err = munger.MungeResponse(resp, dialer)
 			if err != nil {
 				return err
@@ -130,9 +222,20 @@ func Serve(ctx context.Context, endpoint string, dialer func(ctx context.Context
 	server := &http.Server{
 		ReadHeaderTimeout: time.Minute,
 		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-			ctx := context.WithValue(req.Context(), requestContext, &RequestContextValue{})
+			contextValue := &RequestContextValue{BackendDialerKey{}: dialer}
+			ctx := context.WithValue(req.Context(), requestContext, contextValue)
 			newReq := req.WithContext(ctx)
-			proxy.ServeHTTP(w, newReq)
+			if opts.AuditLog == nil {
+				proxy.ServeHTTP(w, newReq)
+				return
+			}
+			start := time.Now()
+			recorder := &auditResponseWriter{ResponseWriter: w}
+			proxy.ServeHTTP(recorder, newReq)
+			record := munger.auditRecord(req, contextValue, recorder, start)
+			if err := opts.AuditLog.Write(record); err != nil {
+				logrus.WithError(err).Warn("could not write audit log")
+			}
 		}),
 	}
 
@@ -146,6 +249,25 @@ func Serve(ctx context.Context, endpoint string, dialer func(ctx context.Context
 	return nil
 }
 
+// rejectedResponse builds the response to a rejected request, in the format
+// of the errors returned by dockerd, so that the client shows the message.
+func rejectedResponse(req *http.Request, rejected *RequestRejectedError) *http.Response {
+	body, err := json.Marshal(map[string]string{"message": rejected.Message})
+	if err != nil {
+		body = []byte(`{"message":"request rejected"}`)
+	}
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	return &http.Response{
+		StatusCode:    rejected.StatusCode,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
 // requestMunger is used to modify the incoming http.Request as required.
 type requestMunger struct {
 	// apiDetectPattern is used to detect the API version request path prefix.
@@ -176,6 +298,29 @@ func (m *requestMunger) getRequestPath(req *http.Request) string {
 	return requestPath
 }
 
+// getRequestVersion returns the API version in the request path, or nil if
+// the request is not versioned.
+func (m *requestMunger) getRequestVersion(req *http.Request) *semver.Version {
+	prefix := m.apiDetectPattern.FindString(req.URL.Path)
+	if prefix == "" {
+		return nil
+	}
+	version, err := semver.NewVersion(strings.Trim(prefix, "/v"))
+	if err != nil {
+		return nil
+	}
+	return version
+}
+
+// mungerVersion returns the API version used to pick the mungers for the
+// request; unversioned requests use the newest version the proxy knows.
+func (m *requestMunger) mungerVersion(req *http.Request) *semver.Version {
+	if version := m.getRequestVersion(req); version != nil {
+		return version
+	}
+	return proxyAPIVersion()
+}
+
 // MungeRequest modifies a given request in-place.
 func (m *requestMunger) MungeRequest(req *http.Request, dialer func(ctx context.Context) (net.Conn, error)) error {
 	requestPath := m.getRequestPath(req)
@@ -191,8 +336,8 @@ func (m *requestMunger) MungeRequest(req *http.Request, dialer func(ctx context.
 		logEntry.Debug("no munger with method")
 		return nil
 	}
-	munger, templates := mapping.getRequestMunger(requestPath)
-	if munger == nil {
+	mungers, templates := mapping.getRequestMungers(requestPath, m.mungerVersion(req))
+	if len(mungers) == 0 {
 		logEntry.Debug("request munger not found")
 		return nil
 	}
@@ -209,11 +354,16 @@ func (m *requestMunger) MungeRequest(req *http.Request, dialer func(ctx context.
 	}
 
 	contextValue, _ := req.Context().Value(requestContext).(*RequestContextValue)
-	logEntry.Debug("calling request munger")
-	err := munger(req, contextValue, templates)
-	if err != nil {
-		logEntry.WithField("munger", munger).WithError(err).Error("munger failed")
-		return fmt.Errorf("munger failed for %s: %w", requestPath, err)
+	for _, munger := range mungers {
+		logEntry.Debug("calling request munger")
+		err := munger(req, contextValue, templates)
+		var rejected *RequestRejectedError
+		if errors.As(err, &rejected) {
+			return err
+		} else if err != nil {
+			logEntry.WithField("munger", munger).WithError(err).Error("munger failed")
+			return fmt.Errorf("munger failed for %s: %w", requestPath, err)
+		}
 	}
 	return nil
 }
@@ -232,9 +382,9 @@ func (m *requestMunger) MungeResponse(resp *http.Response, dialer func(ctx conte
 		logEntry.Debug("no munger with method")
 		return nil
 	}
-	munger, templates := mapping.getResponseMunger(requestPath)
-	if munger == nil {
-		logEntry.Debug("request munger not found")
+	mungers, templates := mapping.getResponseMungers(requestPath, m.mungerVersion(resp.Request))
+	if len(mungers) == 0 {
+		logEntry.Debug("response munger not found")
 		return nil
 	}
 
@@ -250,11 +400,13 @@ func (m *requestMunger) MungeResponse(resp *http.Response, dialer func(ctx conte
 	}
 
 	contextValue, _ := resp.Request.Context().Value(requestContext).(*RequestContextValue)
-	logEntry.Debug("calling response munger")
-	err := munger(resp, contextValue, templates)
-	if err != nil {
-		logEntry.WithField("munger", munger).WithError(err).Error("munger failed")
-		return fmt.Errorf("munger failed for %s: %w", requestPath, err)
+	for _, munger := range mungers {
+		logEntry.Debug("calling response munger")
+		err := munger(resp, contextValue, templates)
+		if err != nil {
+			logEntry.WithField("munger", munger).WithError(err).Error("munger failed")
+			return fmt.Errorf("munger failed for %s: %w", requestPath, err)
+		}
 	}
 	return nil
 }
@@ -306,14 +458,6 @@ func (m *requestMunger) CanonicalizeContainerID(req *http.Request, id string, di
 	return &body, nil
 }
 
-// dockerSpec contains information about the embedded OpenAPI specification for
-// docker.
-var dockerSpec struct {
-	Info struct {
-		Version semver.Version
-	}
-}
-
 // requestMungerFunc is a munger for an incoming request; it also receives an
 // arbitrary mapping that can be reused in the response munger, as well as a
 // mapping of any path templating patterns that were matched.
@@ -324,59 +468,164 @@ type requestMungerFunc func(*http.Request, *RequestContextValue, map[string]stri
 // as well as a mapping of any path templating patterns that were matched.
 type responseMungerFunc func(*http.Response, *RequestContextValue, map[string]string) error
 
-// mungerMethodMapping is a helper structure to find a munger given an API path,
-// specialized for a given HTTP method (GET, POST, etc.).
+// mungerMethodMapping is a helper structure to find mungers given an API path,
+// specialized for a given HTTP method (GET, POST, etc.).  Multiple mungers
+// for the same API path are called in the order they were registered.
 // This should only be written to during init(), at which point it's protected
 // by the lock on mungerMapping.
 type mungerMethodMapping struct {
 	// requests that are simple (have no path templating)
-	requests map[string]requestMungerFunc
-	// requestPatterns are requests that involve path templating
-	requestPatterns map[*regexp.Regexp]requestMungerFunc
+	requests map[string][]versionedMunger[requestMungerFunc]
+	// requestPatterns are requests that involve path templating; the key is
+	// the API path, as each call to convertPattern returns a new regexp.
+	requestPatterns map[string]*mungerPattern[requestMungerFunc]
 	// responses that are simple (have no path templating)
-	responses map[string]responseMungerFunc
+	responses map[string][]versionedMunger[responseMungerFunc]
 	// responsePatterns are responses that involve path templating
-	responsePatterns map[*regexp.Regexp]responseMungerFunc
-}
-
-// getRequestMunger gets the munger to use for this request, as well as the
-// path templating elements (if relevant for the munger).
-func (m *mungerMethodMapping) getRequestMunger(apiPath string) (requestMungerFunc, map[string]string) {
-	if munger, ok := m.requests[apiPath]; ok {
-		return munger, nil
-	}
-	for pattern, munger := range m.requestPatterns {
-		matches := pattern.FindStringSubmatch(apiPath)
-		if matches != nil {
-			names := pattern.SubexpNames()
-			results := make(map[string]string)
-			for i, name := range names {
-				results[name] = matches[i]
-			}
-			return munger, results
+	responsePatterns map[string]*mungerPattern[responseMungerFunc]
+}
+
+// versionedMunger is a munger, along with the API versions it applies to; an
+// API path template may only exist in some versions of the API.
+type versionedMunger[T any] struct {
+	munger T
+	// versions the munger applies to; nil for all versions.
+	versions *semver.Constraints
+}
+
+type mungerPattern[T any] struct {
+	pattern *regexp.Regexp
+	mungers []versionedMunger[T]
+}
+
+// mungersForVersion returns the mungers that apply to the given API version.
+func mungersForVersion[T any](entries []versionedMunger[T], version *semver.Version) []T {
+	var result []T
+	for _, entry := range entries {
+		if entry.versions == nil || entry.versions.Check(version) {
+			result = append(result, entry.munger)
 		}
 	}
-	return nil, nil
+	return result
 }
 
-func (m *mungerMethodMapping) getResponseMunger(apiPath string) (responseMungerFunc, map[string]string) {
-	if munger, ok := m.responses[apiPath]; ok {
-		return munger, nil
+// getMungers gets the mungers to use for the API path in the given API
+// version, as well as the path templating elements (if relevant for the
+// mungers).
+func getMungers[T any](simple map[string][]versionedMunger[T], patterns map[string]*mungerPattern[T], apiPath string, version *semver.Version) ([]T, map[string]string) {
+	if mungers := mungersForVersion(simple[apiPath], version); len(mungers) > 0 {
+		return mungers, nil
 	}
-	for pattern, munger := range m.responsePatterns {
-		matches := pattern.FindStringSubmatch(apiPath)
-		if matches != nil {
-			names := pattern.SubexpNames()
-			results := make(map[string]string)
-			for i, name := range names {
-				results[name] = matches[i]
+	for _, entry := range patterns {
+		if results := matchPattern(entry.pattern, apiPath); results != nil {
+			if mungers := mungersForVersion(entry.mungers, version); len(mungers) > 0 {
+				return mungers, results
 			}
-			return munger, results
 		}
 	}
 	return nil, nil
 }
 
+// getRequestMungers gets the mungers to use for this request, as well as the
+// path templating elements (if relevant for the mungers).
+func (m *mungerMethodMapping) getRequestMungers(apiPath string, version *semver.Version) ([]requestMungerFunc, map[string]string) {
+	return getMungers(m.requests, m.requestPatterns, apiPath, version)
+}
+
+func (m *mungerMethodMapping) getResponseMungers(apiPath string, version *semver.Version) ([]responseMungerFunc, map[string]string) {
+	return getMungers(m.responses, m.responsePatterns, apiPath, version)
+}
+
+// matchPattern returns the path templating elements if the API path matches
+// the pattern, or nil otherwise.
+func matchPattern(pattern *regexp.Regexp, apiPath string) map[string]string {
+	matches := pattern.FindStringSubmatch(apiPath)
+	if matches == nil {
+		return nil
+	}
+	results := make(map[string]string)
+	for i, name := range pattern.SubexpNames() {
+		results[name] = matches[i]
+	}
+	return results
+}
+
+// routeTable contains API path templates by HTTP method.  It is used to
+// describe requests, e.g. in the audit log.
+type routeTable struct {
+	sync.RWMutex
+	// exact are the templates without path templating.
+	exact map[string]map[string]bool
+	// patterns are the templates with path templating, sorted by template.
+	patterns map[string][]routePattern
+}
+
+type routePattern struct {
+	template string
+	pattern  *regexp.Regexp
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{
+		exact:    make(map[string]map[string]bool),
+		patterns: make(map[string][]routePattern),
+	}
+}
+
+// add an API path template to the route table.
+func (t *routeTable) add(method, apiPath string) {
+	t.Lock()
+	defer t.Unlock()
+	pattern := convertPattern(apiPath)
+	if pattern == nil {
+		if t.exact[method] == nil {
+			t.exact[method] = make(map[string]bool)
+		}
+		t.exact[method][apiPath] = true
+		return
+	}
+	patterns := t.patterns[method]
+	index := sort.Search(len(patterns), func(i int) bool { return patterns[i].template >= apiPath })
+	if index < len(patterns) && patterns[index].template == apiPath {
+		return
+	}
+	patterns = append(patterns, routePattern{})
+	copy(patterns[index+1:], patterns[index:])
+	patterns[index] = routePattern{template: apiPath, pattern: pattern}
+	t.patterns[method] = patterns
+}
+
+// match returns the API path template matching the (unversioned) request
+// path, and the path templating elements; the template is empty if the path
+// is unknown.
+func (t *routeTable) match(method, apiPath string) (string, map[string]string) {
+	t.RLock()
+	defer t.RUnlock()
+	if t.exact[method][apiPath] {
+		return apiPath, nil
+	}
+	for _, route := range t.patterns[method] {
+		if results := matchPattern(route.pattern, apiPath); results != nil {
+			return route.template, results
+		}
+	}
+	return "", nil
+}
+
+// mungerRoutes are the API path templates of the registered mungers.
+var mungerRoutes = newRouteTable()
+
+// matchRoute returns the API path template matching the (unversioned)
+// request path in the docker API spec for the given version (nil for
+// unversioned requests), falling back to the templates of the registered
+// mungers; see routeTable.match.
+func matchRoute(version *semver.Version, method, apiPath string) (string, map[string]string) {
+	if template, templates := specForVersion(version).routes.match(method, apiPath); template != "" {
+		return template, templates
+	}
+	return mungerRoutes.match(method, apiPath)
+}
+
 // mungerMapping contains mungers that will handle particular API endpoints.
 var mungerMapping struct {
 	sync.RWMutex
@@ -409,10 +658,10 @@ func getMungerMethodMapping(method string) *mungerMethodMapping {
 	mapping, ok := mungerMapping.mungers[method]
 	if !ok {
 		mapping = &mungerMethodMapping{
-			requests:         make(map[string]requestMungerFunc),
-			requestPatterns:  make(map[*regexp.Regexp]requestMungerFunc),
-			responses:        make(map[string]responseMungerFunc),
-			responsePatterns: make(map[*regexp.Regexp]responseMungerFunc),
+			requests:         make(map[string][]versionedMunger[requestMungerFunc]),
+			requestPatterns:  make(map[string]*mungerPattern[requestMungerFunc]),
+			responses:        make(map[string][]versionedMunger[responseMungerFunc]),
+			responsePatterns: make(map[string]*mungerPattern[responseMungerFunc]),
 		}
 		mungerMapping.mungers[method] = mapping
 	}
@@ -420,33 +669,70 @@ func getMungerMethodMapping(method string) *mungerMethodMapping {
 }
 
 func RegisterRequestMunger(method, apiPath string, munger requestMungerFunc) {
+	RegisterVersionedRequestMunger("", method, apiPath, munger)
+}
+
+// RegisterVersionedRequestMunger registers a request munger that is only used
+// for the API versions matching the given constraint (e.g. ">= 1.44"), for
+// API path templates that differ between versions of the API.  An empty
+// constraint matches all versions.
+func RegisterVersionedRequestMunger(versions, method, apiPath string, munger requestMungerFunc) {
+	entry := versionedMunger[requestMungerFunc]{munger: munger, versions: mustParseVersions(versions)}
+	mungerRoutes.add(method, apiPath)
 	mungerMapping.Lock()
 	defer mungerMapping.Unlock()
 
 	mapping := getMungerMethodMapping(method)
 	if pattern := convertPattern(apiPath); pattern == nil {
-		mapping.requests[apiPath] = munger
+		mapping.requests[apiPath] = append(mapping.requests[apiPath], entry)
+	} else if existing, ok := mapping.requestPatterns[apiPath]; ok {
+		existing.mungers = append(existing.mungers, entry)
 	} else {
-		mapping.requestPatterns[pattern] = munger
+		mapping.requestPatterns[apiPath] = &mungerPattern[requestMungerFunc]{pattern: pattern, mungers: []versionedMunger[requestMungerFunc]{entry}}
 	}
 }
 
 func RegisterResponseMunger(method, apiPath string, munger responseMungerFunc) {
+	RegisterVersionedResponseMunger("", method, apiPath, munger)
+}
+
+// RegisterVersionedResponseMunger registers a response munger that is only
+// used for the API versions matching the given constraint; see
+// RegisterVersionedRequestMunger.
+func RegisterVersionedResponseMunger(versions, method, apiPath string, munger responseMungerFunc) {
+	entry := versionedMunger[responseMungerFunc]{munger: munger, versions: mustParseVersions(versions)}
+	mungerRoutes.add(method, apiPath)
 	mungerMapping.Lock()
 	defer mungerMapping.Unlock()
 
 	mapping := getMungerMethodMapping(method)
 	if pattern := convertPattern(apiPath); pattern == nil {
-		mapping.responses[apiPath] = munger
+		mapping.responses[apiPath] = append(mapping.responses[apiPath], entry)
+	} else if existing, ok := mapping.responsePatterns[apiPath]; ok {
+		existing.mungers = append(existing.mungers, entry)
 	} else {
-		mapping.responsePatterns[pattern] = munger
+		mapping.responsePatterns[apiPath] = &mungerPattern[responseMungerFunc]{pattern: pattern, mungers: []versionedMunger[responseMungerFunc]{entry}}
+	}
+}
+
+// mustParseVersions parses a munger version constraint; mungers are
+// registered during init(), so an invalid constraint is a programming error.
+func mustParseVersions(versions string) *semver.Constraints {
+	if versions == "" {
+		return nil
+	}
+	constraints, err := semver.NewConstraint(versions)
+	if err != nil {
+		panic(fmt.Sprintf("invalid munger API version constraint %q: %s", versions, err))
 	}
+	return constraints
 }
 
 func init() {
 	mungerMapping.mungers = make(map[string]*mungerMethodMapping)
-	err := json.Unmarshal(models.SwaggerJSON, &dockerSpec)
+	spec, err := parseAPISpec(models.SwaggerJSON)
 	if err != nil {
 		panic("could not parse embedded spec version")
 	}
+	registerAPISpec(spec)
 }
@@ -39,6 +39,10 @@ type ReverseProxy struct {
 	// Director allows modification of the outgoing request before forwarding
 	Director func(*http.Request)
 
+	// Intercept is called after the Director; if it returns a response, that
+	// response is sent to the client and the request is not forwarded.
+	Intercept func(*http.Request) *http.Response
+
 	// ModifyResponse enables post-processing of the backend response
 	ModifyResponse func(*http.Response) error
 
@@ -110,6 +114,21 @@ func (proxy *ReverseProxy) forwardRequest(w http.ResponseWriter, r *http.Request
 	if proxy.Director != nil {
 		proxy.Director(newReq)
 	}
+	if proxy.Intercept != nil {
+		if response := proxy.Intercept(newReq); response != nil {
+			defer response.Body.Close()
+			for key, values := range response.Header {
+				for _, value := range values {
+					w.Header().Add(key, value)
+				}
+			}
+			w.WriteHeader(response.StatusCode)
+			if _, err := io.Copy(w, response.Body); err != nil {
+				proxy.logf("failed to send the intercepted response to the client: %v", err)
+			}
+			return
+		}
+	}
 	// Prevent automatic connection closure
 	newReq.Close = false
 